@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/MrJamesThe3rd/finny/internal/auth"
+)
+
+// runForEachUser runs job once per user every interval, until ctx is done.
+// Each run gets a context carrying the user's ID, as requests do. A failure
+// for one user is logged and does not stop the others.
+func runForEachUser(ctx context.Context, name string, interval time.Duration, users *auth.Service, job func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		list, err := users.ListUsers(ctx)
+		if err != nil {
+			slog.Error("job: failed to list users", "job", name, "error", err)
+			continue
+		}
+
+		for _, u := range list {
+			if err := job(auth.WithUserID(ctx, u.ID)); err != nil {
+				slog.Error("job failed", "job", name, "user_id", u.ID, "error", err)
+			}
+		}
+	}
+}
@@ -12,9 +12,13 @@ import (
 	"github.com/MrJamesThe3rd/finny/internal/config"
 	"github.com/MrJamesThe3rd/finny/internal/database"
 	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/document/crypt"
 	"github.com/MrJamesThe3rd/finny/internal/document/local"
 	"github.com/MrJamesThe3rd/finny/internal/document/paperless"
+	"github.com/MrJamesThe3rd/finny/internal/document/s3"
+	"github.com/MrJamesThe3rd/finny/internal/document/sftp"
 	docStore "github.com/MrJamesThe3rd/finny/internal/document/store"
+	"github.com/MrJamesThe3rd/finny/internal/document/webdav"
 	"github.com/MrJamesThe3rd/finny/internal/export"
 	finnyHttp "github.com/MrJamesThe3rd/finny/internal/http"
 	authHandler "github.com/MrJamesThe3rd/finny/internal/http/auth"
@@ -22,10 +26,15 @@ import (
 	exportHandler "github.com/MrJamesThe3rd/finny/internal/http/export"
 	importHandler "github.com/MrJamesThe3rd/finny/internal/http/importcsv"
 	matchingHandler "github.com/MrJamesThe3rd/finny/internal/http/matching"
+	merchantHandler "github.com/MrJamesThe3rd/finny/internal/http/merchant"
+	reconcileHandler "github.com/MrJamesThe3rd/finny/internal/http/reconcile"
 	txHandler "github.com/MrJamesThe3rd/finny/internal/http/transaction"
 	"github.com/MrJamesThe3rd/finny/internal/importer"
 	"github.com/MrJamesThe3rd/finny/internal/matching"
 	matchingStore "github.com/MrJamesThe3rd/finny/internal/matching/store"
+	"github.com/MrJamesThe3rd/finny/internal/merchant"
+	merchantStore "github.com/MrJamesThe3rd/finny/internal/merchant/store"
+	"github.com/MrJamesThe3rd/finny/internal/reconcile"
 	"github.com/MrJamesThe3rd/finny/internal/transaction"
 	txStore "github.com/MrJamesThe3rd/finny/internal/transaction/store"
 )
@@ -47,14 +56,28 @@ func main() {
 	registry := document.NewRegistry()
 	registry.Register("paperless", paperless.NewFromConfig)
 	registry.Register("local", local.NewFromConfig)
+	registry.Register("s3", s3.NewFromConfig)
+	registry.Register("sftp", sftp.NewFromConfig)
+	registry.Register("webdav", webdav.NewFromConfig)
+
+	if cfg.Documents.MasterKey != "" {
+		masterKey, err := crypt.ParseMasterKey(cfg.Documents.MasterKey)
+		if err != nil {
+			slog.Error("invalid document master key", "error", err)
+			os.Exit(1)
+		}
+		registry.SetEncryption(crypt.NewEncryption(masterKey))
+	}
 
 	var (
 		authService        = auth.NewService(authStore.New(db), cfg.Auth.JWTSecret, cfg.Auth.AccessTokenExpiry, cfg.Auth.RefreshTokenExpiry)
 		transactionService = transaction.NewService(txStore.New(db))
-		matchingService    = matching.NewService(matchingStore.New(db))
+		merchantService    = merchant.NewService(merchantStore.New(db))
+		matchingService    = matching.NewService(matchingStore.New(db), merchantService)
 		importService      = importer.NewService()
 		documentService    = document.NewService(docStore.New(db), registry)
 		exportService      = export.NewService(transactionService, documentService)
+		reconcileService   = reconcile.NewService(transactionService, documentService)
 	)
 
 	if cfg.Paperless.BaseURL != "" {
@@ -65,13 +88,53 @@ func main() {
 		}
 	}
 
+	if interval := cfg.Documents.ReplicationInterval; interval > 0 {
+		go runForEachUser(context.Background(), "replicate documents", interval, authService, func(ctx context.Context) error {
+			report, err := documentService.Replicate(ctx)
+			if err != nil {
+				return err
+			}
+
+			if report.Copied > 0 || report.Repaired > 0 || report.Relocated > 0 || len(report.Failures) > 0 {
+				slog.Info("replicated documents", "user_id", auth.UserID(ctx), "documents", report.Documents,
+					"copied", report.Copied, "repaired", report.Repaired, "relocated", report.Relocated,
+					"failed", len(report.Failures))
+			}
+
+			for _, f := range report.Failures {
+				slog.Warn("failed to replicate document", "document_id", f.DocumentID, "backend_id", f.BackendID, "error", f.Err)
+			}
+
+			return nil
+		})
+	}
+
+	if interval := cfg.Documents.HealthCheckInterval; interval > 0 {
+		go runForEachUser(context.Background(), "check backends", interval, authService, func(ctx context.Context) error {
+			backends, err := documentService.CheckBackends(ctx)
+			if err != nil {
+				return err
+			}
+
+			for _, b := range backends {
+				if b.Enabled && !b.Health.Healthy() {
+					slog.Warn("backend unhealthy", "user_id", auth.UserID(ctx), "backend", b.Name, "error", b.Health.Error)
+				}
+			}
+
+			return nil
+		})
+	}
+
 	var (
 		authH        = authHandler.NewHandler(authService)
-		transactionH = txHandler.NewHandler(transactionService)
-		importH      = importHandler.NewHandler(importService, transactionService, matchingService)
-		matchingH    = matchingHandler.NewHandler(matchingService)
+		transactionH = txHandler.NewHandler(transactionService, matchingService, merchantService)
+		importH      = importHandler.NewHandler(importService, transactionService, matchingService, merchantService)
+		matchingH    = matchingHandler.NewHandler(matchingService, transactionService)
 		exportH      = exportHandler.NewHandler(exportService)
 		documentH    = docHandler.NewHandler(documentService, transactionService, registry)
+		merchantH    = merchantHandler.NewHandler(merchantService)
+		reconcileH   = reconcileHandler.NewHandler(reconcileService)
 	)
 
 	router := finnyHttp.New(
@@ -80,6 +143,8 @@ func main() {
 		matchingH,
 		exportH,
 		documentH,
+		merchantH,
+		reconcileH,
 		authH,
 		finnyHttp.Config{
 			JWTSecret:         cfg.Auth.JWTSecret,
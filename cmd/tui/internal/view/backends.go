@@ -4,11 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
 
 	"github.com/MrJamesThe3rd/finny/internal/document"
 )
@@ -18,8 +23,12 @@ type backendState int
 const (
 	backendStateList backendState = iota
 	backendStateAdding
+	backendStateDraining // choosing where to move the documents
 )
 
+// drainPollInterval is how often a running drain's progress is refreshed.
+const drainPollInterval = 500 * time.Millisecond
+
 type BackendsModel struct {
 	CommonModel
 	docService *document.Service
@@ -31,12 +40,42 @@ type BackendsModel struct {
 	status   string
 	err      error
 
+	// drainID is the backend whose drain progress is shown in status.
+	drainID uuid.UUID
+
 	// Form bindings
-	formType    string
-	formName    string
-	formBaseURL string
-	formToken   string
-	formPath    string
+	formType        string
+	formName        string
+	formBaseURL     string
+	formToken       string
+	formPath        string
+	formEncrypt     bool
+	formKeyTemplate string
+	formTarget      string
+
+	// S3 form bindings
+	formBucket    string
+	formAccessKey string
+	formSecretKey string
+	formEndpoint  string
+	formRegion    string
+	formPrefix    string
+	formPathStyle bool
+
+	// WebDAV form bindings
+	formURL      string
+	formUsername string
+	formPassword string
+	formFolder   string
+
+	// SFTP form bindings
+	formHost         string
+	formPort         string
+	formSFTPUser     string
+	formSFTPPassword string
+	formKeyFile      string
+	formBaseDir      string
+	formHostKey      string
 }
 
 func NewBackendsModel(baseCtx context.Context, docSvc *document.Service) BackendsModel {
@@ -50,11 +89,11 @@ func (m BackendsModel) Title() string { return "Manage Backends" }
 
 func (m BackendsModel) ShortHelp() string {
 	switch m.state {
-	case backendStateAdding:
+	case backendStateAdding, backendStateDraining:
 		return "Esc: cancel | Enter/Tab: navigate form"
 	}
 
-	return "Esc: back | a: add | d: delete | space: toggle enabled"
+	return "Esc: back | a: add | d: delete | space: toggle enabled | t: test | m: move documents | r: replicate"
 }
 
 func (m BackendsModel) Init() tea.Cmd {
@@ -81,6 +120,35 @@ func (m BackendsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = backendStateList
 		m.form = nil
 		return m, m.loadBackendsCmd()
+
+	case replicateMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+		} else {
+			m.status = fmt.Sprintf("Replicated %d documents: %d copied, %d repaired, %d relocated, %d failed.",
+				msg.report.Documents, msg.report.Copied, msg.report.Repaired, msg.report.Relocated, len(msg.report.Failures))
+		}
+		return m, nil
+
+	case backendTestMsg:
+		switch {
+		case msg.err != nil:
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+		case msg.health.Healthy():
+			m.status = fmt.Sprintf("%s works.", msg.name)
+		default:
+			m.status = fmt.Sprintf("%s failed: %s", msg.name, msg.health.Error)
+		}
+		return m, m.loadBackendsCmd()
+
+	case drainMsg:
+		return m.updateDrain(msg)
+
+	case drainPollMsg:
+		if msg.backendID != m.drainID {
+			return m, nil
+		}
+		return m, m.getDrainCmd(msg.backendID)
 	}
 
 	switch m.state {
@@ -88,6 +156,8 @@ func (m BackendsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateList(msg)
 	case backendStateAdding:
 		return m.updateAdding(msg)
+	case backendStateDraining:
+		return m.updateChoosingTarget(msg)
 	}
 
 	return m, nil
@@ -116,6 +186,16 @@ func (m BackendsModel) updateList(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.deleteBackendCmd()
 	case " ":
 		return m, m.toggleEnabledCmd()
+	case "t":
+		if m.cursor < len(m.backends) {
+			m.status = fmt.Sprintf("Testing %s…", m.backends[m.cursor].Name)
+		}
+		return m, m.testBackendCmd()
+	case "m":
+		return m.startChoosingTarget()
+	case "r":
+		m.status = "Replicating…"
+		return m, m.replicateCmd()
 	}
 
 	return m, nil
@@ -127,6 +207,26 @@ func (m BackendsModel) startAdding() (tea.Model, tea.Cmd) {
 	m.formBaseURL = ""
 	m.formToken = ""
 	m.formPath = ""
+	m.formEncrypt = false
+	m.formKeyTemplate = ""
+	m.formBucket = ""
+	m.formAccessKey = ""
+	m.formSecretKey = ""
+	m.formEndpoint = ""
+	m.formRegion = ""
+	m.formPrefix = ""
+	m.formPathStyle = false
+	m.formURL = ""
+	m.formUsername = ""
+	m.formPassword = ""
+	m.formFolder = ""
+	m.formHost = ""
+	m.formPort = ""
+	m.formSFTPUser = ""
+	m.formSFTPPassword = ""
+	m.formKeyFile = ""
+	m.formBaseDir = ""
+	m.formHostKey = ""
 
 	m.form = huh.NewForm(
 		huh.NewGroup(
@@ -136,6 +236,9 @@ func (m BackendsModel) startAdding() (tea.Model, tea.Cmd) {
 				Options(
 					huh.NewOption("Local filesystem", "local"),
 					huh.NewOption("Paperless-ngx", "paperless"),
+					huh.NewOption("S3 / MinIO", "s3"),
+					huh.NewOption("WebDAV / Nextcloud", "webdav"),
+					huh.NewOption("SFTP", "sftp"),
 				).
 				Value(&m.formType),
 
@@ -150,6 +253,18 @@ func (m BackendsModel) startAdding() (tea.Model, tea.Cmd) {
 					}
 					return nil
 				}),
+
+			huh.NewConfirm().
+				Key("encrypt").
+				Title("Encrypt documents?").
+				Description("Needs DOCUMENTS_MASTERKEY. Not available for Paperless.").
+				Value(&m.formEncrypt).
+				Validate(func(encrypt bool) error {
+					if encrypt && m.formType == "paperless" {
+						return document.ErrEncryptionUnsupported
+					}
+					return nil
+				}),
 		),
 		huh.NewGroup(
 			huh.NewInput().
@@ -176,6 +291,204 @@ func (m BackendsModel) startAdding() (tea.Model, tea.Cmd) {
 					return nil
 				}),
 		).WithHideFunc(func() bool { return m.formType != "local" }),
+		huh.NewGroup(
+			huh.NewInput().
+				Key("bucket").
+				Title("Bucket").
+				Value(&m.formBucket).
+				Validate(func(s string) error {
+					if m.formType == "s3" && strings.TrimSpace(s) == "" {
+						return fmt.Errorf("bucket is required for S3 backend")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("access_key_id").
+				Title("Access Key ID").
+				Value(&m.formAccessKey).
+				Validate(func(s string) error {
+					if m.formType == "s3" && strings.TrimSpace(s) == "" {
+						return fmt.Errorf("access_key_id is required for S3 backend")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("secret_access_key").
+				Title("Secret Access Key").
+				EchoMode(huh.EchoModePassword).
+				Value(&m.formSecretKey).
+				Validate(func(s string) error {
+					if m.formType == "s3" && strings.TrimSpace(s) == "" {
+						return fmt.Errorf("secret_access_key is required for S3 backend")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("endpoint").
+				Title("Endpoint").
+				Description("Empty for AWS S3.").
+				Placeholder("http://minio:9000").
+				Value(&m.formEndpoint),
+
+			huh.NewInput().
+				Key("region").
+				Title("Region").
+				Placeholder("us-east-1").
+				Value(&m.formRegion),
+
+			huh.NewInput().
+				Key("prefix").
+				Title("Key Prefix").
+				Placeholder("finny/").
+				Value(&m.formPrefix),
+
+			huh.NewConfirm().
+				Key("path_style").
+				Title("Path-style addressing?").
+				Description("MinIO and most self-hosted services need it.").
+				Value(&m.formPathStyle),
+		).WithHideFunc(func() bool { return m.formType != "s3" }),
+		huh.NewGroup(
+			huh.NewInput().
+				Key("url").
+				Title("WebDAV URL").
+				Placeholder("https://cloud.example.com/remote.php/dav/files/alice").
+				Value(&m.formURL).
+				Validate(func(s string) error {
+					if m.formType == "webdav" && strings.TrimSpace(s) == "" {
+						return fmt.Errorf("URL is required for WebDAV backend")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("username").
+				Title("Username").
+				Value(&m.formUsername),
+
+			huh.NewInput().
+				Key("password").
+				Title("App Password").
+				Description("For Nextcloud, create an app password rather than using the account password.").
+				EchoMode(huh.EchoModePassword).
+				Value(&m.formPassword),
+
+			huh.NewInput().
+				Key("folder").
+				Title("Folder").
+				Placeholder("Documents/Invoices").
+				Value(&m.formFolder),
+		).WithHideFunc(func() bool { return m.formType != "webdav" }),
+		huh.NewGroup(
+			huh.NewInput().
+				Key("host").
+				Title("Host").
+				Placeholder("nas.example.com").
+				Value(&m.formHost).
+				Validate(func(s string) error {
+					if m.formType == "sftp" && strings.TrimSpace(s) == "" {
+						return fmt.Errorf("host is required for SFTP backend")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("port").
+				Title("Port").
+				Placeholder("22").
+				Value(&m.formPort).
+				Validate(func(s string) error {
+					if m.formType != "sftp" || strings.TrimSpace(s) == "" {
+						return nil
+					}
+					if port, err := strconv.Atoi(strings.TrimSpace(s)); err != nil || port < 1 || port > 65535 {
+						return fmt.Errorf("port must be a number between 1 and 65535")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("sftp_user").
+				Title("User").
+				Value(&m.formSFTPUser).
+				Validate(func(s string) error {
+					if m.formType == "sftp" && strings.TrimSpace(s) == "" {
+						return fmt.Errorf("user is required for SFTP backend")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("private_key_file").
+				Title("Private Key File").
+				Description("PEM key to log in with; leave empty to use a password.").
+				Placeholder("~/.ssh/id_ed25519").
+				Value(&m.formKeyFile).
+				Validate(func(s string) error {
+					if m.formType != "sftp" || strings.TrimSpace(s) == "" {
+						return nil
+					}
+					_, err := os.Stat(expandHome(strings.TrimSpace(s)))
+					return err
+				}),
+
+			huh.NewInput().
+				Key("sftp_password").
+				Title("Password").
+				EchoMode(huh.EchoModePassword).
+				Value(&m.formSFTPPassword).
+				Validate(func(s string) error {
+					if m.formType == "sftp" && s == "" && strings.TrimSpace(m.formKeyFile) == "" {
+						return fmt.Errorf("a private key file or a password is required")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("base_dir").
+				Title("Base Directory").
+				Placeholder("documents").
+				Value(&m.formBaseDir).
+				Validate(func(s string) error {
+					if m.formType == "sftp" && strings.TrimSpace(s) == "" {
+						return fmt.Errorf("base directory is required for SFTP backend")
+					}
+					return nil
+				}),
+
+			huh.NewInput().
+				Key("host_key").
+				Title("Host Key").
+				Description("Run `ssh-keyscan <host>` to obtain it.").
+				Placeholder("ssh-ed25519 AAAA...").
+				Value(&m.formHostKey).
+				Validate(func(s string) error {
+					if m.formType == "sftp" && strings.TrimSpace(s) == "" {
+						return fmt.Errorf("host key is required for SFTP backend")
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool { return m.formType != "sftp" }),
+		huh.NewGroup(
+			huh.NewInput().
+				Key("key_template").
+				Title("Key Template").
+				Description("Optional, e.g. {{year}}/{{month}}/{{date}}_{{merchant}}_{{amount}}.{{ext}}").
+				Value(&m.formKeyTemplate).
+				Validate(func(s string) error {
+					if m.formType == "paperless" || strings.TrimSpace(s) == "" {
+						return nil
+					}
+					if m.formEncrypt {
+						return document.ErrKeyTemplateEncrypted
+					}
+					_, err := document.ParseKeyTemplate(strings.TrimSpace(s))
+					return err
+				}),
+		).WithHideFunc(func() bool { return m.formType == "paperless" }),
 	).WithWidth(55).WithShowHelp(false)
 
 	m.state = backendStateAdding
@@ -204,9 +517,136 @@ func (m BackendsModel) updateAdding(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, m.createBackendCmd()
 }
 
+// startChoosingTarget asks where to move the selected backend's documents.
+func (m BackendsModel) startChoosingTarget() (tea.Model, tea.Cmd) {
+	if m.cursor < 0 || m.cursor >= len(m.backends) {
+		return m, nil
+	}
+
+	source := m.backends[m.cursor]
+
+	var options []huh.Option[string]
+	for _, b := range m.backends {
+		if b.ID != source.ID {
+			options = append(options, huh.NewOption(fmt.Sprintf("%s (%s)", b.Name, b.Type), b.ID.String()))
+		}
+	}
+
+	if len(options) == 0 {
+		m.status = "Add another backend to move the documents to first."
+		return m, nil
+	}
+
+	m.formTarget = options[0].Value
+
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Key("target").
+				Title(fmt.Sprintf("Move every document off %s to", source.Name)).
+				Description("The backend is disabled first; its copies are deleted once moved.").
+				Options(options...).
+				Value(&m.formTarget),
+		),
+	).WithWidth(55).WithShowHelp(false)
+
+	m.state = backendStateDraining
+
+	return m, m.form.Init()
+}
+
+func (m BackendsModel) updateChoosingTarget(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		if keyMsg.Type == tea.KeyEsc {
+			m.state = backendStateList
+			m.form = nil
+			return m, nil
+		}
+	}
+
+	form, cmd := m.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.form = f
+	}
+
+	if m.form.State != huh.StateCompleted {
+		return m, cmd
+	}
+
+	targetID, err := uuid.Parse(m.form.GetString("target"))
+	m.state = backendStateList
+	m.form = nil
+
+	if err != nil {
+		m.status = fmt.Sprintf("Error: %v", err)
+		return m, nil
+	}
+
+	return m, m.startDrainCmd(m.backends[m.cursor].ID, targetID)
+}
+
+// updateDrain shows a drain's progress and keeps polling while it runs.
+func (m BackendsModel) updateDrain(msg drainMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.status = fmt.Sprintf("Error: %v", msg.err)
+		return m, nil
+	}
+
+	d := msg.drain
+	m.drainID = d.BackendID
+	m.status = m.drainStatus(d)
+
+	if d.Running {
+		return m, tea.Tick(drainPollInterval, func(time.Time) tea.Msg {
+			return drainPollMsg{backendID: d.BackendID}
+		})
+	}
+
+	// The backend was disabled, and may now be empty enough to delete.
+	return m, m.loadBackendsCmd()
+}
+
+func (m BackendsModel) drainStatus(d *document.Drain) string {
+	source, target := m.backendName(d.BackendID), m.backendName(d.TargetID)
+
+	switch {
+	case d.Running:
+		return fmt.Sprintf("Moving %s → %s: %d/%d moved, %d failed…", source, target, d.Moved, d.Total, d.Failed)
+	case d.FinishedAt != nil:
+		return fmt.Sprintf("Moved all %d documents from %s to %s; it can now be deleted.", d.Moved, source, target)
+	default:
+		return fmt.Sprintf("Stopped moving %s → %s at %d/%d, %d failed: %s. Press m to resume.",
+			source, target, d.Moved, d.Total, d.Failed, d.LastError)
+	}
+}
+
+func (m BackendsModel) backendName(id uuid.UUID) string {
+	for _, b := range m.backends {
+		if b.ID == id {
+			return b.Name
+		}
+	}
+
+	return id.String()
+}
+
+// healthLabel summarizes a backend's latest health check for the list.
+func healthLabel(h *document.BackendHealth) string {
+	switch {
+	case h == nil:
+		return lipgloss.NewStyle().Faint(true).Render("not checked")
+	case h.Healthy():
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("46")).
+			Render("ok " + h.CheckedAt.Format("02 Jan 15:04"))
+	default:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("196")).
+			Render(fmt.Sprintf("failed %s: %s", h.CheckedAt.Format("02 Jan 15:04"), h.Error))
+	}
+}
+
 func (m BackendsModel) View() string {
 	switch m.state {
-	case backendStateAdding:
+	case backendStateAdding, backendStateDraining:
 		if m.form != nil {
 			return lipgloss.NewStyle().Padding(1).Render(m.form.View())
 		}
@@ -243,7 +683,7 @@ func (m BackendsModel) viewList() string {
 				line = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(line)
 			}
 
-			sb.WriteString(line + "\n")
+			sb.WriteString(line + "  " + healthLabel(b.Health) + "\n")
 		}
 	}
 
@@ -265,6 +705,28 @@ type backendSaveMsg struct {
 	err error
 }
 
+type backendTestMsg struct {
+	name   string
+	health *document.BackendHealth
+	err    error
+}
+
+type drainMsg struct {
+	drain *document.Drain
+	err   error
+}
+
+type drainPollMsg struct {
+	backendID uuid.UUID
+}
+
+type replicateMsg struct {
+	report *document.ReplicationReport
+	err    error
+}
+
+const replicateTimeout = 10 * time.Minute
+
 func (m BackendsModel) loadBackendsCmd() tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := DbCtx(m.baseCtx)
@@ -281,6 +743,26 @@ func (m BackendsModel) createBackendCmd() tea.Cmd {
 	baseURL := m.form.GetString("base_url")
 	token := m.form.GetString("token")
 	path := m.form.GetString("path")
+	encrypt := m.form.GetBool("encrypt")
+	keyTemplate := strings.TrimSpace(m.form.GetString("key_template"))
+	bucket := m.form.GetString("bucket")
+	accessKey := m.form.GetString("access_key_id")
+	secretKey := m.form.GetString("secret_access_key")
+	endpoint := strings.TrimSpace(m.form.GetString("endpoint"))
+	region := strings.TrimSpace(m.form.GetString("region"))
+	prefix := strings.TrimSpace(m.form.GetString("prefix"))
+	pathStyle := m.form.GetBool("path_style")
+	davURL := strings.TrimSpace(m.form.GetString("url"))
+	username := m.form.GetString("username")
+	password := m.form.GetString("password")
+	folder := strings.TrimSpace(m.form.GetString("folder"))
+	host := strings.TrimSpace(m.form.GetString("host"))
+	port := strings.TrimSpace(m.form.GetString("port"))
+	sftpUser := strings.TrimSpace(m.form.GetString("sftp_user"))
+	sftpPassword := m.form.GetString("sftp_password")
+	keyFile := strings.TrimSpace(m.form.GetString("private_key_file"))
+	baseDir := strings.TrimSpace(m.form.GetString("base_dir"))
+	hostKey := strings.TrimSpace(m.form.GetString("host_key"))
 	docSvc := m.docService
 	baseCtx := m.baseCtx
 
@@ -288,21 +770,73 @@ func (m BackendsModel) createBackendCmd() tea.Cmd {
 		ctx, cancel := DbCtx(baseCtx)
 		defer cancel()
 
-		var config json.RawMessage
-		var err error
+		var fields map[string]any
 
 		switch backendType {
 		case "paperless":
-			config, err = json.Marshal(map[string]string{
+			fields = map[string]any{
 				"base_url": baseURL,
 				"token":    token,
-			})
+			}
 		case "local":
-			config, err = json.Marshal(map[string]string{
+			fields = map[string]any{
 				"base_path": path,
-			})
+			}
+		case "s3":
+			fields = map[string]any{
+				"bucket":            bucket,
+				"access_key_id":     accessKey,
+				"secret_access_key": secretKey,
+				"path_style":        pathStyle,
+			}
+			if endpoint != "" {
+				fields["endpoint"] = endpoint
+			}
+			if region != "" {
+				fields["region"] = region
+			}
+			if prefix != "" {
+				fields["prefix"] = prefix
+			}
+		case "webdav":
+			fields = map[string]any{
+				"url":      davURL,
+				"username": username,
+				"password": password,
+			}
+			if folder != "" {
+				fields["folder"] = folder
+			}
+		case "sftp":
+			fields = map[string]any{
+				"host":     host,
+				"user":     sftpUser,
+				"base_dir": baseDir,
+				"host_key": hostKey,
+			}
+			if port != "" {
+				// Checked by the form.
+				fields["port"], _ = strconv.Atoi(port)
+			}
+			if sftpPassword != "" {
+				fields["password"] = sftpPassword
+			}
+			// The config holds the key itself, not its path.
+			if keyFile != "" {
+				key, err := os.ReadFile(expandHome(keyFile))
+				if err != nil {
+					return backendSaveMsg{err: fmt.Errorf("reading private key: %w", err)}
+				}
+				fields["private_key"] = string(key)
+			}
+		}
+
+		fields["encrypt"] = encrypt
+		if keyTemplate != "" && backendType != "paperless" {
+			fields["key_template"] = keyTemplate
 		}
 
+		config, err := json.Marshal(fields)
 		if err != nil {
 			return backendSaveMsg{err: fmt.Errorf("building config: %w", err)}
 		}
@@ -318,6 +852,10 @@ func (m BackendsModel) createBackendCmd() tea.Cmd {
 			return backendSaveMsg{err: err}
 		}
 
+		// Record whether the config works, for the list to show; a failing
+		// backend is still saved.
+		_, _ = docSvc.CheckBackend(baseCtx, cfg.ID)
+
 		return backendSaveMsg{}
 	}
 }
@@ -364,3 +902,74 @@ func (m BackendsModel) toggleEnabledCmd() tea.Cmd {
 		return backendSaveMsg{}
 	}
 }
+
+func (m BackendsModel) replicateCmd() tea.Cmd {
+	docSvc := m.docService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(baseCtx, replicateTimeout)
+		defer cancel()
+
+		report, err := docSvc.Replicate(ctx)
+		return replicateMsg{report: report, err: err}
+	}
+}
+
+func (m BackendsModel) startDrainCmd(backendID, targetID uuid.UUID) tea.Cmd {
+	docSvc := m.docService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(baseCtx)
+		defer cancel()
+
+		// The drain itself keeps running after ctx is done.
+		drain, err := docSvc.StartDrain(ctx, backendID, targetID)
+		return drainMsg{drain: drain, err: err}
+	}
+}
+
+func (m BackendsModel) getDrainCmd(backendID uuid.UUID) tea.Cmd {
+	docSvc := m.docService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(baseCtx)
+		defer cancel()
+
+		drain, err := docSvc.GetDrain(ctx, backendID)
+		return drainMsg{drain: drain, err: err}
+	}
+}
+
+func (m BackendsModel) testBackendCmd() tea.Cmd {
+	if m.cursor < 0 || m.cursor >= len(m.backends) {
+		return nil
+	}
+
+	b := m.backends[m.cursor]
+	docSvc := m.docService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		// The check has its own, longer timeout.
+		health, err := docSvc.CheckBackend(baseCtx, b.ID)
+		return backendTestMsg{name: b.Name, health: health, err: err}
+	}
+}
+
+// expandHome replaces a leading "~/" in path with the user's home directory.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(home, rest)
+}
@@ -0,0 +1,272 @@
+package view
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/MrJamesThe3rd/finny/internal/reconcile"
+)
+
+const (
+	// invoiceCandidates is how many transactions are offered per document.
+	invoiceCandidates = 3
+	// invoiceAutoSelectScore preselects suggestions whose best candidate is at
+	// least this likely, so the usual case is a single Enter.
+	invoiceAutoSelectScore = 0.9
+	invoiceVisibleRows     = 15
+)
+
+// InvoiceMatchModel lists inbox documents with the pending transactions they
+// probably pay for, and attaches the selected pairs in bulk.
+type InvoiceMatchModel struct {
+	CommonModel
+	reconcileService *reconcile.Service
+
+	suggestions []reconcile.Suggestion
+	choice      []int // index of the chosen candidate per suggestion
+	selected    []bool
+	cursor      int
+	loading     bool
+	status      string
+}
+
+func NewInvoiceMatchModel(baseCtx context.Context, reconcileSvc *reconcile.Service) InvoiceMatchModel {
+	return InvoiceMatchModel{
+		CommonModel:      CommonModel{baseCtx: baseCtx},
+		reconcileService: reconcileSvc,
+		loading:          true,
+	}
+}
+
+func (m InvoiceMatchModel) Title() string { return "Match Invoices" }
+
+func (m InvoiceMatchModel) ShortHelp() string {
+	return "Esc: back | Space: toggle | a: toggle all | ←/→: other candidate | Enter: attach selected | r: refresh"
+}
+
+func (m InvoiceMatchModel) Init() tea.Cmd {
+	return m.loadSuggestionsCmd()
+}
+
+func (m InvoiceMatchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case loadSuggestionsMsg:
+		m.loading = false
+
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+
+		m.suggestions = msg.suggestions
+		m.choice = make([]int, len(msg.suggestions))
+		m.selected = make([]bool, len(msg.suggestions))
+
+		for i, s := range msg.suggestions {
+			m.selected[i] = s.Candidates[0].Score >= invoiceAutoSelectScore
+		}
+
+		m.cursor = min(m.cursor, max(0, len(m.suggestions)-1))
+
+		return m, nil
+
+	case acceptMatchesMsg:
+		m.status = msg.status
+		m.loading = true
+
+		return m, m.loadSuggestionsCmd()
+
+	case tea.KeyMsg:
+		return m.updateKeys(msg)
+	}
+
+	return m, nil
+}
+
+func (m InvoiceMatchModel) updateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		return m, Back
+	case "r":
+		m.loading = true
+		m.status = ""
+
+		return m, m.loadSuggestionsCmd()
+	}
+
+	if m.loading || len(m.suggestions) == 0 {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.suggestions)-1 {
+			m.cursor++
+		}
+	case "left", "h":
+		if m.choice[m.cursor] > 0 {
+			m.choice[m.cursor]--
+		}
+	case "right", "l":
+		if m.choice[m.cursor] < len(m.suggestions[m.cursor].Candidates)-1 {
+			m.choice[m.cursor]++
+		}
+	case " ":
+		m.selected[m.cursor] = !m.selected[m.cursor]
+	case "a":
+		// Select all unless everything is already selected, then clear.
+		all := !slices.Contains(m.selected, false)
+		for i := range m.selected {
+			m.selected[i] = !all
+		}
+	case "enter":
+		return m, m.acceptCmd()
+	}
+
+	return m, nil
+}
+
+func (m InvoiceMatchModel) View() string {
+	var sb strings.Builder
+
+	if m.status != "" {
+		sb.WriteString(lipgloss.NewStyle().Faint(true).Render(m.status) + "\n\n")
+	}
+
+	switch {
+	case m.loading:
+		sb.WriteString("Looking for matching transactions...")
+		return lipgloss.NewStyle().Padding(1).Render(sb.String())
+	case len(m.suggestions) == 0:
+		sb.WriteString("No suggestions. Inbox documents need an invoice total to be matched.")
+		return lipgloss.NewStyle().Padding(1).Render(sb.String())
+	}
+
+	selected := 0
+	for _, ok := range m.selected {
+		if ok {
+			selected++
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("%d of %d documents selected:\n\n", selected, len(m.suggestions)))
+
+	start := max(0, m.cursor-invoiceVisibleRows+1)
+	end := min(len(m.suggestions), start+invoiceVisibleRows)
+
+	for i := start; i < end; i++ {
+		s := m.suggestions[i]
+		c := s.Candidates[m.choice[i]]
+
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+
+		check := "[ ]"
+		if m.selected[i] {
+			check = "[✓]"
+		}
+
+		line := fmt.Sprintf("%s%s %s → %s %s %s (%.0f%%)", cursor, check, s.Document.Filename,
+			FormatDate(c.Transaction.Date), FormatAmountSigned(c.Transaction.Amount, c.Transaction.Type),
+			c.Transaction.Description, c.Score*100)
+		if i == m.cursor {
+			line = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(line)
+		}
+
+		sb.WriteString(line + "\n")
+	}
+
+	cur := m.suggestions[m.cursor]
+	inv := cur.Document.Invoice
+	detail := fmt.Sprintf("Invoice: %.2f", float64(*inv.Total)/100)
+
+	if inv.Date != nil {
+		detail += " on " + FormatDate(*inv.Date)
+	}
+
+	if inv.IssuerName != "" {
+		detail += " from " + inv.IssuerName
+	}
+
+	detail += fmt.Sprintf(" | candidate %d of %d", m.choice[m.cursor]+1, len(cur.Candidates))
+
+	sb.WriteString("\n" + lipgloss.NewStyle().Faint(true).Render(detail))
+
+	return lipgloss.NewStyle().Padding(1).Render(sb.String())
+}
+
+// Messages
+
+type loadSuggestionsMsg struct {
+	suggestions []reconcile.Suggestion
+	err         error
+}
+
+type acceptMatchesMsg struct {
+	status string
+}
+
+func (m InvoiceMatchModel) loadSuggestionsCmd() tea.Cmd {
+	svc := m.reconcileService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(baseCtx)
+		defer cancel()
+
+		suggestions, err := svc.Suggest(ctx, invoiceCandidates)
+
+		return loadSuggestionsMsg{suggestions: suggestions, err: err}
+	}
+}
+
+func (m InvoiceMatchModel) acceptCmd() tea.Cmd {
+	var matches []reconcile.Match
+
+	for i, s := range m.suggestions {
+		if m.selected[i] {
+			matches = append(matches, reconcile.Match{
+				DocumentID:    s.Document.ID,
+				TransactionID: s.Candidates[m.choice[i]].Transaction.ID,
+			})
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil
+	}
+
+	svc := m.reconcileService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(baseCtx)
+		defer cancel()
+
+		var errs []error
+
+		for _, res := range svc.Accept(ctx, matches) {
+			if res.Err != nil {
+				errs = append(errs, res.Err)
+			}
+		}
+
+		status := fmt.Sprintf("Attached %d of %d documents.", len(matches)-len(errs), len(matches))
+		if len(errs) > 0 {
+			status += fmt.Sprintf(" First error: %v", errs[0])
+		}
+
+		return acceptMatchesMsg{status: status}
+	}
+}
@@ -25,6 +25,7 @@ const (
 	listStateBrowse listState = iota
 	listStateEdit
 	listStateFilePick
+	listStatePreview
 )
 
 type ListModel struct {
@@ -47,6 +48,11 @@ type ListModel struct {
 	err     error
 	status  string
 
+	preview *documentPreview
+	// imageShown is set once a preview image was drawn, which the list then
+	// clears.
+	imageShown bool
+
 	// Form bindings
 	formDesc      string
 	formDocAction string
@@ -105,9 +111,11 @@ func (m ListModel) ShortHelp() string {
 		return "Navigate form | Esc: cancel"
 	case listStateFilePick:
 		return "Esc: cancel | Enter: select file"
+	case listStatePreview:
+		return "Esc: back"
 	}
 
-	return "Esc: back | e: edit | s: status filter | d: date filter | r: refresh"
+	return "Esc: back | e: edit | v: view document | s: status filter | d: date filter | r: refresh"
 }
 
 func (m ListModel) Init() tea.Cmd {
@@ -141,6 +149,20 @@ func (m ListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, m.loadTxsCmd()
 
+	case previewMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error loading document: %v", msg.err)
+			return m, nil
+		}
+
+		m.status = ""
+		m.preview = msg.preview
+		m.imageShown = m.imageShown || msg.preview.image != ""
+		m.state = listStatePreview
+		m.table.Blur()
+
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.table.SetHeight(msg.Height - 10)
 		return m, nil
@@ -153,6 +175,14 @@ func (m ListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateEdit(msg)
 	case listStateFilePick:
 		return m.updateFilePick(msg)
+	case listStatePreview:
+		if keyMsg, ok := msg.(tea.KeyMsg); ok && (keyMsg.Type == tea.KeyEsc || keyMsg.String() == "v") {
+			m.state = listStateBrowse
+			m.preview = nil
+			m.table.Focus()
+		}
+
+		return m, nil
 	}
 
 	return m, nil
@@ -169,6 +199,20 @@ func (m ListModel) updateBrowse(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.loadTxsCmd()
 		case "e":
 			return m.enterEditMode()
+		case "v":
+			idx := m.table.Cursor()
+			if idx < 0 || idx >= len(m.txs) {
+				return m, nil
+			}
+
+			if m.txs[idx].DocumentID == nil {
+				m.status = "No document attached."
+				return m, nil
+			}
+
+			m.status = "Loading document..."
+
+			return m, loadPreviewCmd(m.baseCtx, m.docService, *m.txs[idx].DocumentID)
 		case "s":
 			m.statusFilterIdx = (m.statusFilterIdx + 1) % 5
 			m.applyFilter()
@@ -300,6 +344,10 @@ func (m ListModel) View() string {
 		)
 	}
 
+	if m.state == listStatePreview && m.preview != nil {
+		return lipgloss.NewStyle().Padding(1).Render(m.preview.View())
+	}
+
 	if m.loading {
 		return lipgloss.NewStyle().Padding(2).Render("Loading transactions...")
 	}
@@ -317,6 +365,10 @@ func (m ListModel) View() string {
 		activeStyle(dateLabels[m.dateFilterIdx]),
 	)
 
+	if m.imageShown {
+		header = kittyDelete + header
+	}
+
 	tableView := lipgloss.NewStyle().
 		BorderStyle(lipgloss.NormalBorder()).
 		BorderForeground(lipgloss.Color("240")).
@@ -460,13 +512,18 @@ func (m ListModel) saveCmd() tea.Cmd {
 			txCopy.Status = transaction.StatusNoInvoice
 		case "remove":
 			if txCopy.DocumentID != nil {
-				if err := docSvc.Delete(ctx, *txCopy.DocumentID); err != nil {
+				if err := txSvc.RemoveDocument(ctx, txCopy.ID, *txCopy.DocumentID); err != nil {
 					return listSaveMsg{err: err}
 				}
-				if err := txSvc.DetachDocument(ctx, txCopy.ID); err != nil {
+				if err := docSvc.Release(ctx, *txCopy.DocumentID); err != nil {
 					return listSaveMsg{err: err}
 				}
-				txCopy.Status = transaction.StatusPendingInvoice
+				// RemoveDocument derived the status from the remaining documents.
+				fresh, err := txSvc.Get(ctx, txCopy.ID)
+				if err != nil {
+					return listSaveMsg{err: err}
+				}
+				txCopy.Status = fresh.Status
 			}
 		}
 
@@ -507,20 +564,20 @@ func (m ListModel) saveWithFileCmd(filePath string) tea.Cmd {
 		}
 
 		if txCopy.DocumentID != nil {
-			if err := docSvc.Delete(ctx, *txCopy.DocumentID); err != nil {
+			if err := txSvc.RemoveDocument(ctx, txCopy.ID, *txCopy.DocumentID); err != nil {
 				return listSaveMsg{err: err}
 			}
-			if err := txSvc.DetachDocument(ctx, txCopy.ID); err != nil {
+			if err := docSvc.Release(ctx, *txCopy.DocumentID); err != nil {
 				return listSaveMsg{err: err}
 			}
 		}
 
-		doc, err := docSvc.Upload(ctx, filepath.Base(filePath), mimeType, f)
+		doc, err := docSvc.Upload(ctx, filepath.Base(filePath), mimeType, f, keyFields(&txCopy))
 		if err != nil {
 			return listSaveMsg{err: fmt.Errorf("uploading document: %w", err)}
 		}
 
-		if err := txSvc.AttachDocument(ctx, txCopy.ID, doc.ID); err != nil {
+		if err := txSvc.AddDocument(ctx, txCopy.ID, doc.ID, transaction.RoleInvoice); err != nil {
 			return listSaveMsg{err: fmt.Errorf("attaching document: %w", err)}
 		}
 
@@ -529,6 +586,11 @@ func (m ListModel) saveWithFileCmd(filePath string) tea.Cmd {
 			return listSaveMsg{err: err}
 		}
 
+		// A deduplicated upload is the existing document, which key templates
+		// may name after another transaction. Best effort: replication moves
+		// it later otherwise.
+		_ = docSvc.Relocate(ctx, doc.ID)
+
 		return listSaveMsg{}
 	}
 }
@@ -0,0 +1,682 @@
+package view
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/filepicker"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// mappingsExportDir is where the TUI writes mapping exports, alongside transaction exports.
+const mappingsExportDir = "./exports"
+
+// reapplyVisibleRows caps how many pending changes are rendered at once.
+const reapplyVisibleRows = 15
+
+type mappingState int
+
+const (
+	mappingStateList mappingState = iota
+	mappingStateEditing
+	mappingStateFilePick
+	mappingStateTimeframe
+	mappingStateReapply
+)
+
+// mappingItem wraps a mapping to implement list.Item.
+type mappingItem struct {
+	mapping *matching.Mapping
+}
+
+func (i mappingItem) Title() string { return i.mapping.PreferredDescription }
+
+func (i mappingItem) Description() string {
+	desc := fmt.Sprintf("← %s  · %d matches", i.mapping.RawPattern, i.mapping.MatchCount)
+	if i.mapping.LastMatchedAt != nil {
+		desc += ", last " + FormatDate(*i.mapping.LastMatchedAt)
+	}
+
+	return desc
+}
+
+func (i mappingItem) FilterValue() string {
+	return i.mapping.RawPattern + " " + i.mapping.PreferredDescription
+}
+
+type MappingsModel struct {
+	CommonModel
+	txService       *transaction.Service
+	matchingService *matching.Service
+
+	state      mappingState
+	list       list.Model
+	form       *huh.Form
+	filePicker filepicker.Model
+	mappings   []*matching.Mapping
+	selected   *matching.Mapping
+	status     string
+	autoLearn  bool
+
+	// Form field bindings
+	formPattern   string
+	formPreferred string
+
+	// Re-apply dry run: pending description changes and which ones to apply.
+	timeframePicker TimeframePicker
+	changes         []matching.DescriptionChange
+	selectedChanges []bool
+	cursor          int
+}
+
+func NewMappingsModel(baseCtx context.Context, txSvc *transaction.Service, matchSvc *matching.Service) MappingsModel {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Description Mappings"
+	l.SetShowStatusBar(true)
+	l.SetFilteringEnabled(true)
+	l.SetShowHelp(false)
+
+	fp := filepicker.New()
+	fp.CurrentDirectory, _ = os.Getwd()
+	fp.ShowHidden = false
+	fp.DirAllowed = false
+	fp.FileAllowed = true
+	fp.AllowedTypes = []string{".csv", ".json"}
+	fp.SetHeight(15)
+
+	return MappingsModel{
+		CommonModel:     CommonModel{baseCtx: baseCtx},
+		txService:       txSvc,
+		matchingService: matchSvc,
+		list:            l,
+		filePicker:      fp,
+		timeframePicker: NewTimeframePicker(TimeframeThisWeek),
+	}
+}
+
+func (m MappingsModel) Title() string { return "Manage Mappings" }
+
+func (m MappingsModel) ShortHelp() string {
+	switch m.state {
+	case mappingStateEditing:
+		return "Esc: cancel | Enter/Tab: navigate form"
+	case mappingStateFilePick:
+		return "Esc: cancel | Enter: select file"
+	case mappingStateTimeframe:
+		return "Esc: cancel | Enter: select"
+	case mappingStateReapply:
+		return "Esc: cancel | Space: toggle | a: toggle all | Enter: apply selected"
+	}
+
+	return "Esc: back | Enter: edit | d: delete | /: search | x: export CSV | i: import | r: re-apply | a: auto-learn"
+}
+
+func (m MappingsModel) Init() tea.Cmd {
+	return m.loadMappingsCmd()
+}
+
+func (m MappingsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case loadMappingsMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+
+		m.mappings = msg.mappings
+		m.autoLearn = msg.settings.AutoLearn
+		m.refreshListItems()
+
+		return m, nil
+
+	case mappingSaveMsg:
+		m.state = mappingStateList
+		m.form = nil
+
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+			return m, nil
+		}
+
+		m.status = msg.status
+
+		return m, m.loadMappingsCmd()
+
+	case TimeframeSelectedMsg:
+		m.status = "Looking for description changes..."
+
+		return m, m.previewReapplyCmd(msg)
+
+	case reapplyPreviewMsg:
+		if msg.err != nil {
+			m.state = mappingStateList
+			m.status = fmt.Sprintf("Error: %v", msg.err)
+
+			return m, nil
+		}
+
+		if len(msg.changes) == 0 {
+			m.state = mappingStateList
+			m.status = "No transactions would change."
+
+			return m, nil
+		}
+
+		m.changes = msg.changes
+		m.selectedChanges = make([]bool, len(msg.changes))
+		for i := range m.selectedChanges {
+			m.selectedChanges[i] = true
+		}
+		m.cursor = 0
+		m.status = ""
+		m.state = mappingStateReapply
+
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width-4, msg.Height-8)
+		return m, nil
+	}
+
+	switch m.state {
+	case mappingStateList:
+		return m.updateList(msg)
+	case mappingStateEditing:
+		return m.updateEditing(msg)
+	case mappingStateFilePick:
+		return m.updateFilePick(msg)
+	case mappingStateTimeframe:
+		return m.updateTimeframe(msg)
+	case mappingStateReapply:
+		return m.updateReapply(msg)
+	}
+
+	return m, nil
+}
+
+func (m MappingsModel) updateList(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// While the filter input is open every key belongs to the list.
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && m.list.FilterState() != list.Filtering {
+		switch keyMsg.String() {
+		case "esc":
+			if m.list.FilterState() == list.FilterApplied {
+				break // let the list clear the filter
+			}
+
+			return m, Back
+		case "enter", "e":
+			return m.startEditing()
+		case "d":
+			return m, m.deleteMappingCmd()
+		case "x":
+			return m, m.exportCmd()
+		case "i":
+			m.state = mappingStateFilePick
+			return m, m.filePicker.Init()
+		case "a":
+			return m, m.toggleAutoLearnCmd()
+		case "r":
+			m.timeframePicker.Reset()
+			m.state = mappingStateTimeframe
+
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+
+	return m, cmd
+}
+
+func (m MappingsModel) startEditing() (tea.Model, tea.Cmd) {
+	selected, ok := m.list.SelectedItem().(mappingItem)
+	if !ok {
+		return m, nil
+	}
+
+	m.selected = selected.mapping
+	m.formPattern = selected.mapping.RawPattern
+	m.formPreferred = selected.mapping.PreferredDescription
+
+	notEmpty := func(field string) func(string) error {
+		return func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return fmt.Errorf("%s cannot be empty", field)
+			}
+			return nil
+		}
+	}
+
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Key("raw_pattern").
+				Title("Raw Pattern").
+				Description("Matches any raw description containing this text").
+				Value(&m.formPattern).
+				Validate(notEmpty("raw pattern")),
+
+			huh.NewInput().
+				Key("preferred_description").
+				Title("Preferred Description").
+				Value(&m.formPreferred).
+				Validate(notEmpty("preferred description")),
+		),
+	).WithWidth(60).WithShowHelp(false)
+
+	m.state = mappingStateEditing
+
+	return m, m.form.Init()
+}
+
+func (m MappingsModel) updateEditing(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		if keyMsg.Type == tea.KeyEsc {
+			m.state = mappingStateList
+			m.form = nil
+
+			return m, nil
+		}
+	}
+
+	form, cmd := m.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.form = f
+	}
+
+	if m.form.State != huh.StateCompleted {
+		return m, cmd
+	}
+
+	return m, m.saveMappingCmd()
+}
+
+func (m MappingsModel) updateFilePick(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		if keyMsg.Type == tea.KeyEsc {
+			m.state = mappingStateList
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.filePicker, cmd = m.filePicker.Update(msg)
+
+	if didSelect, path := m.filePicker.DidSelectFile(msg); didSelect {
+		m.status = fmt.Sprintf("Importing from %s...", path)
+		return m, m.importCmd(path)
+	}
+
+	return m, cmd
+}
+
+func (m MappingsModel) updateTimeframe(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		if keyMsg.Type == tea.KeyEsc && m.timeframePicker.IsSelecting() {
+			m.state = mappingStateList
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.timeframePicker, cmd = m.timeframePicker.Update(msg)
+
+	return m, cmd
+}
+
+func (m MappingsModel) updateReapply(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.String() {
+	case "esc":
+		m.state = mappingStateList
+		m.changes = nil
+		m.selectedChanges = nil
+
+		return m, nil
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.changes)-1 {
+			m.cursor++
+		}
+	case " ":
+		m.selectedChanges[m.cursor] = !m.selectedChanges[m.cursor]
+	case "a":
+		// Select all unless everything is already selected, then clear.
+		all := !slices.Contains(m.selectedChanges, false)
+		for i := range m.selectedChanges {
+			m.selectedChanges[i] = !all
+		}
+	case "enter":
+		return m, m.applyReapplyCmd()
+	}
+
+	return m, nil
+}
+
+func (m MappingsModel) View() string {
+	switch m.state {
+	case mappingStateEditing:
+		if m.form == nil {
+			return ""
+		}
+
+		return lipgloss.NewStyle().Padding(1).Render(m.form.View())
+
+	case mappingStateFilePick:
+		return lipgloss.NewStyle().Padding(1).Render(
+			"Select a mappings file to import (.csv or .json):\n\n" + m.filePicker.View(),
+		)
+
+	case mappingStateTimeframe:
+		return lipgloss.NewStyle().Padding(1).Render(
+			"Re-apply mappings to existing transactions.\n\n" + m.timeframePicker.View(),
+		)
+
+	case mappingStateReapply:
+		return m.reapplyView()
+	}
+
+	statusLine := ""
+	if m.status != "" {
+		statusLine = lipgloss.NewStyle().Faint(true).Render(m.status) + "\n"
+	}
+
+	if len(m.mappings) == 0 {
+		return lipgloss.NewStyle().Padding(1).Render(statusLine + "No mappings yet. Press 'i' to import some.")
+	}
+
+	return lipgloss.NewStyle().Padding(1).Render(statusLine + m.list.View())
+}
+
+func (m MappingsModel) reapplyView() string {
+	var sb strings.Builder
+
+	selected := 0
+	for _, ok := range m.selectedChanges {
+		if ok {
+			selected++
+		}
+	}
+
+	sb.WriteString(fmt.Sprintf("%d of %d changes selected:\n\n", selected, len(m.changes)))
+
+	// Keep the cursor inside a window of reapplyVisibleRows rows.
+	start := max(0, m.cursor-reapplyVisibleRows+1)
+	end := min(len(m.changes), start+reapplyVisibleRows)
+
+	for i := start; i < end; i++ {
+		c := m.changes[i]
+
+		cursor := "  "
+		if i == m.cursor {
+			cursor = "> "
+		}
+
+		check := "[ ]"
+		if m.selectedChanges[i] {
+			check = "[✓]"
+		}
+
+		line := fmt.Sprintf("%s%s %s  %s → %s", cursor, check,
+			FormatDate(c.Transaction.Date), c.Transaction.Description, c.Description)
+		if i == m.cursor {
+			line = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(line)
+		}
+
+		sb.WriteString(line + "\n")
+	}
+
+	if cur := m.changes[m.cursor]; cur.Transaction.RawDescription != "" {
+		sb.WriteString("\n" + lipgloss.NewStyle().Faint(true).Render(
+			fmt.Sprintf("%s (matched %q)", cur.Transaction.RawDescription, cur.Mapping.RawPattern),
+		))
+	}
+
+	return lipgloss.NewStyle().Padding(1).Render(sb.String())
+}
+
+func (m *MappingsModel) refreshListItems() {
+	m.list.Title = "Description Mappings (auto-learn off)"
+	if m.autoLearn {
+		m.list.Title = "Description Mappings (auto-learn on)"
+	}
+
+	items := make([]list.Item, len(m.mappings))
+	for i, mapping := range m.mappings {
+		items[i] = mappingItem{mapping: mapping}
+	}
+
+	m.list.SetItems(items)
+}
+
+// Messages
+
+type loadMappingsMsg struct {
+	mappings []*matching.Mapping
+	settings *matching.Settings
+	err      error
+}
+
+type mappingSaveMsg struct {
+	status string
+	err    error
+}
+
+type reapplyPreviewMsg struct {
+	changes []matching.DescriptionChange
+	err     error
+}
+
+func (m MappingsModel) loadMappingsCmd() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(m.baseCtx)
+		defer cancel()
+
+		mappings, err := m.matchingService.List(ctx, matching.ListFilter{})
+		if err != nil {
+			return loadMappingsMsg{err: err}
+		}
+
+		settings, err := m.matchingService.Settings(ctx)
+
+		return loadMappingsMsg{mappings: mappings, settings: settings, err: err}
+	}
+}
+
+// toggleAutoLearnCmd flips whether renaming an imported transaction creates a
+// mapping straight away or only proposes one.
+func (m MappingsModel) toggleAutoLearnCmd() tea.Cmd {
+	settings := matching.Settings{AutoLearn: !m.autoLearn}
+	matchSvc := m.matchingService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(baseCtx)
+		defer cancel()
+
+		if err := matchSvc.UpdateSettings(ctx, &settings); err != nil {
+			return mappingSaveMsg{err: err}
+		}
+
+		if settings.AutoLearn {
+			return mappingSaveMsg{status: "Auto-learn on: renaming a transaction creates a mapping."}
+		}
+
+		return mappingSaveMsg{status: "Auto-learn off: renaming a transaction proposes a mapping."}
+	}
+}
+
+func (m MappingsModel) saveMappingCmd() tea.Cmd {
+	updated := *m.selected
+	updated.RawPattern = strings.TrimSpace(m.form.GetString("raw_pattern"))
+	updated.PreferredDescription = strings.TrimSpace(m.form.GetString("preferred_description"))
+	matchSvc := m.matchingService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(baseCtx)
+		defer cancel()
+
+		if err := matchSvc.Update(ctx, &updated); err != nil {
+			return mappingSaveMsg{err: err}
+		}
+
+		return mappingSaveMsg{status: "Saved."}
+	}
+}
+
+func (m MappingsModel) deleteMappingCmd() tea.Cmd {
+	selected, ok := m.list.SelectedItem().(mappingItem)
+	if !ok {
+		return nil
+	}
+
+	id := selected.mapping.ID
+	matchSvc := m.matchingService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(baseCtx)
+		defer cancel()
+
+		if err := matchSvc.Delete(ctx, id); err != nil {
+			return mappingSaveMsg{err: err}
+		}
+
+		return mappingSaveMsg{status: "Deleted."}
+	}
+}
+
+func (m MappingsModel) exportCmd() tea.Cmd {
+	mappings := m.mappings
+
+	return func() tea.Msg {
+		if err := os.MkdirAll(mappingsExportDir, 0o755); err != nil {
+			return mappingSaveMsg{err: fmt.Errorf("creating export directory: %w", err)}
+		}
+
+		path := filepath.Join(mappingsExportDir, fmt.Sprintf("mappings_%s.csv", time.Now().Format("20060102")))
+
+		f, err := os.Create(path)
+		if err != nil {
+			return mappingSaveMsg{err: fmt.Errorf("creating export file: %w", err)}
+		}
+		defer f.Close()
+
+		if err := matching.Encode(f, matching.FormatCSV, mappings); err != nil {
+			return mappingSaveMsg{err: fmt.Errorf("writing export: %w", err)}
+		}
+
+		return mappingSaveMsg{status: fmt.Sprintf("Exported %d mappings to %s.", len(mappings), path)}
+	}
+}
+
+func (m MappingsModel) importCmd(path string) tea.Cmd {
+	matchSvc := m.matchingService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		f, err := os.Open(path)
+		if err != nil {
+			return mappingSaveMsg{err: fmt.Errorf("opening file: %w", err)}
+		}
+		defer f.Close()
+
+		format := matching.FormatJSON
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			format = matching.FormatCSV
+		}
+
+		mappings, err := matching.Decode(f, format)
+		if err != nil {
+			return mappingSaveMsg{err: err}
+		}
+
+		ctx, cancel := context.WithTimeout(baseCtx, importTimeout)
+		defer cancel()
+
+		n, err := matchSvc.Import(ctx, mappings)
+		if err != nil {
+			return mappingSaveMsg{err: err}
+		}
+
+		return mappingSaveMsg{status: fmt.Sprintf("Imported %d mappings.", n)}
+	}
+}
+
+func (m MappingsModel) previewReapplyCmd(tf TimeframeSelectedMsg) tea.Cmd {
+	txSvc := m.txService
+	matchSvc := m.matchingService
+	baseCtx := m.baseCtx
+
+	filter := transaction.ListFilter{}
+	if !tf.All {
+		filter.StartDate = &tf.Start
+		filter.EndDate = &tf.End
+	}
+
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(baseCtx, importTimeout)
+		defer cancel()
+
+		txs, err := txSvc.List(ctx, filter)
+		if err != nil {
+			return reapplyPreviewMsg{err: err}
+		}
+
+		changes, err := matchSvc.PreviewReapply(ctx, txs)
+
+		return reapplyPreviewMsg{changes: changes, err: err}
+	}
+}
+
+func (m MappingsModel) applyReapplyCmd() tea.Cmd {
+	descriptions := make(map[uuid.UUID]string)
+
+	var applied []matching.DescriptionChange
+
+	for i, c := range m.changes {
+		if m.selectedChanges[i] {
+			descriptions[c.Transaction.ID] = c.Description
+			applied = append(applied, c)
+		}
+	}
+
+	txSvc := m.txService
+	matchSvc := m.matchingService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(baseCtx)
+		defer cancel()
+
+		if err := txSvc.UpdateDescriptions(ctx, descriptions); err != nil {
+			return mappingSaveMsg{err: err}
+		}
+
+		// Usage statistics are informational; the descriptions are already saved.
+		_ = matchSvc.RecordReapplied(ctx, applied)
+
+		return mappingSaveMsg{status: fmt.Sprintf("Updated %d transactions.", len(descriptions))}
+	}
+}
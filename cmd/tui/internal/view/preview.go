@@ -0,0 +1,230 @@
+package view
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+// previewTimeout bounds loading a preview, which downloads the document the
+// first time.
+const previewTimeout = time.Minute
+
+const (
+	// previewImageID identifies the preview image to the terminal, so drawing
+	// it again replaces it.
+	previewImageID = 4711
+	previewRows    = 20
+	previewMaxCols = 60
+)
+
+// graphicsProtocol is how the terminal draws images.
+type graphicsProtocol int
+
+const (
+	graphicsNone graphicsProtocol = iota
+	graphicsKitty
+	// graphicsSixel terminals get the document's details only: sixel images
+	// move the cursor and cannot be placed without breaking the TUI's layout.
+	graphicsSixel
+)
+
+// detectGraphics tells how the terminal draws images, from the environment.
+// FINNY_GRAPHICS=kitty, =sixel or =none overrides the detection. Terminals
+// that speak both protocols are taken as kitty ones.
+func detectGraphics() graphicsProtocol {
+	switch os.Getenv("FINNY_GRAPHICS") {
+	case "kitty":
+		return graphicsKitty
+	case "sixel":
+		return graphicsSixel
+	case "none":
+		return graphicsNone
+	}
+
+	term := os.Getenv("TERM")
+
+	if os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty" {
+		return graphicsKitty
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "WezTerm", "ghostty":
+		return graphicsKitty
+	}
+
+	if strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.Contains(term, "sixel") ||
+		os.Getenv("WT_SESSION") != "" {
+		return graphicsSixel
+	}
+
+	return graphicsNone
+}
+
+// kittyImage returns the escape sequences drawing a PNG at the cursor, scaled
+// to cols × rows cells, without moving the cursor.
+func kittyImage(pngData []byte, cols, rows int) string {
+	const chunkSize = 4096
+
+	payload := base64.StdEncoding.EncodeToString(pngData)
+
+	var sb strings.Builder
+
+	for first := true; first || payload != ""; first = false {
+		chunk := payload[:min(chunkSize, len(payload))]
+		payload = payload[len(chunk):]
+
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+
+		if first {
+			fmt.Fprintf(&sb, "\x1b_Ga=T,f=100,i=%d,p=1,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", previewImageID, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&sb, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+
+	return sb.String()
+}
+
+// kittyDelete is the escape sequence removing the preview image. Images stay
+// on screen when the text around them is redrawn.
+var kittyDelete = fmt.Sprintf("\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", previewImageID)
+
+// documentPreview is a document's details, and its preview when the
+// terminal can draw it.
+type documentPreview struct {
+	doc   *document.Document
+	image string // the preview drawn in previewRows rows; "" if none
+	note  string // why there is no image
+}
+
+type previewMsg struct {
+	preview *documentPreview
+	err     error
+}
+
+// loadPreviewCmd loads the document and, if the terminal draws images, its
+// preview.
+func loadPreviewCmd(baseCtx context.Context, docSvc *document.Service, documentID uuid.UUID) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(baseCtx, previewTimeout)
+		defer cancel()
+
+		doc, err := docSvc.Get(ctx, documentID)
+		if err != nil {
+			return previewMsg{err: err}
+		}
+
+		p := &documentPreview{doc: doc}
+
+		switch detectGraphics() {
+		case graphicsNone:
+			p.note = "This terminal cannot draw images; set FINNY_GRAPHICS=kitty if it supports the kitty protocol."
+			return previewMsg{preview: p}
+		case graphicsSixel:
+			p.note = "Sixel images cannot be shown here; set FINNY_GRAPHICS=kitty if the terminal also supports the kitty protocol."
+			return previewMsg{preview: p}
+		}
+
+		data, err := docSvc.Preview(ctx, documentID)
+		if err != nil {
+			p.note = fmt.Sprintf("No preview: %v", err)
+			return previewMsg{preview: p}
+		}
+
+		// The kitty protocol takes PNG, not JPEG.
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			p.note = fmt.Sprintf("No preview: %v", err)
+			return previewMsg{preview: p}
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			p.note = fmt.Sprintf("No preview: %v", err)
+			return previewMsg{preview: p}
+		}
+
+		// Cells are about twice as high as they are wide.
+		b := img.Bounds()
+		rows := previewRows
+		cols := min(previewMaxCols, max(1, 2*rows*b.Dx()/b.Dy()))
+
+		p.image = kittyImage(buf.Bytes(), cols, rows) + strings.Repeat(" ", cols) +
+			strings.Repeat("\n"+strings.Repeat(" ", cols), rows-1)
+
+		return previewMsg{preview: p}
+	}
+}
+
+// View lays the preview out next to the document's details.
+func (p *documentPreview) View() string {
+	doc := p.doc
+	inv := doc.Invoice
+
+	lines := []string{
+		lipgloss.NewStyle().Bold(true).Render(doc.Filename),
+		"",
+		"Type:    " + doc.MIMEType,
+	}
+
+	if doc.Size > 0 {
+		lines = append(lines, "Size:    "+formatSize(doc.Size))
+	}
+
+	lines = append(lines, "Added:   "+FormatDate(doc.CreatedAt))
+
+	if inv.Number != "" {
+		lines = append(lines, "Number:  "+inv.Number)
+	}
+	if inv.IssuerName != "" || inv.IssuerNIF != "" {
+		lines = append(lines, "Issuer:  "+strings.TrimSpace(inv.IssuerName+" "+inv.IssuerNIF))
+	}
+	if inv.Date != nil {
+		lines = append(lines, "Issued:  "+FormatDate(*inv.Date))
+	}
+	if inv.Total != nil {
+		lines = append(lines, fmt.Sprintf("Total:   %.2f", float64(*inv.Total)/100))
+	}
+	if inv.VAT != nil {
+		lines = append(lines, fmt.Sprintf("VAT:     %.2f", float64(*inv.VAT)/100))
+	}
+
+	details := strings.Join(lines, "\n")
+
+	if p.image == "" {
+		if p.note != "" {
+			details += "\n\n" + lipgloss.NewStyle().Faint(true).Render(p.note)
+		}
+
+		return details
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, p.image, "   ", details)
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
@@ -22,6 +22,12 @@ import (
 	"github.com/MrJamesThe3rd/finny/internal/transaction"
 )
 
+// txSuggestions is how many classifier suggestions the edit form shows per field.
+const txSuggestions = 3
+
+// prefillConfidence is the classifier confidence needed to pre-fill the description.
+const prefillConfidence = 0.6
+
 type txState int
 
 const (
@@ -29,6 +35,7 @@ const (
 	txStateList
 	txStateEditing
 	txStateFilePick
+	txStateLearnConfirm
 )
 
 // txItem wraps a transaction to implement list.Item.
@@ -87,6 +94,13 @@ type TransactionsModel struct {
 	// Form field bindings
 	formDesc      string
 	formDocAction string
+	formLearn     bool
+
+	// proposal is the mapping offered after a description edit, awaiting confirmation.
+	proposal *matching.Proposal
+
+	// prediction holds classifier suggestions for the transaction being edited.
+	prediction matching.Prediction
 }
 
 func NewTransactionsModel(baseCtx context.Context, txSvc *transaction.Service, matchSvc *matching.Service, docSvc *document.Service) TransactionsModel {
@@ -126,6 +140,8 @@ func (m TransactionsModel) ShortHelp() string {
 		return "Esc: cancel | Enter/Tab: navigate form"
 	case txStateFilePick:
 		return "Esc: cancel | Enter: select file"
+	case txStateLearnConfirm:
+		return "Esc: skip | Enter: confirm"
 	}
 
 	return ""
@@ -173,8 +189,27 @@ func (m TransactionsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.status = "Saved."
 		m.state = txStateList
 
+		if p := msg.proposal; p != nil {
+			if p.Created {
+				m.status = fmt.Sprintf("Saved. Learned mapping %q → %q.", p.RawPattern, p.PreferredDescription)
+				return m, m.loadTxsCmd()
+			}
+
+			return m.startLearnConfirm(p)
+		}
+
 		return m, m.loadTxsCmd()
 
+	case learnResultMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error saving mapping: %v", msg.err)
+			return m, nil
+		}
+
+		m.status = fmt.Sprintf("Saved. Learned mapping %q → %q.", msg.proposal.RawPattern, msg.proposal.PreferredDescription)
+
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.list.SetSize(msg.Width-4, msg.Height-8)
 		return m, nil
@@ -189,6 +224,8 @@ func (m TransactionsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateEditing(msg)
 	case txStateFilePick:
 		return m.updateFilePick(msg)
+	case txStateLearnConfirm:
+		return m.updateLearnConfirm(msg)
 	}
 
 	return m, nil
@@ -240,19 +277,37 @@ func (m TransactionsModel) startEditing() (tea.Model, tea.Cmd) {
 	m.selectedTx = selected.tx
 	m.formDesc = selected.tx.Description
 	m.formDocAction = "skip"
+	m.prediction = matching.Prediction{}
 
-	// Pre-populate description with suggestion if empty
-	if m.formDesc == "" && selected.tx.RawDescription != "" {
+	if tx := selected.tx; tx.RawDescription != "" {
 		ctx, cancel := DbCtx(m.baseCtx)
 		defer cancel()
 
-		suggestion, _ := m.matchingService.Suggest(ctx, selected.tx.RawDescription)
-		if suggestion != "" {
-			m.formDesc = suggestion
+		// Suggestions are a hint only; the form works without them.
+		m.prediction, _ = m.matchingService.Classify(ctx, matching.Candidate{
+			RawDescription:        tx.RawDescription,
+			NormalizedDescription: tx.NormalizedDescription,
+			Amount:                tx.Amount,
+			Type:                  tx.Type,
+			Date:                  tx.Date,
+			Source:                tx.Source,
+		}, txSuggestions)
+
+		// Pre-populate description: exact mapping first, then a confident
+		// classifier guess, then the raw text.
+		if m.formDesc == "" {
+			suggestion, _ := m.matchingService.Suggest(ctx, tx.Source, tx.RawDescription)
+			if suggestion != "" {
+				m.formDesc = suggestion
+			}
+		}
+
+		if d := m.prediction.Descriptions; m.formDesc == "" && len(d) > 0 && d[0].Confidence >= prefillConfidence {
+			m.formDesc = d[0].Value
 		}
 
 		if m.formDesc == "" {
-			m.formDesc = selected.tx.RawDescription
+			m.formDesc = tx.RawDescription
 		}
 	}
 
@@ -347,6 +402,63 @@ func (m TransactionsModel) updateFilePick(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// startLearnConfirm asks whether to turn a description edit into a mapping.
+// The transaction list reloads in the background meanwhile.
+func (m TransactionsModel) startLearnConfirm(p *matching.Proposal) (tea.Model, tea.Cmd) {
+	m.proposal = p
+	m.formLearn = true
+
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Key("learn").
+				Title("Remember this description?").
+				Description(fmt.Sprintf("Future transactions containing %q will be described as %q.",
+					p.RawPattern, p.PreferredDescription)).
+				Affirmative("Yes").
+				Negative("No").
+				Value(&m.formLearn),
+		),
+	).WithWidth(60).WithShowHelp(false)
+
+	m.state = txStateLearnConfirm
+
+	return m, tea.Batch(m.form.Init(), m.loadTxsCmd())
+}
+
+func (m TransactionsModel) updateLearnConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		if keyMsg.Type == tea.KeyEsc {
+			m.state = txStateList
+			m.form = nil
+			m.proposal = nil
+
+			return m, nil
+		}
+	}
+
+	form, cmd := m.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.form = f
+	}
+
+	if m.form.State != huh.StateCompleted {
+		return m, cmd
+	}
+
+	learn := m.form.GetBool("learn")
+	proposal := m.proposal
+	m.state = txStateList
+	m.form = nil
+	m.proposal = nil
+
+	if !learn {
+		return m, nil
+	}
+
+	return m, m.learnCmd(proposal)
+}
+
 func (m TransactionsModel) View() string {
 	switch m.state {
 	case txStateTimeframe:
@@ -379,6 +491,13 @@ func (m TransactionsModel) View() string {
 		return lipgloss.NewStyle().Padding(1).Render(
 			"Select a file to upload:\n\n" + m.filePicker.View(),
 		)
+
+	case txStateLearnConfirm:
+		if m.form == nil {
+			return ""
+		}
+
+		return lipgloss.NewStyle().Padding(1).Render(m.form.View())
 	}
 
 	return ""
@@ -389,17 +508,41 @@ func (m TransactionsModel) txInfoView() string {
 		return ""
 	}
 
+	info := fmt.Sprintf(
+		"Date: %s  |  Type: %s  |  Amount: %s\nRaw: %s",
+		FormatDate(m.selectedTx.Date),
+		m.selectedTx.Type,
+		FormatAmountSigned(m.selectedTx.Amount, m.selectedTx.Type),
+		m.selectedTx.RawDescription,
+	)
+
+	if m.selectedTx.MerchantName != "" {
+		info += "\nMerchant: " + m.selectedTx.MerchantName
+	}
+
+	if s := formatSuggestions(m.prediction.Descriptions); s != "" {
+		info += "\nLikely: " + s
+	}
+
+	if s := formatSuggestions(m.prediction.Categories); s != "" {
+		info += "\nCategory: " + s
+	}
+
 	return lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
 		BorderForeground(lipgloss.Color("240")).
 		Padding(0, 1).
-		Render(fmt.Sprintf(
-			"Date: %s  |  Type: %s  |  Amount: %s\nRaw: %s",
-			FormatDate(m.selectedTx.Date),
-			m.selectedTx.Type,
-			FormatAmountSigned(m.selectedTx.Amount, m.selectedTx.Type),
-			m.selectedTx.RawDescription,
-		))
+		Render(info)
+}
+
+// formatSuggestions renders suggestions as "Continente 82% · Pingo Doce 10%".
+func formatSuggestions(suggestions []matching.Suggestion) string {
+	parts := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		parts[i] = fmt.Sprintf("%s %.0f%%", s.Value, s.Confidence*100)
+	}
+
+	return strings.Join(parts, " · ")
 }
 
 func (m *TransactionsModel) refreshListItems() {
@@ -438,7 +581,28 @@ func (m TransactionsModel) loadTxsCmd() tea.Cmd {
 }
 
 type saveTxResultMsg struct {
-	err error
+	// proposal is the mapping learned from a description change, if any.
+	proposal *matching.Proposal
+	err      error
+}
+
+type learnResultMsg struct {
+	proposal *matching.Proposal
+	err      error
+}
+
+func (m TransactionsModel) learnCmd(p *matching.Proposal) tea.Cmd {
+	matchSvc := m.matchingService
+	baseCtx := m.baseCtx
+
+	return func() tea.Msg {
+		ctx, cancel := DbCtx(baseCtx)
+		defer cancel()
+
+		err := matchSvc.Learn(ctx, p.RawPattern, p.PreferredDescription)
+
+		return learnResultMsg{proposal: p, err: err}
+	}
 }
 
 // saveTxCmd handles all non-upload actions (skip, no_invoice, remove).
@@ -456,10 +620,7 @@ func (m TransactionsModel) saveTxCmd() tea.Cmd {
 		ctx, cancel := DbCtx(baseCtx)
 		defer cancel()
 
-		if rawDesc != "" && desc != "" {
-			_ = matchSvc.Learn(ctx, rawDesc, desc)
-		}
-
+		oldDesc := txCopy.Description
 		txCopy.Description = desc
 
 		switch action {
@@ -467,13 +628,18 @@ func (m TransactionsModel) saveTxCmd() tea.Cmd {
 			txCopy.Status = transaction.StatusNoInvoice
 		case "remove":
 			if txCopy.DocumentID != nil {
-				if err := docSvc.Delete(ctx, *txCopy.DocumentID); err != nil {
+				if err := txSvc.RemoveDocument(ctx, txCopy.ID, *txCopy.DocumentID); err != nil {
 					return saveTxResultMsg{err: err}
 				}
-				if err := txSvc.DetachDocument(ctx, txCopy.ID); err != nil {
+				if err := docSvc.Release(ctx, *txCopy.DocumentID); err != nil {
 					return saveTxResultMsg{err: err}
 				}
-				txCopy.Status = transaction.StatusPendingInvoice
+				// RemoveDocument derived the status from the remaining documents.
+				fresh, err := txSvc.Get(ctx, txCopy.ID)
+				if err != nil {
+					return saveTxResultMsg{err: err}
+				}
+				txCopy.Status = fresh.Status
 			}
 		case "skip":
 			// Keep current status
@@ -483,7 +649,7 @@ func (m TransactionsModel) saveTxCmd() tea.Cmd {
 			return saveTxResultMsg{err: err}
 		}
 
-		return saveTxResultMsg{}
+		return saveTxResultMsg{proposal: learnFromEdit(ctx, matchSvc, txCopy.Source, rawDesc, oldDesc, desc)}
 	}
 }
 
@@ -501,10 +667,7 @@ func (m TransactionsModel) saveTxWithFileCmd(filePath string) tea.Cmd {
 		ctx, cancel := context.WithTimeout(baseCtx, importTimeout)
 		defer cancel()
 
-		if rawDesc != "" && desc != "" {
-			_ = matchSvc.Learn(ctx, rawDesc, desc)
-		}
-
+		oldDesc := txCopy.Description
 		txCopy.Description = desc
 
 		f, err := os.Open(filePath)
@@ -518,35 +681,56 @@ func (m TransactionsModel) saveTxWithFileCmd(filePath string) tea.Cmd {
 			return saveTxResultMsg{err: fmt.Errorf("seeking file: %w", err)}
 		}
 
-		// If replacing, delete the existing document first.
+		// If replacing, detach the existing document and delete it unless shared.
 		if txCopy.DocumentID != nil {
-			if err := docSvc.Delete(ctx, *txCopy.DocumentID); err != nil {
+			if err := txSvc.RemoveDocument(ctx, txCopy.ID, *txCopy.DocumentID); err != nil {
 				return saveTxResultMsg{err: err}
 			}
-			if err := txSvc.DetachDocument(ctx, txCopy.ID); err != nil {
+			if err := docSvc.Release(ctx, *txCopy.DocumentID); err != nil {
 				return saveTxResultMsg{err: err}
 			}
 		}
 
-		doc, err := docSvc.Upload(ctx, filepath.Base(filePath), mimeType, f)
+		doc, err := docSvc.Upload(ctx, filepath.Base(filePath), mimeType, f, keyFields(&txCopy))
 		if err != nil {
 			return saveTxResultMsg{err: fmt.Errorf("uploading document: %w", err)}
 		}
 
-		if err := txSvc.AttachDocument(ctx, txCopy.ID, doc.ID); err != nil {
+		if err := txSvc.AddDocument(ctx, txCopy.ID, doc.ID, transaction.RoleInvoice); err != nil {
 			return saveTxResultMsg{err: fmt.Errorf("attaching document: %w", err)}
 		}
 
-		// AttachDocument already set status=complete; Update only touches description/status.
+		// AddDocument already set status=complete; Update only touches description/status.
 		txCopy.Status = transaction.StatusComplete
 		if err := txSvc.Update(ctx, &txCopy); err != nil {
 			return saveTxResultMsg{err: err}
 		}
 
-		return saveTxResultMsg{}
+		// A deduplicated upload is the existing document, which key templates
+		// may name after another transaction. Best effort: replication moves
+		// it later otherwise.
+		_ = docSvc.Relocate(ctx, doc.ID)
+
+		return saveTxResultMsg{proposal: learnFromEdit(ctx, matchSvc, txCopy.Source, rawDesc, oldDesc, desc)}
 	}
 }
 
+// keyFields are the fields of t backend key templates name documents after.
+func keyFields(t *transaction.Transaction) *document.TransactionFields {
+	return &document.TransactionFields{Date: t.Date, Amount: t.Amount, Description: t.Description, Merchant: t.MerchantName}
+}
+
+// learnFromEdit derives a mapping from a description change. Failures are
+// ignored: the transaction itself has already been saved.
+func learnFromEdit(ctx context.Context, matchSvc *matching.Service, source, rawDesc, oldDesc, newDesc string) *matching.Proposal {
+	p, err := matchSvc.LearnFromEdit(ctx, source, rawDesc, oldDesc, newDesc)
+	if err != nil {
+		return nil
+	}
+
+	return p
+}
+
 // detectMIMEFromFile sniffs MIME type from the first 512 bytes, falling back to
 // the file extension.
 func detectMIMEFromFile(f *os.File, filePath string) string {
@@ -14,24 +14,32 @@ import (
 	"github.com/MrJamesThe3rd/finny/internal/config"
 	"github.com/MrJamesThe3rd/finny/internal/database"
 	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/document/crypt"
 	"github.com/MrJamesThe3rd/finny/internal/document/local"
 	"github.com/MrJamesThe3rd/finny/internal/document/paperless"
+	"github.com/MrJamesThe3rd/finny/internal/document/s3"
+	"github.com/MrJamesThe3rd/finny/internal/document/sftp"
 	docStore "github.com/MrJamesThe3rd/finny/internal/document/store"
+	"github.com/MrJamesThe3rd/finny/internal/document/webdav"
 	"github.com/MrJamesThe3rd/finny/internal/export"
 	"github.com/MrJamesThe3rd/finny/internal/importer"
 	"github.com/MrJamesThe3rd/finny/internal/matching"
 	matchingStore "github.com/MrJamesThe3rd/finny/internal/matching/store"
+	"github.com/MrJamesThe3rd/finny/internal/merchant"
+	merchantStore "github.com/MrJamesThe3rd/finny/internal/merchant/store"
+	"github.com/MrJamesThe3rd/finny/internal/reconcile"
 	"github.com/MrJamesThe3rd/finny/internal/transaction"
 	txStore "github.com/MrJamesThe3rd/finny/internal/transaction/store"
 )
 
 type model struct {
-	baseCtx         context.Context
-	txService       *transaction.Service
-	matchingService *matching.Service
-	importService   *importer.Service
-	documentService *document.Service
-	exportService   *export.Service
+	baseCtx          context.Context
+	txService        *transaction.Service
+	matchingService  *matching.Service
+	importService    *importer.Service
+	documentService  *document.Service
+	exportService    *export.Service
+	reconcileService *reconcile.Service
 
 	activeView view.View // nil when showing menu
 	width      int
@@ -56,12 +64,26 @@ func initialModel() model {
 	registry := document.NewRegistry()
 	registry.Register("paperless", paperless.NewFromConfig)
 	registry.Register("local", local.NewFromConfig)
+	registry.Register("s3", s3.NewFromConfig)
+	registry.Register("sftp", sftp.NewFromConfig)
+	registry.Register("webdav", webdav.NewFromConfig)
+
+	if cfg.Documents.MasterKey != "" {
+		masterKey, err := crypt.ParseMasterKey(cfg.Documents.MasterKey)
+		if err != nil {
+			slog.Error("invalid document master key", "error", err)
+			os.Exit(1)
+		}
+		registry.SetEncryption(crypt.NewEncryption(masterKey))
+	}
 
 	txSvc := transaction.NewService(txStore.New(db))
-	matchSvc := matching.NewService(matchingStore.New(db))
+	merchantSvc := merchant.NewService(merchantStore.New(db))
+	matchSvc := matching.NewService(matchingStore.New(db), merchantSvc)
 	impSvc := importer.NewService()
 	docSvc := document.NewService(docStore.New(db), registry)
 	expSvc := export.NewService(txSvc, docSvc)
+	recSvc := reconcile.NewService(txSvc, docSvc)
 
 	baseCtx := auth.WithUserID(context.Background(), auth.DefaultUserID)
 
@@ -72,12 +94,13 @@ func initialModel() model {
 	}
 
 	return model{
-		baseCtx:         baseCtx,
-		txService:       txSvc,
-		matchingService: matchSvc,
-		importService:   impSvc,
-		documentService: docSvc,
-		exportService:   expSvc,
+		baseCtx:          baseCtx,
+		txService:        txSvc,
+		matchingService:  matchSvc,
+		importService:    impSvc,
+		documentService:  docSvc,
+		exportService:    expSvc,
+		reconcileService: recSvc,
 	}
 }
 
@@ -119,6 +142,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m.navigate(view.NewExportModel(m.baseCtx, m.exportService))
 			case "5":
 				return m.navigate(view.NewBackendsModel(m.baseCtx, m.documentService))
+			case "6":
+				return m.navigate(view.NewMappingsModel(m.baseCtx, m.txService, m.matchingService))
+			case "7":
+				return m.navigate(view.NewInvoiceMatchModel(m.baseCtx, m.reconcileService))
 			}
 
 			return m, nil
@@ -143,7 +170,9 @@ func (m model) View() string {
 				"2. Manage Transactions\n" +
 				"3. List All Transactions\n" +
 				"4. Export Transactions\n" +
-				"5. Manage Backends\n\n" +
+				"5. Manage Backends\n" +
+				"6. Manage Mappings\n" +
+				"7. Match Invoices\n\n" +
 				"q. Quit",
 		)
 	}
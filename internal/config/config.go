@@ -31,6 +31,18 @@ type Config struct {
 		Token   string `envconfig:"PAPERLESS_TOKEN"`
 	}
 
+	Documents struct {
+		// ReplicationInterval is how often every document is copied to the
+		// enabled backends missing it and its copies verified; 0 disables it.
+		ReplicationInterval time.Duration `envconfig:"DOCUMENTS_REPLICATIONINTERVAL" default:"24h"`
+		// HealthCheckInterval is how often the enabled backends are checked
+		// and their health recorded; 0 disables it.
+		HealthCheckInterval time.Duration `envconfig:"DOCUMENTS_HEALTHCHECKINTERVAL" default:"1h"`
+		// MasterKey wraps the keys of documents stored on backends set to
+		// encrypt: 32 bytes, base64-encoded. Losing it loses those documents.
+		MasterKey string `envconfig:"DOCUMENTS_MASTERKEY"`
+	}
+
 	Auth struct {
 		JWTSecret          string        `envconfig:"AUTH_JWTSECRET"          required:"true"`
 		AccessTokenExpiry  time.Duration `envconfig:"AUTH_ACCESSTOKENEXPIRY"  default:"15m"`
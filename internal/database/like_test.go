@@ -0,0 +1,16 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MrJamesThe3rd/finny/internal/database"
+)
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\%`, database.EscapeLike("100%"))
+	assert.Equal(t, `PT\_50`, database.EscapeLike("PT_50"))
+	assert.Equal(t, `C:\\docs`, database.EscapeLike(`C:\docs`))
+	assert.Equal(t, "Continente", database.EscapeLike("Continente"))
+}
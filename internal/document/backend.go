@@ -1,8 +1,16 @@
 package document
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 // Backend abstracts a single document storage service (Paperless, Google Drive, local FS, etc.).
@@ -20,3 +28,112 @@ type Backend interface {
 	// Delete removes a document by its backend-specific key.
 	Delete(ctx context.Context, key string) error
 }
+
+// KeyedUploader is implemented by backends that can store a document under a
+// key chosen by the caller, which key templates need.
+type KeyedUploader interface {
+	// UploadAt stores the content under key, a relative slash-separated path,
+	// creating missing folders, and returns the key to retrieve it with. Fails
+	// with ErrKeyExists if something is already stored there.
+	UploadAt(ctx context.Context, key string, content io.Reader) (string, error)
+}
+
+// ContentParser is implemented by backends that parse the documents they
+// store, which encrypted documents would defeat.
+type ContentParser interface {
+	// ParsesContent reports whether the backend reads stored documents.
+	ParsesContent() bool
+}
+
+// HealthChecker is implemented by backends that check their own health, for
+// those where writing a probe object is costly or has side effects.
+type HealthChecker interface {
+	// CheckHealth reports why the backend cannot store or serve documents, or
+	// nil if it can.
+	CheckHealth(ctx context.Context) error
+}
+
+// probeFilename and probeContent make up the object CheckHealth writes.
+const (
+	probeFilename = "finny-health-check.txt"
+	probeContent  = "Finny backend health check. Safe to delete."
+)
+
+// CheckHealth checks that b can store and serve documents. Backends that
+// implement HealthChecker check themselves; others are probed.
+func CheckHealth(ctx context.Context, b Backend) error {
+	if hc, ok := b.(HealthChecker); ok {
+		return hc.CheckHealth(ctx)
+	}
+
+	return Probe(ctx, b)
+}
+
+// Probe writes a small object to b, reads it back and deletes it.
+func Probe(ctx context.Context, b Backend) error {
+	key, err := writeProbe(ctx, b)
+	if err != nil {
+		return fmt.Errorf("writing probe: %w", err)
+	}
+
+	readErr := readProbe(ctx, b, key)
+
+	if err := b.Delete(ctx, key); err != nil {
+		if readErr != nil {
+			slog.Warn("failed to delete health check probe", "type", b.Type(), "key", key, "error", err)
+			return readErr
+		}
+		return fmt.Errorf("deleting probe: %w", err)
+	}
+
+	return readErr
+}
+
+// writeProbe stores the probe at the root of backends that take a key, so it
+// leaves none of the dated folders Upload would create. The key is unique so
+// concurrent checks do not read or delete each other's probe.
+func writeProbe(ctx context.Context, b Backend) (string, error) {
+	if ku, ok := b.(KeyedUploader); ok {
+		name := strings.TrimSuffix(probeFilename, ".txt") + "-" + uuid.NewString() + ".txt"
+
+		key, err := ku.UploadAt(ctx, name, strings.NewReader(probeContent))
+		if !errors.Is(err, ErrKeyTemplateUnsupported) {
+			return key, err
+		}
+	}
+
+	return b.Upload(ctx, probeFilename, strings.NewReader(probeContent))
+}
+
+func readProbe(ctx context.Context, b Backend, key string) error {
+	rc, err := b.Download(ctx, key)
+	if err != nil {
+		return fmt.Errorf("reading probe: %w", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, int64(len(probeContent))+1))
+	if err != nil {
+		return fmt.Errorf("reading probe: %w", err)
+	}
+
+	if !bytes.Equal(data, []byte(probeContent)) {
+		return errors.New("reading probe: content differs from what was written")
+	}
+
+	return nil
+}
+
+// ResolveKey joins a slash-separated storage key onto base and rejects keys that
+// resolve to base itself or to anything outside it (e.g. "../x" or "/etc/x").
+// Backends that map keys onto a directory tree use it to keep stored keys from
+// reaching other files.
+func ResolveKey(base, key string) (string, error) {
+	clean := path.Clean(key)
+
+	if key == "" || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || path.IsAbs(clean) {
+		return "", fmt.Errorf("%w: %q", ErrKeyEscapesBase, key)
+	}
+
+	return path.Join(base, clean), nil
+}
@@ -0,0 +1,190 @@
+// Package crypt encrypts documents before they reach a storage backend.
+//
+// Every object is encrypted with its own random AES-256-GCM data key, which is
+// stored with it, wrapped by a master key. An encrypted object is laid out as
+//
+//	"FINNYENC" | version (1 byte) | wrapped key length (2 bytes) | wrapped key | chunks
+//
+// where the content is sealed in chunks of 64 KiB, so objects of any size are
+// encrypted and decrypted as they stream.
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+var (
+	// ErrCorrupt is returned when an encrypted object fails authentication:
+	// it was modified or truncated.
+	ErrCorrupt = errors.New("crypt: encrypted object is corrupt")
+
+	// ErrWrongKey is returned when an object was encrypted under another
+	// master key.
+	ErrWrongKey = errors.New("crypt: object was encrypted with another master key")
+)
+
+const (
+	magic   = "FINNYENC"
+	version = 1
+)
+
+// Encryption wraps backends to encrypt with a master key. It implements
+// document.Encryption.
+type Encryption struct {
+	keys KeyWrapper
+}
+
+func NewEncryption(keys KeyWrapper) *Encryption {
+	return &Encryption{keys: keys}
+}
+
+// Wrap returns b encrypting uploads if encrypt is set. Downloads of encrypted
+// objects are always decrypted; other objects, such as those stored before
+// encryption was turned on, are returned as they are.
+func (e *Encryption) Wrap(b document.Backend, encrypt bool) document.Backend {
+	return &Backend{inner: b, keys: e.keys, encrypt: encrypt}
+}
+
+// Backend encrypts the content of another backend.
+type Backend struct {
+	inner   document.Backend
+	keys    KeyWrapper
+	encrypt bool
+}
+
+func (b *Backend) Type() string { return b.inner.Type() }
+
+func (b *Backend) Upload(ctx context.Context, filename string, content io.Reader) (string, error) {
+	content, err := b.seal(ctx, content)
+	if err != nil {
+		return "", err
+	}
+
+	return b.inner.Upload(ctx, filename, content)
+}
+
+// UploadAt stores under key on backends that implement
+// document.KeyedUploader, and fails with document.ErrKeyTemplateUnsupported
+// on others.
+func (b *Backend) UploadAt(ctx context.Context, key string, content io.Reader) (string, error) {
+	ku, ok := b.inner.(document.KeyedUploader)
+	if !ok {
+		return "", document.ErrKeyTemplateUnsupported
+	}
+
+	content, err := b.seal(ctx, content)
+	if err != nil {
+		return "", err
+	}
+
+	return ku.UploadAt(ctx, key, content)
+}
+
+// seal returns content encrypted under a new data key, or as it is if the
+// backend does not encrypt.
+func (b *Backend) seal(ctx context.Context, content io.Reader) (io.Reader, error) {
+	if !b.encrypt {
+		return content, nil
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("crypt: generating data key: %w", err)
+	}
+
+	wrapped, err := b.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("crypt: wrapping data key: %w", err)
+	}
+
+	if len(wrapped) > math.MaxUint16 {
+		return nil, fmt.Errorf("crypt: wrapped data key of %d bytes is too long", len(wrapped))
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(magic), version)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+
+	return newEncryptReader(header, content, aead), nil
+}
+
+func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	rc, err := b.inner.Download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(rc)
+
+	if head, err := br.Peek(len(magic)); err != nil || !bytes.Equal(head, []byte(magic)) {
+		return readCloser{Reader: br, Closer: rc}, nil
+	}
+
+	aead, err := b.readHeader(ctx, br)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+
+	return newDecryptReader(br, rc, aead), nil
+}
+
+// readHeader reads the header of an encrypted object and returns the cipher
+// of its data key.
+func (b *Backend) readHeader(ctx context.Context, r io.Reader) (cipher.AEAD, error) {
+	head := make([]byte, len(magic)+3)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, ErrCorrupt
+	}
+
+	if v := head[len(magic)]; v != version {
+		return nil, fmt.Errorf("crypt: unsupported version %d", v)
+	}
+
+	wrapped := make([]byte, binary.BigEndian.Uint16(head[len(magic)+1:]))
+	if _, err := io.ReadFull(r, wrapped); err != nil {
+		return nil, ErrCorrupt
+	}
+
+	dataKey, err := b.keys.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAEAD(dataKey)
+}
+
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	return b.inner.Delete(ctx, key)
+}
+
+// CheckHealth defers to the wrapped backend's own check, or probes through
+// the encryption, which checks the master key as well.
+func (b *Backend) CheckHealth(ctx context.Context) error {
+	if hc, ok := b.inner.(document.HealthChecker); ok {
+		return hc.CheckHealth(ctx)
+	}
+
+	return document.Probe(ctx, b)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,201 @@
+package crypt_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/document/crypt"
+)
+
+// memBackend keeps objects in memory, so tests can read and tamper with what
+// was stored.
+type memBackend struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{objects: make(map[string][]byte)}
+}
+
+func (b *memBackend) Type() string { return "mem" }
+
+func (b *memBackend) Upload(_ context.Context, filename string, content io.Reader) (string, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := strconv.Itoa(len(b.objects)) + "_" + filename
+	b.objects[key] = data
+
+	return key, nil
+}
+
+func (b *memBackend) Download(_ context.Context, key string) (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data, ok := b.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *memBackend) Delete(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.objects, key)
+
+	return nil
+}
+
+func newMasterKey(t *testing.T) *crypt.MasterKey {
+	t.Helper()
+
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	require.NoError(t, err)
+
+	key, err := crypt.ParseMasterKey(base64.StdEncoding.EncodeToString(raw))
+	require.NoError(t, err)
+
+	return key
+}
+
+func download(t *testing.T, b document.Backend, key string) ([]byte, error) {
+	t.Helper()
+
+	rc, err := b.Download(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+func TestBackend_RoundTrip(t *testing.T) {
+	inner := newMemBackend()
+	b := crypt.NewEncryption(newMasterKey(t)).Wrap(inner, true)
+
+	const chunk = 64 << 10
+
+	for _, size := range []int{0, 1, chunk - 1, chunk, chunk + 1, 3 * chunk, 3*chunk + 17} {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			content := make([]byte, size)
+			_, _ = rand.Read(content)
+
+			key, err := b.Upload(context.Background(), "invoice.pdf", bytes.NewReader(content))
+			require.NoError(t, err)
+
+			stored := inner.objects[key]
+			if size > 16 {
+				assert.False(t, bytes.Contains(stored, content[:16]), "content is stored encrypted")
+			}
+
+			got, err := download(t, b, key)
+			require.NoError(t, err)
+			assert.Equal(t, content, got)
+		})
+	}
+}
+
+func TestBackend_DetectsTampering(t *testing.T) {
+	inner := newMemBackend()
+	b := crypt.NewEncryption(newMasterKey(t)).Wrap(inner, true)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 10<<10) // several chunks
+	key, err := b.Upload(context.Background(), "invoice.pdf", bytes.NewReader(content))
+	require.NoError(t, err)
+
+	stored := inner.objects[key]
+
+	flipped := bytes.Clone(stored)
+	flipped[len(flipped)/2] ^= 1
+
+	cases := map[string][]byte{
+		"flipped bit":             flipped,
+		"truncated mid chunk":     stored[:len(stored)-100],
+		"truncated at a boundary": stored[:len(stored)-(len(content)%(64<<10))-16],
+		"header only":             stored[:60],
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			inner.objects[key] = data
+
+			_, err := download(t, b, key)
+			assert.ErrorIs(t, err, crypt.ErrCorrupt)
+		})
+	}
+}
+
+func TestBackend_WrongMasterKey(t *testing.T) {
+	inner := newMemBackend()
+
+	key, err := crypt.NewEncryption(newMasterKey(t)).Wrap(inner, true).
+		Upload(context.Background(), "invoice.pdf", bytes.NewReader([]byte("secret")))
+	require.NoError(t, err)
+
+	_, err = download(t, crypt.NewEncryption(newMasterKey(t)).Wrap(inner, true), key)
+	assert.ErrorIs(t, err, crypt.ErrWrongKey)
+}
+
+func TestBackend_ReadsWhatEncryptionWasOffFor(t *testing.T) {
+	inner := newMemBackend()
+	enc := crypt.NewEncryption(newMasterKey(t))
+
+	plainKey, err := inner.Upload(context.Background(), "old.pdf", bytes.NewReader([]byte("stored in the clear")))
+	require.NoError(t, err)
+
+	encryptedKey, err := enc.Wrap(inner, true).Upload(context.Background(), "new.pdf", bytes.NewReader([]byte("stored encrypted")))
+	require.NoError(t, err)
+
+	// Encryption turned off again: new uploads are plain, old ones still read.
+	off := enc.Wrap(inner, false)
+
+	got, err := download(t, off, plainKey)
+	require.NoError(t, err)
+	assert.Equal(t, "stored in the clear", string(got))
+
+	got, err = download(t, off, encryptedKey)
+	require.NoError(t, err)
+	assert.Equal(t, "stored encrypted", string(got))
+
+	key, err := off.Upload(context.Background(), "plain.pdf", bytes.NewReader([]byte("plain")))
+	require.NoError(t, err)
+	assert.Equal(t, "plain", string(inner.objects[key]))
+}
+
+func TestBackend_CheckHealth(t *testing.T) {
+	inner := newMemBackend()
+
+	require.NoError(t, document.CheckHealth(context.Background(), crypt.NewEncryption(newMasterKey(t)).Wrap(inner, true)))
+	assert.Empty(t, inner.objects)
+}
+
+func TestParseMasterKey_Invalid(t *testing.T) {
+	_, err := crypt.ParseMasterKey("not base64!")
+	assert.Error(t, err)
+
+	_, err = crypt.ParseMasterKey(base64.StdEncoding.EncodeToString([]byte("too short")))
+	assert.Error(t, err)
+}
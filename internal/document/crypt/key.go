@@ -0,0 +1,97 @@
+package crypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// KeyWrapper encrypts the data keys of stored objects. MasterKey wraps them
+// locally; a KMS client could implement it to keep the master key off the
+// server.
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// keyIDSize is the length of the master key fingerprint that prefixes wrapped
+// keys, so a wrong master key is told apart from a corrupt object.
+const keyIDSize = 4
+
+// MasterKey wraps data keys with AES-256-GCM.
+type MasterKey struct {
+	aead cipher.AEAD
+	id   []byte
+}
+
+// ParseMasterKey decodes a base64-encoded 32-byte key, as generated by
+// `openssl rand -base64 32`.
+func ParseMasterKey(s string) (*MasterKey, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("crypt: master key is not base64: %w", err)
+	}
+
+	return NewMasterKey(key)
+}
+
+// NewMasterKey returns a MasterKey for a 32-byte key.
+func NewMasterKey(key []byte) (*MasterKey, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("crypt: master key must be 32 bytes, got %d", len(key))
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(key)
+
+	return &MasterKey{aead: aead, id: sum[:keyIDSize]}, nil
+}
+
+// WrapKey returns the key ID, a random nonce and the sealed data key.
+func (k *MasterKey) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("crypt: generating nonce: %w", err)
+	}
+
+	wrapped := append(append([]byte{}, k.id...), nonce...)
+
+	return k.aead.Seal(wrapped, nonce, dataKey, k.id), nil
+}
+
+func (k *MasterKey) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < keyIDSize+k.aead.NonceSize()+k.aead.Overhead() {
+		return nil, ErrCorrupt
+	}
+
+	if !bytes.Equal(wrapped[:keyIDSize], k.id) {
+		return nil, ErrWrongKey
+	}
+
+	nonce := wrapped[keyIDSize : keyIDSize+k.aead.NonceSize()]
+
+	dataKey, err := k.aead.Open(nil, nonce, wrapped[keyIDSize+k.aead.NonceSize():], k.id)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+
+	return dataKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("crypt: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,165 @@
+package crypt
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// chunkSize is the plaintext size of every chunk but the last.
+const chunkSize = 64 << 10
+
+// chunkNonce derives the nonce of a chunk from its index and whether it is
+// the last one. Every object has its own data key, so nonces only need to be
+// unique within an object; the last-chunk flag makes truncation at a chunk
+// boundary fail authentication.
+func chunkNonce(aead cipher.AEAD, counter uint64, last bool) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-9:], counter)
+
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+
+	return nonce
+}
+
+// encryptReader reads plaintext from src and yields header followed by the
+// sealed chunks.
+type encryptReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	counter uint64
+	plain   []byte
+	sealed  []byte
+	pending []byte // output not yet read
+	done    bool
+}
+
+func newEncryptReader(header []byte, src io.Reader, aead cipher.AEAD) *encryptReader {
+	return &encryptReader{
+		src:     bufio.NewReader(src),
+		aead:    aead,
+		plain:   make([]byte, chunkSize),
+		sealed:  make([]byte, 0, chunkSize+aead.Overhead()),
+		pending: header,
+	}
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.seal(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
+
+// seal encrypts the next chunk. A chunk is the last one when src ends in or
+// right after it, so empty content is a single empty last chunk.
+func (r *encryptReader) seal() error {
+	n, err := io.ReadFull(r.src, r.plain)
+
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		r.done = true
+	case err != nil:
+		return err
+	default:
+		if _, err := r.src.Peek(1); errors.Is(err, io.EOF) {
+			r.done = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	r.pending = r.aead.Seal(r.sealed[:0], chunkNonce(r.aead, r.counter, r.done), r.plain[:n], nil)
+	r.counter++
+
+	return nil
+}
+
+// decryptReader reads the sealed chunks following the header from src and
+// yields the plaintext.
+type decryptReader struct {
+	src     *bufio.Reader
+	closer  io.Closer
+	aead    cipher.AEAD
+	counter uint64
+	sealed  []byte
+	plain   []byte
+	pending []byte
+	done    bool
+}
+
+func newDecryptReader(src *bufio.Reader, closer io.Closer, aead cipher.AEAD) *decryptReader {
+	return &decryptReader{
+		src:    src,
+		closer: closer,
+		aead:   aead,
+		sealed: make([]byte, chunkSize+aead.Overhead()),
+		plain:  make([]byte, 0, chunkSize),
+	}
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
+
+func (r *decryptReader) open() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	last := false
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrCorrupt // the last chunk is missing
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := r.src.Peek(1); errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	plain, err := r.aead.Open(r.plain[:0], chunkNonce(r.aead, r.counter, last), r.sealed[:n], nil)
+	if err != nil {
+		return ErrCorrupt
+	}
+
+	r.pending = plain
+	r.counter++
+	r.done = last
+
+	return nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.closer.Close()
+}
@@ -0,0 +1,306 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/auth"
+)
+
+// Drain tracks moving every document off a backend onto another, so the
+// backend can be deleted. Total is the number of documents on the backend when
+// the drain started; Moved and Failed count those handled so far. A drain with
+// no FinishedAt that is not Running stopped early; starting it again moves
+// what is left.
+type Drain struct {
+	BackendID  uuid.UUID
+	TargetID   uuid.UUID
+	Total      int
+	Moved      int
+	Failed     int    // in the latest run; failed documents stay where they are
+	LastError  string // of the latest failure
+	StartedAt  time.Time
+	FinishedAt *time.Time
+	Running    bool // the backend holds a fresh draining mark; not stored
+}
+
+// drainStaleAfter is how long a backend's draining mark holds. A running drain
+// refreshes it after each document, so an older mark was left by a process
+// that stopped, and another drain may take it over.
+const drainStaleAfter = 15 * time.Minute
+
+// StartDrain moves every document on backendID to targetID in the background
+// and returns the drain, whose progress GetDrain reports. The backend is
+// disabled first so no new uploads land on it. A stopped drain to the same
+// target resumes where it left off; one to another target starts over with
+// the documents left.
+//
+// Each document is downloaded, checked against its SHA-256, uploaded to the
+// target and recorded there before the old copy is forgotten and deleted, so
+// a drain that stops halfway leaves every document readable.
+func (s *Service) StartDrain(ctx context.Context, backendID, targetID uuid.UUID) (*Drain, error) {
+	if backendID == targetID {
+		return nil, ErrDrainToSelf
+	}
+
+	for _, id := range []uuid.UUID{backendID, targetID} {
+		if _, err := s.userBackend(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	// The mark lives on the backend row, so no two processes drain the same
+	// backend at once.
+	now := time.Now()
+
+	claimed, err := s.repo.ClaimDrain(ctx, backendID, now, now.Add(-drainStaleAfter))
+	if err != nil {
+		return nil, fmt.Errorf("claiming drain: %w", err)
+	}
+
+	if !claimed {
+		return nil, ErrDrainRunning
+	}
+
+	// The drain outlives the request that started it.
+	bg := context.WithoutCancel(ctx)
+
+	drain, err := s.prepareDrain(ctx, backendID, targetID)
+	if err != nil {
+		s.releaseDrain(bg, backendID)
+		return nil, err
+	}
+
+	// Copy the drain before it starts changing.
+	started := *drain
+	started.Running = true
+
+	go func() {
+		defer s.releaseDrain(bg, backendID)
+
+		if err := s.runDrain(bg, drain); err != nil {
+			slog.Error("drain stopped", "backend_id", backendID, "error", err)
+		}
+	}()
+
+	return &started, nil
+}
+
+// GetDrain returns the latest drain of a backend, or ErrDrainNotFound.
+func (s *Service) GetDrain(ctx context.Context, backendID uuid.UUID) (*Drain, error) {
+	drain, err := s.repo.GetDrain(ctx, backendID)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := s.repo.GetBackend(ctx, backendID)
+	if err != nil {
+		return nil, err
+	}
+
+	drain.Running = cfg.DrainingAt != nil && time.Since(*cfg.DrainingAt) < drainStaleAfter
+
+	return drain, nil
+}
+
+// releaseDrain clears the backend's draining mark.
+func (s *Service) releaseDrain(ctx context.Context, backendID uuid.UUID) {
+	if err := s.repo.ReleaseDrain(ctx, backendID); err != nil {
+		slog.Error("failed to release drain", "backend_id", backendID, "error", err)
+	}
+}
+
+// userBackend returns the user's backend with the given ID.
+func (s *Service) userBackend(ctx context.Context, id uuid.UUID) (*BackendConfig, error) {
+	cfg, err := s.repo.GetBackend(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.UserID != auth.UserID(ctx) {
+		return nil, ErrBackendNotFound
+	}
+
+	return cfg, nil
+}
+
+// prepareDrain disables the backend and records the drain to run, resuming a
+// stopped one to the same target.
+func (s *Service) prepareDrain(ctx context.Context, backendID, targetID uuid.UUID) (*Drain, error) {
+	disabled := false
+	if err := s.repo.UpdateBackend(ctx, backendID, nil, nil, &disabled); err != nil {
+		return nil, fmt.Errorf("disabling backend: %w", err)
+	}
+
+	drain, err := s.repo.GetDrain(ctx, backendID)
+	if err != nil && !errors.Is(err, ErrDrainNotFound) {
+		return nil, err
+	}
+
+	if err != nil || drain.TargetID != targetID || drain.FinishedAt != nil {
+		locations, err := s.repo.ListBackendLocations(ctx, backendID)
+		if err != nil {
+			return nil, fmt.Errorf("listing locations: %w", err)
+		}
+
+		drain = &Drain{BackendID: backendID, TargetID: targetID, Total: len(locations), StartedAt: time.Now()}
+	}
+
+	drain.Failed = 0
+	drain.LastError = ""
+
+	if err := s.repo.SaveDrain(ctx, drain); err != nil {
+		return nil, fmt.Errorf("saving drain: %w", err)
+	}
+
+	return drain, nil
+}
+
+// runDrain moves the documents left on the drained backend, saving progress
+// after each one.
+func (s *Service) runDrain(ctx context.Context, drain *Drain) error {
+	source, err := s.loadBackend(ctx, drain.BackendID)
+	if err != nil {
+		return err
+	}
+
+	target, err := s.loadBackend(ctx, drain.TargetID)
+	if err != nil {
+		return err
+	}
+
+	locations, err := s.repo.ListBackendLocations(ctx, drain.BackendID)
+	if err != nil {
+		return fmt.Errorf("listing locations: %w", err)
+	}
+
+	for _, loc := range locations {
+		source.loc = loc
+
+		if err := s.move(ctx, source, target); err != nil {
+			slog.Warn("failed to move document", "document_id", loc.DocumentID, "backend", source.cfg.Name, "target", target.cfg.Name, "error", err)
+			drain.Failed++
+			drain.LastError = fmt.Sprintf("document %s: %v", loc.DocumentID, err)
+		} else {
+			drain.Moved++
+		}
+
+		if err := s.repo.SaveDrain(ctx, drain); err != nil {
+			return fmt.Errorf("saving drain: %w", err)
+		}
+
+		// Refresh the mark, whatever its age: this drain holds it.
+		now := time.Now()
+		if _, err := s.repo.ClaimDrain(ctx, drain.BackendID, now, now); err != nil {
+			return fmt.Errorf("refreshing drain: %w", err)
+		}
+	}
+
+	if drain.Failed == 0 {
+		now := time.Now()
+		drain.FinishedAt = &now
+
+		if err := s.repo.SaveDrain(ctx, drain); err != nil {
+			return fmt.Errorf("saving drain: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// loadBackend instantiates a backend whether or not it is enabled.
+func (s *Service) loadBackend(ctx context.Context, id uuid.UUID) (backendCopy, error) {
+	cfg, err := s.repo.GetBackend(ctx, id)
+	if err != nil {
+		return backendCopy{}, fmt.Errorf("getting backend %s: %w", id, err)
+	}
+
+	backend, err := s.registry.Create(cfg.Type, cfg.Config)
+	if err != nil {
+		return backendCopy{}, fmt.Errorf("creating backend %s: %w", cfg.Name, err)
+	}
+
+	return backendCopy{cfg: *cfg, backend: backend}, nil
+}
+
+// move copies the document at source.loc onto target, unless a copy is
+// already there, then removes it from source. When the copy on source cannot
+// be read, the document's other copies are tried.
+func (s *Service) move(ctx context.Context, source, target backendCopy) error {
+	doc, err := s.repo.GetDocument(ctx, source.loc.DocumentID)
+	if err != nil {
+		return fmt.Errorf("getting document: %w", err)
+	}
+
+	locations, err := s.repo.ListLocations(ctx, doc.ID)
+	if err != nil {
+		return fmt.Errorf("listing locations: %w", err)
+	}
+
+	onTarget := false
+	var others []Location
+
+	for _, loc := range locations {
+		switch loc.BackendID {
+		case target.cfg.ID:
+			onTarget = true
+		case source.cfg.ID:
+		default:
+			others = append(others, loc)
+		}
+	}
+
+	if !onTarget {
+		if err := s.copyTo(ctx, doc, source, others, target); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.DeleteLocation(ctx, source.loc.ID); err != nil {
+		return fmt.Errorf("removing location: %w", err)
+	}
+
+	if err := source.backend.Delete(ctx, source.loc.Key); err != nil {
+		slog.Warn("failed to delete moved copy", "backend", source.cfg.Name, "key", source.loc.Key, "error", err)
+	}
+
+	return nil
+}
+
+// copyTo stores doc on target from its copy on source, or from others when
+// that one cannot be read, and records the new location.
+func (s *Service) copyTo(ctx context.Context, doc *Document, source backendCopy, others []Location, target backendCopy) error {
+	sp, err := s.fetch(ctx, source, doc.SHA256)
+	if err != nil && len(others) > 0 {
+		var fallbackErr error
+		if sp, fallbackErr = s.fetchAny(ctx, others, doc.SHA256); fallbackErr != nil {
+			err = errors.Join(err, fallbackErr)
+		} else {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("reading document: %w", err)
+	}
+	defer sp.Close()
+
+	key, err := s.put(ctx, target, doc, nil, sp.reader)
+	if err != nil {
+		return fmt.Errorf("uploading: %w", err)
+	}
+
+	if err := s.repo.AddLocation(ctx, &Location{DocumentID: doc.ID, BackendID: target.cfg.ID, Key: key}); err != nil {
+		if delErr := target.backend.Delete(ctx, key); delErr != nil {
+			slog.Warn("failed to delete unrecorded copy", "backend", target.cfg.Name, "key", key, "error", delErr)
+		}
+		return fmt.Errorf("recording location: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package document_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+func TestService_Drain(t *testing.T) {
+	env := newTestEnv(t, "old")
+
+	var docs []*document.Document
+	for _, content := range []string{"first", "second", "third"} {
+		doc, err := env.svc.Upload(env.ctx, content+".txt", "text/plain", bytes.NewReader([]byte(content)), nil)
+		require.NoError(t, err)
+		docs = append(docs, doc)
+	}
+
+	old := env.backends["old"]
+	newBackend := env.addBackend(t, "new")
+
+	backends, err := env.svc.ListBackends(env.ctx)
+	require.NoError(t, err)
+	oldID, newID := backends[0].ID, backends[1].ID
+
+	_, err = env.svc.StartDrain(env.ctx, oldID, oldID)
+	require.ErrorIs(t, err, document.ErrDrainToSelf)
+
+	// The third document's only copy is damaged, so it cannot be moved yet.
+	var damagedKey string
+	for key, data := range old.objects {
+		if string(data) == "third" {
+			damagedKey = key
+			old.objects[key] = []byte("garbage")
+		}
+	}
+
+	wait := func() *document.Drain {
+		t.Helper()
+
+		var drain *document.Drain
+		require.Eventually(t, func() bool {
+			drain, err = env.svc.GetDrain(env.ctx, oldID)
+			require.NoError(t, err)
+			return !drain.Running
+		}, 5*time.Second, 10*time.Millisecond)
+
+		return drain
+	}
+
+	drain, err := env.svc.StartDrain(env.ctx, oldID, newID)
+	require.NoError(t, err)
+	assert.True(t, drain.Running)
+	assert.Equal(t, 3, drain.Total)
+
+	drain = wait()
+	assert.Equal(t, 2, drain.Moved)
+	assert.Equal(t, 1, drain.Failed)
+	assert.Contains(t, drain.LastError, docs[2].ID.String())
+	assert.Nil(t, drain.FinishedAt)
+
+	cfg, err := env.repo.GetBackend(env.ctx, oldID)
+	require.NoError(t, err)
+	assert.False(t, cfg.Enabled, "drained backend takes no new uploads")
+
+	// Once the copy is fixed, starting again moves only what is left.
+	old.objects[damagedKey] = []byte("third")
+
+	_, err = env.svc.StartDrain(env.ctx, oldID, newID)
+	require.NoError(t, err)
+
+	drain = wait()
+	assert.Equal(t, 3, drain.Total)
+	assert.Equal(t, 3, drain.Moved)
+	assert.Zero(t, drain.Failed)
+	assert.NotNil(t, drain.FinishedAt)
+
+	assert.Empty(t, old.objects)
+	assert.Len(t, newBackend.objects, 3)
+
+	locations, err := env.repo.ListBackendLocations(env.ctx, oldID)
+	require.NoError(t, err)
+	assert.Empty(t, locations)
+
+	for _, doc := range docs {
+		locations, err := env.repo.ListLocations(env.ctx, doc.ID)
+		require.NoError(t, err)
+		require.Len(t, locations, 1)
+		assert.Equal(t, newID, locations[0].BackendID)
+	}
+
+	_, err = env.svc.GetDrain(env.ctx, uuid.New())
+	assert.ErrorIs(t, err, document.ErrDrainNotFound)
+}
+
+func TestService_StartDrain_MarkedByAnotherProcess(t *testing.T) {
+	env := newTestEnv(t, "old")
+	env.addBackend(t, "new")
+
+	backends, err := env.svc.ListBackends(env.ctx)
+	require.NoError(t, err)
+	oldID, newID := backends[0].ID, backends[1].ID
+
+	// Another process marked the backend a minute ago.
+	now := time.Now()
+	claimed, err := env.repo.ClaimDrain(env.ctx, oldID, now.Add(-time.Minute), now)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	_, err = env.svc.StartDrain(env.ctx, oldID, newID)
+	require.ErrorIs(t, err, document.ErrDrainRunning)
+
+	// A mark that was not refreshed for an hour was left by a process that
+	// stopped, so the drain takes it over.
+	_, err = env.repo.ClaimDrain(env.ctx, oldID, now.Add(-time.Hour), now)
+	require.NoError(t, err)
+
+	_, err = env.svc.StartDrain(env.ctx, oldID, newID)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		cfg, err := env.repo.GetBackend(env.ctx, oldID)
+		require.NoError(t, err)
+		return cfg.DrainingAt == nil
+	}, 5*time.Second, 10*time.Millisecond, "a finished drain releases the mark")
+
+	drain, err := env.svc.GetDrain(env.ctx, oldID)
+	require.NoError(t, err)
+	assert.False(t, drain.Running)
+	assert.NotNil(t, drain.FinishedAt)
+}
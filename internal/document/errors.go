@@ -20,4 +20,66 @@ var (
 	// ErrBackendNotFound is returned when a backend ID does not exist or does not
 	// belong to the requesting user.
 	ErrBackendNotFound = errors.New("backend not found")
+
+	// ErrDuplicateContent is returned by Repository.CreateDocument when the user
+	// already has a document with the same SHA-256.
+	ErrDuplicateContent = errors.New("document with the same content already exists")
+
+	// ErrDocumentInUse is returned when deleting an inbox document that has been
+	// attached to a transaction in the meantime.
+	ErrDocumentInUse = errors.New("document is attached to a transaction")
+
+	// ErrNoText is returned when extracting text from a document that is not a
+	// PDF, or whose PDF carries no readable text (scans, encrypted files).
+	ErrNoText = errors.New("document has no extractable text")
+
+	// ErrNoPreview is returned for documents that are neither PDFs nor images
+	// in a supported format, or whose PDF cannot be read.
+	ErrNoPreview = errors.New("document has no preview")
+
+	// ErrPreviewNotFound is returned by Repository.GetPreview when no preview
+	// has been stored for the document.
+	ErrPreviewNotFound = errors.New("preview not found")
+
+	// ErrInvalidQRCode is returned for invoice QR code payloads that are not
+	// in the tax authority's format.
+	ErrInvalidQRCode = errors.New("invalid invoice QR code")
+
+	// ErrDrainNotFound is returned when a backend has never been drained.
+	ErrDrainNotFound = errors.New("drain not found")
+
+	// ErrDrainRunning is returned when starting a drain of a backend that is
+	// already being drained.
+	ErrDrainRunning = errors.New("backend is already being drained")
+
+	// ErrDrainToSelf is returned when a backend is drained onto itself.
+	ErrDrainToSelf = errors.New("cannot drain a backend onto itself")
+
+	// ErrEncryptionUnavailable is returned when loading a backend whose config
+	// asks for encryption while no master key is configured.
+	ErrEncryptionUnavailable = errors.New("backend is set to encrypt but no master key is configured")
+
+	// ErrEncryptionUnsupported is returned when encryption is asked of a
+	// backend that parses the documents it stores, such as Paperless.
+	ErrEncryptionUnsupported = errors.New("backend does not support encryption")
+
+	// ErrInvalidKeyTemplate is returned for backend key templates that do not
+	// parse or use unknown fields.
+	ErrInvalidKeyTemplate = errors.New("invalid key template")
+
+	// ErrKeyTemplateUnsupported is returned when a key template is set on a
+	// backend that chooses its own keys, such as Paperless.
+	ErrKeyTemplateUnsupported = errors.New("backend does not support key templates")
+
+	// ErrKeyTemplateEncrypted is returned when a key template is set on a
+	// backend that encrypts: the keys would reveal the invoice details the
+	// encryption hides.
+	ErrKeyTemplateEncrypted = errors.New("key templates cannot be used with encryption")
+
+	// ErrKeyExists is returned by KeyedUploader.UploadAt when the key is taken.
+	ErrKeyExists = errors.New("key already exists")
+
+	// ErrKeyEscapesBase is returned when a storage key would resolve outside the
+	// directory a backend stores documents in.
+	ErrKeyEscapesBase = errors.New("key escapes base path")
 )
@@ -0,0 +1,66 @@
+package document
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// healthCheckTimeout bounds a single backend health check.
+const healthCheckTimeout = 30 * time.Second
+
+// CheckBackend checks that the backend can store and serve documents, records
+// the outcome and returns it. A backend that fails the check is not an error;
+// the reason is in the returned health.
+func (s *Service) CheckBackend(ctx context.Context, id uuid.UUID) (*BackendHealth, error) {
+	cfg, err := s.userBackend(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.checkBackend(ctx, cfg)
+}
+
+// CheckBackends checks every enabled backend of the user and records the
+// outcomes.
+func (s *Service) CheckBackends(ctx context.Context) ([]BackendConfig, error) {
+	configs, err := s.repo.ListBackends(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing backends: %w", err)
+	}
+
+	for i := range configs {
+		if !configs[i].Enabled {
+			continue
+		}
+
+		if configs[i].Health, err = s.checkBackend(ctx, &configs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return configs, nil
+}
+
+func (s *Service) checkBackend(ctx context.Context, cfg *BackendConfig) (*BackendHealth, error) {
+	health := &BackendHealth{CheckedAt: time.Now()}
+
+	backend, err := s.registry.Create(cfg.Type, cfg.Config)
+	if err == nil {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		err = CheckHealth(checkCtx, backend)
+		cancel()
+	}
+
+	if err != nil {
+		health.Error = err.Error()
+	}
+
+	if err := s.repo.SetBackendHealth(ctx, cfg.ID, *health); err != nil {
+		return nil, fmt.Errorf("recording backend health: %w", err)
+	}
+
+	return health, nil
+}
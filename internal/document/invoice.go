@@ -0,0 +1,340 @@
+package document
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+)
+
+// ParseInvoice reads the invoice fields out of a document's text, as laid
+// out by pdftext: one printed line per line. It knows the labels of
+// Portuguese invoices (and their usual English equivalents); fields it cannot
+// find are left unknown.
+func ParseInvoice(text string) Invoice {
+	lines := strings.Split(text, "\n")
+
+	folded := make([]string, len(lines))
+	for i, line := range lines {
+		folded[i] = matching.FoldAccents(strings.ToLower(line))
+	}
+
+	inv := Invoice{
+		IssuerNIF:  findIssuerNIF(folded),
+		Number:     findInvoiceNumber(lines),
+		Date:       findIssueDate(folded),
+		Total:      findAmount(folded, totalLabels, totalExclusions),
+		VAT:        findAmount(folded, vatLabels, nil),
+		IssuerName: findIssuerName(lines, folded),
+		ATCUD:      findATCUD(lines),
+	}
+
+	return inv
+}
+
+var (
+	nifPattern = regexp.MustCompile(`\b(?:pt\s?)?(\d{9})\b`)
+	nifLabel   = regexp.MustCompile(`\b(?:nif|nipc|contribuinte|vat|tax id|n\.?\s?i\.?\s?f)\b`)
+	buyerLabel = regexp.MustCompile(`\b(?:cliente|adquirente|customer|buyer|consumidor|destinatario|bill to)\b`)
+)
+
+// findIssuerNIF returns the first valid NIF outside the buyer's details,
+// preferring labelled ones.
+func findIssuerNIF(lines []string) string {
+	var unlabelled string
+
+	for _, line := range lines {
+		if buyerLabel.MatchString(line) {
+			continue
+		}
+
+		for _, m := range nifPattern.FindAllStringSubmatch(line, -1) {
+			if !ValidNIF(m[1]) {
+				continue
+			}
+
+			if nifLabel.MatchString(line) {
+				return m[1]
+			}
+
+			if unlabelled == "" {
+				unlabelled = m[1]
+			}
+		}
+	}
+
+	return unlabelled
+}
+
+// ValidNIF reports whether s is a Portuguese tax number (NIF or NIPC): nine
+// digits, the last of which is a mod-11 check digit.
+func ValidNIF(s string) bool {
+	if len(s) != 9 || strings.Trim(s, "0123456789") != "" || strings.IndexByte("1235689", s[0]) < 0 {
+		return false
+	}
+
+	sum := 0
+	for i := range 8 {
+		sum += int(s[i]-'0') * (9 - i)
+	}
+
+	check := 11 - sum%11
+	if check >= 10 {
+		check = 0
+	}
+
+	return int(s[8]-'0') == check
+}
+
+var (
+	// atNumber matches the document numbers the tax authority mandates:
+	// document type, series and sequence, as in "FT 2024A/123".
+	atNumber = regexp.MustCompile(`\b(FT|FR|FS|NC|ND)\s+([A-Za-z0-9][A-Za-z0-9._-]*)/(\d+)\b`)
+
+	labelledNumber = regexp.MustCompile(`(?i)\b(?:fatura|factura|fatura-recibo|invoice|documento)\s*(?:n\.?\s?[ºo°]\.?|no\.?|nr\.?|number|#)\s*[:.]?\s*([A-Za-z0-9][A-Za-z0-9/._-]*)`)
+)
+
+func findInvoiceNumber(lines []string) string {
+	for _, line := range lines {
+		if m := atNumber.FindStringSubmatch(line); m != nil {
+			return m[1] + " " + m[2] + "/" + m[3]
+		}
+	}
+
+	for _, line := range lines {
+		if m := labelledNumber.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+
+	return ""
+}
+
+// atcud matches the unique document code: the series validation code, a
+// dash and the sequence number.
+var atcud = regexp.MustCompile(`\bATCUD\s*:?\s*([A-Z0-9]{8,}-\d+)\b`)
+
+func findATCUD(lines []string) string {
+	for _, line := range lines {
+		if m := atcud.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+
+	return ""
+}
+
+var (
+	dayFirstDate = regexp.MustCompile(`\b(\d{1,2})[-/.](\d{1,2})[-/.](\d{4})\b`)
+	isoDate      = regexp.MustCompile(`\b(\d{4})[-/.](\d{2})[-/.](\d{2})\b`)
+
+	issueDateLabel = regexp.MustCompile(`\b(?:data de emissao|data emissao|data da fatura|data do documento|invoice date|issue date|date of issue)\b`)
+	dateLabel      = regexp.MustCompile(`\b(?:data|date)\b`)
+	otherDateLabel = regexp.MustCompile(`\b(?:vencimento|venc|limite|validade|due|pagamento|entrega|carga|descarga)\b`)
+)
+
+// findIssueDate prefers a date labelled as the issue date, then one labelled
+// only as a date, then the first date in the text. Due dates are skipped.
+func findIssueDate(lines []string) *time.Time {
+	var best *time.Time
+
+	bestRank := -1
+
+	for i, line := range lines {
+		if otherDateLabel.MatchString(line) {
+			continue
+		}
+
+		rank := 0
+
+		switch {
+		case issueDateLabel.MatchString(line):
+			rank = 2
+		case dateLabel.MatchString(line):
+			rank = 1
+		}
+
+		if rank <= bestRank {
+			continue
+		}
+
+		d := parseDate(line)
+		if d == nil && rank > 0 && i+1 < len(lines) {
+			// Tabular layouts print the label above the value.
+			d = parseDate(lines[i+1])
+		}
+
+		if d != nil {
+			best, bestRank = d, rank
+		}
+	}
+
+	return best
+}
+
+// parseDate returns the first valid date in s.
+func parseDate(s string) *time.Time {
+	type match struct {
+		pos          int
+		day, mon, yr string
+	}
+
+	var found []match
+
+	for _, m := range dayFirstDate.FindAllStringSubmatchIndex(s, -1) {
+		found = append(found, match{m[0], s[m[2]:m[3]], s[m[4]:m[5]], s[m[6]:m[7]]})
+	}
+
+	for _, m := range isoDate.FindAllStringSubmatchIndex(s, -1) {
+		found = append(found, match{m[0], s[m[6]:m[7]], s[m[4]:m[5]], s[m[2]:m[3]]})
+	}
+
+	var (
+		first    *time.Time
+		firstPos = len(s)
+	)
+
+	for _, m := range found {
+		day, _ := strconv.Atoi(m.day)
+		mon, _ := strconv.Atoi(m.mon)
+		yr, _ := strconv.Atoi(m.yr)
+
+		d := time.Date(yr, time.Month(mon), day, 0, 0, 0, 0, time.UTC)
+		if d.Day() != day || int(d.Month()) != mon || yr < 2000 || yr > 2100 {
+			continue
+		}
+
+		if m.pos < firstPos {
+			first, firstPos = &d, m.pos
+		}
+	}
+
+	return first
+}
+
+// amountLabel ranks a label; the highest ranked labelled amount wins, and
+// labels ranked above 1 may have their amount on the following line.
+type amountLabel struct {
+	pattern *regexp.Regexp
+	rank    int
+}
+
+var (
+	totalLabels = []amountLabel{
+		{regexp.MustCompile(`\b(?:total a pagar|valor a pagar|a pagar|amount due|total due|balance due)\b`), 4},
+		{regexp.MustCompile(`\btotal\s*\(?\s*(?:c/|com)\s*iva\b|\btotal incl`), 3},
+		{regexp.MustCompile(`\b(?:total (?:do |da )?(?:documento|fatura|factura)|valor total|total geral|grand total|invoice total)\b`), 2},
+		{regexp.MustCompile(`^\s*total\b`), 1},
+	}
+	totalExclusions = regexp.MustCompile(`\b(?:subtotal|sub-total|s/\s*iva|sem iva|total (?:de |do )?iva|iva total|liquido|iliquido|descontos?|incidencia|base tributavel|excl\w*|net|total vat|vat total|vat amount)\b`)
+
+	vatLabels = []amountLabel{
+		{regexp.MustCompile(`\b(?:total (?:de |do )?iva|iva total|valor (?:do )?iva|total vat|vat total|vat amount)\b`), 1},
+	}
+)
+
+// findAmount returns the amount of the best-ranked labelled line: the last
+// amount on the line or, failing that, the first on the next. Among equal
+// ranks the last line wins, as totals close the document.
+func findAmount(lines []string, labels []amountLabel, exclusions *regexp.Regexp) *int64 {
+	var best *int64
+
+	bestRank := 0
+
+	for i, line := range lines {
+		if exclusions != nil && exclusions.MatchString(line) {
+			continue
+		}
+
+		rank := 0
+
+		for _, l := range labels {
+			if l.pattern.MatchString(line) {
+				rank = l.rank
+				break
+			}
+		}
+
+		if rank == 0 || rank < bestRank {
+			continue
+		}
+
+		amounts := parseAmounts(line)
+		if len(amounts) == 0 && rank > 1 && i+1 < len(lines) {
+			if next := parseAmounts(lines[i+1]); len(next) > 0 {
+				amounts = next[:1]
+			}
+		}
+
+		if len(amounts) > 0 && amounts[len(amounts)-1] > 0 {
+			amount := amounts[len(amounts)-1]
+			best, bestRank = &amount, rank
+		}
+	}
+
+	return best
+}
+
+// amountPattern does not take spaces as thousands separators, which would
+// join a quantity to the amount that follows it.
+var amountPattern = regexp.MustCompile(`-?\d{1,3}(?:[.,]\d{3})+[.,]\d{2}\b|-?\d+[.,]\d{2}\b`)
+
+// parseAmounts returns the amounts in s, in cents. Amounts have two decimals,
+// in either the Portuguese (1.234,56) or the English (1,234.56) format;
+// percentages and dates are ignored.
+func parseAmounts(s string) []int64 {
+	s = dayFirstDate.ReplaceAllString(s, " ")
+	s = isoDate.ReplaceAllString(s, " ")
+
+	var amounts []int64
+
+	for _, m := range amountPattern.FindAllStringIndex(s, -1) {
+		if strings.HasPrefix(strings.TrimLeft(s[m[1]:], " "), "%") {
+			continue
+		}
+
+		var digits strings.Builder
+
+		for _, r := range s[m[0]:m[1]] {
+			if r == '-' || (r >= '0' && r <= '9') {
+				digits.WriteRune(r)
+			}
+		}
+
+		cents, err := strconv.ParseInt(digits.String(), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		amounts = append(amounts, cents)
+	}
+
+	return amounts
+}
+
+// issuerName matches a company name up to its legal form.
+var issuerName = regexp.MustCompile(`(?i)^\s*(.*?\S.*?\b(?:lda|limitada|unipessoal|s\.\s?a|sa|sgps|ltd|limited|inc|gmbh|s\.\s?l))\.?(?:[\s,]|$)`)
+
+// findIssuerName returns the first company name near the top of the
+// document, which is where invoices print the issuer.
+func findIssuerName(lines, folded []string) string {
+	for i, line := range lines[:min(len(lines), 15)] {
+		if buyerLabel.MatchString(folded[i]) {
+			continue
+		}
+
+		if m := issuerName.FindStringSubmatchIndex(line); m != nil {
+			// Keep the dot of abbreviated forms such as "Lda." and "S.A.".
+			end := m[3]
+			if end < len(line) && line[end] == '.' {
+				end++
+			}
+
+			return strings.TrimSpace(line[m[2]:end])
+		}
+	}
+
+	return ""
+}
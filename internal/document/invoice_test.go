@@ -0,0 +1,109 @@
+package document_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+func TestParseInvoice(t *testing.T) {
+	cents := func(v int64) *int64 { return &v }
+	date := func(y int, m time.Month, d int) *time.Time {
+		t := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want document.Invoice
+	}{
+		{
+			name: "portuguese invoice",
+			text: `Continente Hipermercados, S.A. NIF 502011475
+Rua João Mendonça, 505 4464-503 Senhora da Hora
+FATURA-RECIBO FR 2026A7/84211
+ATCUD: JJ37MMBG-84211
+Data de emissão: 14-10-2026
+Data de vencimento: 14-11-2026
+Cliente: João Silva NIF 245889965
+Artigo Qtd Preço IVA Total
+Leite meio gordo 6 0,89 6% 5,34
+Detergente 1 7,49 23% 7,49
+Subtotal 11,43
+Total IVA 1,40
+Total a pagar 12,83 €`,
+			want: document.Invoice{
+				Total:      cents(1283),
+				VAT:        cents(140),
+				Date:       date(2026, time.October, 14),
+				Number:     "FR 2026A7/84211",
+				IssuerName: "Continente Hipermercados, S.A.",
+				IssuerNIF:  "502011475",
+				ATCUD:      "JJ37MMBG-84211",
+			},
+		},
+		{
+			name: "buyer NIF listed first, thousands and tabular totals",
+			text: `Adquirente NIF: 245889965
+Oficina Silva & Filhos, Lda.
+Contribuinte n.º 516254839
+Fatura n.º 2026/77
+Data
+03/09/2026
+Base tributável 1.016,26
+Valor IVA 233,74
+Total do documento
+1.250,00`,
+			want: document.Invoice{
+				Total:      cents(125000),
+				VAT:        cents(23374),
+				Date:       date(2026, time.September, 3),
+				Number:     "2026/77",
+				IssuerName: "Oficina Silva & Filhos, Lda.",
+				IssuerNIF:  "516254839",
+			},
+		},
+		{
+			name: "english invoice",
+			text: `Acme Cloud Ltd
+VAT PT980123453
+Invoice number: INV-0042
+Invoice date 2026-10-01
+Due date 2026-10-31
+Total excl. VAT 100.00
+VAT total 23.00
+Amount due 1,123.00`,
+			want: document.Invoice{
+				Total:      cents(112300),
+				VAT:        cents(2300),
+				Date:       date(2026, time.October, 1),
+				Number:     "INV-0042",
+				IssuerName: "Acme Cloud Ltd",
+				IssuerNIF:  "980123453",
+			},
+		},
+		{
+			name: "nothing to find",
+			text: "Thank you for shopping with us",
+			want: document.Invoice{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, document.ParseInvoice(tt.text))
+		})
+	}
+}
+
+func TestValidNIF(t *testing.T) {
+	assert.True(t, document.ValidNIF("502011475"))
+	assert.True(t, document.ValidNIF("245889965"))
+	assert.False(t, document.ValidNIF("502011476"), "wrong check digit")
+	assert.False(t, document.ValidNIF("402011475"), "no such first digit")
+	assert.False(t, document.ValidNIF("50201147"))
+}
@@ -0,0 +1,270 @@
+package document
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeyTemplate lays out where a backend stores documents, e.g.
+// "{{year}}/{{month}}/{{date}}_{{merchant}}_{{amount}}.{{ext}}". Slashes make
+// folders. The fields are:
+//
+//	year, month, day, date  the transaction date, else the invoice issue
+//	                        date, else the upload date; date is 2026-10-18
+//	merchant                the transaction's merchant, else the invoice issuer
+//	description             the transaction description
+//	amount                  the transaction amount, else the invoice total, e.g.
+//	                        -12.30 for a debit
+//	number                  the invoice number
+//	filename, name, ext     the uploaded filename, without its extension, and
+//	                        its extension
+//	id                      the document ID
+//
+// Unknown fields render empty, and the separators around them are dropped.
+type KeyTemplate struct {
+	parts []keyPart
+}
+
+// keyPart is literal text or, if field is set, a field.
+type keyPart struct {
+	text  string
+	field string
+}
+
+// TransactionFields are the fields of the transaction a document is attached
+// to that key templates use.
+type TransactionFields struct {
+	Date        time.Time
+	Amount      int64 // in cents
+	Description string
+	Merchant    string // "" when no merchant is linked
+}
+
+var keyFields = map[string]bool{
+	"year": true, "month": true, "day": true, "date": true,
+	"merchant": true, "description": true, "amount": true, "number": true,
+	"filename": true, "name": true, "ext": true, "id": true,
+}
+
+var keyFieldRe = regexp.MustCompile(`\{\{\s*([a-z]+)\s*\}\}`)
+
+// maxKeyValueLength bounds the length of a field's value, in characters, as
+// descriptions run long.
+const maxKeyValueLength = 64
+
+// ParseKeyTemplate parses a key template, failing with ErrInvalidKeyTemplate.
+func ParseKeyTemplate(s string) (*KeyTemplate, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidKeyTemplate)
+	}
+
+	if strings.HasPrefix(s, "/") || strings.HasSuffix(s, "/") {
+		return nil, fmt.Errorf("%w: must not start or end with /", ErrInvalidKeyTemplate)
+	}
+
+	for _, segment := range strings.Split(s, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("%w: empty, . or .. folder in %q", ErrInvalidKeyTemplate, s)
+		}
+	}
+
+	t := &KeyTemplate{}
+	last := 0
+
+	for _, m := range keyFieldRe.FindAllStringSubmatchIndex(s, -1) {
+		field := s[m[2]:m[3]]
+		if !keyFields[field] {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidKeyTemplate, field)
+		}
+
+		t.parts = append(t.parts, keyPart{text: s[last:m[0]]}, keyPart{field: field})
+		last = m[1]
+	}
+
+	t.parts = append(t.parts, keyPart{text: s[last:]})
+
+	for _, p := range t.parts {
+		if strings.Contains(p.text, "{{") || strings.Contains(p.text, "}}") {
+			return nil, fmt.Errorf("%w: unbalanced braces in %q", ErrInvalidKeyTemplate, s)
+		}
+	}
+
+	return t, nil
+}
+
+// Render returns the key of doc, attached to tx if it is not nil.
+func (t *KeyTemplate) Render(doc *Document, tx *TransactionFields) string {
+	values := keyValues(doc, tx)
+
+	var sb strings.Builder
+
+	for _, p := range t.parts {
+		if p.field == "" {
+			sb.WriteString(p.text)
+		} else if v := cleanKeyValue(values[p.field]); v != "" {
+			sb.WriteString(strings.ReplaceAll(v, "-", valueDash))
+		} else {
+			sb.WriteByte(emptyField)
+		}
+	}
+
+	segments := strings.Split(sb.String(), "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(tidySegment(segment), valueDash, "-")
+	}
+
+	return strings.Join(segments, "/")
+}
+
+func keyValues(doc *Document, tx *TransactionFields) map[string]string {
+	inv := doc.Invoice
+
+	date := doc.CreatedAt
+	if inv.Date != nil {
+		date = *inv.Date
+	}
+
+	merchant := inv.IssuerName
+
+	var amount, description string
+	if inv.Total != nil {
+		amount = formatKeyAmount(*inv.Total)
+	}
+
+	if tx != nil {
+		date = tx.Date
+		amount = formatKeyAmount(tx.Amount)
+		description = tx.Description
+
+		if tx.Merchant != "" {
+			merchant = tx.Merchant
+		}
+	}
+
+	ext := path.Ext(doc.Filename)
+
+	return map[string]string{
+		"year":        date.Format("2006"),
+		"month":       date.Format("01"),
+		"day":         date.Format("02"),
+		"date":        date.Format("2006-01-02"),
+		"merchant":    merchant,
+		"description": description,
+		"amount":      amount,
+		"number":      inv.Number,
+		"filename":    doc.Filename,
+		"name":        strings.TrimSuffix(doc.Filename, ext),
+		"ext":         strings.TrimPrefix(ext, "."),
+		"id":          doc.ID.String(),
+	}
+}
+
+func formatKeyAmount(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// cleanKeyValue makes a field's value safe in a file name on any system:
+// path separators and characters Windows and SMB shares reject become dashes.
+func cleanKeyValue(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case strings.ContainsRune(`/\:*?"<>|`, r), unicode.IsControl(r):
+			return '-'
+		}
+		return r
+	}, s)
+
+	s = strings.Join(strings.Fields(s), " ")
+
+	if utf8.RuneCountInString(s) > maxKeyValueLength {
+		s = strings.TrimSpace(string([]rune(s)[:maxKeyValueLength]))
+	}
+
+	return s
+}
+
+// emptyField marks where a field rendered empty, until tidySegment drops it.
+const emptyField = 0
+
+// valueDash stands in for the dashes of field values while tidySegment runs,
+// so they are not taken for separators: a negative amount after an empty
+// field keeps its sign.
+const valueDash = "\x01"
+
+// aroundEmptyField matches a run of empty fields with the separators around
+// them.
+var aroundEmptyField = regexp.MustCompile("([_ -]*)\x00(?:[_ -]*\x00)*([_ -]*)")
+
+// tidySegment drops the empty fields of a segment with the separators doubled
+// around them, so "{{date}}_{{merchant}}_{{amount}}.pdf" without a merchant
+// gives "2026-10-18_12.30.pdf", and leading dots, which would hide a file or
+// climb out of its folder.
+func tidySegment(s string) string {
+	var sb strings.Builder
+
+	last := 0
+
+	for _, m := range aroundEmptyField.FindAllStringSubmatchIndex(s, -1) {
+		sb.WriteString(s[last:m[0]])
+		last = m[1]
+
+		// Keep the separator before or else after, none at either end or
+		// before the extension.
+		if m[0] == 0 || m[1] == len(s) || s[m[1]] == '.' {
+			continue
+		}
+
+		if m[3] > m[2] {
+			sb.WriteString(s[m[2]:m[3]])
+		} else {
+			sb.WriteString(s[m[4]:m[5]])
+		}
+	}
+
+	sb.WriteString(s[last:])
+
+	s = strings.TrimLeft(sb.String(), ".")
+	s = strings.TrimRight(s, ". ")
+
+	if s == "" {
+		return "unknown"
+	}
+
+	return s
+}
+
+// numberedKey returns key with "_<n>" before its extension, for the nth
+// document rendering to the same key; the first keeps key as it is.
+func numberedKey(key string, n int) string {
+	if n <= 1 {
+		return key
+	}
+
+	ext := path.Ext(path.Base(key))
+
+	return strings.TrimSuffix(key, ext) + "_" + strconv.Itoa(n) + ext
+}
+
+// matchesKey reports whether key is want, or want numbered by numberedKey.
+// Backends may prefix the keys they return, as S3 does with its prefix.
+func matchesKey(key, want string) bool {
+	ext := path.Ext(path.Base(want))
+	stem := strings.TrimSuffix(want, ext)
+
+	re := regexp.MustCompile(`(^|/)` + regexp.QuoteMeta(stem) + `(_\d+)?` + regexp.QuoteMeta(ext) + `$`)
+
+	return re.MatchString(key)
+}
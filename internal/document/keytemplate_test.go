@@ -0,0 +1,115 @@
+package document_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+func TestParseKeyTemplate_Invalid(t *testing.T) {
+	for _, tmpl := range []string{
+		"",
+		"/{{year}}/{{filename}}",
+		"{{year}}/",
+		"{{year}}//{{filename}}",
+		"{{year}}/../{{filename}}",
+		"{{year}}/{{vendor}}.pdf",
+		"{{year}}/{{filename}",
+	} {
+		_, err := document.ParseKeyTemplate(tmpl)
+		assert.ErrorIs(t, err, document.ErrInvalidKeyTemplate, tmpl)
+	}
+}
+
+func TestKeyTemplate_Render(t *testing.T) {
+	issued := time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC)
+	total := int64(4599)
+
+	doc := &document.Document{
+		ID:        uuid.MustParse("8f0c6a52-4e43-4c5e-9d55-2f1f2d1b7c10"),
+		Filename:  "Fatura 123.pdf",
+		CreatedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Invoice: document.Invoice{
+			Date:       &issued,
+			Total:      &total,
+			Number:     "FT 2026/123",
+			IssuerName: "Continente: Modelo",
+		},
+	}
+
+	tx := &document.TransactionFields{
+		Date:        time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+		Amount:      -1230,
+		Description: "COMPRA  CONTINENTE\tLISBOA",
+		Merchant:    "Continente",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		tx       *document.TransactionFields
+		want     string
+	}{
+		{
+			name:     "transaction fields",
+			template: "{{year}}/{{month}}/{{date}}_{{merchant}}_{{amount}}.{{ext}}",
+			tx:       tx,
+			want:     "2026/10/2026-10-02_Continente_-12.30.pdf",
+		},
+		{
+			name:     "sign kept after an empty field",
+			template: "{{description}}_{{amount}}",
+			tx:       &document.TransactionFields{Date: tx.Date, Amount: tx.Amount},
+			want:     "-12.30",
+		},
+		{
+			name:     "invoice fields without a transaction",
+			template: "{{year}}/{{month}}/{{date}}_{{merchant}}_{{amount}}.{{ext}}",
+			want:     "2026/09/2026-09-30_Continente- Modelo_45.99.pdf",
+		},
+		{
+			name:     "values never make folders",
+			template: "{{number}}/{{description}}",
+			tx:       tx,
+			want:     "FT 2026-123/COMPRA CONTINENTE LISBOA",
+		},
+		{
+			name:     "document fields",
+			template: "{{name}}-{{id}}.{{ext}}",
+			want:     "Fatura 123-8f0c6a52-4e43-4c5e-9d55-2f1f2d1b7c10.pdf",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := document.ParseKeyTemplate(tt.template)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tmpl.Render(doc, tt.tx))
+		})
+	}
+}
+
+func TestKeyTemplate_Render_DropsEmptyFields(t *testing.T) {
+	doc := &document.Document{
+		Filename:  "receipt.jpg",
+		CreatedAt: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+
+	tests := map[string]string{
+		"{{date}}_{{merchant}}_{{amount}}.{{ext}}": "2026-10-18.jpg",
+		"{{merchant}}_{{date}}.{{ext}}":            "2026-10-18.jpg",
+		"{{date}} - {{merchant}} - {{name}}":       "2026-10-18 - receipt",
+		"{{year}}/{{merchant}}/{{filename}}":       "2026/unknown/receipt.jpg",
+	}
+
+	for template, want := range tests {
+		tmpl, err := document.ParseKeyTemplate(template)
+		require.NoError(t, err)
+		assert.Equal(t, want, tmpl.Render(doc, nil), template)
+	}
+}
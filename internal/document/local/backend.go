@@ -7,7 +7,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/google/uuid"
 
@@ -41,26 +40,36 @@ func (b *Backend) Type() string { return "local" }
 
 // resolveKey joins the key with basePath and rejects any path that escapes basePath.
 func (b *Backend) resolveKey(key string) (string, error) {
-	path := filepath.Join(b.basePath, filepath.Clean(key))
-	base := filepath.Clean(b.basePath) + string(os.PathSeparator)
-	if !strings.HasPrefix(path+string(os.PathSeparator), base) {
-		return "", fmt.Errorf("local: key escapes base path: %s", key)
+	path, err := document.ResolveKey(filepath.ToSlash(b.basePath), filepath.ToSlash(key))
+	if err != nil {
+		return "", fmt.Errorf("local: %w", err)
 	}
-	return path, nil
+	return filepath.FromSlash(path), nil
 }
 
 // Upload writes the content to <basePath>/<uuid>_<filename> and returns the
 // relative path as the storage key.
-func (b *Backend) Upload(_ context.Context, filename string, content io.Reader) (string, error) {
-	if err := os.MkdirAll(b.basePath, 0o750); err != nil {
-		return "", fmt.Errorf("local: creating base path: %w", err)
+func (b *Backend) Upload(ctx context.Context, filename string, content io.Reader) (string, error) {
+	return b.UploadAt(ctx, uuid.New().String()+"_"+filepath.Base(filename), content)
+}
+
+// UploadAt writes the content to <basePath>/<key>, creating missing
+// directories.
+func (b *Backend) UploadAt(_ context.Context, key string, content io.Reader) (string, error) {
+	dst, err := b.resolveKey(key)
+	if err != nil {
+		return "", err
 	}
 
-	key := uuid.New().String() + "_" + filepath.Base(filename)
-	dst := filepath.Join(b.basePath, key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return "", fmt.Errorf("local: creating directory: %w", err)
+	}
 
-	f, err := os.Create(dst)
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
 	if err != nil {
+		if os.IsExist(err) {
+			return "", fmt.Errorf("local: %w: %s", document.ErrKeyExists, key)
+		}
 		return "", fmt.Errorf("local: creating file: %w", err)
 	}
 	defer f.Close()
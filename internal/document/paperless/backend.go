@@ -3,9 +3,13 @@ package paperless
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,9 +17,14 @@ import (
 )
 
 // Config holds the Paperless-ngx connection details stored in document_backends.config.
+// Tags, Correspondent and DocumentType are Paperless object IDs applied to
+// every uploaded document; they are optional.
 type Config struct {
-	BaseURL string `json:"base_url"`
-	Token   string `json:"token"`
+	BaseURL       string `json:"base_url"`
+	Token         string `json:"token"`
+	Tags          []int  `json:"tags,omitempty"`
+	Correspondent *int   `json:"correspondent,omitempty"`
+	DocumentType  *int   `json:"document_type,omitempty"`
 }
 
 // BuildConfig serialises a Paperless config into raw JSON for storage.
@@ -23,10 +32,20 @@ func BuildConfig(baseURL, token string) (json.RawMessage, error) {
 	return json.Marshal(Config{BaseURL: baseURL, Token: token})
 }
 
+const (
+	// taskTimeout bounds how long Upload waits for Paperless to consume a document.
+	taskTimeout = 2 * time.Minute
+	// taskPollInterval is the delay between task status checks.
+	taskPollInterval = time.Second
+)
+
 type Backend struct {
-	client  *http.Client
-	baseURL string
-	token   string
+	client        *http.Client
+	baseURL       string
+	token         string
+	tags          []int
+	correspondent *int
+	documentType  *int
 }
 
 // NewFromConfig creates a Paperless Backend from the JSONB config stored in the DB.
@@ -41,26 +60,33 @@ func NewFromConfig(raw json.RawMessage) (document.Backend, error) {
 	}
 
 	return &Backend{
-		client:  &http.Client{Timeout: 30 * time.Second},
-		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
-		token:   cfg.Token,
+		client:        &http.Client{Timeout: 30 * time.Second},
+		baseURL:       strings.TrimRight(cfg.BaseURL, "/"),
+		token:         cfg.Token,
+		tags:          cfg.Tags,
+		correspondent: cfg.Correspondent,
+		documentType:  cfg.DocumentType,
 	}, nil
 }
 
 func (b *Backend) Type() string { return "paperless" }
 
-// Download retrieves a document by its Paperless numeric ID.
+// ParsesContent reports true: Paperless only consumes files it can OCR and
+// index, so it cannot take encrypted uploads.
+func (b *Backend) ParsesContent() bool { return true }
+
+// Download retrieves a document by its Paperless numeric ID. It asks for the
+// original file: by default Paperless serves its archived PDF/A rendition,
+// whose bytes differ from what was uploaded.
 func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, error) {
-	url := fmt.Sprintf("%s/api/documents/%s/download/", b.baseURL, key)
+	url := fmt.Sprintf("%s/api/documents/%s/download/?original=true", b.baseURL, key)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	if b.token != "" {
-		req.Header.Set("Authorization", "Token "+b.token)
-	}
+	b.authorize(req)
 
 	resp, err := b.client.Do(req)
 	if err != nil {
@@ -75,12 +101,221 @@ func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, erro
 	return resp.Body, nil
 }
 
-// Upload is not yet implemented; added in Phase 1 (document upload).
-func (b *Backend) Upload(_ context.Context, _ string, _ io.Reader) (string, error) {
-	return "", fmt.Errorf("paperless: upload not yet implemented")
+// Upload posts the document to /api/documents/post_document/ and waits for the
+// consumption task to finish. The key is the ID of the created Paperless document.
+// Content is streamed to Paperless rather than buffered.
+func (b *Backend) Upload(ctx context.Context, filename string, content io.Reader) (string, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		pw.CloseWithError(b.writeUploadForm(mw, filename, content))
+	}()
+
+	url := b.baseURL + "/api/documents/post_document/"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, pr)
+	if err != nil {
+		pr.Close()
+		return "", fmt.Errorf("paperless: creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	b.authorize(req)
+
+	// Uploads of large scans can exceed the client's default timeout; the
+	// caller's context bounds them instead.
+	client := *b.client
+	client.Timeout = 0
+
+	resp, err := client.Do(req)
+	if err != nil {
+		pr.Close()
+		return "", fmt.Errorf("paperless: posting document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("paperless: unexpected status %d posting document: %s", resp.StatusCode, readSnippet(resp.Body))
+	}
+
+	// The response body is the consumption task ID as a JSON string.
+	var taskID string
+	if err := json.NewDecoder(resp.Body).Decode(&taskID); err != nil {
+		return "", fmt.Errorf("paperless: decoding task ID: %w", err)
+	}
+
+	if taskID == "" {
+		return "", errors.New("paperless: empty task ID")
+	}
+
+	return b.waitForDocument(ctx, taskID)
+}
+
+// writeUploadForm writes the multipart body of a post_document request.
+func (b *Backend) writeUploadForm(mw *multipart.Writer, filename string, content io.Reader) error {
+	for _, tag := range b.tags {
+		if err := mw.WriteField("tags", strconv.Itoa(tag)); err != nil {
+			return err
+		}
+	}
+
+	if b.correspondent != nil {
+		if err := mw.WriteField("correspondent", strconv.Itoa(*b.correspondent)); err != nil {
+			return err
+		}
+	}
+
+	if b.documentType != nil {
+		if err := mw.WriteField("document_type", strconv.Itoa(*b.documentType)); err != nil {
+			return err
+		}
+	}
+
+	part, err := mw.CreateFormFile("document", filename)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(part, content); err != nil {
+		return err
+	}
+
+	return mw.Close()
+}
+
+// task is the subset of a Paperless task object Upload needs.
+type task struct {
+	Status string `json:"status"`
+	Result string `json:"result"`
+	// RelatedDocument is the created document ID. Paperless has returned it
+	// both as a string and as a number across versions.
+	RelatedDocument json.RawMessage `json:"related_document"`
+}
+
+// waitForDocument polls the task until Paperless has consumed the document and
+// returns the new document's ID.
+func (b *Backend) waitForDocument(ctx context.Context, taskID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, taskTimeout)
+	defer cancel()
+
+	for {
+		t, err := b.getTask(ctx, taskID)
+		if err != nil {
+			return "", err
+		}
+
+		switch t.Status {
+		case "SUCCESS":
+			id := strings.Trim(string(t.RelatedDocument), `"`)
+			if id == "" || id == "null" {
+				return "", fmt.Errorf("paperless: task %s succeeded without a document", taskID)
+			}
+
+			return id, nil
+		case "FAILURE", "REVOKED":
+			return "", fmt.Errorf("paperless: consuming document failed: %s", t.Result)
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("paperless: waiting for task %s: %w", taskID, ctx.Err())
+		case <-time.After(taskPollInterval):
+		}
+	}
+}
+
+func (b *Backend) getTask(ctx context.Context, taskID string) (*task, error) {
+	endpoint := b.baseURL + "/api/tasks/?" + url.Values{"task_id": {taskID}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("paperless: creating request: %w", err)
+	}
+
+	b.authorize(req)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("paperless: getting task %s: %w", taskID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("paperless: unexpected status %d for task %s", resp.StatusCode, taskID)
+	}
+
+	var tasks []task
+	if err := json.NewDecoder(resp.Body).Decode(&tasks); err != nil {
+		return nil, fmt.Errorf("paperless: decoding task %s: %w", taskID, err)
+	}
+
+	// The task may not be registered yet right after posting.
+	if len(tasks) == 0 {
+		return &task{Status: "PENDING"}, nil
+	}
+
+	return &tasks[0], nil
+}
+
+// Delete removes the document from Paperless. A document that is already gone
+// is not an error.
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	url := fmt.Sprintf("%s/api/documents/%s/", b.baseURL, key)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("paperless: creating request: %w", err)
+	}
+
+	b.authorize(req)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("paperless: deleting document %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("paperless: unexpected status %d deleting document %s", resp.StatusCode, key)
+	}
+}
+
+// CheckHealth lists a single document to check the URL and token. Unlike the
+// probe other backends get, it creates nothing: a probe upload would go
+// through Paperless's consumption, OCR and mail rules like any document.
+func (b *Backend) CheckHealth(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/api/documents/?page_size=1", nil)
+	if err != nil {
+		return fmt.Errorf("paperless: creating request: %w", err)
+	}
+
+	b.authorize(req)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("paperless: listing documents: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("paperless: unexpected status %d listing documents: %s", resp.StatusCode, readSnippet(resp.Body))
+	}
+
+	return nil
+}
+
+func (b *Backend) authorize(req *http.Request) {
+	if b.token != "" {
+		req.Header.Set("Authorization", "Token "+b.token)
+	}
 }
 
-// Delete is not yet implemented; added in Phase 1.
-func (b *Backend) Delete(_ context.Context, _ string) error {
-	return fmt.Errorf("paperless: delete not yet implemented")
+// readSnippet returns the start of an error response body for diagnostics.
+func readSnippet(r io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(r, 512))
+	return strings.TrimSpace(string(data))
 }
@@ -0,0 +1,239 @@
+package paperless_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/document/paperless"
+)
+
+// fakePaperless is a minimal stand-in for the Paperless-ngx API endpoints the
+// backend uses. Consumed documents are kept in memory by ID.
+type fakePaperless struct {
+	t *testing.T
+
+	mu         sync.Mutex
+	docs       map[string]string
+	form       map[string][]string
+	taskID     string
+	taskStatus string
+}
+
+func newFakePaperless(t *testing.T) (*fakePaperless, *httptest.Server) {
+	f := &fakePaperless{t: t, docs: make(map[string]string), taskID: "task-1", taskStatus: "SUCCESS"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/documents/post_document/", f.postDocument)
+	mux.HandleFunc("GET /api/tasks/", f.getTask)
+	mux.HandleFunc("GET /api/documents/{id}/download/", f.download)
+	mux.HandleFunc("DELETE /api/documents/{id}/", f.delete)
+	mux.HandleFunc("GET /api/documents/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `{"count":0,"results":[]}`)
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return f, srv
+}
+
+func (f *fakePaperless) postDocument(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("document")
+	if !assert.NoError(f.t, err) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	content, _ := io.ReadAll(file)
+
+	f.mu.Lock()
+	f.docs["42"] = header.Filename + ":" + string(content)
+	f.form = r.MultipartForm.Value
+	f.mu.Unlock()
+
+	_ = json.NewEncoder(w).Encode(f.taskID)
+}
+
+func (f *fakePaperless) getTask(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	taskID, status := f.taskID, f.taskStatus
+	f.mu.Unlock()
+
+	assert.Equal(f.t, taskID, r.URL.Query().Get("task_id"))
+
+	task := map[string]any{"task_id": taskID, "status": status}
+	if status == "SUCCESS" {
+		task["related_document"] = 42
+	} else {
+		task["result"] = "duplicate document"
+	}
+
+	_ = json.NewEncoder(w).Encode([]any{task})
+}
+
+func (f *fakePaperless) download(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	doc, ok := f.docs[r.PathValue("id")]
+	f.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Like Paperless, serve the archived rendition unless the original is
+	// asked for.
+	if r.URL.Query().Get("original") != "true" {
+		doc = "archived:" + doc
+	}
+
+	_, _ = io.WriteString(w, doc)
+}
+
+func (f *fakePaperless) delete(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.docs[r.PathValue("id")]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	delete(f.docs, r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newBackend(t *testing.T, cfg paperless.Config) document.Backend {
+	raw, err := json.Marshal(cfg)
+	require.NoError(t, err)
+
+	b, err := paperless.NewFromConfig(raw)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestBackend_UploadDownloadDelete(t *testing.T) {
+	fake, srv := newFakePaperless(t)
+	correspondent, docType := 7, 3
+
+	b := newBackend(t, paperless.Config{
+		BaseURL:       srv.URL + "/",
+		Token:         "secret",
+		Tags:          []int{1, 2},
+		Correspondent: &correspondent,
+		DocumentType:  &docType,
+	})
+	ctx := context.Background()
+
+	key, err := b.Upload(ctx, "invoice.pdf", strings.NewReader("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Equal(t, "42", key)
+
+	assert.Equal(t, []string{"1", "2"}, fake.form["tags"])
+	assert.Equal(t, []string{"7"}, fake.form["correspondent"])
+	assert.Equal(t, []string{"3"}, fake.form["document_type"])
+
+	rc, err := b.Download(ctx, key)
+	require.NoError(t, err)
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "invoice.pdf:%PDF-1.7", string(content))
+
+	require.NoError(t, b.Delete(ctx, key))
+	assert.Empty(t, fake.docs)
+
+	assert.NoError(t, b.Delete(ctx, key), "deleting a missing document is not an error")
+}
+
+func TestBackend_DownloadOriginal(t *testing.T) {
+	fake, srv := newFakePaperless(t)
+	b := newBackend(t, paperless.Config{BaseURL: srv.URL, Token: "secret"})
+	ctx := context.Background()
+
+	fake.docs["42"] = "%PDF-1.7 original"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/documents/42/download/", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Token secret")
+
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	archived, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	rc, err := b.Download(ctx, "42")
+	require.NoError(t, err)
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+
+	// The stored checksum is of the uploaded bytes, so only the original
+	// verifies.
+	assert.NotEqual(t, sha256.Sum256(archived), sha256.Sum256(content))
+	assert.Equal(t, sha256.Sum256([]byte("%PDF-1.7 original")), sha256.Sum256(content))
+}
+
+func TestBackend_UploadTaskFailure(t *testing.T) {
+	fake, srv := newFakePaperless(t)
+	fake.taskStatus = "FAILURE"
+
+	b := newBackend(t, paperless.Config{BaseURL: srv.URL, Token: "secret"})
+
+	_, err := b.Upload(context.Background(), "invoice.pdf", strings.NewReader("%PDF-1.7"))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate document")
+}
+
+func TestBackend_UploadEscapesTaskID(t *testing.T) {
+	fake, srv := newFakePaperless(t)
+	fake.taskID = "a&task_id=b #1"
+
+	b := newBackend(t, paperless.Config{BaseURL: srv.URL, Token: "secret"})
+
+	key, err := b.Upload(context.Background(), "invoice.pdf", strings.NewReader("%PDF-1.7"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "42", key)
+}
+
+func TestBackend_UploadUnauthorized(t *testing.T) {
+	_, srv := newFakePaperless(t)
+
+	b := newBackend(t, paperless.Config{BaseURL: srv.URL, Token: "wrong"})
+
+	_, err := b.Upload(context.Background(), "invoice.pdf", strings.NewReader("%PDF-1.7"))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestBackend_CheckHealth(t *testing.T) {
+	fake, srv := newFakePaperless(t)
+	ctx := context.Background()
+
+	require.NoError(t, document.CheckHealth(ctx, newBackend(t, paperless.Config{BaseURL: srv.URL, Token: "secret"})))
+	assert.Empty(t, fake.docs, "the check uploads nothing")
+
+	err := document.CheckHealth(ctx, newBackend(t, paperless.Config{BaseURL: srv.URL, Token: "wrong"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
@@ -0,0 +1,453 @@
+package pdftext
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// maxDecoded bounds the size of a decoded stream, as a guard against
+// compression bombs.
+const maxDecoded = 64 << 20
+
+const (
+	// scanChunk is how much of the file is read at a time while searching it.
+	scanChunk = 1 << 20
+	// scanOverlap is kept from one chunk to the next so that object headers
+	// across a chunk boundary are found; they are far shorter.
+	scanOverlap = 256
+	// objectWindow is how much is read to parse an object at first; the window
+	// doubles until the object fits, up to maxObjectSize. Stream data is not
+	// part of it.
+	objectWindow  = 4 << 10
+	maxObjectSize = 4 << 20
+)
+
+// stream is a dictionary with the location of the raw, still encoded, bytes
+// that follow it. They are read when the stream is decoded.
+type stream struct {
+	dict dict
+	off  int
+	n    int
+}
+
+// file is the object table of a PDF. Objects are located by scanning for
+// "N G obj" rather than through the cross-reference table, which is often
+// damaged in generated files; later definitions win, as with incremental
+// updates. Only the scan reads the whole file, a chunk at a time; objects are
+// read from r when first used.
+type file struct {
+	r         io.ReaderAt
+	size      int
+	offsets   map[int]int // object number → offset of its "N G obj" header
+	objects   map[int]any // parsed objects, including those from object streams
+	loading   map[int]bool
+	encrypted bool  // the file has an encryption dictionary
+	err       error // first parse error that makes the result unreliable
+}
+
+var objHeader = regexp.MustCompile(`(?m)(?:^|[^0-9])(\d+)\s+(\d+)\s+obj\b`)
+
+func openFile(r io.ReaderAt, size int64) (*file, error) {
+	f := &file{
+		r:       r,
+		size:    int(size),
+		offsets: make(map[int]int),
+		objects: make(map[int]any),
+		loading: make(map[int]bool),
+	}
+
+	head, err := f.read(0, 1024)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n"), []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+
+	if err := f.scan(); err != nil {
+		return nil, err
+	}
+
+	if len(f.offsets) == 0 {
+		return nil, fmt.Errorf("%w: no objects found", ErrNotPDF)
+	}
+
+	f.loadObjectStreams()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return f, nil
+}
+
+// read returns the n bytes at off, fewer at the end of the file.
+func (f *file) read(off, n int) ([]byte, error) {
+	n = min(n, f.size-off)
+	if n <= 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, n)
+	if _, err := f.r.ReadAt(buf, int64(off)); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	return buf, nil
+}
+
+// scan records the offset of every object header and whether the file has
+// an encryption dictionary.
+func (f *file) scan() error {
+	var carry []byte // the end of the previous chunk
+
+	for pos := 0; pos < f.size; pos += scanChunk {
+		chunk, err := f.read(pos, scanChunk)
+		if err != nil {
+			return err
+		}
+
+		buf := append(carry, chunk...)
+		base := pos - len(carry)
+
+		// Headers starting in the overlap are left for the next chunk, which
+		// also sees what follows them.
+		limit := len(buf)
+		if pos+len(chunk) < f.size {
+			limit -= scanOverlap
+		}
+
+		for _, m := range objHeader.FindAllSubmatchIndex(buf, -1) {
+			// A number at the very start of a later chunk continues one
+			// found in the previous chunk.
+			if m[2] >= limit || (carry != nil && m[2] == 0) {
+				continue
+			}
+
+			num, err := strconv.Atoi(string(buf[m[2]:m[3]]))
+			if err != nil {
+				continue
+			}
+
+			f.offsets[num] = base + m[2]
+		}
+
+		if encryptKey.Match(buf) {
+			f.encrypted = true
+		}
+
+		// Keep the byte before the overlap so a header there is known to
+		// start a number.
+		carry = bytes.Clone(buf[max(limit-1, 0):])
+	}
+
+	return nil
+}
+
+// index returns the offset of the first sep at or after from, or -1.
+func (f *file) index(from int, sep []byte) int {
+	for pos := from; pos < f.size; pos += scanChunk {
+		chunk, err := f.read(pos, scanChunk+len(sep)-1)
+		if err != nil {
+			f.fail(err)
+			return -1
+		}
+
+		if i := bytes.Index(chunk, sep); i >= 0 {
+			return pos + i
+		}
+	}
+
+	return -1
+}
+
+// fail records err, keeping the first one.
+func (f *file) fail(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// object returns object num, parsing it on first use. Missing objects are nil.
+func (f *file) object(num int) any {
+	if obj, ok := f.objects[num]; ok {
+		return obj
+	}
+
+	off, ok := f.offsets[num]
+	if !ok || f.loading[num] {
+		return nil
+	}
+
+	f.loading[num] = true
+	defer delete(f.loading, num)
+
+	obj := f.parseAt(off)
+	f.objects[num] = obj
+
+	return obj
+}
+
+// parseAt parses the indirect object whose header starts at off.
+func (f *file) parseAt(off int) any {
+	for n := objectWindow; ; n *= 2 {
+		data, err := f.read(off, n)
+		if err != nil {
+			f.fail(err)
+			return nil
+		}
+
+		whole := off+len(data) >= f.size || n >= maxObjectSize
+
+		if obj, ok := f.parseIn(data, off, whole); ok {
+			return obj
+		}
+	}
+}
+
+// parseIn parses the object at the start of data, read from off. Unless data
+// is whole, it reports false if the object may continue past data.
+func (f *file) parseIn(data []byte, off int, whole bool) (any, bool) {
+	l := &lexer{data: data}
+
+	// "N G obj"
+	l.next()
+	l.next()
+	l.next()
+
+	obj, ok := l.parseObject()
+	if !ok {
+		if !whole && l.pos >= len(data) {
+			return nil, false
+		}
+
+		f.fail(l.err)
+		return nil, true
+	}
+
+	d, isDict := obj.(dict)
+	if !isDict {
+		return obj, whole || l.pos < len(data)
+	}
+
+	// The data starts after the end-of-line that follows the keyword, so
+	// that must be read too.
+	t := l.next()
+	if !whole && l.pos+2 > len(data) {
+		return nil, false
+	}
+
+	if t.kind != tokKeyword || t.value != keyword("stream") {
+		return d, true
+	}
+
+	start := l.pos
+	if start < len(data) && data[start] == '\r' {
+		start++
+	}
+	if start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	start += off
+
+	return &stream{dict: d, off: start, n: f.streamLength(d, start)}, true
+}
+
+// streamLength returns the length of the stream data that starts at start.
+func (f *file) streamLength(d dict, start int) int {
+	if n, ok := f.resolve(d["Length"]).(float64); ok {
+		end := start + int(n)
+		if n >= 0 && end <= f.size {
+			if tail, err := f.read(end, 32); err == nil && bytes.Contains(tail, []byte("endstream")) {
+				return int(n)
+			}
+		}
+	}
+
+	// The length is missing or wrong: fall back to the end marker.
+	end := f.index(start, []byte("endstream"))
+	if end < 0 {
+		return f.size - start
+	}
+
+	tail, err := f.read(max(start, end-2), end-max(start, end-2))
+	if err != nil {
+		return end - start
+	}
+
+	return end - start - (len(tail) - len(bytes.TrimRight(tail, "\r\n")))
+}
+
+// raw reads the still encoded data of s.
+func (f *file) raw(s *stream) ([]byte, error) {
+	return f.read(s.off, s.n)
+}
+
+// rawReader returns a reader over the still encoded data of s.
+func (f *file) rawReader(s *stream) io.Reader {
+	return io.NewSectionReader(f.r, int64(s.off), int64(s.n))
+}
+
+// resolve follows references until it reaches a direct object.
+func (f *file) resolve(v any) any {
+	for range 32 {
+		r, ok := v.(ref)
+		if !ok {
+			return v
+		}
+
+		v = f.object(r.num)
+	}
+
+	return nil
+}
+
+func (f *file) dict(v any) dict {
+	switch o := f.resolve(v).(type) {
+	case dict:
+		return o
+	case *stream:
+		return o.dict
+	}
+
+	return nil
+}
+
+// loadObjectStreams adds the objects compressed into object streams (PDF 1.5)
+// that are not also defined directly.
+func (f *file) loadObjectStreams() {
+	for num := range f.offsets {
+		s, ok := f.object(num).(*stream)
+		if !ok || s.dict["Type"] != name("ObjStm") {
+			continue
+		}
+
+		data, err := f.decode(s)
+		if err != nil {
+			continue
+		}
+
+		n, _ := s.dict["N"].(float64)
+		first, _ := s.dict["First"].(float64)
+
+		if int(first) > len(data) {
+			continue
+		}
+
+		l := &lexer{data: data}
+		header := make([]int, 0, 2*int(n))
+
+		for range 2 * int(n) {
+			t := l.next()
+
+			v, ok := t.value.(float64)
+			if !ok {
+				break
+			}
+
+			header = append(header, int(v))
+		}
+
+		for i := 0; i+1 < len(header); i += 2 {
+			objNum, off := header[i], int(first)+header[i+1]
+
+			if _, direct := f.offsets[objNum]; direct || off >= len(data) {
+				continue
+			}
+
+			ol := &lexer{data: data, pos: off}
+			if obj, ok := ol.parseObject(); ok {
+				f.objects[objNum] = obj
+			}
+
+			f.fail(ol.err)
+		}
+	}
+}
+
+// decode applies the stream's filters.
+func (f *file) decode(s *stream) ([]byte, error) {
+	var filters []any
+
+	switch v := f.resolve(s.dict["Filter"]).(type) {
+	case name:
+		filters = []any{v}
+	case array:
+		filters = v
+	}
+
+	data, err := f.raw(s)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fv := range filters {
+		var err error
+
+		switch f.resolve(fv) {
+		case name("FlateDecode"), name("Fl"):
+			data, err = inflate(data)
+		case name("ASCIIHexDecode"), name("AHx"):
+			data = decodeHex(bytes.TrimSuffix(bytes.TrimSpace(data), []byte(">")))
+		case name("ASCII85Decode"), name("A85"):
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("%w: %v", errUnsupportedFilter, fv)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+var errUnsupportedFilter = errors.New("unsupported filter")
+
+// inflate decompresses zlib data, tolerating truncated streams and streams
+// written as raw deflate without the zlib header.
+func inflate(data []byte) ([]byte, error) {
+	var r io.Reader
+
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		r = flate.NewReader(bytes.NewReader(data))
+	} else {
+		r = zr
+	}
+
+	out, err := io.ReadAll(io.LimitReader(r, maxDecoded))
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && len(out) == 0 {
+		return nil, fmt.Errorf("inflating stream: %w", err)
+	}
+
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+
+	// "z" expands to four bytes, so size the buffer for the worst case.
+	out := make([]byte, 4*len(data)+4)
+
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, fmt.Errorf("decoding ascii85: %w", err)
+	}
+
+	return out[:n], nil
+}
@@ -0,0 +1,389 @@
+package pdftext
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// font turns the codes of shown strings into text.
+type font struct {
+	// cids is set for composite (Type0) fonts, whose codes are two bytes
+	// unless the ToUnicode map declares other code space ranges.
+	cids  bool
+	toUni *cmap
+	enc   *[256]rune
+}
+
+func (f *file) loadFont(v any) *font {
+	d := f.dict(v)
+	ft := &font{enc: &winAnsi}
+
+	if d == nil {
+		return ft
+	}
+
+	ft.cids = d["Subtype"] == name("Type0")
+
+	if s, ok := f.resolve(d["ToUnicode"]).(*stream); ok {
+		if data, err := f.decode(s); err == nil {
+			var cmErr error
+
+			ft.toUni, cmErr = parseCMap(data)
+			f.fail(cmErr)
+		}
+	}
+
+	if !ft.cids {
+		ft.enc = f.encoding(d["Encoding"])
+	}
+
+	return ft
+}
+
+// encoding builds a simple font's code → rune table from its Encoding entry.
+func (f *file) encoding(v any) *[256]rune {
+	switch e := f.resolve(v).(type) {
+	case name:
+		return baseEncoding(e)
+	case dict:
+		base, _ := f.resolve(e["BaseEncoding"]).(name)
+		enc := *baseEncoding(base)
+
+		diffs, _ := f.resolve(e["Differences"]).(array)
+		code := 0
+
+		for _, item := range diffs {
+			switch it := f.resolve(item).(type) {
+			case float64:
+				code = int(it)
+			case name:
+				if code >= 0 && code < 256 {
+					if r, ok := glyphRune(string(it)); ok {
+						enc[code] = r
+					}
+				}
+				code++
+			}
+		}
+
+		return &enc
+	}
+
+	return &winAnsi
+}
+
+func baseEncoding(n name) *[256]rune {
+	if n == "MacRomanEncoding" {
+		return &macRoman
+	}
+
+	return &winAnsi
+}
+
+// decode converts a shown string to text.
+func (ft *font) decode(s []byte) string {
+	var sb strings.Builder
+
+	for len(s) > 0 {
+		n := 1
+		if ft.cids {
+			n = 2
+		}
+
+		if ft.toUni != nil {
+			n = ft.toUni.codeLen(s, n)
+		}
+
+		n = min(n, len(s))
+		code := s[:n]
+		s = s[n:]
+
+		if ft.toUni != nil {
+			if text, ok := ft.toUni.lookup(code); ok {
+				sb.WriteString(text)
+				continue
+			}
+		}
+
+		if !ft.cids {
+			if r := ft.enc[code[0]]; r != 0 {
+				sb.WriteRune(r)
+			}
+		}
+		// Composite fonts without a ToUnicode entry carry glyph IDs only,
+		// which cannot be mapped back to text.
+	}
+
+	return sb.String()
+}
+
+// cmap is the part of a ToUnicode CMap (Adobe technical note 5411) needed to
+// map character codes to text.
+type cmap struct {
+	spaces []codeRange
+	chars  map[string]string
+	ranges []bfRange
+}
+
+type codeRange struct{ lo, hi []byte }
+
+type bfRange struct {
+	lo, hi uint32
+	n      int
+	base   []rune   // destination of lo; later codes increment the last rune
+	list   []string // explicit destinations, when given as an array
+}
+
+func parseCMap(data []byte) (*cmap, error) {
+	cm := &cmap{chars: make(map[string]string)}
+	l := &lexer{data: data}
+
+	var operands []any
+
+	for {
+		obj, ok := l.parseObject()
+		if !ok {
+			return cm, l.err
+		}
+
+		kw, isKw := obj.(keyword)
+		if !isKw {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch kw {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].([]byte)
+				hi, ok2 := operands[i+1].([]byte)
+
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					cm.spaces = append(cm.spaces, codeRange{lo, hi})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok := operands[i].([]byte)
+				if !ok {
+					continue
+				}
+
+				switch dst := operands[i+1].(type) {
+				case []byte:
+					cm.chars[string(src)] = utf16String(dst)
+				case name:
+					if r, ok := glyphRune(string(dst)); ok {
+						cm.chars[string(src)] = string(r)
+					}
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].([]byte)
+				hi, ok2 := operands[i+1].([]byte)
+
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+					continue
+				}
+
+				r := bfRange{lo: codeValue(lo), hi: codeValue(hi), n: len(lo)}
+				if r.hi < r.lo {
+					continue
+				}
+
+				switch dst := operands[i+2].(type) {
+				case []byte:
+					r.base = []rune(utf16String(dst))
+				case array:
+					for _, d := range dst {
+						b, _ := d.([]byte)
+						r.list = append(r.list, utf16String(b))
+					}
+				}
+
+				cm.ranges = append(cm.ranges, r)
+			}
+		}
+
+		operands = operands[:0]
+	}
+}
+
+// codeLen returns the length of the code at the start of s: the length of the
+// code space range it falls in, or def if there are none.
+func (cm *cmap) codeLen(s []byte, def int) int {
+	for _, sp := range cm.spaces {
+		n := len(sp.lo)
+		if n > len(s) {
+			continue
+		}
+
+		in := true
+
+		for i := range n {
+			if s[i] < sp.lo[i] || s[i] > sp.hi[i] {
+				in = false
+				break
+			}
+		}
+
+		if in {
+			return n
+		}
+	}
+
+	return def
+}
+
+func (cm *cmap) lookup(code []byte) (string, bool) {
+	if text, ok := cm.chars[string(code)]; ok {
+		return text, true
+	}
+
+	v := codeValue(code)
+
+	for _, r := range cm.ranges {
+		if r.n != len(code) || v < r.lo || v > r.hi {
+			continue
+		}
+
+		off := int(v - r.lo)
+
+		if r.list != nil {
+			if off < len(r.list) {
+				return r.list[off], true
+			}
+
+			continue
+		}
+
+		if len(r.base) == 0 {
+			continue
+		}
+
+		out := append([]rune(nil), r.base...)
+		out[len(out)-1] += rune(off)
+
+		return string(out), true
+	}
+
+	return "", false
+}
+
+func codeValue(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+
+	return v
+}
+
+func utf16String(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+
+	return string(utf16.Decode(u))
+}
+
+// glyphRune maps a glyph name to its character: "uniXXXX" and "uXXXX" names,
+// single letters and digits, and the names in glyphNames. Suffixes such as
+// ".sc" are ignored.
+func glyphRune(g string) (rune, bool) {
+	if i := strings.IndexByte(g, '.'); i > 0 {
+		g = g[:i]
+	}
+
+	if r, ok := glyphNames[g]; ok {
+		return r, true
+	}
+
+	if len(g) == 1 {
+		return rune(g[0]), true
+	}
+
+	for _, prefix := range []string{"uni", "u"} {
+		if hex, ok := strings.CutPrefix(g, prefix); ok && len(hex) >= 4 && len(hex) <= 6 {
+			if v, err := strconv.ParseUint(hex[:4], 16, 32); err == nil && prefix == "uni" {
+				return rune(v), true
+			}
+
+			if v, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				return rune(v), true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$',
+	"percent": '%', "ampersand": '&', "quotesingle": '\'', "quoteright": '’', "quoteleft": '‘',
+	"parenleft": '(', "parenright": ')', "asterisk": '*', "plus": '+', "comma": ',',
+	"hyphen": '-', "minus": '-', "period": '.', "slash": '/', "colon": ':', "semicolon": ';',
+	"less": '<', "equal": '=', "greater": '>', "question": '?', "at": '@',
+	"bracketleft": '[', "backslash": '\\', "bracketright": ']', "underscore": '_',
+	"braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"Euro": '€', "euro": '€', "degree": '°', "ordfeminine": 'ª', "ordmasculine": 'º',
+	"endash": '–', "emdash": '—', "bullet": '•', "quotedblleft": '“', "quotedblright": '”',
+	"guillemotleft": '«', "guillemotright": '»', "section": '§', "copyright": '©',
+	"nbspace": ' ', "periodcentered": '·', "multiply": '×', "sterling": '£',
+	"aacute": 'á', "agrave": 'à', "acircumflex": 'â', "atilde": 'ã', "adieresis": 'ä',
+	"Aacute": 'Á', "Agrave": 'À', "Acircumflex": 'Â', "Atilde": 'Ã', "Adieresis": 'Ä',
+	"eacute": 'é', "egrave": 'è', "ecircumflex": 'ê', "edieresis": 'ë',
+	"Eacute": 'É', "Egrave": 'È', "Ecircumflex": 'Ê', "Edieresis": 'Ë',
+	"iacute": 'í', "igrave": 'ì', "icircumflex": 'î', "idieresis": 'ï',
+	"Iacute": 'Í', "Igrave": 'Ì', "Icircumflex": 'Î', "Idieresis": 'Ï',
+	"oacute": 'ó', "ograve": 'ò', "ocircumflex": 'ô', "otilde": 'õ', "odieresis": 'ö',
+	"Oacute": 'Ó', "Ograve": 'Ò', "Ocircumflex": 'Ô', "Otilde": 'Õ', "Odieresis": 'Ö',
+	"uacute": 'ú', "ugrave": 'ù', "ucircumflex": 'û', "udieresis": 'ü',
+	"Uacute": 'Ú', "Ugrave": 'Ù', "Ucircumflex": 'Û', "Udieresis": 'Ü',
+	"ccedilla": 'ç', "Ccedilla": 'Ç', "ntilde": 'ñ', "Ntilde": 'Ñ',
+	"fi": 'ﬁ', "fl": 'ﬂ',
+}
+
+// winAnsi is the Windows-1252 code page, used for simple fonts without a
+// known encoding as well: StandardEncoding differs only in rarely used codes.
+var winAnsi = func() [256]rune {
+	var t [256]rune
+
+	for i := 0x20; i < 0x7f; i++ {
+		t[i] = rune(i)
+	}
+
+	for i := 0xa0; i < 0x100; i++ {
+		t[i] = rune(i)
+	}
+
+	for i, r := range []rune("€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ") {
+		t[0x80+i] = r
+	}
+
+	t[0xad] = '-' // soft hyphen
+	t['\t'] = '\t'
+
+	return t
+}()
+
+// macRoman is the Mac OS Roman code page.
+var macRoman = func() [256]rune {
+	var t [256]rune
+
+	for i := 0x20; i < 0x7f; i++ {
+		t[i] = rune(i)
+	}
+
+	high := []rune("ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø¿¡¬√ƒ≈∆«»… ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+	for i, r := range high {
+		t[0x80+i] = r
+	}
+
+	return t
+}()
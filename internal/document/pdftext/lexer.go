@@ -0,0 +1,384 @@
+package pdftext
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// This file tokenizes PDF syntax (ISO 32000-1, section 7.2) and parses the
+// objects the extractor needs. Content streams use the same lexer.
+
+// Object values: nil (null), bool, float64, name, []byte (string), array,
+// dict, ref and keyword.
+type (
+	name    string
+	array   []any
+	dict    map[name]any
+	keyword string
+	ref     struct{ num, gen int }
+)
+
+// maxDepth bounds the nesting of arrays and dictionaries. Real files stay
+// within a few levels; deeper nesting would only exhaust the stack.
+const maxDepth = 256
+
+var errTooDeep = fmt.Errorf("%w: objects nested more than %d deep", ErrNotPDF, maxDepth)
+
+type lexer struct {
+	data  []byte
+	pos   int
+	depth int
+	err   error // set once nesting exceeds maxDepth; parsing stops
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+
+	return false
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+
+		switch {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token is one lexical element. Delimiters "<<", ">>", "[" and "]" are
+// returned as keywords.
+type token struct {
+	kind  int
+	value any
+}
+
+const (
+	tokEOF = iota
+	tokValue
+	tokKeyword
+)
+
+func (l *lexer) next() token {
+	l.skipSpace()
+
+	if l.pos >= len(l.data) {
+		return token{kind: tokEOF}
+	}
+
+	c := l.data[l.pos]
+
+	switch {
+	case c == '/':
+		return token{tokValue, l.readName()}
+	case c == '(':
+		return token{tokValue, l.readLiteral()}
+	case c == '<' && l.peek(1) == '<':
+		l.pos += 2
+		return token{tokKeyword, keyword("<<")}
+	case c == '>' && l.peek(1) == '>':
+		l.pos += 2
+		return token{tokKeyword, keyword(">>")}
+	case c == '<':
+		return token{tokValue, l.readHex()}
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return token{tokKeyword, keyword(c)}
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		start := l.pos
+		l.pos++
+
+		for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelim(l.data[l.pos]) {
+			l.pos++
+		}
+
+		if f, err := strconv.ParseFloat(string(l.data[start:l.pos]), 64); err == nil {
+			return token{tokValue, f}
+		}
+
+		return token{tokKeyword, keyword(l.data[start:l.pos])}
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelim(l.data[l.pos]) {
+		l.pos++
+	}
+
+	if l.pos == start {
+		// A stray delimiter such as ')' or '>'.
+		l.pos++
+	}
+
+	switch kw := string(l.data[start:l.pos]); kw {
+	case "true":
+		return token{tokValue, true}
+	case "false":
+		return token{tokValue, false}
+	case "null":
+		return token{tokValue, nil}
+	default:
+		return token{tokKeyword, keyword(kw)}
+	}
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.pos+n < len(l.data) {
+		return l.data[l.pos+n]
+	}
+
+	return 0
+}
+
+func (l *lexer) readName() name {
+	l.pos++ // '/'
+
+	var b []byte
+
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelim(l.data[l.pos]) {
+		c := l.data[l.pos]
+
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+
+				continue
+			}
+		}
+
+		b = append(b, c)
+		l.pos++
+	}
+
+	return name(b)
+}
+
+func (l *lexer) readLiteral() []byte {
+	l.pos++ // '('
+
+	var b []byte
+
+	depth := 1
+
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return b
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return b
+			}
+
+			e := l.data[l.pos]
+			l.pos++
+
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+
+		b = append(b, c)
+	}
+
+	return b
+}
+
+func (l *lexer) readHex() []byte {
+	l.pos++ // '<'
+
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		end = len(l.data) - l.pos
+	}
+
+	raw := l.data[l.pos : l.pos+end]
+	l.pos += end + 1
+
+	return decodeHex(raw)
+}
+
+// decodeHex decodes hex digits, ignoring white space. A missing final digit
+// is taken as 0.
+func decodeHex(raw []byte) []byte {
+	out := make([]byte, 0, len(raw)/2)
+
+	var (
+		v    byte
+		half bool
+	)
+
+	for _, c := range raw {
+		var d byte
+
+		switch {
+		case c >= '0' && c <= '9':
+			d = c - '0'
+		case c >= 'a' && c <= 'f':
+			d = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			d = c - 'A' + 10
+		default:
+			continue
+		}
+
+		if half {
+			out = append(out, v<<4|d)
+		} else {
+			v = d
+		}
+
+		half = !half
+	}
+
+	if half {
+		out = append(out, v<<4)
+	}
+
+	return out
+}
+
+// parseObject reads one object. Keywords other than those that open arrays and
+// dictionaries are returned as keyword values, so a caller parsing a content
+// stream sees its operators. Past maxDepth it sets l.err and reports no
+// object.
+func (l *lexer) parseObject() (any, bool) {
+	if l.err != nil {
+		return nil, false
+	}
+
+	tok := l.next()
+
+	switch tok.kind {
+	case tokEOF:
+		return nil, false
+	case tokValue:
+		if f, ok := tok.value.(float64); ok {
+			return l.maybeRef(f), true
+		}
+
+		return tok.value, true
+	}
+
+	kw := tok.value.(keyword)
+	if kw == "[" || kw == "<<" {
+		if l.depth == maxDepth {
+			l.err = errTooDeep
+			return nil, false
+		}
+
+		l.depth++
+		defer func() { l.depth-- }()
+	}
+
+	switch kw {
+	case "[":
+		var arr array
+
+		for {
+			save := l.pos
+
+			if t := l.next(); t.kind == tokEOF || (t.kind == tokKeyword && t.value == keyword("]")) {
+				return arr, true
+			}
+
+			l.pos = save
+
+			v, ok := l.parseObject()
+			if !ok {
+				return arr, l.err == nil
+			}
+
+			arr = append(arr, v)
+		}
+	case "<<":
+		d := make(dict)
+
+		for {
+			t := l.next()
+			if t.kind == tokEOF || (t.kind == tokKeyword && t.value == keyword(">>")) {
+				return d, true
+			}
+
+			key, ok := t.value.(name)
+			if !ok {
+				continue
+			}
+
+			v, ok := l.parseObject()
+			if !ok {
+				return d, l.err == nil
+			}
+
+			d[key] = v
+		}
+	default:
+		return kw, true
+	}
+}
+
+// maybeRef turns "num gen R" into a ref, leaving other numbers alone.
+func (l *lexer) maybeRef(num float64) any {
+	save := l.pos
+
+	gen := l.next()
+	if g, ok := gen.value.(float64); ok && gen.kind == tokValue {
+		if r := l.next(); r.kind == tokKeyword && r.value == keyword("R") {
+			return ref{int(num), int(g)}
+		}
+	}
+
+	l.pos = save
+
+	return num
+}
@@ -0,0 +1,254 @@
+package pdftext
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+)
+
+// maxImagePixels bounds the size of the images decoded for a page.
+const maxImagePixels = 40 << 20
+
+// Page is the content of a page laid out for rendering, in points with the
+// origin at the bottom left of the page.
+type Page struct {
+	Width, Height float64
+	Texts         []Text
+	Images        []Image
+}
+
+// Text is a piece of text drawn with its baseline starting at X, Y.
+type Text struct {
+	X, Y, Size float64
+	Text       string
+}
+
+// Image is an image drawn into the rectangle of which X, Y is the bottom left
+// corner. Images in colour spaces or encodings that are not supported, such
+// as CMYK or JPEG 2000, are left out.
+type Image struct {
+	X, Y, Width, Height float64
+	Image               image.Image
+}
+
+// FirstPage returns the text and images of the first page, in drawing order,
+// reading the size bytes of the file from r.
+func FirstPage(r io.ReaderAt, size int64) (*Page, error) {
+	f, err := openFile(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.encrypted {
+		return nil, ErrEncrypted
+	}
+
+	pages := f.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: no pages found", ErrNotPDF)
+	}
+
+	p := pages[0]
+
+	// US Letter is the default page size when the MediaBox is missing.
+	box := [4]float64{0, 0, 612, 792}
+	if len(p.mediaBox) == 4 {
+		for i := range box {
+			box[i], _ = f.resolve(p.mediaBox[i]).(float64)
+		}
+	}
+
+	var images []Image
+
+	in := &interpreter{f: f, fonts: make(map[any]*font), images: &images}
+
+	for _, c := range p.contents {
+		in.run(c, p.resources, translate(-box[0], -box[1]), 0)
+	}
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	page := &Page{Width: box[2] - box[0], Height: box[3] - box[1], Images: images}
+
+	for _, r := range in.runs {
+		page.Texts = append(page.Texts, Text{X: r.x, Y: r.y, Size: r.size, Text: r.text})
+	}
+
+	return page, nil
+}
+
+// image collects an image XObject, drawn into the unit square mapped by ctm.
+func (in *interpreter) image(s *stream, ctm matrix) {
+	img := in.f.decodeImage(s)
+	if img == nil {
+		return
+	}
+
+	xs := []float64{ctm[4], ctm[0] + ctm[4], ctm[2] + ctm[4], ctm[0] + ctm[2] + ctm[4]}
+	ys := []float64{ctm[5], ctm[1] + ctm[5], ctm[3] + ctm[5], ctm[1] + ctm[3] + ctm[5]}
+
+	x0, x1 := minMax(xs)
+	y0, y1 := minMax(ys)
+
+	*in.images = append(*in.images, Image{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0, Image: img})
+}
+
+func minMax(v []float64) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, f := range v {
+		lo, hi = math.Min(lo, f), math.Max(hi, f)
+	}
+
+	return lo, hi
+}
+
+// decodeImage decodes a JPEG image, or one of 8-bit gray or RGB samples, or
+// returns nil.
+func (f *file) decodeImage(s *stream) image.Image {
+	if filter := f.resolve(s.dict["Filter"]); filter == name("DCTDecode") || filter == name("DCT") {
+		cfg, err := jpeg.DecodeConfig(f.rawReader(s))
+		if err != nil || cfg.Width*cfg.Height > maxImagePixels || cfg.ColorModel == color.CMYKModel {
+			return nil
+		}
+
+		img, err := jpeg.Decode(f.rawReader(s))
+		if err != nil {
+			return nil
+		}
+
+		return img
+	}
+
+	w, _ := f.resolve(s.dict["Width"]).(float64)
+	h, _ := f.resolve(s.dict["Height"]).(float64)
+	bpc, _ := f.resolve(s.dict["BitsPerComponent"]).(float64)
+
+	if w <= 0 || h <= 0 || w*h > maxImagePixels || bpc != 8 {
+		return nil
+	}
+
+	components := f.components(s.dict["ColorSpace"])
+	if components == 0 {
+		return nil
+	}
+
+	data, err := f.decode(s)
+	if err != nil {
+		return nil
+	}
+
+	width, height := int(w), int(h)
+	stride := width * components
+
+	if params := f.dict(s.dict["DecodeParms"]); params != nil {
+		if p, _ := f.resolve(params["Predictor"]).(float64); p >= 10 {
+			data = unpredictPNG(data, stride, components)
+		}
+	}
+
+	if len(data) < stride*height {
+		return nil
+	}
+
+	if components == 1 {
+		return &image.Gray{Pix: data[:stride*height], Stride: stride, Rect: image.Rect(0, 0, width, height)}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height; i++ {
+		copy(img.Pix[i*4:], data[i*3:i*3+3])
+		img.Pix[i*4+3] = 0xff
+	}
+
+	return img
+}
+
+// components returns the number of colour components of a gray or RGB colour
+// space, or 0 for others.
+func (f *file) components(v any) int {
+	switch cs := f.resolve(v).(type) {
+	case name:
+		switch cs {
+		case "DeviceGray", "G", "CalGray":
+			return 1
+		case "DeviceRGB", "RGB", "CalRGB":
+			return 3
+		}
+	case array:
+		if len(cs) == 2 && f.resolve(cs[0]) == name("ICCBased") {
+			if s, ok := f.resolve(cs[1]).(*stream); ok {
+				if n, _ := f.resolve(s.dict["N"]).(float64); n == 1 || n == 3 {
+					return int(n)
+				}
+			}
+		}
+
+		if len(cs) > 0 {
+			return f.components(cs[0])
+		}
+	}
+
+	return 0
+}
+
+// unpredictPNG reverses the PNG row filters, each row being prefixed with
+// its filter type.
+func unpredictPNG(data []byte, stride, bpp int) []byte {
+	out := make([]byte, 0, len(data))
+	prev := make([]byte, stride)
+
+	for len(data) >= stride+1 {
+		ft, row := data[0], data[1:stride+1]
+		data = data[stride+1:]
+
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+
+			switch ft {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += prev[i]
+			case 3:
+				row[i] += byte((int(left) + int(prev[i])) / 2)
+			case 4:
+				row[i] += paeth(left, prev[i], upLeft)
+			}
+		}
+
+		out = append(out, row...)
+		prev = row
+	}
+
+	return out
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,244 @@
+package pdftext_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document/pdftext"
+)
+
+// buildPDF assembles a PDF from numbered object bodies, with a valid
+// cross-reference table. Object 1 must be the catalog.
+func buildPDF(objects ...string) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+
+	offsets := make([]int, len(objects))
+
+	for i, body := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+func extract(data []byte) (string, error) {
+	return pdftext.Extract(bytes.NewReader(data), int64(len(data)))
+}
+
+func firstPage(data []byte) (*pdftext.Page, error) {
+	return pdftext.FirstPage(bytes.NewReader(data), int64(len(data)))
+}
+
+func streamObject(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	w := zlib.NewWriter(&buf)
+	_, err := w.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestExtract_SimpleFont(t *testing.T) {
+	content := `BT /F1 12 Tf 72 720 Td (Fatura FT A/123) Tj
+0 -20 Td [(Total a pagar:) -3000 (12,30 \200)] TJ
+ET
+BT /F1 12 Tf 72 760 Td (Pr\351dio Lda) Tj ET`
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R >>",
+		streamObject("", []byte(content)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	)
+
+	text, err := extract(data)
+	require.NoError(t, err)
+	assert.Equal(t, "Prédio Lda\nFatura FT A/123\nTotal a pagar: 12,30 €", text)
+}
+
+func TestExtract_CompressedObjectsAndType0Font(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <0049>
+<0002> <0056>
+endbfchar
+1 beginbfrange
+<0003> <0004> [<0041> <00C9>]
+endbfrange
+endcmap`
+
+	// "IVA" and then "AÉ" on the line below.
+	content := deflate(t, "BT /F1 10 Tf 1 0 0 1 50 500 Tm <000100020003> Tj 0 -14 TD <0003> Tj <0004> Tj ET")
+
+	// Objects 2 and 5 live in the object stream (object 6).
+	pages := "<< /Type /Pages /Kids [3 0 R] /Count 1 >> "
+	objStm := fmt.Sprintf("2 0 5 %d ", len(pages)) + pages +
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Foo /Encoding /Identity-H /ToUnicode 7 0 R >>"
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"null",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents [4 0 R] >>",
+		streamObject("/Filter /FlateDecode", content),
+		"null",
+		streamObject("/Type /ObjStm /N 2 /First 9 /Filter /FlateDecode", deflate(t, objStm)),
+		streamObject("", []byte(cmap)),
+	)
+
+	// Objects 2 and 5 are only defined by the object stream.
+	data = bytes.Replace(data, []byte("2 0 obj\nnull\nendobj\n"), nil, 1)
+	data = bytes.Replace(data, []byte("5 0 obj\nnull\nendobj\n"), nil, 1)
+
+	text, err := extract(data)
+	require.NoError(t, err)
+	assert.Equal(t, "IVA\nAÉ", text)
+}
+
+func TestExtract_FormXObject(t *testing.T) {
+	form := "BT /F1 9 Tf 0 0 Td (NIF 123456789) Tj ET"
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /XObject << /X1 5 0 R >> >> /Contents 4 0 R >>",
+		streamObject("", []byte("q 1 0 0 1 100 700 cm /X1 Do Q")),
+		streamObject("/Type /XObject /Subtype /Form /BBox [0 0 200 20] /Resources << /Font << /F1 6 0 R >> >>", []byte(form)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+	)
+
+	text, err := extract(data)
+	require.NoError(t, err)
+	assert.Equal(t, "NIF 123456789", text)
+}
+
+func TestExtract_ChunkBoundary(t *testing.T) {
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		streamObject("", []byte("BT /F1 12 Tf 72 720 Td (Fatura FT A/123) Tj ET")),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+
+	header := bytes.Index(data, []byte("4 0 obj"))
+	firstLine := bytes.IndexByte(data, '\n') + 1
+
+	// The file is searched a megabyte at a time. Pad it so that the header
+	// of the content stream starts just before, at or just after the end of
+	// the first megabyte.
+	for _, at := range []int{1<<20 - 8, 1<<20 - 1, 1 << 20, 1<<20 + 1} {
+		padding := "%" + strings.Repeat("x", at-header-2) + "\n"
+		padded := slices.Concat(data[:firstLine], []byte(padding), data[firstLine:])
+
+		text, err := extract(padded)
+		require.NoError(t, err, at)
+		assert.Equal(t, "Fatura FT A/123", text, at)
+	}
+}
+
+func TestExtract_Errors(t *testing.T) {
+	_, err := extract([]byte("\x89PNG\r\n"))
+	assert.ErrorIs(t, err, pdftext.ErrNotPDF)
+
+	encrypted := buildPDF("<< /Type /Catalog /Pages 2 0 R >>", "<< /Filter /Standard /V 2 /R 3 >>")
+	encrypted = bytes.Replace(encrypted, []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 2 0 R"), 1)
+
+	_, err = extract(encrypted)
+	assert.ErrorIs(t, err, pdftext.ErrEncrypted)
+}
+
+func TestExtract_DeepNesting(t *testing.T) {
+	deep := strings.Repeat("[", 1_000_000)
+
+	page := func(content string) []byte {
+		return buildPDF(
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+			"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+			streamObject("", []byte(content)),
+		)
+	}
+
+	for name, data := range map[string][]byte{
+		"object":         buildPDF("<< /Type /Catalog /Pages " + deep + " >>"),
+		"dictionaries":   buildPDF(strings.Repeat("<< /A ", 1_000_000)),
+		"content stream": page(deep),
+	} {
+		_, err := extract(data)
+		assert.ErrorIs(t, err, pdftext.ErrNotPDF, name)
+
+		_, err = firstPage(data)
+		assert.ErrorIs(t, err, pdftext.ErrNotPDF, name)
+	}
+
+	// Nesting within the cap is read as usual.
+	text, err := extract(page(strings.Repeat("[", 200) + strings.Repeat("]", 200) + " BT (ok) Tj ET"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", text)
+}
+
+func TestFirstPage(t *testing.T) {
+	// A 2×1 RGB image: one red pixel and one blue one.
+	pixels := deflate(t, "\xff\x00\x00\x00\x00\xff")
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 7 0 R] /Count 2 /MediaBox [10 20 310 420] >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> /XObject << /Im1 6 0 R >> >> /Contents 4 0 R >>",
+		streamObject("", []byte("q 100 0 0 50 60 120 cm /Im1 Do Q BT /F1 12 Tf 40 400 Td (Recibo) Tj ET")),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		streamObject("/Type /XObject /Subtype /Image /Width 2 /Height 1 /BitsPerComponent 8 /ColorSpace /DeviceRGB /Filter /FlateDecode", pixels),
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+	)
+
+	page, err := firstPage(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, 300.0, page.Width)
+	assert.Equal(t, 400.0, page.Height)
+
+	require.Len(t, page.Texts, 1)
+	assert.Equal(t, pdftext.Text{X: 30, Y: 380, Size: 12, Text: "Recibo"}, page.Texts[0])
+
+	require.Len(t, page.Images, 1)
+	img := page.Images[0]
+	assert.Equal(t, []float64{50, 100, 100, 50}, []float64{img.X, img.Y, img.Width, img.Height})
+	assert.Equal(t, image.Rect(0, 0, 2, 1), img.Image.Bounds())
+
+	r, g, b, _ := img.Image.At(1, 0).RGBA()
+	assert.Equal(t, []uint32{0, 0, 0xffff}, []uint32{r, g, b})
+}
@@ -0,0 +1,500 @@
+// Package pdftext extracts the text embedded in PDF files, such as invoices
+// generated by billing software. Scanned documents, which only contain
+// images, yield no text; there is no OCR. FirstPage also lays out the images
+// of a page, enough to render a thumbnail of it.
+package pdftext
+
+import (
+	"cmp"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrNotPDF is returned when the data is not a PDF file.
+	ErrNotPDF = errors.New("not a PDF file")
+
+	// ErrEncrypted is returned for encrypted PDF files, whose text cannot be
+	// read without decrypting them.
+	ErrEncrypted = errors.New("PDF file is encrypted")
+)
+
+const (
+	// maxFormDepth bounds the nesting of form XObjects.
+	maxFormDepth = 8
+	// spaceThreshold is the TJ displacement, in thousandths of an em, from
+	// which a gap is taken as a word space.
+	spaceThreshold = 180
+	// avgGlyphWidth estimates the width of a glyph in ems, as glyph widths
+	// are not read from the fonts.
+	avgGlyphWidth = 0.5
+)
+
+var encryptKey = regexp.MustCompile(`/Encrypt\s*(?:\d+\s+\d+\s+R|<<)`)
+
+// Extract returns the text of every page, in reading order: lines from top to
+// bottom, with pages separated by a blank line. It reads the size bytes of the
+// file from r.
+func Extract(r io.ReaderAt, size int64) (string, error) {
+	f, err := openFile(r, size)
+	if err != nil {
+		return "", err
+	}
+
+	if f.encrypted {
+		return "", ErrEncrypted
+	}
+
+	var pages []string
+
+	for _, p := range f.pages() {
+		in := &interpreter{f: f, fonts: make(map[any]*font)}
+
+		for _, c := range p.contents {
+			in.run(c, p.resources, identity, 0)
+		}
+
+		if text := layout(in.runs); text != "" {
+			pages = append(pages, text)
+		}
+	}
+
+	if f.err != nil {
+		return "", f.err
+	}
+
+	return strings.Join(pages, "\n\n"), nil
+}
+
+type page struct {
+	contents  [][]byte
+	resources dict
+	mediaBox  array
+}
+
+// pages walks the page tree from the document catalog.
+func (f *file) pages() []page {
+	var root dict
+
+	nums := make([]int, 0, len(f.offsets)+len(f.objects))
+	for num := range f.offsets {
+		nums = append(nums, num)
+	}
+	for num := range f.objects {
+		if _, ok := f.offsets[num]; !ok {
+			nums = append(nums, num)
+		}
+	}
+	slices.Sort(nums)
+
+	for _, num := range nums {
+		if d := f.dict(ref{num: num}); d["Type"] == name("Catalog") {
+			root = f.dict(d["Pages"])
+			break
+		}
+	}
+
+	if root == nil {
+		return nil
+	}
+
+	var (
+		pages []page
+		walk  func(node dict, res dict, box array, depth int)
+	)
+
+	walk = func(node dict, res dict, box array, depth int) {
+		// The depth bound also stops cycles in malformed page trees.
+		if node == nil || depth > 64 {
+			return
+		}
+
+		if r := f.dict(node["Resources"]); r != nil {
+			res = r
+		}
+
+		if b, ok := f.resolve(node["MediaBox"]).(array); ok && len(b) == 4 {
+			box = b
+		}
+
+		kids, isTree := f.resolve(node["Kids"]).(array)
+		if isTree {
+			for _, kid := range kids {
+				walk(f.dict(kid), res, box, depth+1)
+			}
+
+			return
+		}
+
+		p := page{resources: res, mediaBox: box}
+
+		contents := f.resolve(node["Contents"])
+		if arr, ok := contents.(array); ok {
+			for _, c := range arr {
+				if s, ok := f.resolve(c).(*stream); ok {
+					if data, err := f.decode(s); err == nil {
+						p.contents = append(p.contents, data)
+					}
+				}
+			}
+		} else if s, ok := contents.(*stream); ok {
+			if data, err := f.decode(s); err == nil {
+				p.contents = append(p.contents, data)
+			}
+		}
+
+		pages = append(pages, p)
+	}
+
+	walk(root, nil, nil, 0)
+
+	return pages
+}
+
+// matrix is an affine transformation [a b c d e f], applied to row vectors as
+// in the PDF specification.
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m × n.
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func translate(tx, ty float64) matrix {
+	return matrix{1, 0, 0, 1, tx, ty}
+}
+
+// run is a piece of text shown at one position, in device space.
+type run struct {
+	x, y, size float64
+	text       string
+	seq        int
+}
+
+type interpreter struct {
+	f     *file
+	fonts map[any]*font
+	runs  []run
+
+	// images collects the images drawn, when set.
+	images *[]Image
+}
+
+// textState is the part of the graphics state that positions text.
+type textState struct {
+	ctm      matrix
+	tm, tlm  matrix
+	font     *font
+	size     float64
+	leading  float64
+	hscale   float64
+	ctmStack []matrix
+}
+
+func (in *interpreter) font(fonts dict, n name) *font {
+	v := fonts[n]
+
+	key := v
+	if _, isRef := v.(ref); !isRef {
+		// Direct font dictionaries are not comparable; cache them per name.
+		key = n
+	}
+
+	if ft, ok := in.fonts[key]; ok {
+		return ft
+	}
+
+	ft := in.f.loadFont(v)
+	in.fonts[key] = ft
+
+	return ft
+}
+
+// run interprets a content stream with its resources.
+func (in *interpreter) run(content []byte, res dict, ctm matrix, depth int) {
+	fonts := in.f.dict(res["Font"])
+	xobjects := in.f.dict(res["XObject"])
+
+	st := &textState{ctm: ctm, tm: identity, tlm: identity, hscale: 1, font: &font{enc: &winAnsi}}
+	l := &lexer{data: content}
+
+	var ops []any
+
+	num := func(i int) float64 {
+		if i < len(ops) {
+			if f, ok := ops[i].(float64); ok {
+				return f
+			}
+		}
+
+		return 0
+	}
+
+	mat := func() matrix {
+		return matrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+	}
+
+	for {
+		obj, ok := l.parseObject()
+		if !ok {
+			in.f.fail(l.err)
+			return
+		}
+
+		op, isOp := obj.(keyword)
+		if !isOp {
+			ops = append(ops, obj)
+			continue
+		}
+
+		switch op {
+		case "q":
+			st.ctmStack = append(st.ctmStack, st.ctm)
+		case "Q":
+			if n := len(st.ctmStack); n > 0 {
+				st.ctm = st.ctmStack[n-1]
+				st.ctmStack = st.ctmStack[:n-1]
+			}
+		case "cm":
+			if len(ops) == 6 {
+				st.ctm = mat().mul(st.ctm)
+			}
+		case "BT":
+			st.tm, st.tlm = identity, identity
+		case "Tf":
+			if len(ops) == 2 {
+				if n, ok := ops[0].(name); ok && fonts != nil {
+					st.font = in.font(fonts, n)
+				}
+				st.size = num(1)
+			}
+		case "Tz":
+			st.hscale = num(0) / 100
+		case "TL":
+			st.leading = num(0)
+		case "Td":
+			st.nextLine(num(0), num(1))
+		case "TD":
+			st.leading = -num(1)
+			st.nextLine(num(0), num(1))
+		case "Tm":
+			if len(ops) == 6 {
+				st.tm, st.tlm = mat(), mat()
+			}
+		case "T*":
+			st.nextLine(0, -st.leading)
+		case "Tj":
+			if s, ok := lastString(ops); ok {
+				in.show(st, st.font.decode(s))
+			}
+		case "'", "\"":
+			st.nextLine(0, -st.leading)
+			if s, ok := lastString(ops); ok {
+				in.show(st, st.font.decode(s))
+			}
+		case "TJ":
+			if len(ops) > 0 {
+				if arr, ok := ops[len(ops)-1].(array); ok {
+					in.show(st, st.decodeTJ(arr))
+				}
+			}
+		case "Do":
+			if len(ops) == 1 && depth < maxFormDepth && xobjects != nil {
+				if n, ok := ops[0].(name); ok {
+					in.xobject(xobjects[n], res, st.ctm, depth)
+				}
+			}
+		case "BI":
+			skipInlineImage(l)
+		}
+
+		ops = ops[:0]
+	}
+}
+
+func lastString(ops []any) ([]byte, bool) {
+	if len(ops) == 0 {
+		return nil, false
+	}
+
+	s, ok := ops[len(ops)-1].([]byte)
+
+	return s, ok
+}
+
+func (st *textState) nextLine(tx, ty float64) {
+	st.tlm = translate(tx, ty).mul(st.tlm)
+	st.tm = st.tlm
+}
+
+// decodeTJ joins the strings of a TJ array, turning wide gaps into spaces.
+func (st *textState) decodeTJ(arr array) string {
+	var sb strings.Builder
+
+	for _, item := range arr {
+		switch v := item.(type) {
+		case []byte:
+			sb.WriteString(st.font.decode(v))
+		case float64:
+			if v < -spaceThreshold && sb.Len() > 0 && !strings.HasSuffix(sb.String(), " ") {
+				sb.WriteByte(' ')
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// show records text at the current position and moves past it by an
+// estimated width.
+func (in *interpreter) show(st *textState, text string) {
+	if strings.TrimSpace(text) == "" {
+		if text != "" {
+			st.tm = translate(st.size*avgGlyphWidth*st.hscale*float64(utf8.RuneCountInString(text)), 0).mul(st.tm)
+		}
+
+		return
+	}
+
+	trm := st.tm.mul(st.ctm)
+
+	size := st.size * math.Hypot(trm[2], trm[3])
+	if size == 0 {
+		size = 1
+	}
+
+	in.runs = append(in.runs, run{x: trm[4], y: trm[5], size: size, text: text, seq: len(in.runs)})
+
+	st.tm = translate(st.size*avgGlyphWidth*st.hscale*float64(utf8.RuneCountInString(text)), 0).mul(st.tm)
+}
+
+// xobject draws an XObject: a form is interpreted, an image collected.
+func (in *interpreter) xobject(v any, res dict, ctm matrix, depth int) {
+	s, ok := in.f.resolve(v).(*stream)
+	if !ok {
+		return
+	}
+
+	switch s.dict["Subtype"] {
+	case name("Form"):
+		in.form(s, res, ctm, depth)
+	case name("Image"):
+		if in.images != nil {
+			in.image(s, ctm)
+		}
+	}
+}
+
+// form interprets a form XObject.
+func (in *interpreter) form(s *stream, res dict, ctm matrix, depth int) {
+	data, err := in.f.decode(s)
+	if err != nil {
+		return
+	}
+
+	if r := in.f.dict(s.dict["Resources"]); r != nil {
+		res = r
+	}
+
+	if m, ok := in.f.resolve(s.dict["Matrix"]).(array); ok && len(m) == 6 {
+		var fm matrix
+		for i := range fm {
+			fm[i], _ = in.f.resolve(m[i]).(float64)
+		}
+		ctm = fm.mul(ctm)
+	}
+
+	in.run(data, res, ctm, depth+1)
+}
+
+// skipInlineImage moves past the data of an inline image (BI … ID data EI).
+func skipInlineImage(l *lexer) {
+	for {
+		t := l.next()
+		if t.kind == tokEOF || (t.kind == tokKeyword && t.value == keyword("ID")) {
+			break
+		}
+	}
+
+	// The data is binary and ends at "EI" between white space.
+	for i := l.pos; i+2 < len(l.data); i++ {
+		if isSpace(l.data[i]) && l.data[i+1] == 'E' && l.data[i+2] == 'I' &&
+			(i+3 == len(l.data) || isSpace(l.data[i+3])) {
+			l.pos = i + 3
+			return
+		}
+	}
+
+	l.pos = len(l.data)
+}
+
+// layout groups runs into lines, top to bottom, and orders each line left to
+// right. Runs separated by more than a narrow gap are joined with a space.
+func layout(runs []run) string {
+	if len(runs) == 0 {
+		return ""
+	}
+
+	slices.SortStableFunc(runs, func(a, b run) int { return cmp.Compare(b.y, a.y) })
+
+	var (
+		lines []string
+		line  []run
+	)
+
+	flush := func() {
+		slices.SortStableFunc(line, func(a, b run) int {
+			if c := cmp.Compare(a.x, b.x); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.seq, b.seq)
+		})
+
+		var sb strings.Builder
+
+		for i, r := range line {
+			if i > 0 {
+				prev := line[i-1]
+				end := prev.x + prev.size*avgGlyphWidth*float64(utf8.RuneCountInString(prev.text))
+
+				if r.x-end > prev.size*0.25 && !strings.HasSuffix(sb.String(), " ") && !strings.HasPrefix(r.text, " ") {
+					sb.WriteByte(' ')
+				}
+			}
+
+			sb.WriteString(r.text)
+		}
+
+		if text := strings.TrimSpace(sb.String()); text != "" {
+			lines = append(lines, text)
+		}
+
+		line = line[:0]
+	}
+
+	for _, r := range runs {
+		if len(line) > 0 && math.Abs(line[0].y-r.y) > min(line[0].size, r.size)*0.5 {
+			flush()
+		}
+
+		line = append(line, r)
+	}
+
+	flush()
+
+	return strings.Join(lines, "\n")
+}
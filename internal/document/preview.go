@@ -0,0 +1,98 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/document/preview"
+)
+
+// maxPreviewSize bounds the files previews are generated for.
+const maxPreviewSize = 32 << 20
+
+// PreviewMIMEType is the type of document previews.
+const PreviewMIMEType = preview.MIMEType
+
+// hasPreview reports whether a preview can be generated for the document's
+// type: PDFs and images.
+func hasPreview(doc *Document) bool {
+	return isPDF(doc) || strings.HasPrefix(doc.MIMEType, "image/")
+}
+
+// Preview returns a small JPEG of the document: the first page of a PDF, or
+// the image scaled down. Previews are generated on upload; those of older and
+// linked documents are generated on first request and kept. Fails with
+// ErrNoPreview for other types of documents, and for those whose content
+// could not be rendered, which is remembered so it is not downloaded again.
+func (s *Service) Preview(ctx context.Context, documentID uuid.UUID) ([]byte, error) {
+	data, err := s.repo.GetPreview(ctx, documentID)
+	if err == nil {
+		return data, nil
+	}
+
+	if !errors.Is(err, ErrPreviewNotFound) {
+		return nil, fmt.Errorf("getting preview: %w", err)
+	}
+
+	doc, err := s.repo.GetDocument(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("getting document: %w", err)
+	}
+
+	if !hasPreview(doc) || doc.NoPreview {
+		return nil, ErrNoPreview
+	}
+
+	rc, _, err := s.Download(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// The preview is rendered from a local copy, not from memory.
+	sp, err := newSpool(io.LimitReader(rc, maxPreviewSize+1))
+	if err != nil {
+		return nil, err
+	}
+	defer sp.Close()
+
+	if sp.size > maxPreviewSize {
+		return nil, s.markNoPreview(ctx, doc, ErrNoPreview)
+	}
+
+	return s.generatePreview(ctx, doc, sp.file, sp.size)
+}
+
+// generatePreview renders the preview of the size bytes of content and stores
+// it. Content that cannot be rendered marks the document as having no preview.
+func (s *Service) generatePreview(ctx context.Context, doc *Document, content io.ReaderAt, size int64) ([]byte, error) {
+	img, err := preview.Generate(content, size)
+	if errors.Is(err, preview.ErrUnsupported) {
+		return nil, s.markNoPreview(ctx, doc, fmt.Errorf("%w: %w", ErrNoPreview, err))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SavePreview(ctx, doc.ID, img); err != nil {
+		return nil, fmt.Errorf("storing preview: %w", err)
+	}
+
+	return img, nil
+}
+
+// markNoPreview records that the document has no preview and returns cause.
+func (s *Service) markNoPreview(ctx context.Context, doc *Document, cause error) error {
+	if err := s.repo.MarkNoPreview(ctx, doc.ID); err != nil {
+		return fmt.Errorf("marking document without preview: %w", err)
+	}
+
+	doc.NoPreview = true
+
+	return cause
+}
@@ -0,0 +1,204 @@
+// Package preview renders small JPEG previews of documents: the first page of
+// a PDF, or a scaled-down copy of an image, so clients can show what a file
+// is without downloading it.
+//
+// PDF pages are drawn from their text and images only, in a single font;
+// vector graphics such as table rules and logos drawn as paths are left out.
+// That is enough to recognise an invoice at thumbnail size.
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+
+	// Image formats accepted as uploads.
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+
+	"github.com/MrJamesThe3rd/finny/internal/document/pdftext"
+)
+
+// ErrUnsupported is returned for content that is neither a readable PDF nor
+// an image in a supported format.
+var ErrUnsupported = errors.New("preview: unsupported content")
+
+const (
+	// MIMEType is the type of the generated previews.
+	MIMEType = "image/jpeg"
+	// MaxSize is the longest side of a preview, in pixels.
+	MaxSize = 512
+
+	// maxPixels bounds the size of the images decoded, as a guard against
+	// decompression bombs.
+	maxPixels = 40 << 20
+	quality   = 80
+
+	// headSize is how much of the content is read to tell PDFs from images
+	// and to find the Exif orientation of a photo, which comes first.
+	headSize = 256 << 10
+)
+
+// Generate renders the preview of a PDF or image, reading the size bytes of
+// the content from r.
+func Generate(r io.ReaderAt, size int64) ([]byte, error) {
+	head := make([]byte, min(size, headSize))
+	if _, err := r.ReadAt(head, 0); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("preview: reading content: %w", err)
+	}
+
+	var (
+		img image.Image
+		err error
+	)
+
+	if bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n"), []byte("%PDF-")) {
+		img, err = renderPDF(r, size)
+	} else {
+		img, err = resizeImage(r, size, head)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("preview: encoding: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// renderPDF draws the first page of a PDF on a white canvas.
+func renderPDF(r io.ReaderAt, size int64) (image.Image, error) {
+	page, err := pdftext.FirstPage(r, size)
+	if errors.Is(err, pdftext.ErrNotPDF) || errors.Is(err, pdftext.ErrEncrypted) {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("preview: reading PDF: %w", err)
+	}
+
+	if page.Width <= 0 || page.Height <= 0 {
+		return nil, fmt.Errorf("%w: page has no size", ErrUnsupported)
+	}
+
+	scale := MaxSize / math.Max(page.Width, page.Height)
+	canvas := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(page.Width*scale)), int(math.Ceil(page.Height*scale))))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+
+	// Page coordinates grow upwards; the canvas's grow downwards.
+	for _, im := range page.Images {
+		r := image.Rect(
+			int(im.X*scale), int((page.Height-im.Y-im.Height)*scale),
+			int(math.Ceil((im.X+im.Width)*scale)), int(math.Ceil((page.Height-im.Y)*scale)),
+		)
+		draw.BiLinear.Scale(canvas, r, im.Image, im.Image.Bounds(), draw.Over, nil)
+	}
+
+	faces, err := newFaceCache()
+	if err != nil {
+		return nil, err
+	}
+	defer faces.Close()
+
+	for _, t := range page.Texts {
+		face, err := faces.get(t.Size * scale)
+		if err != nil {
+			return nil, err
+		}
+
+		d := font.Drawer{
+			Dst:  canvas,
+			Src:  image.NewUniform(color.Gray{Y: 0x20}),
+			Face: face,
+			Dot:  fixed.P(int(t.X*scale), int((page.Height-t.Y)*scale)),
+		}
+		d.DrawString(t.Text)
+	}
+
+	return canvas, nil
+}
+
+// faceCache holds a font face per pixel size.
+type faceCache struct {
+	font  *opentype.Font
+	faces map[int]font.Face
+}
+
+func newFaceCache() (*faceCache, error) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("preview: parsing font: %w", err)
+	}
+
+	return &faceCache{font: f, faces: make(map[int]font.Face)}, nil
+}
+
+// get returns the face for size, rounded to a whole pixel of at least one.
+func (c *faceCache) get(size float64) (font.Face, error) {
+	px := max(1, int(math.Round(size)))
+
+	if face, ok := c.faces[px]; ok {
+		return face, nil
+	}
+
+	face, err := opentype.NewFace(c.font, &opentype.FaceOptions{Size: float64(px), DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("preview: creating font face: %w", err)
+	}
+
+	c.faces[px] = face
+
+	return face, nil
+}
+
+func (c *faceCache) Close() {
+	for _, face := range c.faces {
+		face.Close()
+	}
+}
+
+// resizeImage decodes an image, scales it down to fit MaxSize and turns it
+// upright. Smaller images are kept at their size. head is the start of the
+// content.
+func resizeImage(r io.ReaderAt, size int64, head []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: image of %d×%d pixels is too large", ErrUnsupported, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("preview: decoding image: %w", err)
+	}
+
+	b := img.Bounds()
+	scale := math.Min(1, MaxSize/float64(max(b.Dx(), b.Dy())))
+
+	dst := image.NewRGBA(image.Rect(0, 0, max(1, int(float64(b.Dx())*scale)), max(1, int(float64(b.Dy())*scale))))
+
+	// Transparent areas are shown on white, as JPEG has no alpha.
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.BiLinear.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+
+	// Turning the scaled copy is cheaper than turning the photo.
+	return orient(dst, exifOrientation(head)), nil
+}
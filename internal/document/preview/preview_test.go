@@ -0,0 +1,122 @@
+package preview_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document/preview"
+)
+
+func generate(data []byte) ([]byte, error) {
+	return preview.Generate(bytes.NewReader(data), int64(len(data)))
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	return img
+}
+
+func TestGenerate_PDF(t *testing.T) {
+	content := "BT /F1 24 Tf 50 700 Td (FATURA) Tj ET"
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	for i, body := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+
+	data, err := generate(buf.Bytes())
+	require.NoError(t, err)
+
+	img := decode(t, data)
+	assert.Equal(t, image.Rect(0, 0, 362, 512), img.Bounds())
+
+	// The title is drawn near the top left; the rest of the page is blank.
+	var dark int
+	for y := 60; y < 90; y++ {
+		for x := 25; x < 120; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+				dark++
+			}
+		}
+	}
+	assert.Positive(t, dark)
+
+	r, g, b, _ := img.At(300, 400).RGBA()
+	assert.Greater(t, min(r, g, b), uint32(0xf000))
+}
+
+func TestGenerate_Image(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2048, 1024))
+	for y := range 1024 {
+		for x := range 2048 {
+			c := color.RGBA{R: 0xff, A: 0xff}
+			if x >= 1024 {
+				c = color.RGBA{B: 0xff, A: 0xff}
+			}
+			src.SetRGBA(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	data, err := generate(buf.Bytes())
+	require.NoError(t, err)
+
+	img := decode(t, data)
+	assert.Equal(t, image.Rect(0, 0, 512, 256), img.Bounds())
+
+	r, _, b, _ := img.At(100, 128).RGBA()
+	assert.Greater(t, r, b)
+
+	r, _, b, _ = img.At(400, 128).RGBA()
+	assert.Greater(t, b, r)
+}
+
+func TestGenerate_ExifOrientation(t *testing.T) {
+	// A wide photo taken with the camera turned: orientation 6 asks for it to
+	// be turned clockwise.
+	src := image.NewGray(image.Rect(0, 0, 64, 32))
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, src, nil))
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00\x00\x00\x00\x00")
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xff, 0xe1, byte((len(app1) + 2) >> 8), byte(len(app1) + 2)}, app1...)
+
+	photo := append(append([]byte{0xff, 0xd8}, segment...), buf.Bytes()[2:]...)
+
+	data, err := generate(photo)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 32, 64), decode(t, data).Bounds())
+}
+
+func TestGenerate_Unsupported(t *testing.T) {
+	_, err := generate([]byte("just some text"))
+	assert.ErrorIs(t, err, preview.ErrUnsupported)
+
+	_, err = generate([]byte("%PDF-1.7\nnot really"))
+	assert.ErrorIs(t, err, preview.ErrUnsupported)
+}
@@ -0,0 +1,64 @@
+package document_test
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+func TestService_Preview(t *testing.T) {
+	env := newTestEnv(t, "main")
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1200, 600))))
+
+	doc, err := env.svc.Upload(env.ctx, "receipt.png", "image/png", bytes.NewReader(buf.Bytes()), nil)
+	require.NoError(t, err)
+
+	// The preview is generated on upload.
+	stored, err := env.repo.GetPreview(env.ctx, doc.ID)
+	require.NoError(t, err)
+
+	data, err := env.svc.Preview(env.ctx, doc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, stored, data)
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, []int{512, 256}, []int{cfg.Width, cfg.Height})
+
+	// Documents stored before previews existed get theirs on first request.
+	delete(env.repo.previews, doc.ID)
+
+	data, err = env.svc.Preview(env.ctx, doc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, stored, data)
+	assert.Contains(t, env.repo.previews, doc.ID)
+
+	text, err := env.svc.Upload(env.ctx, "notes.txt", "text/plain", bytes.NewReader([]byte("notes")), nil)
+	require.NoError(t, err)
+
+	_, err = env.svc.Preview(env.ctx, text.ID)
+	assert.ErrorIs(t, err, document.ErrNoPreview)
+}
+
+func TestService_Preview_RemembersUnrenderable(t *testing.T) {
+	env := newTestEnv(t, "main")
+
+	doc, err := env.svc.Upload(env.ctx, "broken.png", "image/png", bytes.NewReader([]byte("not a PNG")), nil)
+	require.NoError(t, err)
+	assert.True(t, env.repo.documents[doc.ID].NoPreview, "the failed render on upload is recorded")
+
+	// Without its content, a second render could only fail on the download.
+	clear(env.backends["main"].objects)
+
+	_, err = env.svc.Preview(env.ctx, doc.ID)
+	assert.ErrorIs(t, err, document.ErrNoPreview)
+}
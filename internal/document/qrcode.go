@@ -0,0 +1,105 @@
+package document
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QRCode is the payload of the QR code printed on Portuguese invoices
+// (Portaria n.º 195/2020), reduced to the fields Finny uses. Amounts are in
+// cents.
+type QRCode struct {
+	IssuerNIF string    // A
+	BuyerNIF  string    // B; 999999990 for a final consumer
+	DocType   string    // D, e.g. "FT", "FR", "NC"
+	Date      time.Time // F
+	InvoiceID string    // G, e.g. "FT 2026A/123"
+	ATCUD     string    // H
+	VAT       int64     // N, total VAT
+	Total     int64     // O, gross total
+}
+
+// ParseQRCode parses a payload such as
+// "A:500000000*B:999999990*C:PT*D:FT*E:N*F:20261018*G:FT A/1*H:CSDF7T5H-1*…*N:2.30*O:12.30*…".
+// Fields Finny does not use are ignored; missing or malformed ones fail with
+// ErrInvalidQRCode.
+func ParseQRCode(payload string) (*QRCode, error) {
+	fields := make(map[string]string)
+
+	for _, part := range strings.Split(strings.TrimSpace(payload), "*") {
+		key, value, ok := strings.Cut(part, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%w: malformed field %q", ErrInvalidQRCode, part)
+		}
+
+		if _, dup := fields[key]; dup {
+			return nil, fmt.Errorf("%w: repeated field %s", ErrInvalidQRCode, key)
+		}
+
+		fields[key] = value
+	}
+
+	for _, key := range []string{"A", "B", "D", "F", "G", "H", "N", "O"} {
+		if fields[key] == "" {
+			return nil, fmt.Errorf("%w: missing field %s", ErrInvalidQRCode, key)
+		}
+	}
+
+	qr := &QRCode{
+		IssuerNIF: fields["A"],
+		BuyerNIF:  fields["B"],
+		DocType:   fields["D"],
+		InvoiceID: fields["G"],
+		ATCUD:     fields["H"],
+	}
+
+	if !ValidNIF(qr.IssuerNIF) {
+		return nil, fmt.Errorf("%w: invalid issuer NIF %q", ErrInvalidQRCode, qr.IssuerNIF)
+	}
+
+	date, err := time.Parse("20060102", fields["F"])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid date %q", ErrInvalidQRCode, fields["F"])
+	}
+
+	qr.Date = date
+
+	if qr.VAT, err = parseQRAmount(fields["N"]); err != nil {
+		return nil, fmt.Errorf("%w: invalid VAT: %w", ErrInvalidQRCode, err)
+	}
+
+	if qr.Total, err = parseQRAmount(fields["O"]); err != nil {
+		return nil, fmt.Errorf("%w: invalid total: %w", ErrInvalidQRCode, err)
+	}
+
+	return qr, nil
+}
+
+// parseQRAmount parses an amount with a dot and two decimals, e.g. "12.30".
+func parseQRAmount(s string) (int64, error) {
+	units, decimals, ok := strings.Cut(s, ".")
+	if !ok || len(decimals) != 2 || units == "" || strings.Trim(units+decimals, "0123456789") != "" {
+		return 0, fmt.Errorf("%q is not an amount with two decimals", s)
+	}
+
+	return strconv.ParseInt(units+decimals, 10, 64)
+}
+
+// Apply returns inv with the fields the QR code carries replaced by its
+// values, which the tax authority validates and so take precedence over both
+// extracted and typed ones.
+func (qr *QRCode) Apply(inv Invoice) Invoice {
+	total, vat, date := qr.Total, qr.VAT, qr.Date
+
+	inv.Total = &total
+	inv.VAT = &vat
+	inv.Date = &date
+	inv.Number = qr.InvoiceID
+	inv.IssuerNIF = qr.IssuerNIF
+	inv.BuyerNIF = qr.BuyerNIF
+	inv.ATCUD = qr.ATCUD
+
+	return inv
+}
@@ -0,0 +1,51 @@
+package document_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+// samplePayload follows the example in the tax authority's QR code
+// specification.
+const samplePayload = "A:502011475*B:999999990*C:PT*D:FT*E:N*F:20261014*G:FT 2026A7/84211*H:JJ37MMBG-84211" +
+	"*I1:PT*I7:11.43*I8:1.40*N:1.40*O:12.83*Q:P0Kx*R:2262"
+
+func TestParseQRCode(t *testing.T) {
+	qr, err := document.ParseQRCode(samplePayload)
+	require.NoError(t, err)
+
+	assert.Equal(t, &document.QRCode{
+		IssuerNIF: "502011475",
+		BuyerNIF:  "999999990",
+		DocType:   "FT",
+		Date:      time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC),
+		InvoiceID: "FT 2026A7/84211",
+		ATCUD:     "JJ37MMBG-84211",
+		VAT:       140,
+		Total:     1283,
+	}, qr)
+}
+
+func TestParseQRCode_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"empty":          "",
+		"missing total":  "A:502011475*B:999999990*D:FT*F:20261014*G:FT A/1*H:JJ37MMBG-1*N:1.40",
+		"bad issuer NIF": "A:502011476*B:999999990*D:FT*F:20261014*G:FT A/1*H:JJ37MMBG-1*N:1.40*O:12.83",
+		"bad date":       "A:502011475*B:999999990*D:FT*F:2026-10-14*G:FT A/1*H:JJ37MMBG-1*N:1.40*O:12.83",
+		"comma decimals": "A:502011475*B:999999990*D:FT*F:20261014*G:FT A/1*H:JJ37MMBG-1*N:1,40*O:12,83",
+		"repeated field": "A:502011475*A:502011475*B:999999990*D:FT*F:20261014*G:FT A/1*H:JJ37MMBG-1*N:1.40*O:12.83",
+		"no separator":   "A502011475",
+	}
+
+	for name, payload := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := document.ParseQRCode(payload)
+			assert.ErrorIs(t, err, document.ErrInvalidQRCode)
+		})
+	}
+}
@@ -8,10 +8,19 @@ import (
 // BackendFactory creates a Backend from the JSONB config stored in document_backends.
 type BackendFactory func(config json.RawMessage) (Backend, error)
 
+// Encryption encrypts what backends store.
+type Encryption interface {
+	// Wrap returns b with uploads encrypted if encrypt is set. Encrypted
+	// objects are decrypted on download either way, so turning encryption
+	// off keeps what was stored readable.
+	Wrap(b Backend, encrypt bool) Backend
+}
+
 // Registry maps backend type strings to their factories.
 // Register backends at startup; the service instantiates them on demand.
 type Registry struct {
-	factories map[string]BackendFactory
+	factories  map[string]BackendFactory
+	encryption Encryption
 }
 
 func NewRegistry() *Registry {
@@ -22,11 +31,74 @@ func (r *Registry) Register(backendType string, factory BackendFactory) {
 	r.factories[backendType] = factory
 }
 
+// SetEncryption wraps the backends Create returns with e. Backends whose
+// config sets "encrypt": true fail to load without it.
+func (r *Registry) SetEncryption(e Encryption) {
+	r.encryption = e
+}
+
 func (r *Registry) Create(backendType string, config json.RawMessage) (Backend, error) {
 	factory, ok := r.factories[backendType]
 	if !ok {
 		return nil, fmt.Errorf("unknown backend type: %s", backendType)
 	}
 
-	return factory(config)
+	backend, err := factory(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every backend config may ask for encryption and a key template,
+	// whatever its type.
+	opts, err := parseBackendOptions(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Encrypt {
+		if p, ok := backend.(ContentParser); ok && p.ParsesContent() {
+			return nil, ErrEncryptionUnsupported
+		}
+	}
+
+	if opts.KeyTemplate != "" {
+		if opts.Encrypt {
+			return nil, ErrKeyTemplateEncrypted
+		}
+
+		if _, ok := backend.(KeyedUploader); !ok {
+			return nil, ErrKeyTemplateUnsupported
+		}
+
+		if _, err := ParseKeyTemplate(opts.KeyTemplate); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.encryption == nil {
+		if opts.Encrypt {
+			return nil, ErrEncryptionUnavailable
+		}
+		return backend, nil
+	}
+
+	return r.encryption.Wrap(backend, opts.Encrypt), nil
+}
+
+// backendOptions are the settings every backend config may carry, whatever
+// its type.
+type backendOptions struct {
+	Encrypt     bool   `json:"encrypt"`
+	KeyTemplate string `json:"key_template"` // see KeyTemplate; "" lets the backend name documents
+}
+
+func parseBackendOptions(config json.RawMessage) (backendOptions, error) {
+	var opts backendOptions
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &opts); err != nil {
+			return opts, fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
+	return opts, nil
 }
@@ -0,0 +1,51 @@
+package document_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/document/paperless"
+)
+
+func TestRegistry_Create_KeyTemplate(t *testing.T) {
+	registry := document.NewRegistry()
+	registry.Register("mem", func(json.RawMessage) (document.Backend, error) {
+		return &memBackend{objects: make(map[string][]byte)}, nil
+	})
+
+	_, err := registry.Create("mem", json.RawMessage(`{"key_template": "{{year}}/{{filename}}"}`))
+	require.NoError(t, err)
+
+	_, err = registry.Create("mem", json.RawMessage(`{"key_template": "{{year}}/{{vendor}}"}`))
+	assert.ErrorIs(t, err, document.ErrInvalidKeyTemplate)
+
+	_, err = registry.Create("mem", json.RawMessage(`{"key_template": "{{year}}/{{filename}}", "encrypt": true}`))
+	assert.ErrorIs(t, err, document.ErrKeyTemplateEncrypted, "object names would leak what encryption hides")
+}
+
+// passthroughEncryption stands in for a configured master key.
+type passthroughEncryption struct{}
+
+func (passthroughEncryption) Wrap(b document.Backend, _ bool) document.Backend { return b }
+
+func TestRegistry_Create_Encrypt(t *testing.T) {
+	registry := document.NewRegistry()
+	registry.SetEncryption(passthroughEncryption{})
+	registry.Register("mem", func(json.RawMessage) (document.Backend, error) {
+		return &memBackend{objects: make(map[string][]byte)}, nil
+	})
+	registry.Register("paperless", paperless.NewFromConfig)
+
+	_, err := registry.Create("mem", json.RawMessage(`{"encrypt": true}`))
+	require.NoError(t, err)
+
+	_, err = registry.Create("paperless", json.RawMessage(`{"base_url": "http://paperless", "token": "t", "encrypt": true}`))
+	assert.ErrorIs(t, err, document.ErrEncryptionUnsupported, "Paperless cannot parse encrypted files")
+
+	_, err = registry.Create("paperless", json.RawMessage(`{"base_url": "http://paperless", "token": "t"}`))
+	require.NoError(t, err)
+}
@@ -0,0 +1,185 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// maxNumberedKeys bounds how many numbered keys put tries when documents
+// render to the same key.
+const maxNumberedKeys = 100
+
+// backendKeyTemplate returns the key template the backend's config sets, or
+// nil if it lets the backend name documents.
+func backendKeyTemplate(cfg BackendConfig) (*KeyTemplate, error) {
+	opts, err := parseBackendOptions(cfg.Config)
+	if err != nil || opts.KeyTemplate == "" {
+		return nil, err
+	}
+
+	return ParseKeyTemplate(opts.KeyTemplate)
+}
+
+// keyTransaction returns the transaction key templates render a document's
+// fields from, or nil if the document is not attached to any.
+func (s *Service) keyTransaction(ctx context.Context, doc *Document) (*TransactionFields, error) {
+	txs, err := s.repo.ListDocumentTransactions(ctx, doc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("listing document transactions: %w", err)
+	}
+
+	if len(txs) == 0 {
+		return nil, nil
+	}
+
+	return &txs[0], nil
+}
+
+// put stores doc on b and returns its key: under the backend's key template
+// if it has one, numbered when another document is there already, else where
+// the backend chooses. The template renders tx, or when tx is nil the
+// transaction doc is attached to. content returns the document's content
+// afresh on each call.
+func (s *Service) put(ctx context.Context, b backendCopy, doc *Document, tx *TransactionFields, content func() io.Reader) (string, error) {
+	tmpl, err := backendKeyTemplate(b.cfg)
+	if err != nil {
+		return "", err
+	}
+
+	if tmpl == nil {
+		return b.backend.Upload(ctx, doc.Filename, content())
+	}
+
+	uploader, ok := b.backend.(KeyedUploader)
+	if !ok {
+		return "", ErrKeyTemplateUnsupported
+	}
+
+	if tx == nil {
+		if tx, err = s.keyTransaction(ctx, doc); err != nil {
+			return "", err
+		}
+	}
+
+	key := tmpl.Render(doc, tx)
+
+	for n := 1; n <= maxNumberedKeys; n++ {
+		stored, err := uploader.UploadAt(ctx, numberedKey(key, n), content())
+		if !errors.Is(err, ErrKeyExists) {
+			return stored, err
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s and %d numbered keys", ErrKeyExists, key, maxNumberedKeys)
+}
+
+// Relocate moves the document's copies on enabled backends with a key template
+// to the key it renders to now, as attaching the document to a transaction or
+// changing the template changes it. Copies that cannot be moved stay where
+// they are.
+func (s *Service) Relocate(ctx context.Context, documentID uuid.UUID) error {
+	doc, err := s.repo.GetDocument(ctx, documentID)
+	if err != nil {
+		return fmt.Errorf("getting document: %w", err)
+	}
+
+	if doc.SHA256 == "" {
+		return nil
+	}
+
+	backends, err := s.enabledBackends(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.relocate(ctx, doc, backends)
+
+	return err
+}
+
+// relocate moves the copies of doc on backends that are not at the key their
+// backend's template renders to, and returns how many it moved.
+func (s *Service) relocate(ctx context.Context, doc *Document, backends map[uuid.UUID]backendCopy) (int, error) {
+	locations, err := s.repo.ListLocations(ctx, doc.ID)
+	if err != nil {
+		return 0, fmt.Errorf("listing locations: %w", err)
+	}
+
+	var (
+		tx       *TransactionFields
+		txLoaded bool
+		moved    int
+		errs     []error
+	)
+
+	for _, loc := range locations {
+		b, ok := backends[loc.BackendID]
+		if !ok {
+			continue
+		}
+
+		tmpl, err := backendKeyTemplate(b.cfg)
+		if err != nil || tmpl == nil {
+			continue
+		}
+
+		if !txLoaded {
+			if tx, err = s.keyTransaction(ctx, doc); err != nil {
+				return moved, err
+			}
+			txLoaded = true
+		}
+
+		if matchesKey(loc.Key, tmpl.Render(doc, tx)) {
+			continue
+		}
+
+		b.loc = loc
+
+		if err := s.rename(ctx, doc, tx, b); err != nil {
+			errs = append(errs, fmt.Errorf("backend %s: %w", b.cfg.Name, err))
+			continue
+		}
+
+		moved++
+	}
+
+	return moved, errors.Join(errs...)
+}
+
+// rename stores the copy of doc at b.loc again under the key rendered for tx,
+// then removes it from the old one.
+func (s *Service) rename(ctx context.Context, doc *Document, tx *TransactionFields, b backendCopy) error {
+	sp, err := s.fetch(ctx, b, doc.SHA256)
+	if err != nil {
+		return fmt.Errorf("reading document: %w", err)
+	}
+	defer sp.Close()
+
+	key, err := s.put(ctx, b, doc, tx, sp.reader)
+	if err != nil {
+		return fmt.Errorf("uploading: %w", err)
+	}
+
+	if err := s.repo.AddLocation(ctx, &Location{DocumentID: doc.ID, BackendID: b.cfg.ID, Key: key}); err != nil {
+		if delErr := b.backend.Delete(ctx, key); delErr != nil {
+			slog.Warn("failed to delete unrecorded copy", "backend", b.cfg.Name, "key", key, "error", delErr)
+		}
+		return fmt.Errorf("recording location: %w", err)
+	}
+
+	if err := s.repo.DeleteLocation(ctx, b.loc.ID); err != nil {
+		return fmt.Errorf("removing old location: %w", err)
+	}
+
+	if err := b.backend.Delete(ctx, b.loc.Key); err != nil {
+		slog.Warn("failed to delete relocated copy", "backend", b.cfg.Name, "key", b.loc.Key, "error", err)
+	}
+
+	return nil
+}
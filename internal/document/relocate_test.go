@@ -0,0 +1,120 @@
+package document_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/auth"
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+// addTemplatedBackend configures an enabled, empty backend that stores
+// documents under keyTemplate.
+func (env *testEnv) addTemplatedBackend(t *testing.T, name, keyTemplate string) *memBackend {
+	t.Helper()
+
+	b := &memBackend{objects: make(map[string][]byte)}
+	env.backends[name] = b
+
+	raw, _ := json.Marshal(map[string]string{"name": name, "key_template": keyTemplate})
+	require.NoError(t, env.repo.CreateBackend(env.ctx, &document.BackendConfig{
+		UserID: auth.UserID(env.ctx), Type: "mem", Name: name, Config: raw, Enabled: true,
+	}))
+
+	return b
+}
+
+func objectKeys(b *memBackend) []string {
+	var keys []string
+	for key := range b.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestService_Upload_KeyTemplate(t *testing.T) {
+	env := newTestEnv(t, "primary")
+	nas := env.addTemplatedBackend(t, "nas", "inbox/{{name}}.{{ext}}")
+
+	first, err := env.svc.Upload(env.ctx, "scan.txt", "text/plain", bytes.NewReader([]byte("first")), nil)
+	require.NoError(t, err)
+
+	second, err := env.svc.Upload(env.ctx, "scan.txt", "text/plain", bytes.NewReader([]byte("second")), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []byte("first"), nas.objects["inbox/scan.txt"])
+	assert.Equal(t, []byte("second"), nas.objects["inbox/scan_2.txt"], "taken keys are numbered")
+	assert.Len(t, env.backends["primary"].objects, 2, "backends without a template name documents themselves")
+
+	for doc, key := range map[*document.Document]string{first: "inbox/scan.txt", second: "inbox/scan_2.txt"} {
+		locations, err := env.repo.ListLocations(env.ctx, doc.ID)
+		require.NoError(t, err)
+		require.Len(t, locations, 2)
+		assert.Equal(t, key, locations[1].Key)
+	}
+}
+
+func TestService_Upload_KeyTemplateForTransaction(t *testing.T) {
+	env := newTestEnv(t)
+	nas := env.addTemplatedBackend(t, "nas", "{{year}}/{{date}}_{{merchant}}_{{amount}}.{{ext}}")
+
+	tx := document.TransactionFields{
+		Date:     time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+		Amount:   -1230,
+		Merchant: "Continente",
+	}
+
+	doc, err := env.svc.Upload(env.ctx, "receipt.txt", "text/plain", bytes.NewReader([]byte("receipt")), &tx)
+	require.NoError(t, err)
+
+	want := "2026/2026-10-02_Continente_-12.30.txt"
+	assert.Equal(t, []string{want}, objectKeys(nas), "stored under the transaction on upload")
+
+	// Once attached, the document is already where it belongs.
+	env.repo.txs[doc.ID] = []document.TransactionFields{tx}
+
+	require.NoError(t, env.svc.Relocate(env.ctx, doc.ID))
+	assert.Equal(t, []string{want}, objectKeys(nas))
+}
+
+func TestService_Relocate(t *testing.T) {
+	env := newTestEnv(t, "primary")
+	nas := env.addTemplatedBackend(t, "nas", "{{year}}/{{month}}/{{date}}_{{merchant}}_{{amount}}.{{ext}}")
+
+	doc, err := env.svc.Upload(env.ctx, "receipt.txt", "text/plain", bytes.NewReader([]byte("receipt")), nil)
+	require.NoError(t, err)
+
+	uploaded := objectKeys(nas)
+	primary := objectKeys(env.backends["primary"])
+
+	env.repo.txs[doc.ID] = []document.TransactionFields{{
+		Date:     time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+		Amount:   -1230,
+		Merchant: "Continente",
+	}}
+
+	require.NoError(t, env.svc.Relocate(env.ctx, doc.ID))
+
+	want := "2026/10/2026-10-02_Continente_-12.30.txt"
+	assert.Equal(t, []string{want}, objectKeys(nas))
+	assert.NotEqual(t, uploaded, objectKeys(nas))
+	assert.Equal(t, primary, objectKeys(env.backends["primary"]), "backends without a template are left alone")
+
+	locations, err := env.repo.ListLocations(env.ctx, doc.ID)
+	require.NoError(t, err)
+	require.Len(t, locations, 2)
+	assert.Equal(t, want, locations[1].Key)
+
+	// Documents already where their template puts them stay there.
+	require.NoError(t, env.svc.Relocate(env.ctx, doc.ID))
+	assert.Equal(t, []string{want}, objectKeys(nas))
+
+	report, err := env.svc.Replicate(env.ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.Relocated)
+}
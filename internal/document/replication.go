@@ -0,0 +1,275 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// ReplicationReport summarizes a Replicate run.
+type ReplicationReport struct {
+	Documents int // documents examined
+	Copied    int // copies written to backends that had none
+	Repaired  int // broken copies replaced
+	Relocated int // copies moved to the key their backend's template renders to
+	Failures  []ReplicationFailure
+}
+
+// ReplicationFailure is a document that could not be brought onto a backend.
+// BackendID is uuid.Nil when no copy of the document could be read at all.
+type ReplicationFailure struct {
+	DocumentID uuid.UUID
+	BackendID  uuid.UUID
+	Err        error
+}
+
+// errChecksumMismatch marks a copy whose content no longer hashes to the
+// document's SHA-256.
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// backendCopy is a document location with the backend it is on.
+type backendCopy struct {
+	loc     Location
+	cfg     BackendConfig
+	backend Backend
+}
+
+// Replicate stores every document of the user on every enabled backend. Each
+// copy on an enabled backend is downloaded and checked against the document's
+// SHA-256; copies that are missing are written from a good one, and copies that
+// fail to download or do not match are replaced. Documents linked by URL are
+// skipped, as there is no checksum to verify them against. Copies on backends
+// with a key template are then moved to the key it renders to, if they are
+// elsewhere.
+//
+// A document that cannot be replicated is reported and does not stop the run.
+func (s *Service) Replicate(ctx context.Context) (*ReplicationReport, error) {
+	backends, err := s.enabledBackends(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.repo.ListDocuments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing documents: %w", err)
+	}
+
+	report := &ReplicationReport{}
+
+	for i := range docs {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+
+		if docs[i].SHA256 == "" {
+			continue
+		}
+
+		report.Documents++
+
+		if err := s.replicate(ctx, &docs[i], backends, report); err != nil {
+			report.Failures = append(report.Failures, ReplicationFailure{DocumentID: docs[i].ID, Err: err})
+			continue
+		}
+
+		moved, err := s.relocate(ctx, &docs[i], backends)
+		report.Relocated += moved
+		if err != nil {
+			report.Failures = append(report.Failures, ReplicationFailure{DocumentID: docs[i].ID, Err: fmt.Errorf("relocating: %w", err)})
+		}
+	}
+
+	return report, nil
+}
+
+// enabledBackends instantiates the user's enabled backends, keyed by ID.
+// Backends whose config does not load are left out with a warning.
+func (s *Service) enabledBackends(ctx context.Context) (map[uuid.UUID]backendCopy, error) {
+	configs, err := s.repo.ListBackends(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing backends: %w", err)
+	}
+
+	backends := make(map[uuid.UUID]backendCopy)
+
+	for _, cfg := range configs {
+		if !cfg.Enabled {
+			continue
+		}
+
+		backend, err := s.registry.Create(cfg.Type, cfg.Config)
+		if err != nil {
+			slog.Warn("skipping backend that fails to load", "backend", cfg.Name, "error", err)
+			continue
+		}
+
+		backends[cfg.ID] = backendCopy{cfg: cfg, backend: backend}
+	}
+
+	return backends, nil
+}
+
+// replicate brings one document onto every backend in backends. It returns an
+// error only when no copy of the document can be read.
+func (s *Service) replicate(ctx context.Context, doc *Document, backends map[uuid.UUID]backendCopy, report *ReplicationReport) error {
+	locations, err := s.repo.ListLocations(ctx, doc.ID)
+	if err != nil {
+		return fmt.Errorf("listing locations: %w", err)
+	}
+
+	var (
+		source *spool
+		good   = make(map[uuid.UUID]bool)
+		broken = make(map[uuid.UUID][]backendCopy)
+	)
+
+	defer func() {
+		if source != nil {
+			source.Close()
+		}
+	}()
+
+	for _, loc := range locations {
+		b, ok := backends[loc.BackendID]
+		if !ok {
+			continue // disabled or unloadable backend; left as it is
+		}
+
+		b.loc = loc
+
+		sp, err := s.fetch(ctx, b, doc.SHA256)
+		if err != nil {
+			slog.Warn("broken document copy", "document_id", doc.ID, "backend", b.cfg.Name, "key", loc.Key, "error", err)
+			broken[loc.BackendID] = append(broken[loc.BackendID], b)
+			continue
+		}
+
+		good[loc.BackendID] = true
+
+		if source == nil {
+			source = sp
+		} else {
+			sp.Close()
+		}
+	}
+
+	var missing []uuid.UUID
+	for id := range backends {
+		if !good[id] {
+			missing = append(missing, id)
+		} else if len(broken[id]) > 0 {
+			// A good copy is already there; the broken ones are just dropped.
+			s.dropCopies(ctx, broken[id])
+			report.Repaired++
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if source == nil {
+		source, err = s.fetchAny(ctx, locations, doc.SHA256)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, id := range missing {
+		b := backends[id]
+
+		key, err := s.put(ctx, b, doc, nil, source.reader)
+		if err != nil {
+			report.Failures = append(report.Failures, ReplicationFailure{DocumentID: doc.ID, BackendID: id, Err: fmt.Errorf("uploading: %w", err)})
+			continue
+		}
+
+		if err := s.repo.AddLocation(ctx, &Location{DocumentID: doc.ID, BackendID: id, Key: key}); err != nil {
+			if delErr := b.backend.Delete(ctx, key); delErr != nil {
+				slog.Warn("failed to delete unrecorded copy", "backend", b.cfg.Name, "key", key, "error", delErr)
+			}
+			report.Failures = append(report.Failures, ReplicationFailure{DocumentID: doc.ID, BackendID: id, Err: fmt.Errorf("recording location: %w", err)})
+			continue
+		}
+
+		if len(broken[id]) == 0 {
+			report.Copied++
+			continue
+		}
+
+		s.dropCopies(ctx, broken[id])
+		report.Repaired++
+	}
+
+	return nil
+}
+
+// dropCopies forgets broken copies and deletes what is left of them.
+func (s *Service) dropCopies(ctx context.Context, copies []backendCopy) {
+	for _, c := range copies {
+		if err := s.repo.DeleteLocation(ctx, c.loc.ID); err != nil {
+			slog.Warn("failed to remove broken location", "location_id", c.loc.ID, "error", err)
+			continue
+		}
+
+		// The copy may be partly there or gone; deleting it is best-effort.
+		if err := c.backend.Delete(ctx, c.loc.Key); err != nil {
+			slog.Debug("failed to delete broken copy", "backend", c.cfg.Name, "key", c.loc.Key, "error", err)
+		}
+	}
+}
+
+// fetch downloads a copy into a spool and checks it against sha256. Copies of
+// documents linked by URL, which have no checksum, are not checked.
+func (s *Service) fetch(ctx context.Context, b backendCopy, sha256 string) (*spool, error) {
+	rc, err := b.backend.Download(ctx, b.loc.Key)
+	if err != nil {
+		return nil, fmt.Errorf("downloading: %w", err)
+	}
+	defer rc.Close()
+
+	sp, err := newSpool(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	if sha256 != "" && sp.sha256 != sha256 {
+		sp.Close()
+		return nil, errChecksumMismatch
+	}
+
+	return sp, nil
+}
+
+// fetchAny returns a verified copy from any backend, including disabled ones,
+// for documents whose copies on enabled backends are all missing or broken.
+func (s *Service) fetchAny(ctx context.Context, locations []Location, sha256 string) (*spool, error) {
+	var errs []error
+
+	for _, loc := range locations {
+		cfg, err := s.repo.GetBackend(ctx, loc.BackendID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("backend %s: %w", loc.BackendID, err))
+			continue
+		}
+
+		backend, err := s.registry.Create(cfg.Type, cfg.Config)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("backend %s: creating backend: %w", loc.BackendID, err))
+			continue
+		}
+
+		sp, err := s.fetch(ctx, backendCopy{loc: loc, cfg: *cfg, backend: backend}, sha256)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("backend %s: %w", loc.BackendID, err))
+			continue
+		}
+
+		return sp, nil
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrNoAvailableLocation, errors.Join(errs...))
+}
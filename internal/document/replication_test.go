@@ -0,0 +1,75 @@
+package document_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+func TestService_Replicate(t *testing.T) {
+	env := newTestEnv(t, "primary", "mirror")
+
+	upload := func(name, content string) *document.Document {
+		doc, err := env.svc.Upload(env.ctx, name, "text/plain", bytes.NewReader([]byte(content)), nil)
+		require.NoError(t, err)
+		return doc
+	}
+
+	healthy := upload("a.txt", "healthy")
+	damaged := upload("b.txt", "damaged on primary")
+	lost := upload("c.txt", "damaged everywhere")
+
+	// Corrupt copies in place, as a bad disk would.
+	for _, name := range []string{"primary", "mirror"} {
+		for key, data := range env.backends[name].objects {
+			if string(data) == "damaged everywhere" || (name == "primary" && string(data) == "damaged on primary") {
+				env.backends[name].objects[key] = []byte("garbage")
+			}
+		}
+	}
+
+	archive := env.addBackend(t, "archive")
+
+	report, err := env.svc.Replicate(env.ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, report.Documents)
+	assert.Equal(t, 2, report.Copied, "healthy and damaged copied to archive")
+	assert.Equal(t, 1, report.Repaired, "damaged rewritten on primary")
+	require.Len(t, report.Failures, 1)
+	assert.Equal(t, lost.ID, report.Failures[0].DocumentID)
+	assert.Equal(t, uuid.Nil, report.Failures[0].BackendID)
+	assert.ErrorIs(t, report.Failures[0].Err, document.ErrNoAvailableLocation)
+
+	assert.Len(t, archive.objects, 2)
+
+	for _, doc := range []*document.Document{healthy, damaged} {
+		locations, err := env.repo.ListLocations(env.ctx, doc.ID)
+		require.NoError(t, err)
+		assert.Len(t, locations, 3, doc.Filename)
+	}
+
+	// Every copy of the damaged document reads back intact.
+	locations, err := env.repo.ListLocations(env.ctx, damaged.ID)
+	require.NoError(t, err)
+
+	for _, loc := range locations {
+		for _, b := range env.backends {
+			if data, ok := b.objects[loc.Key]; ok {
+				assert.Equal(t, "damaged on primary", string(data))
+			}
+		}
+	}
+
+	// A second run has nothing left to do but report the lost document.
+	report, err = env.svc.Replicate(env.ctx)
+	require.NoError(t, err)
+	assert.Zero(t, report.Copied)
+	assert.Zero(t, report.Repaired)
+	assert.Len(t, report.Failures, 1)
+}
@@ -3,6 +3,7 @@ package document
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -17,13 +18,55 @@ type Repository interface {
 	UpdateBackend(ctx context.Context, id uuid.UUID, name *string, config json.RawMessage, enabled *bool) error
 	DeleteBackend(ctx context.Context, id uuid.UUID) error
 	BackendHasDocuments(ctx context.Context, backendID uuid.UUID) (bool, error)
+	// SetBackendHealth records the latest health check of the backend.
+	SetBackendHealth(ctx context.Context, id uuid.UUID, health BackendHealth) error
+	// GetDrain returns the latest drain of the backend, or ErrDrainNotFound.
+	GetDrain(ctx context.Context, backendID uuid.UUID) (*Drain, error)
+	// SaveDrain creates or replaces the drain of drain.BackendID.
+	SaveDrain(ctx context.Context, drain *Drain) error
+	// ClaimDrain marks the backend as draining at the given time, unless
+	// another drain marked it after staleBefore. It reports whether the mark
+	// was taken.
+	ClaimDrain(ctx context.Context, backendID uuid.UUID, at, staleBefore time.Time) (bool, error)
+	// ReleaseDrain clears the backend's draining mark.
+	ReleaseDrain(ctx context.Context, backendID uuid.UUID) error
 
 	// Document operations
 	CreateDocument(ctx context.Context, doc *Document) error
 	GetDocument(ctx context.Context, id uuid.UUID) (*Document, error)
+	// ListDocuments returns all of the user's documents, oldest first.
+	ListDocuments(ctx context.Context) ([]Document, error)
+	// FindDocumentBySHA256 returns the user's document with the given content
+	// hash, or ErrDocumentNotFound.
+	FindDocumentBySHA256(ctx context.Context, sha256 string) (*Document, error)
+	// ListUnattachedDocuments returns the user's documents no transaction
+	// references, newest first. A non-empty search keeps the documents whose
+	// filename, invoice fields or text contain it, ignoring case.
+	ListUnattachedDocuments(ctx context.Context, search string) ([]Document, error)
+	// DocumentInUse reports whether any transaction references the document.
+	DocumentInUse(ctx context.Context, id uuid.UUID) (bool, error)
+	// ListDocumentTransactions returns the user's transactions the document
+	// is attached to, those it is the invoice of first, then oldest
+	// attachment first.
+	ListDocumentTransactions(ctx context.Context, documentID uuid.UUID) ([]TransactionFields, error)
 	DeleteDocument(ctx context.Context, id uuid.UUID) error
+	UpdateInvoice(ctx context.Context, id uuid.UUID, inv Invoice) error
+	// SetText stores the text extracted from the document's content.
+	SetText(ctx context.Context, id uuid.UUID, text string) error
+	// GetPreview returns the stored preview of the document, or
+	// ErrPreviewNotFound.
+	GetPreview(ctx context.Context, documentID uuid.UUID) ([]byte, error)
+	// SavePreview stores the preview of the document, replacing any other.
+	SavePreview(ctx context.Context, documentID uuid.UUID, data []byte) error
+	// MarkNoPreview records that the document's content cannot be rendered
+	// as a preview.
+	MarkNoPreview(ctx context.Context, documentID uuid.UUID) error
 
 	// Location operations
 	AddLocation(ctx context.Context, loc *Location) error
 	ListLocations(ctx context.Context, documentID uuid.UUID) ([]Location, error)
+	DeleteLocation(ctx context.Context, id uuid.UUID) error
+	// ListBackendLocations returns the user's document locations on the
+	// backend, oldest first.
+	ListBackendLocations(ctx context.Context, backendID uuid.UUID) ([]Location, error)
 }
@@ -0,0 +1,274 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+// Config holds the S3-compatible bucket configuration stored in document_backends.config.
+type Config struct {
+	// Endpoint is the service URL, e.g. "http://minio:9000". Defaults to AWS S3
+	// in Region.
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	Prefix          string `json:"prefix"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	// PathStyle addresses the bucket as <endpoint>/<bucket> instead of
+	// <bucket>.<endpoint>. MinIO and most self-hosted services need it.
+	PathStyle bool `json:"path_style"`
+}
+
+// defaultRegion is used when the config leaves the region empty; MinIO accepts it too.
+const defaultRegion = "us-east-1"
+
+type Backend struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	pathStyle bool
+	signer    signer
+}
+
+// NewFromConfig creates an S3 Backend from the JSONB config stored in the DB.
+func NewFromConfig(raw json.RawMessage) (document.Backend, error) {
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("s3: invalid config: %w", err)
+	}
+
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3: bucket is required")
+	}
+
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3: access_key_id and secret_access_key are required")
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = defaultRegion
+	}
+
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3: endpoint must be an absolute URL: %q", cfg.Endpoint)
+	}
+
+	return &Backend{
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		endpoint:  endpoint,
+		bucket:    cfg.Bucket,
+		prefix:    strings.Trim(cfg.Prefix, "/"),
+		pathStyle: cfg.PathStyle,
+		signer: signer{
+			accessKey: cfg.AccessKeyID,
+			secretKey: cfg.SecretAccessKey,
+			region:    cfg.Region,
+		},
+	}, nil
+}
+
+func (b *Backend) Type() string { return "s3" }
+
+// objectURL returns the URL of the object with the given key. Keys that could
+// address anything outside the bucket (and prefix) are rejected, like the local
+// backend rejects keys that escape its base path.
+func (b *Backend) objectURL(key string) (*url.URL, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return nil, fmt.Errorf("s3: invalid key: %q", key)
+	}
+
+	u := *b.endpoint
+
+	if b.pathStyle {
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + b.bucket + "/" + key
+	} else {
+		u.Host = b.bucket + "." + u.Host
+		u.Path = strings.TrimRight(u.Path, "/") + "/" + key
+	}
+
+	// Send the path encoded exactly as it is signed.
+	u.RawPath = canonicalURI(&u)
+
+	return &u, nil
+}
+
+// Upload stores the content as <prefix>/<uuid>_<filename> and returns the full
+// object key.
+func (b *Backend) Upload(ctx context.Context, filename string, content io.Reader) (string, error) {
+	return b.put(ctx, b.prefixed(uuid.New().String()+"_"+filepath.Base(filename)), content, false)
+}
+
+// UploadAt stores the content as <prefix>/<key> and returns the full object
+// key. Existing objects are not replaced: S3 has no folders to create, but
+// would overwrite them.
+func (b *Backend) UploadAt(ctx context.Context, key string, content io.Reader) (string, error) {
+	key = b.prefixed(key)
+
+	// Not every S3-compatible service honours If-None-Match, so look first.
+	resp, err := b.do(ctx, http.MethodHead, key)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("s3: %w: %s", document.ErrKeyExists, key)
+	}
+
+	return b.put(ctx, key, content, true)
+}
+
+func (b *Backend) prefixed(key string) string {
+	if b.prefix == "" {
+		return key
+	}
+
+	return b.prefix + "/" + key
+}
+
+// put stores the content under key; if exclusive, only when there is no object
+// there yet. S3 needs the length and, for a signed payload, the hash up front,
+// so the content is spooled to a temporary file first.
+func (b *Backend) put(ctx context.Context, key string, content io.Reader, exclusive bool) (string, error) {
+	spool, err := os.CreateTemp("", "finny-s3-*")
+	if err != nil {
+		return "", fmt.Errorf("s3: creating spool file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	h := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(spool, h), content)
+	if err != nil {
+		return "", fmt.Errorf("s3: spooling content: %w", err)
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("s3: rewinding spool file: %w", err)
+	}
+
+	u, err := b.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), io.NopCloser(spool))
+	if err != nil {
+		return "", fmt.Errorf("s3: creating request: %w", err)
+	}
+
+	req.ContentLength = size
+	if exclusive {
+		req.Header.Set("If-None-Match", "*")
+	}
+	b.signer.sign(req, hex.EncodeToString(h.Sum(nil)), time.Now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("s3: putting object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return "", fmt.Errorf("s3: %w: %s", document.ErrKeyExists, key)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("s3: putting object %s: %w", key, responseError(resp))
+	}
+
+	return key, nil
+}
+
+// Download retrieves the object stored under key.
+func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := b.do(ctx, http.MethodGet, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("s3: getting object %s: %w", key, responseError(resp))
+	}
+
+	return resp.Body, nil
+}
+
+// Delete removes the object stored under key. S3 reports success for keys that
+// do not exist.
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	resp, err := b.do(ctx, http.MethodDelete, key)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("s3: deleting object %s: %w", key, responseError(resp))
+	}
+
+	return nil
+}
+
+// do sends a signed request without a body for the object under key.
+func (b *Backend) do(ctx context.Context, method, key string) (*http.Response, error) {
+	u, err := b.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("s3: creating request: %w", err)
+	}
+
+	b.signer.sign(req, emptyPayloadHash, time.Now())
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %s object %s: %w", strings.ToLower(method), key, err)
+	}
+
+	return resp, nil
+}
+
+// responseError turns an S3 error response into an error, using the XML error
+// code and message when the body has them.
+func responseError(resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if xml.Unmarshal(data, &body) == nil && body.Code != "" {
+		return fmt.Errorf("status %d: %s: %s", resp.StatusCode, body.Code, body.Message)
+	}
+
+	return fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
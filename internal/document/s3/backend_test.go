@@ -0,0 +1,221 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+const (
+	testAccessKey = "minioadmin"
+	testSecretKey = "minio-secret"
+)
+
+// fakeS3 is a path-style, in-memory stand-in for an S3-compatible service.
+// It verifies the request signature and payload hash like a real server would.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte // by "/<bucket>/<key>"
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{objects: make(map[string][]byte)}
+
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	return f, srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	if !f.verify(r, body) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `<Error><Code>SignatureDoesNotMatch</Code><Message>bad signature</Message></Error>`)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		obj, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		_, _ = w.Write(obj)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// verify re-signs the received request with the shared secret and compares
+// the result with its Authorization header.
+func (f *fakeS3) verify(r *http.Request, body []byte) bool {
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		return false
+	}
+
+	date, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	got := r.Header.Get("Authorization")
+
+	// Only the headers the client signed take part in the signature.
+	signed := got[strings.Index(got, "SignedHeaders=")+len("SignedHeaders="):]
+	signed = signed[:strings.Index(signed, ",")]
+
+	clone, _ := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	for _, name := range strings.Split(signed, ";") {
+		if name != "host" && name != "x-amz-date" && name != "x-amz-content-sha256" {
+			clone.Header.Set(name, r.Header.Get(name))
+		}
+	}
+
+	signer{accessKey: testAccessKey, secretKey: testSecretKey, region: defaultRegion}.
+		sign(clone, r.Header.Get("X-Amz-Content-Sha256"), date)
+
+	return clone.Header.Get("Authorization") == got
+}
+
+func newBackend(t *testing.T, cfg Config) document.Backend {
+	raw, err := json.Marshal(cfg)
+	require.NoError(t, err)
+
+	b, err := NewFromConfig(raw)
+	require.NoError(t, err)
+
+	return b
+}
+
+func testRoundTrip(t *testing.T, b document.Backend, wantPrefix string) string {
+	ctx := context.Background()
+
+	key, err := b.Upload(ctx, "Fatura 2026 (1).pdf", strings.NewReader("%PDF-1.7 invoice"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, wantPrefix), "key %q should start with %q", key, wantPrefix)
+	assert.True(t, strings.HasSuffix(key, "_Fatura 2026 (1).pdf"))
+
+	rc, err := b.Download(ctx, key)
+	require.NoError(t, err)
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "%PDF-1.7 invoice", string(content))
+
+	require.NoError(t, b.Delete(ctx, key))
+
+	_, err = b.Download(ctx, key)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "NoSuchKey")
+
+	return key
+}
+
+func TestBackend_PathStyle(t *testing.T) {
+	fake, srv := newFakeS3(t)
+
+	b := newBackend(t, Config{
+		Endpoint:        srv.URL,
+		Bucket:          "invoices",
+		Prefix:          "/finny/",
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: testSecretKey,
+		PathStyle:       true,
+	})
+
+	testRoundTrip(t, b, "finny/")
+	assert.Empty(t, fake.objects)
+}
+
+func TestBackend_WrongSecret(t *testing.T) {
+	_, srv := newFakeS3(t)
+
+	b := newBackend(t, Config{
+		Endpoint:        srv.URL,
+		Bucket:          "invoices",
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: "wrong",
+		PathStyle:       true,
+	})
+
+	_, err := b.Upload(context.Background(), "a.pdf", strings.NewReader("x"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SignatureDoesNotMatch")
+}
+
+func TestBackend_InvalidKey(t *testing.T) {
+	_, srv := newFakeS3(t)
+
+	b := newBackend(t, Config{
+		Endpoint:        srv.URL,
+		Bucket:          "invoices",
+		AccessKeyID:     testAccessKey,
+		SecretAccessKey: testSecretKey,
+		PathStyle:       true,
+	})
+
+	for _, key := range []string{"", "/abs.pdf", "../escape.pdf", "a/../../b.pdf", "a//b.pdf"} {
+		_, err := b.Download(context.Background(), key)
+		assert.ErrorContains(t, err, "invalid key", key)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	_, err := NewFromConfig(json.RawMessage(`{"access_key_id":"a","secret_access_key":"b"}`))
+	assert.ErrorContains(t, err, "bucket is required")
+
+	_, err = NewFromConfig(json.RawMessage(`{"bucket":"b"}`))
+	assert.ErrorContains(t, err, "access_key_id")
+
+	b, err := NewFromConfig(json.RawMessage(`{"bucket":"docs","region":"eu-west-1","access_key_id":"a","secret_access_key":"b"}`))
+	require.NoError(t, err)
+
+	u, err := b.(*Backend).objectURL("x/y.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "https://docs.s3.eu-west-1.amazonaws.com/x/y.pdf", u.String())
+}
+
+// TestBackend_Live runs the round trip against a real S3-compatible service
+// (e.g. a local MinIO) when FINNY_TEST_S3_ENDPOINT is set.
+func TestBackend_Live(t *testing.T) {
+	endpoint := os.Getenv("FINNY_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("FINNY_TEST_S3_ENDPOINT not set")
+	}
+
+	b := newBackend(t, Config{
+		Endpoint:        endpoint,
+		Region:          os.Getenv("FINNY_TEST_S3_REGION"),
+		Bucket:          os.Getenv("FINNY_TEST_S3_BUCKET"),
+		Prefix:          "finny-test",
+		AccessKeyID:     os.Getenv("FINNY_TEST_S3_ACCESS_KEY"),
+		SecretAccessKey: os.Getenv("FINNY_TEST_S3_SECRET_KEY"),
+		PathStyle:       true,
+	})
+
+	testRoundTrip(t, b, "finny-test/")
+}
@@ -1,24 +1,30 @@
 package document
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/google/uuid"
 
 	"github.com/MrJamesThe3rd/finny/internal/auth"
+	"github.com/MrJamesThe3rd/finny/internal/document/pdftext"
 )
 
 // paperlessKeyRe extracts the numeric document ID from a Paperless-ngx URL.
 // e.g. "https://paperless.example.com/api/documents/42/download/" → "42"
 var paperlessKeyRe = regexp.MustCompile(`/api/documents/(\d+)/`)
 
+// maxExtractSize bounds the PDFs whose text is extracted. Larger files are
+// almost always scans, which carry no text.
+const maxExtractSize = 32 << 20
+
 // LegacyPaperlessBackendID is the well-known UUID seeded by the
 // 20260404000002_add_document_store.sql migration for the migrated Paperless backend.
 var LegacyPaperlessBackendID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
@@ -127,9 +133,18 @@ func (s *Service) AttachFromURL(ctx context.Context, rawURL string) (*Document,
 }
 
 // Upload stores the file on all enabled backends and returns the created Document.
-// Fails if no enabled backends are configured. Fails if any backend upload fails.
-// The caller must not close content before this returns.
-func (s *Service) Upload(ctx context.Context, filename, mimeType string, content io.Reader) (*Document, error) {
+// Content is read once, into a temporary file, while its size and SHA-256 are
+// computed. If the user already has a document with the same content, that
+// document is returned instead and nothing is stored. Fails if no enabled
+// backends are configured. Fails if any backend upload fails. The caller must
+// not close content before this returns.
+//
+// The invoice fields of PDFs are filled from their text, and a preview of PDFs
+// and images is generated; a file that cannot be read is still stored.
+//
+// tx is the transaction the document is uploaded for, or nil. Key templates
+// name the document after it, so it is stored where it belongs once attached.
+func (s *Service) Upload(ctx context.Context, filename, mimeType string, content io.Reader, tx *TransactionFields) (*Document, error) {
 	userID := auth.UserID(ctx)
 
 	backends, err := s.repo.ListBackends(ctx)
@@ -148,19 +163,39 @@ func (s *Service) Upload(ctx context.Context, filename, mimeType string, content
 		return nil, ErrNoBackends
 	}
 
-	// Buffer content once so it can be re-read by each backend.
-	buf, err := io.ReadAll(content)
+	// Spool content to disk once so it can be re-read by each backend without
+	// keeping large scans in memory.
+	sp, err := newSpool(content)
 	if err != nil {
 		return nil, fmt.Errorf("reading upload content: %w", err)
 	}
+	defer sp.Close()
+
+	// Identical content is stored once: reuse the existing document and its
+	// locations so several transactions can reference the same file.
+	existing, err := s.repo.FindDocumentBySHA256(ctx, sp.sha256)
+	if err == nil {
+		return existing, nil
+	}
+
+	if !errors.Is(err, ErrDocumentNotFound) {
+		return nil, fmt.Errorf("finding duplicate document: %w", err)
+	}
 
 	doc := &Document{
 		UserID:   userID,
 		Filename: filename,
 		MIMEType: mimeType,
+		Size:     sp.size,
+		SHA256:   sp.sha256,
 	}
 
 	if err := s.repo.CreateDocument(ctx, doc); err != nil {
+		if errors.Is(err, ErrDuplicateContent) {
+			// A concurrent upload of the same content created it first.
+			return s.repo.FindDocumentBySHA256(ctx, sp.sha256)
+		}
+
 		return nil, fmt.Errorf("creating document: %w", err)
 	}
 
@@ -181,6 +216,14 @@ func (s *Service) Upload(ctx context.Context, filename, mimeType string, content
 		_ = s.repo.DeleteDocument(ctx, doc.ID)
 	}
 
+	// Invoice fields are read before storing the document, as key templates
+	// may name it after them.
+	if isPDF(doc) && sp.size <= maxExtractSize {
+		if err := s.analyze(ctx, doc, sp.file, sp.size); err != nil && !errors.Is(err, ErrNoText) {
+			slog.Warn("failed to extract document text", "document_id", doc.ID, "error", err)
+		}
+	}
+
 	for _, cfg := range enabled {
 		backend, err := s.registry.Create(cfg.Type, cfg.Config)
 		if err != nil {
@@ -188,7 +231,7 @@ func (s *Service) Upload(ctx context.Context, filename, mimeType string, content
 			return nil, fmt.Errorf("creating backend %s: %w", cfg.Name, err)
 		}
 
-		key, err := backend.Upload(ctx, filename, bytes.NewReader(buf))
+		key, err := s.put(ctx, backendCopy{cfg: cfg, backend: backend}, doc, tx, sp.reader)
 		if err != nil {
 			rollback()
 			return nil, fmt.Errorf("uploading to backend %s: %w", cfg.Name, err)
@@ -208,9 +251,81 @@ func (s *Service) Upload(ctx context.Context, filename, mimeType string, content
 		}
 	}
 
+	if hasPreview(doc) && sp.size <= maxPreviewSize {
+		if _, err := s.generatePreview(ctx, doc, sp.file, sp.size); err != nil && !errors.Is(err, ErrNoPreview) {
+			slog.Warn("failed to generate document preview", "document_id", doc.ID, "error", err)
+		}
+	}
+
+	return doc, nil
+}
+
+// Extract reads the document's text again and fills its unknown invoice
+// fields from it, leaving the fields already known untouched. Fails with
+// ErrNoText for documents without readable text.
+func (s *Service) Extract(ctx context.Context, documentID uuid.UUID) (*Document, error) {
+	rc, doc, err := s.Download(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	if !isPDF(doc) {
+		return nil, ErrNoText
+	}
+
+	// The text is read from a local copy, not from memory.
+	sp, err := newSpool(io.LimitReader(rc, maxExtractSize+1))
+	if err != nil {
+		return nil, err
+	}
+	defer sp.Close()
+
+	if sp.size > maxExtractSize {
+		return nil, ErrNoText
+	}
+
+	if err := s.analyze(ctx, doc, sp.file, sp.size); err != nil {
+		return nil, err
+	}
+
 	return doc, nil
 }
 
+func isPDF(doc *Document) bool {
+	return doc.MIMEType == "application/pdf" || strings.EqualFold(filepath.Ext(doc.Filename), ".pdf")
+}
+
+// analyze extracts the text of a PDF of the given size, stores it and fills
+// the document's unknown invoice fields from it. The PDF is read piecemeal
+// from content.
+func (s *Service) analyze(ctx context.Context, doc *Document, content io.ReaderAt, size int64) error {
+	text, err := pdftext.Extract(content, size)
+	if errors.Is(err, pdftext.ErrNotPDF) || errors.Is(err, pdftext.ErrEncrypted) {
+		return fmt.Errorf("%w: %w", ErrNoText, err)
+	}
+	if err != nil {
+		return fmt.Errorf("extracting text: %w", err)
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return ErrNoText
+	}
+
+	if err := s.repo.SetText(ctx, doc.ID, text); err != nil {
+		return fmt.Errorf("storing text: %w", err)
+	}
+
+	inv := doc.Invoice.fill(ParseInvoice(text))
+	if err := s.repo.UpdateInvoice(ctx, doc.ID, inv); err != nil {
+		return fmt.Errorf("storing invoice fields: %w", err)
+	}
+
+	doc.Invoice = inv
+
+	return nil
+}
+
 // Delete removes a document from all backends and deletes its DB record.
 // Backend deletions are best-effort; DB deletion always runs.
 func (s *Service) Delete(ctx context.Context, documentID uuid.UUID) error {
@@ -241,11 +356,93 @@ func (s *Service) Delete(ctx context.Context, documentID uuid.UUID) error {
 	return s.repo.DeleteDocument(ctx, documentID)
 }
 
+// Release deletes a document once no transaction references it any more.
+// Callers detach the document from a transaction first and then release it, so
+// documents shared through deduplication survive until their last reference goes.
+func (s *Service) Release(ctx context.Context, documentID uuid.UUID) error {
+	inUse, err := s.repo.DocumentInUse(ctx, documentID)
+	if err != nil {
+		return fmt.Errorf("checking document references: %w", err)
+	}
+
+	if inUse {
+		return nil
+	}
+
+	return s.Delete(ctx, documentID)
+}
+
+// Inbox returns the documents uploaded but not yet attached to any transaction,
+// newest first. A non-empty search narrows them to those whose filename,
+// invoice fields or text contain it.
+func (s *Service) Inbox(ctx context.Context, search string) ([]Document, error) {
+	return s.repo.ListUnattachedDocuments(ctx, search)
+}
+
+// Get returns a document's metadata.
+func (s *Service) Get(ctx context.Context, documentID uuid.UUID) (*Document, error) {
+	return s.repo.GetDocument(ctx, documentID)
+}
+
+// UpdateInvoice replaces the document's invoice fields.
+func (s *Service) UpdateInvoice(ctx context.Context, documentID uuid.UUID, inv Invoice) error {
+	return s.repo.UpdateInvoice(ctx, documentID, inv)
+}
+
+// ApplyQRCode parses the payload of the document's invoice QR code and stores
+// the fields it carries, replacing those already known. Fails with
+// ErrInvalidQRCode for malformed payloads.
+func (s *Service) ApplyQRCode(ctx context.Context, documentID uuid.UUID, payload string) (*Document, error) {
+	qr, err := ParseQRCode(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.repo.GetDocument(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := qr.Apply(doc.Invoice)
+	if err := s.repo.UpdateInvoice(ctx, documentID, inv); err != nil {
+		return nil, err
+	}
+
+	doc.Invoice = inv
+
+	return doc, nil
+}
+
+// Discard deletes a document from the inbox. Fails with ErrDocumentInUse once
+// the document is attached to a transaction; detach it there instead.
+func (s *Service) Discard(ctx context.Context, documentID uuid.UUID) error {
+	if _, err := s.repo.GetDocument(ctx, documentID); err != nil {
+		return err
+	}
+
+	inUse, err := s.repo.DocumentInUse(ctx, documentID)
+	if err != nil {
+		return fmt.Errorf("checking document references: %w", err)
+	}
+
+	if inUse {
+		return ErrDocumentInUse
+	}
+
+	return s.Delete(ctx, documentID)
+}
+
 // ListBackends returns all backends configured for the requesting user.
 func (s *Service) ListBackends(ctx context.Context) ([]BackendConfig, error) {
 	return s.repo.ListBackends(ctx)
 }
 
+// GetBackend returns one of the requesting user's backends. Fails with
+// ErrBackendNotFound if the user has no such backend.
+func (s *Service) GetBackend(ctx context.Context, id uuid.UUID) (*BackendConfig, error) {
+	return s.userBackend(ctx, id)
+}
+
 // CreateBackend creates a new backend configuration.
 func (s *Service) CreateBackend(ctx context.Context, cfg *BackendConfig) error {
 	return s.repo.CreateBackend(ctx, cfg)
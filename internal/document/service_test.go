@@ -0,0 +1,717 @@
+package document_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/auth"
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+// memRepo is an in-memory document.Repository for a single user.
+type memRepo struct {
+	mu        sync.Mutex
+	backends  []document.BackendConfig
+	documents map[uuid.UUID]*document.Document
+	locations []document.Location
+	inUse     map[uuid.UUID]bool // documents referenced by a transaction
+	texts     map[uuid.UUID]string
+	previews  map[uuid.UUID][]byte
+	txs       map[uuid.UUID][]document.TransactionFields // transactions documents are attached to
+	drains    map[uuid.UUID]document.Drain
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{
+		documents: make(map[uuid.UUID]*document.Document),
+		inUse:     make(map[uuid.UUID]bool),
+		texts:     make(map[uuid.UUID]string),
+		previews:  make(map[uuid.UUID][]byte),
+		txs:       make(map[uuid.UUID][]document.TransactionFields),
+		drains:    make(map[uuid.UUID]document.Drain),
+	}
+}
+
+func (r *memRepo) ListBackends(context.Context) ([]document.BackendConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]document.BackendConfig(nil), r.backends...), nil
+}
+
+func (r *memRepo) GetBackend(_ context.Context, id uuid.UUID) (*document.BackendConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, b := range r.backends {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+
+	return nil, document.ErrBackendNotFound
+}
+
+func (r *memRepo) SetBackendConfig(context.Context, uuid.UUID, json.RawMessage) error { return nil }
+
+func (r *memRepo) CreateBackend(_ context.Context, cfg *document.BackendConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg.ID = uuid.New()
+	r.backends = append(r.backends, *cfg)
+
+	return nil
+}
+
+func (r *memRepo) UpdateBackend(_ context.Context, id uuid.UUID, _ *string, _ json.RawMessage, enabled *bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.backends {
+		if r.backends[i].ID == id && enabled != nil {
+			r.backends[i].Enabled = *enabled
+		}
+	}
+
+	return nil
+}
+
+func (r *memRepo) DeleteBackend(context.Context, uuid.UUID) error { return nil }
+
+func (r *memRepo) BackendHasDocuments(context.Context, uuid.UUID) (bool, error) { return false, nil }
+
+func (r *memRepo) SetBackendHealth(_ context.Context, id uuid.UUID, health document.BackendHealth) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.backends {
+		if r.backends[i].ID == id {
+			r.backends[i].Health = &health
+			return nil
+		}
+	}
+
+	return document.ErrBackendNotFound
+}
+
+func (r *memRepo) GetDrain(_ context.Context, backendID uuid.UUID) (*document.Drain, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.drains[backendID]
+	if !ok {
+		return nil, document.ErrDrainNotFound
+	}
+
+	return &d, nil
+}
+
+func (r *memRepo) SaveDrain(_ context.Context, d *document.Drain) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.drains[d.BackendID] = *d
+
+	return nil
+}
+
+func (r *memRepo) ClaimDrain(_ context.Context, backendID uuid.UUID, at, staleBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.backends {
+		b := &r.backends[i]
+		if b.ID != backendID {
+			continue
+		}
+
+		if b.DrainingAt != nil && !b.DrainingAt.Before(staleBefore) {
+			return false, nil
+		}
+
+		b.DrainingAt = &at
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (r *memRepo) ReleaseDrain(_ context.Context, backendID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.backends {
+		if r.backends[i].ID == backendID {
+			r.backends[i].DrainingAt = nil
+		}
+	}
+
+	return nil
+}
+
+func (r *memRepo) CreateDocument(_ context.Context, doc *document.Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc.ID = uuid.New()
+	doc.CreatedAt = time.Now()
+	stored := *doc
+	r.documents[doc.ID] = &stored
+
+	return nil
+}
+
+func (r *memRepo) GetDocument(_ context.Context, id uuid.UUID) (*document.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.documents[id]
+	if !ok {
+		return nil, document.ErrDocumentNotFound
+	}
+
+	return doc, nil
+}
+
+func (r *memRepo) ListDocuments(context.Context) ([]document.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []document.Document
+	for _, doc := range r.documents {
+		out = append(out, *doc)
+	}
+
+	return out, nil
+}
+
+func (r *memRepo) FindDocumentBySHA256(_ context.Context, sha256 string) (*document.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, doc := range r.documents {
+		if sha256 != "" && doc.SHA256 == sha256 {
+			return doc, nil
+		}
+	}
+
+	return nil, document.ErrDocumentNotFound
+}
+
+func (r *memRepo) ListUnattachedDocuments(_ context.Context, search string) ([]document.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []document.Document
+
+	for id, doc := range r.documents {
+		text := strings.ToLower(doc.Filename + " " + r.texts[id])
+		if !r.inUse[id] && strings.Contains(text, strings.ToLower(search)) {
+			out = append(out, *doc)
+		}
+	}
+
+	return out, nil
+}
+
+func (r *memRepo) DocumentInUse(_ context.Context, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.inUse[id], nil
+}
+
+func (r *memRepo) DeleteDocument(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.documents, id)
+
+	kept := r.locations[:0]
+	for _, l := range r.locations {
+		if l.DocumentID != id {
+			kept = append(kept, l)
+		}
+	}
+	r.locations = kept
+
+	return nil
+}
+
+func (r *memRepo) UpdateInvoice(_ context.Context, id uuid.UUID, inv document.Invoice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.documents[id]
+	if !ok {
+		return document.ErrDocumentNotFound
+	}
+
+	doc.Invoice = inv
+
+	return nil
+}
+
+func (r *memRepo) SetText(_ context.Context, id uuid.UUID, text string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.texts[id] = text
+
+	return nil
+}
+
+func (r *memRepo) ListDocumentTransactions(_ context.Context, id uuid.UUID) ([]document.TransactionFields, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.txs[id], nil
+}
+
+func (r *memRepo) GetPreview(_ context.Context, id uuid.UUID) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, ok := r.previews[id]
+	if !ok {
+		return nil, document.ErrPreviewNotFound
+	}
+
+	return data, nil
+}
+
+func (r *memRepo) SavePreview(_ context.Context, id uuid.UUID, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.previews[id] = data
+
+	return nil
+}
+
+func (r *memRepo) MarkNoPreview(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, ok := r.documents[id]
+	if !ok {
+		return document.ErrDocumentNotFound
+	}
+
+	doc.NoPreview = true
+
+	return nil
+}
+
+func (r *memRepo) AddLocation(_ context.Context, loc *document.Location) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loc.ID = uuid.New()
+	r.locations = append(r.locations, *loc)
+
+	return nil
+}
+
+func (r *memRepo) ListLocations(_ context.Context, documentID uuid.UUID) ([]document.Location, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []document.Location
+
+	for _, l := range r.locations {
+		if l.DocumentID == documentID {
+			out = append(out, l)
+		}
+	}
+
+	return out, nil
+}
+
+func (r *memRepo) ListBackendLocations(_ context.Context, backendID uuid.UUID) ([]document.Location, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []document.Location
+
+	for _, l := range r.locations {
+		if l.BackendID == backendID {
+			out = append(out, l)
+		}
+	}
+
+	return out, nil
+}
+
+func (r *memRepo) DeleteLocation(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.locations[:0]
+	for _, l := range r.locations {
+		if l.ID != id {
+			kept = append(kept, l)
+		}
+	}
+	r.locations = kept
+
+	return nil
+}
+
+// memBackend keeps objects in memory. Backends are shared by name so a test
+// can inspect what the service stored through registry-created instances.
+type memBackend struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	failing bool
+}
+
+func (b *memBackend) Type() string { return "mem" }
+
+func (b *memBackend) Upload(_ context.Context, filename string, content io.Reader) (string, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+
+	if b.failing {
+		return "", errors.New("backend unavailable")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := strconv.Itoa(len(b.objects)) + "_" + filename
+	b.objects[key] = data
+
+	return key, nil
+}
+
+func (b *memBackend) UploadAt(_ context.Context, key string, content io.Reader) (string, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+
+	if b.failing {
+		return "", errors.New("backend unavailable")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.objects[key]; ok {
+		return "", document.ErrKeyExists
+	}
+
+	b.objects[key] = data
+
+	return key, nil
+}
+
+func (b *memBackend) Download(_ context.Context, key string) (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data, ok := b.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *memBackend) Delete(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.objects, key)
+
+	return nil
+}
+
+type testEnv struct {
+	ctx      context.Context
+	repo     *memRepo
+	svc      *document.Service
+	backends map[string]*memBackend
+}
+
+// newTestEnv creates a service with one enabled "mem" backend per name.
+func newTestEnv(t *testing.T, names ...string) *testEnv {
+	t.Helper()
+
+	userID := uuid.New()
+	env := &testEnv{
+		ctx:      auth.WithUserID(context.Background(), userID),
+		repo:     newMemRepo(),
+		backends: make(map[string]*memBackend),
+	}
+
+	registry := document.NewRegistry()
+	registry.Register("mem", func(raw json.RawMessage) (document.Backend, error) {
+		var cfg struct{ Name string }
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, err
+		}
+
+		return env.backends[cfg.Name], nil
+	})
+
+	for _, name := range names {
+		env.addBackend(t, name)
+	}
+
+	env.svc = document.NewService(env.repo, registry)
+
+	return env
+}
+
+// addBackend configures an enabled, empty backend for the user.
+func (env *testEnv) addBackend(t *testing.T, name string) *memBackend {
+	t.Helper()
+
+	b := &memBackend{objects: make(map[string][]byte)}
+	env.backends[name] = b
+
+	raw, _ := json.Marshal(map[string]string{"name": name})
+	require.NoError(t, env.repo.CreateBackend(env.ctx, &document.BackendConfig{
+		UserID: auth.UserID(env.ctx), Type: "mem", Name: name, Config: raw, Enabled: true,
+	}))
+
+	return b
+}
+
+func TestService_Upload_ReplaysContentToEveryBackend(t *testing.T) {
+	env := newTestEnv(t, "primary", "mirror")
+
+	// Several megabytes, so a short read or a shared reader would show.
+	content := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	sum := sha256.Sum256(content)
+
+	doc, err := env.svc.Upload(env.ctx, "scan.pdf", "application/pdf", bytes.NewReader(content), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(len(content)), doc.Size)
+	assert.Equal(t, hex.EncodeToString(sum[:]), doc.SHA256)
+
+	locations, err := env.repo.ListLocations(env.ctx, doc.ID)
+	require.NoError(t, err)
+	assert.Len(t, locations, 2)
+
+	for name, b := range env.backends {
+		require.Len(t, b.objects, 1, name)
+
+		for _, data := range b.objects {
+			assert.True(t, bytes.Equal(content, data), "%s received different content", name)
+		}
+	}
+
+	rc, got, err := env.svc.Download(env.ctx, doc.ID)
+	require.NoError(t, err)
+	defer rc.Close()
+	assert.Equal(t, doc.SHA256, got.SHA256)
+}
+
+func TestService_Upload_RollsBackOnBackendFailure(t *testing.T) {
+	env := newTestEnv(t, "primary", "broken")
+	env.backends["broken"].failing = true
+
+	_, err := env.svc.Upload(env.ctx, "scan.pdf", "application/pdf", bytes.NewReader([]byte("%PDF")), nil)
+	require.ErrorContains(t, err, "backend unavailable")
+
+	assert.Empty(t, env.backends["primary"].objects)
+	assert.Empty(t, env.repo.documents)
+	assert.Empty(t, env.repo.locations)
+}
+
+func TestService_Upload_ReusesIdenticalContent(t *testing.T) {
+	env := newTestEnv(t, "primary")
+
+	first, err := env.svc.Upload(env.ctx, "fatura.pdf", "application/pdf", bytes.NewReader([]byte("%PDF invoice 42")), nil)
+	require.NoError(t, err)
+
+	second, err := env.svc.Upload(env.ctx, "fatura (1).pdf", "application/pdf", bytes.NewReader([]byte("%PDF invoice 42")), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, "fatura.pdf", second.Filename)
+	assert.Len(t, env.backends["primary"].objects, 1)
+	assert.Len(t, env.repo.locations, 1)
+
+	other, err := env.svc.Upload(env.ctx, "other.pdf", "application/pdf", bytes.NewReader([]byte("%PDF invoice 43")), nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, other.ID)
+}
+
+func TestService_Release(t *testing.T) {
+	env := newTestEnv(t, "primary")
+
+	doc, err := env.svc.Upload(env.ctx, "fatura.pdf", "application/pdf", bytes.NewReader([]byte("%PDF")), nil)
+	require.NoError(t, err)
+
+	// Still referenced by another transaction: nothing is deleted.
+	env.repo.inUse[doc.ID] = true
+	require.NoError(t, env.svc.Release(env.ctx, doc.ID))
+	assert.Contains(t, env.repo.documents, doc.ID)
+	assert.Len(t, env.backends["primary"].objects, 1)
+
+	env.repo.inUse[doc.ID] = false
+	require.NoError(t, env.svc.Release(env.ctx, doc.ID))
+	assert.NotContains(t, env.repo.documents, doc.ID)
+	assert.Empty(t, env.backends["primary"].objects)
+}
+
+func TestService_InboxAndDiscard(t *testing.T) {
+	env := newTestEnv(t, "primary")
+
+	attached, err := env.svc.Upload(env.ctx, "fatura.pdf", "application/pdf", bytes.NewReader([]byte("%PDF 1")), nil)
+	require.NoError(t, err)
+	env.repo.inUse[attached.ID] = true
+
+	inbox, err := env.svc.Upload(env.ctx, "email.pdf", "application/pdf", bytes.NewReader([]byte("%PDF 2")), nil)
+	require.NoError(t, err)
+
+	docs, err := env.svc.Inbox(env.ctx, "")
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, inbox.ID, docs[0].ID)
+
+	assert.ErrorIs(t, env.svc.Discard(env.ctx, attached.ID), document.ErrDocumentInUse)
+	assert.ErrorIs(t, env.svc.Discard(env.ctx, uuid.New()), document.ErrDocumentNotFound)
+
+	require.NoError(t, env.svc.Discard(env.ctx, inbox.ID))
+	assert.NotContains(t, env.repo.documents, inbox.ID)
+	assert.Contains(t, env.repo.documents, attached.ID)
+	assert.Len(t, env.backends["primary"].objects, 1)
+}
+
+// invoicePDF is a one-page PDF showing the given lines of text.
+func invoicePDF(lines ...string) []byte {
+	var content strings.Builder
+
+	content.WriteString("BT /F1 10 Tf 14 TL 50 800 Td")
+	for _, line := range lines {
+		content.WriteString(" (" + line + ") ' ")
+	}
+	content.WriteString("ET")
+
+	return []byte("%PDF-1.4\n" +
+		"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+		"2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n" +
+		"3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R" +
+		" /Resources << /Font << /F1 << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> >> >> >> endobj\n" +
+		"4 0 obj << /Length " + strconv.Itoa(content.Len()) + " >> stream\n" + content.String() + "\nendstream endobj\n" +
+		"trailer << /Root 1 0 R >>\n%%EOF\n")
+}
+
+func TestService_Upload_ExtractsInvoiceFields(t *testing.T) {
+	env := newTestEnv(t, "primary")
+
+	pdf := invoicePDF("Padaria Lusitana, Lda.", "NIF 502011475", "Fatura FT 2026/15", "Data: 02-10-2026", "Total a pagar 4,35")
+
+	doc, err := env.svc.Upload(env.ctx, "fatura.pdf", "application/pdf", bytes.NewReader(pdf), nil)
+	require.NoError(t, err)
+
+	require.NotNil(t, doc.Invoice.Total)
+	assert.Equal(t, int64(435), *doc.Invoice.Total)
+	assert.Equal(t, "FT 2026/15", doc.Invoice.Number)
+	assert.Equal(t, "502011475", doc.Invoice.IssuerNIF)
+	assert.Equal(t, "Padaria Lusitana, Lda.", doc.Invoice.IssuerName)
+	assert.Equal(t, doc.Invoice, env.repo.documents[doc.ID].Invoice)
+
+	docs, err := env.svc.Inbox(env.ctx, "lusitana")
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+
+	// Re-extracting keeps the fields the user corrected.
+	issuer := "Padaria Lusitana"
+	require.NoError(t, env.svc.UpdateInvoice(env.ctx, doc.ID, document.Invoice{IssuerName: issuer}))
+
+	doc, err = env.svc.Extract(env.ctx, doc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, issuer, doc.Invoice.IssuerName)
+	assert.Equal(t, "FT 2026/15", doc.Invoice.Number)
+
+	// Text-less uploads are stored all the same.
+	scan, err := env.svc.Upload(env.ctx, "scan.pdf", "application/pdf", bytes.NewReader([]byte("%PDF-1.4 no objects")), nil)
+	require.NoError(t, err)
+	assert.Equal(t, document.Invoice{}, scan.Invoice)
+
+	_, err = env.svc.Extract(env.ctx, scan.ID)
+	assert.ErrorIs(t, err, document.ErrNoText)
+}
+
+func TestService_ApplyQRCode(t *testing.T) {
+	env := newTestEnv(t, "primary")
+
+	doc, err := env.svc.Upload(env.ctx, "talao.jpg", "image/jpeg", bytes.NewReader([]byte("JFIF")), nil)
+	require.NoError(t, err)
+	require.NoError(t, env.svc.UpdateInvoice(env.ctx, doc.ID, document.Invoice{IssuerName: "Continente", Number: "typo"}))
+
+	doc, err = env.svc.ApplyQRCode(env.ctx, doc.ID, samplePayload)
+	require.NoError(t, err)
+
+	// QR fields replace the typed ones; the issuer name is not in the QR code.
+	assert.Equal(t, "Continente", doc.Invoice.IssuerName)
+	assert.Equal(t, "FT 2026A7/84211", doc.Invoice.Number)
+	assert.Equal(t, "JJ37MMBG-84211", doc.Invoice.ATCUD)
+	require.NotNil(t, doc.Invoice.Total)
+	assert.Equal(t, int64(1283), *doc.Invoice.Total)
+	assert.Equal(t, doc.Invoice, env.repo.documents[doc.ID].Invoice)
+
+	_, err = env.svc.ApplyQRCode(env.ctx, doc.ID, "A:1")
+	assert.ErrorIs(t, err, document.ErrInvalidQRCode)
+
+	_, err = env.svc.ApplyQRCode(env.ctx, uuid.New(), samplePayload)
+	assert.ErrorIs(t, err, document.ErrDocumentNotFound)
+}
+
+func TestService_Upload_NoBackends(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.svc.Upload(env.ctx, "scan.pdf", "application/pdf", bytes.NewReader(nil), nil)
+	assert.ErrorIs(t, err, document.ErrNoBackends)
+}
+
+func TestService_CheckBackend(t *testing.T) {
+	env := newTestEnv(t, "healthy", "broken")
+	env.backends["broken"].failing = true
+
+	backends, err := env.svc.ListBackends(env.ctx)
+	require.NoError(t, err)
+
+	health, err := env.svc.CheckBackend(env.ctx, backends[0].ID)
+	require.NoError(t, err)
+	assert.True(t, health.Healthy())
+	assert.Empty(t, env.backends["healthy"].objects, "the probe is deleted")
+
+	health, err = env.svc.CheckBackend(env.ctx, backends[1].ID)
+	require.NoError(t, err)
+	assert.False(t, health.Healthy())
+	assert.Contains(t, health.Error, "backend unavailable")
+
+	backends, err = env.svc.ListBackends(env.ctx)
+	require.NoError(t, err)
+	require.NotNil(t, backends[1].Health)
+	assert.Equal(t, health.Error, backends[1].Health.Error, "the outcome is recorded")
+
+	_, err = env.svc.CheckBackend(env.ctx, uuid.New())
+	assert.ErrorIs(t, err, document.ErrBackendNotFound)
+}
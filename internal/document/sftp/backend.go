@@ -0,0 +1,360 @@
+package sftp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+// Config holds the SSH server details stored in document_backends.config.
+// Either a password or a private key is required, and the server's host key
+// must be pinned with HostKey or KnownHostsFile.
+type Config struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// PrivateKey is a PEM-encoded private key (OpenSSH, PKCS#1 or PKCS#8),
+	// optionally protected by Passphrase.
+	PrivateKey string `json:"private_key"`
+	Passphrase string `json:"passphrase"`
+	// BaseDir is the directory documents are stored in. Relative paths are
+	// resolved against the user's login directory. Missing directories are created.
+	BaseDir string `json:"base_dir"`
+	// HostKey is the server's public key in authorized_keys format, e.g.
+	// "ssh-ed25519 AAAA...". Run `ssh-keyscan <host>` to obtain it.
+	HostKey string `json:"host_key"`
+	// KnownHostsFile is the path of an OpenSSH known_hosts file, readable by
+	// the API process, used instead of HostKey.
+	KnownHostsFile string `json:"known_hosts_file"`
+}
+
+const (
+	defaultPort = 22
+	dialTimeout = 30 * time.Second
+)
+
+type Backend struct {
+	addr    string
+	config  *ssh.ClientConfig
+	baseDir string
+}
+
+// NewFromConfig creates an SFTP Backend from the JSONB config stored in the DB.
+func NewFromConfig(raw json.RawMessage) (document.Backend, error) {
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("sftp: invalid config: %w", err)
+	}
+
+	if cfg.Host == "" || cfg.User == "" || cfg.BaseDir == "" {
+		return nil, fmt.Errorf("sftp: host, user and base_dir are required")
+	}
+
+	if cfg.Port == 0 {
+		cfg.Port = defaultPort
+	}
+
+	var auth []ssh.AuthMethod
+
+	if cfg.PrivateKey != "" {
+		signer, err := parsePrivateKey(cfg.PrivateKey, cfg.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("sftp: invalid private_key: %w", err)
+		}
+
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	if cfg.Password != "" {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("sftp: password or private_key is required")
+	}
+
+	clientConfig := &ssh.ClientConfig{
+		User:    cfg.User,
+		Auth:    auth,
+		Timeout: dialTimeout,
+	}
+
+	switch {
+	case cfg.HostKey != "":
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cfg.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("sftp: invalid host_key: %w", err)
+		}
+
+		clientConfig.HostKeyCallback = ssh.FixedHostKey(key)
+		// Ask for the pinned key's algorithm, or the server may present another key.
+		clientConfig.HostKeyAlgorithms = hostKeyAlgorithms(key.Type())
+	case cfg.KnownHostsFile != "":
+		callback, err := knownhosts.New(cfg.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("sftp: reading known_hosts_file: %w", err)
+		}
+
+		clientConfig.HostKeyCallback = callback
+	default:
+		return nil, fmt.Errorf("sftp: host_key or known_hosts_file is required")
+	}
+
+	return &Backend{
+		addr:    net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		config:  clientConfig,
+		baseDir: path.Clean(cfg.BaseDir),
+	}, nil
+}
+
+func parsePrivateKey(pemKey, passphrase string) (ssh.Signer, error) {
+	if passphrase != "" {
+		return ssh.ParsePrivateKeyWithPassphrase([]byte(pemKey), []byte(passphrase))
+	}
+
+	return ssh.ParsePrivateKey([]byte(pemKey))
+}
+
+// hostKeyAlgorithms returns the signature algorithms a host key of the given type
+// can be verified with. RSA keys sign with SHA-2 on any maintained server.
+func hostKeyAlgorithms(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+
+	return []string{keyType}
+}
+
+func (b *Backend) Type() string { return "sftp" }
+
+// session is an SFTP client on its own SSH connection.
+type session struct {
+	*client
+	conn *ssh.Client
+	stop func() bool
+}
+
+// connect dials the server and starts the sftp subsystem. The connection is
+// closed when ctx is cancelled, which aborts any request in flight.
+func (b *Backend) connect(ctx context.Context) (*session, error) {
+	var d net.Dialer
+
+	netConn, err := d.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: connecting to %s: %w", b.addr, err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, b.addr, b.config)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("sftp: ssh handshake with %s: %w", b.addr, err)
+	}
+
+	conn := ssh.NewClient(sshConn, chans, reqs)
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+
+	c, err := startSubsystem(conn)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, fmt.Errorf("sftp: %w", err)
+	}
+
+	return &session{client: c, conn: conn, stop: stop}, nil
+}
+
+func startSubsystem(conn *ssh.Client) (*client, error) {
+	s, err := conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("opening session: %w", err)
+	}
+
+	w, err := s.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("opening stdin: %w", err)
+	}
+
+	r, err := s.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("opening stdout: %w", err)
+	}
+
+	if err := s.RequestSubsystem("sftp"); err != nil {
+		return nil, fmt.Errorf("starting sftp subsystem: %w", err)
+	}
+
+	return newClient(r, w)
+}
+
+func (s *session) Close() error {
+	s.stop()
+	return s.conn.Close()
+}
+
+// resolveKey joins the key with baseDir and rejects any path that escapes it.
+func (b *Backend) resolveKey(key string) (string, error) {
+	p, err := document.ResolveKey(b.baseDir, key)
+	if err != nil {
+		return "", fmt.Errorf("sftp: %w", err)
+	}
+
+	return p, nil
+}
+
+// Upload writes the content to <baseDir>/<uuid>_<filename> and returns the
+// path relative to baseDir as the storage key.
+func (b *Backend) Upload(ctx context.Context, filename string, content io.Reader) (string, error) {
+	return b.UploadAt(ctx, uuid.New().String()+"_"+filepath.Base(filename), content)
+}
+
+// UploadAt writes the content to <baseDir>/<key>, creating missing
+// directories.
+func (b *Backend) UploadAt(ctx context.Context, key string, content io.Reader) (string, error) {
+	p, err := b.resolveKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	s, err := b.connect(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+
+	if err := s.mkdirAll(path.Dir(p)); err != nil {
+		return "", fmt.Errorf("sftp: creating directory: %w", err)
+	}
+
+	// Servers report an exclusive create of an existing file as a generic
+	// failure, so look first.
+	if err := s.stat(p); err == nil {
+		return "", fmt.Errorf("sftp: %w: %s", document.ErrKeyExists, key)
+	}
+
+	handle, err := s.open(p, fxfWrite|fxfCreat|fxfExcl)
+	if err != nil {
+		return "", fmt.Errorf("sftp: creating file: %w", err)
+	}
+
+	werr := s.writeAll(handle, content)
+
+	if err := errors.Join(werr, s.close(handle)); err != nil {
+		_ = s.remove(p)
+		return "", fmt.Errorf("sftp: writing file: %w", err)
+	}
+
+	return key, nil
+}
+
+// writeAll copies content into the open file in chunks.
+func (s *session) writeAll(handle string, content io.Reader) error {
+	buf := make([]byte, chunkSize)
+
+	var offset uint64
+
+	for {
+		n, err := io.ReadFull(content, buf)
+		if n > 0 {
+			if werr := s.write(handle, offset, buf[:n]); werr != nil {
+				return werr
+			}
+
+			offset += uint64(n)
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// mkdirAll creates dir and any missing parents.
+func (s *session) mkdirAll(dir string) error {
+	err := s.stat(dir)
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if parent := path.Dir(dir); parent != dir {
+		if err := s.mkdirAll(parent); err != nil {
+			return err
+		}
+	}
+
+	if err := s.mkdir(dir); err != nil {
+		// Another upload may have created it in the meantime.
+		if s.stat(dir) == nil {
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Download streams the file at <baseDir>/<key>. The SSH connection stays open
+// until the returned reader is closed.
+func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := b.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := b.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := s.open(p, fxfRead)
+	if err != nil {
+		s.Close()
+
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("sftp: file not found: %s", key)
+		}
+
+		return nil, fmt.Errorf("sftp: opening file: %w", err)
+	}
+
+	return &fileReader{c: s.client, handle: handle, closer: s.Close}, nil
+}
+
+// Delete removes the file at <baseDir>/<key>. A file that is already gone is not an error.
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	p, err := b.resolveKey(key)
+	if err != nil {
+		return err
+	}
+
+	s, err := b.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := s.remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("sftp: removing file: %w", err)
+	}
+
+	return nil
+}
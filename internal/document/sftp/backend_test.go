@@ -0,0 +1,419 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+// testServer is an SSH server whose sftp subsystem serves an in-memory tree.
+type testServer struct {
+	host    string
+	port    int
+	hostKey ssh.PublicKey
+
+	mu    sync.Mutex
+	dirs  map[string]bool
+	files map[string][]byte
+	// emptyReads is how many empty data packets reads get before the data.
+	emptyReads int
+}
+
+func newTestServer(t *testing.T, clientKey ssh.PublicKey, password string) *testServer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	hostSigner, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if clientKey != nil && bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+		PasswordCallback: func(_ ssh.ConnMetadata, pw []byte) (*ssh.Permissions, error) {
+			if password != "" && string(pw) == password {
+				return nil, nil
+			}
+			return nil, assert.AnError
+		},
+	}
+	cfg.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	addr := ln.Addr().(*net.TCPAddr)
+	s := &testServer{
+		host:    addr.IP.String(),
+		port:    addr.Port,
+		hostKey: hostSigner.PublicKey(),
+		dirs:    map[string]bool{".": true, "/": true},
+		files:   make(map[string][]byte),
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go s.serveConn(conn, cfg)
+		}
+	}()
+
+	return s
+}
+
+func (s *testServer) serveConn(conn net.Conn, cfg *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	go ssh.DiscardRequests(reqs)
+
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			_ = nc.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			for req := range requests {
+				ok := req.Type == "subsystem" && string(req.Payload[4:]) == "sftp"
+				_ = req.Reply(ok, nil)
+
+				if ok {
+					go s.serveSFTP(ch)
+				}
+			}
+		}()
+	}
+}
+
+// serveSFTP answers the requests the client sends, with OpenSSH's status codes.
+func (s *testServer) serveSFTP(ch io.ReadWriteCloser) {
+	defer ch.Close()
+
+	if typ, _, err := readPacket(ch); err != nil || typ != fxpInit {
+		return
+	}
+
+	_ = writePacket(ch, fxpVersion, appendUint32(nil, protocolVersion))
+
+	handles := make(map[string]string)
+
+	for {
+		typ, data, err := readPacket(ch)
+		if err != nil {
+			return
+		}
+
+		id, data, _ := takeUint32(data)
+		reply := func(typ byte, payload []byte) {
+			_ = writePacket(ch, typ, append(appendUint32(nil, id), payload...))
+		}
+		status := func(code uint32) {
+			reply(fxpStatus, appendString(appendString(appendUint32(nil, code), "status"), ""))
+		}
+
+		s.mu.Lock()
+
+		switch typ {
+		case fxpOpen:
+			p, rest, _ := takeString(data)
+			flags, _, _ := takeUint32(rest)
+			_, exists := s.files[p]
+
+			switch {
+			case flags&fxfCreat != 0 && !s.dirs[path.Dir(p)]:
+				status(fxNoSuchFile)
+			case flags&fxfCreat == 0 && !exists:
+				status(fxNoSuchFile)
+			case flags&fxfExcl != 0 && exists:
+				status(4)
+			default:
+				if flags&fxfCreat != 0 {
+					s.files[p] = nil
+				}
+
+				h := strconv.Itoa(len(handles))
+				handles[h] = p
+				reply(fxpHandle, appendString(nil, h))
+			}
+		case fxpWrite:
+			h, rest, _ := takeString(data)
+			offset := binary.BigEndian.Uint64(rest)
+			chunk, _, _ := takeString(rest[8:])
+
+			f := s.files[handles[h]]
+			f = append(f[:min(uint64(len(f)), offset)], chunk...)
+			s.files[handles[h]] = f
+			status(fxOK)
+		case fxpRead:
+			h, rest, _ := takeString(data)
+			offset := binary.BigEndian.Uint64(rest)
+			n := binary.BigEndian.Uint32(rest[8:])
+
+			f := s.files[handles[h]]
+			switch {
+			case s.emptyReads > 0:
+				s.emptyReads--
+				reply(fxpData, appendString(nil, ""))
+			case offset >= uint64(len(f)):
+				status(fxEOF)
+			default:
+				reply(fxpData, appendString(nil, string(f[offset:min(uint64(len(f)), offset+uint64(n))])))
+			}
+		case fxpClose:
+			status(fxOK)
+		case fxpRemove:
+			p, _, _ := takeString(data)
+			if _, ok := s.files[p]; !ok {
+				status(fxNoSuchFile)
+			} else {
+				delete(s.files, p)
+				status(fxOK)
+			}
+		case fxpMkdir:
+			p, _, _ := takeString(data)
+
+			switch {
+			case s.dirs[p]:
+				status(4)
+			case !s.dirs[path.Dir(p)]:
+				status(fxNoSuchFile)
+			default:
+				s.dirs[p] = true
+				status(fxOK)
+			}
+		case fxpStat:
+			p, _, _ := takeString(data)
+			if _, ok := s.files[p]; ok || s.dirs[p] {
+				reply(fxpAttrs, appendUint32(nil, 0))
+			} else {
+				status(fxNoSuchFile)
+			}
+		default:
+			status(8) // SSH_FX_OP_UNSUPPORTED
+		}
+
+		s.mu.Unlock()
+	}
+}
+
+func newClientKey(t *testing.T) (string, ssh.PublicKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	require.NoError(t, err)
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(block)), sshPub
+}
+
+func newBackend(t *testing.T, cfg Config) document.Backend {
+	raw, err := json.Marshal(cfg)
+	require.NoError(t, err)
+
+	b, err := NewFromConfig(raw)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestBackend_KeyAuthRoundTrip(t *testing.T) {
+	pemKey, pub := newClientKey(t)
+	srv := newTestServer(t, pub, "")
+
+	b := newBackend(t, Config{
+		Host:       srv.host,
+		Port:       srv.port,
+		User:       "finny",
+		PrivateKey: pemKey,
+		BaseDir:    "nas/finny/documents",
+		HostKey:    string(ssh.MarshalAuthorizedKey(srv.hostKey)),
+	})
+	ctx := context.Background()
+
+	// Larger than one chunk, so writes and reads are split.
+	content := bytes.Repeat([]byte("%PDF-1.7 invoice "), 5000)
+
+	key, err := b.Upload(ctx, "/tmp/../Fatura 12.pdf", bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(key, "_Fatura 12.pdf"), key)
+	assert.NotContains(t, key, "/")
+	assert.True(t, srv.dirs["nas/finny/documents"])
+	assert.Equal(t, content, srv.files["nas/finny/documents/"+key])
+
+	rc, err := b.Download(ctx, key)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, content, got)
+
+	require.NoError(t, b.Delete(ctx, key))
+	require.NoError(t, b.Delete(ctx, key), "deleting a missing file is not an error")
+
+	_, err = b.Download(ctx, key)
+	assert.ErrorContains(t, err, "file not found")
+}
+
+func TestBackend_DownloadEmptyReads(t *testing.T) {
+	pemKey, pub := newClientKey(t)
+	srv := newTestServer(t, pub, "")
+
+	b := newBackend(t, Config{
+		Host:       srv.host,
+		Port:       srv.port,
+		User:       "finny",
+		PrivateKey: pemKey,
+		BaseDir:    "docs",
+		HostKey:    string(ssh.MarshalAuthorizedKey(srv.hostKey)),
+	})
+	ctx := context.Background()
+
+	key, err := b.Upload(ctx, "invoice.pdf", strings.NewReader("%PDF-1.7"))
+	require.NoError(t, err)
+
+	download := func() ([]byte, error) {
+		rc, err := b.Download(ctx, key)
+		require.NoError(t, err)
+		defer rc.Close()
+
+		return io.ReadAll(rc)
+	}
+
+	srv.mu.Lock()
+	srv.emptyReads = 3
+	srv.mu.Unlock()
+
+	got, err := download()
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.7", string(got), "a few empty packets are skipped")
+
+	srv.mu.Lock()
+	srv.emptyReads = 1000
+	srv.mu.Unlock()
+
+	_, err = download()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF, "a server sending only empty packets fails the read")
+}
+
+func TestBackend_PasswordAuthWithKnownHosts(t *testing.T) {
+	srv := newTestServer(t, nil, "s3cret")
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{net.JoinHostPort(srv.host, strconv.Itoa(srv.port))}, srv.hostKey)
+	require.NoError(t, os.WriteFile(knownHosts, []byte(line+"\n"), 0o600))
+
+	b := newBackend(t, Config{
+		Host:           srv.host,
+		Port:           srv.port,
+		User:           "finny",
+		Password:       "s3cret",
+		BaseDir:        "/srv/finny",
+		KnownHostsFile: knownHosts,
+	})
+
+	srv.dirs["/srv"] = true
+
+	key, err := b.Upload(context.Background(), "receipt.jpg", strings.NewReader("jpeg"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("jpeg"), srv.files["/srv/finny/"+key])
+}
+
+func TestBackend_RejectsUnpinnedHostKey(t *testing.T) {
+	srv := newTestServer(t, nil, "s3cret")
+
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	otherSigner, err := ssh.NewSignerFromKey(other)
+	require.NoError(t, err)
+
+	b := newBackend(t, Config{
+		Host:     srv.host,
+		Port:     srv.port,
+		User:     "finny",
+		Password: "s3cret",
+		BaseDir:  "docs",
+		HostKey:  string(ssh.MarshalAuthorizedKey(otherSigner.PublicKey())),
+	})
+
+	_, err = b.Upload(context.Background(), "a.pdf", strings.NewReader("x"))
+	assert.ErrorContains(t, err, "handshake")
+	assert.Empty(t, srv.files)
+}
+
+func TestBackend_RejectsEscapingKeys(t *testing.T) {
+	b := newBackend(t, Config{
+		Host:     "nas.local",
+		User:     "finny",
+		Password: "pw",
+		BaseDir:  "/srv/finny",
+		HostKey:  "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl",
+	})
+
+	for _, key := range []string{"", ".", "..", "../etc/passwd", "/etc/passwd", "a/../../b"} {
+		_, err := b.Download(context.Background(), key)
+		assert.ErrorIs(t, err, document.ErrKeyEscapesBase, key)
+		assert.ErrorIs(t, b.Delete(context.Background(), key), document.ErrKeyEscapesBase, key)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"missing host", `{"user":"u","base_dir":"d","password":"p","host_key":"x"}`, "host, user and base_dir"},
+		{"missing credentials", `{"host":"h","user":"u","base_dir":"d","host_key":"x"}`, "password or private_key"},
+		{"bad private key", `{"host":"h","user":"u","base_dir":"d","private_key":"nope","host_key":"x"}`, "invalid private_key"},
+		{"no host key pinning", `{"host":"h","user":"u","base_dir":"d","password":"p"}`, "host_key or known_hosts_file"},
+		{"bad host key", `{"host":"h","user":"u","base_dir":"d","password":"p","host_key":"x"}`, "invalid host_key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFromConfig(json.RawMessage(tt.config))
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// This file implements the subset of SFTP version 3
+// (draft-ietf-secsh-filexfer-02) the backend needs: opening, reading, writing
+// and removing files, and creating and inspecting directories.
+
+const protocolVersion = 3
+
+// Packet types.
+const (
+	fxpInit    = 1
+	fxpVersion = 2
+	fxpOpen    = 3
+	fxpClose   = 4
+	fxpRead    = 5
+	fxpWrite   = 6
+	fxpRemove  = 13
+	fxpMkdir   = 14
+	fxpStat    = 17
+	fxpStatus  = 101
+	fxpHandle  = 102
+	fxpData    = 103
+	fxpAttrs   = 105
+)
+
+// Open flags.
+const (
+	fxfRead  = 0x01
+	fxfWrite = 0x02
+	fxfCreat = 0x08
+	fxfExcl  = 0x20
+)
+
+// Status codes.
+const (
+	fxOK         = 0
+	fxEOF        = 1
+	fxNoSuchFile = 2
+)
+
+const (
+	// chunkSize is the payload size of a single read or write. Servers must
+	// accept packets of at least 32 KiB plus headers.
+	chunkSize = 32 * 1024
+	// maxPacket bounds the size of packets accepted from the server.
+	maxPacket = 256 * 1024
+)
+
+// StatusError is an SFTP status response other than OK.
+type StatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sftp status %d: %s", e.Code, e.Message)
+}
+
+// Is makes a missing file match fs.ErrNotExist.
+func (e *StatusError) Is(target error) bool {
+	return target == fs.ErrNotExist && e.Code == fxNoSuchFile
+}
+
+// client sends SFTP requests one at a time over a byte stream, usually the
+// stdin/stdout of an SSH "sftp" subsystem.
+type client struct {
+	mu     sync.Mutex
+	r      io.Reader
+	w      io.Writer
+	nextID uint32
+}
+
+// newClient performs the version handshake.
+func newClient(r io.Reader, w io.Writer) (*client, error) {
+	c := &client{r: r, w: w}
+
+	if err := writePacket(w, fxpInit, appendUint32(nil, protocolVersion)); err != nil {
+		return nil, fmt.Errorf("sending init: %w", err)
+	}
+
+	typ, data, err := readPacket(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading version: %w", err)
+	}
+
+	if typ != fxpVersion {
+		return nil, fmt.Errorf("expected version packet, got type %d", typ)
+	}
+
+	if v, _, err := takeUint32(data); err != nil || v < protocolVersion {
+		return nil, fmt.Errorf("unsupported server version %d", v)
+	}
+
+	return c, nil
+}
+
+// request sends a packet and returns the response with the same request ID.
+func (c *client) request(typ byte, payload []byte) (byte, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	id := c.nextID
+
+	if err := writePacket(c.w, typ, append(appendUint32(nil, id), payload...)); err != nil {
+		return 0, nil, err
+	}
+
+	respType, data, err := readPacket(c.r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	respID, data, err := takeUint32(data)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if respID != id {
+		return 0, nil, fmt.Errorf("response for request %d, expected %d", respID, id)
+	}
+
+	return respType, data, nil
+}
+
+// status sends a request whose only successful answer is an OK status.
+func (c *client) status(typ byte, payload []byte) error {
+	respType, data, err := c.request(typ, payload)
+	if err != nil {
+		return err
+	}
+
+	return expectStatus(respType, data)
+}
+
+func (c *client) open(path string, flags uint32) (string, error) {
+	payload := appendString(nil, path)
+	payload = appendUint32(payload, flags)
+	payload = appendUint32(payload, 0) // no attributes
+
+	respType, data, err := c.request(fxpOpen, payload)
+	if err != nil {
+		return "", err
+	}
+
+	if respType != fxpHandle {
+		return "", expectStatus(respType, data)
+	}
+
+	handle, _, err := takeString(data)
+
+	return handle, err
+}
+
+func (c *client) close(handle string) error {
+	return c.status(fxpClose, appendString(nil, handle))
+}
+
+func (c *client) write(handle string, offset uint64, p []byte) error {
+	payload := appendString(nil, handle)
+	payload = appendUint64(payload, offset)
+	payload = appendString(payload, string(p))
+
+	return c.status(fxpWrite, payload)
+}
+
+// read returns up to n bytes at offset, or io.EOF at the end of the file.
+func (c *client) read(handle string, offset uint64, n uint32) ([]byte, error) {
+	payload := appendString(nil, handle)
+	payload = appendUint64(payload, offset)
+	payload = appendUint32(payload, n)
+
+	respType, data, err := c.request(fxpRead, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if respType != fxpData {
+		err := expectStatus(respType, data)
+		if se := (*StatusError)(nil); errors.As(err, &se) && se.Code == fxEOF {
+			return nil, io.EOF
+		}
+
+		return nil, err
+	}
+
+	p, _, err := takeString(data)
+
+	return []byte(p), err
+}
+
+func (c *client) remove(path string) error {
+	return c.status(fxpRemove, appendString(nil, path))
+}
+
+func (c *client) mkdir(path string) error {
+	return c.status(fxpMkdir, appendUint32(appendString(nil, path), 0))
+}
+
+// stat reports whether path exists. Its attributes are not needed.
+func (c *client) stat(path string) error {
+	respType, data, err := c.request(fxpStat, appendString(nil, path))
+	if err != nil {
+		return err
+	}
+
+	if respType != fxpAttrs {
+		return expectStatus(respType, data)
+	}
+
+	return nil
+}
+
+// expectStatus turns a response into nil for an OK status and an error otherwise.
+func expectStatus(respType byte, data []byte) error {
+	if respType != fxpStatus {
+		return fmt.Errorf("unexpected response type %d", respType)
+	}
+
+	code, data, err := takeUint32(data)
+	if err != nil {
+		return err
+	}
+
+	if code == fxOK {
+		return nil
+	}
+
+	// Servers older than version 3 omit the message.
+	msg, _, _ := takeString(data)
+
+	return &StatusError{Code: code, Message: msg}
+}
+
+// fileReader streams a remote file sequentially.
+type fileReader struct {
+	c      *client
+	handle string
+	offset uint64
+	buf    []byte
+	closer func() error
+}
+
+// maxEmptyReads bounds the empty data packets a server may send in a row
+// before a read gives up, so a misbehaving server cannot stall a copy forever.
+const maxEmptyReads = 8
+
+func (f *fileReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for empty := 0; len(f.buf) == 0; empty++ {
+		if empty == maxEmptyReads {
+			return 0, fmt.Errorf("%d empty reads at offset %d: %w", empty, f.offset, io.ErrUnexpectedEOF)
+		}
+
+		data, err := f.c.read(f.handle, f.offset, chunkSize)
+		if err != nil {
+			return 0, err
+		}
+
+		f.buf = data
+		f.offset += uint64(len(data))
+	}
+
+	n := copy(p, f.buf)
+	f.buf = f.buf[n:]
+
+	return n, nil
+}
+
+// Close releases the remote handle and then the underlying connection.
+func (f *fileReader) Close() error {
+	err := f.c.close(f.handle)
+
+	return errors.Join(err, f.closer())
+}
+
+// Wire encoding: packets are a uint32 length followed by a type byte and the
+// payload; integers are big-endian and strings are length-prefixed.
+
+func writePacket(w io.Writer, typ byte, payload []byte) error {
+	pkt := appendUint32(make([]byte, 0, 5+len(payload)), uint32(1+len(payload)))
+	pkt = append(pkt, typ)
+	pkt = append(pkt, payload...)
+
+	_, err := w.Write(pkt)
+
+	return err
+}
+
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > maxPacket {
+		return 0, nil, fmt.Errorf("invalid packet length %d", length)
+	}
+
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+
+	return header[4], data, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte { return binary.BigEndian.AppendUint32(b, v) }
+
+func appendUint64(b []byte, v uint64) []byte { return binary.BigEndian.AppendUint64(b, v) }
+
+func appendString(b []byte, s string) []byte {
+	return append(appendUint32(b, uint32(len(s))), s...)
+}
+
+var errShortPacket = errors.New("short packet")
+
+func takeUint32(b []byte) (uint32, []byte, error) {
+	if len(b) < 4 {
+		return 0, nil, errShortPacket
+	}
+
+	return binary.BigEndian.Uint32(b), b[4:], nil
+}
+
+func takeString(b []byte) (string, []byte, error) {
+	n, b, err := takeUint32(b)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if uint32(len(b)) < n {
+		return "", nil, errShortPacket
+	}
+
+	return string(b[:n]), b[n:], nil
+}
@@ -0,0 +1,49 @@
+package document
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// spool is an on-disk copy of upload content that can be replayed to several
+// backends without holding the file in memory. Its size and SHA-256 are
+// computed while it is written.
+type spool struct {
+	file   *os.File
+	size   int64
+	sha256 string
+}
+
+// newSpool copies content into a temporary file. The caller must Close it.
+func newSpool(content io.Reader) (*spool, error) {
+	f, err := os.CreateTemp("", "finny-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating spool file: %w", err)
+	}
+
+	s := &spool{file: f}
+	h := sha256.New()
+
+	s.size, err = io.Copy(io.MultiWriter(f, h), content)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("spooling content: %w", err), s.Close())
+	}
+
+	s.sha256 = hex.EncodeToString(h.Sum(nil))
+
+	return s, nil
+}
+
+// reader returns an independent reader over the whole content.
+func (s *spool) reader() io.Reader {
+	return io.NewSectionReader(s.file, 0, s.size)
+}
+
+// Close removes the temporary file.
+func (s *spool) Close() error {
+	return errors.Join(s.file.Close(), os.Remove(s.file.Name()))
+}
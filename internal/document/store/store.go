@@ -4,14 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/MrJamesThe3rd/finny/internal/auth"
+	"github.com/MrJamesThe3rd/finny/internal/database"
 	"github.com/MrJamesThe3rd/finny/internal/document"
 )
 
+// uniqueViolation is the Postgres SQLSTATE for a unique constraint violation.
+const uniqueViolation = "23505"
+
 type Store struct {
 	db *sql.DB
 }
@@ -20,11 +27,40 @@ func New(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
 // Backend operations
 
+const selectBackendColumns = `id, user_id, type, name, config, enabled, health_checked_at, health_error, draining_at, created_at`
+
+// scanBackend scans a row of selectBackendColumns.
+func scanBackend(row rowScanner) (*document.BackendConfig, error) {
+	var (
+		b           document.BackendConfig
+		rawConfig   []byte
+		checkedAt   sql.NullTime
+		healthError string
+	)
+
+	if err := row.Scan(&b.ID, &b.UserID, &b.Type, &b.Name, &rawConfig, &b.Enabled, &checkedAt, &healthError, &b.DrainingAt, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	b.Config = json.RawMessage(rawConfig)
+
+	if checkedAt.Valid {
+		b.Health = &document.BackendHealth{CheckedAt: checkedAt.Time, Error: healthError}
+	}
+
+	return &b, nil
+}
+
 func (s *Store) ListBackends(ctx context.Context) ([]document.BackendConfig, error) {
 	query := `
-		SELECT id, user_id, type, name, config, enabled, created_at
+		SELECT ` + selectBackendColumns + `
 		FROM document_backends
 		WHERE user_id = $1
 		ORDER BY created_at ASC
@@ -39,15 +75,12 @@ func (s *Store) ListBackends(ctx context.Context) ([]document.BackendConfig, err
 	var backends []document.BackendConfig
 
 	for rows.Next() {
-		var b document.BackendConfig
-		var rawConfig []byte
-
-		if err := rows.Scan(&b.ID, &b.UserID, &b.Type, &b.Name, &rawConfig, &b.Enabled, &b.CreatedAt); err != nil {
+		b, err := scanBackend(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scanning backend: %w", err)
 		}
 
-		b.Config = json.RawMessage(rawConfig)
-		backends = append(backends, b)
+		backends = append(backends, *b)
 	}
 
 	return backends, rows.Err()
@@ -55,17 +88,12 @@ func (s *Store) ListBackends(ctx context.Context) ([]document.BackendConfig, err
 
 func (s *Store) GetBackend(ctx context.Context, id uuid.UUID) (*document.BackendConfig, error) {
 	query := `
-		SELECT id, user_id, type, name, config, enabled, created_at
+		SELECT ` + selectBackendColumns + `
 		FROM document_backends
 		WHERE id = $1
 	`
 
-	var b document.BackendConfig
-	var rawConfig []byte
-
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&b.ID, &b.UserID, &b.Type, &b.Name, &rawConfig, &b.Enabled, &b.CreatedAt,
-	)
+	b, err := scanBackend(s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, document.ErrBackendNotFound
@@ -74,9 +102,7 @@ func (s *Store) GetBackend(ctx context.Context, id uuid.UUID) (*document.Backend
 		return nil, fmt.Errorf("getting backend: %w", err)
 	}
 
-	b.Config = json.RawMessage(rawConfig)
-
-	return &b, nil
+	return b, nil
 }
 
 func (s *Store) SetBackendConfig(ctx context.Context, id uuid.UUID, config json.RawMessage) error {
@@ -163,46 +189,283 @@ func (s *Store) BackendHasDocuments(ctx context.Context, backendID uuid.UUID) (b
 	return exists, nil
 }
 
+func (s *Store) SetBackendHealth(ctx context.Context, id uuid.UUID, health document.BackendHealth) error {
+	query := `
+		UPDATE document_backends
+		SET health_checked_at = $1, health_error = $2
+		WHERE id = $3 AND user_id = $4
+	`
+
+	result, err := s.db.ExecContext(ctx, query, health.CheckedAt, health.Error, id, auth.UserID(ctx))
+	if err != nil {
+		return fmt.Errorf("setting backend health: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return document.ErrBackendNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) GetDrain(ctx context.Context, backendID uuid.UUID) (*document.Drain, error) {
+	query := `
+		SELECT d.backend_id, d.target_id, d.total, d.moved, d.failed, d.last_error, d.started_at, d.finished_at
+		FROM backend_drains d
+		JOIN document_backends b ON b.id = d.backend_id
+		WHERE d.backend_id = $1 AND b.user_id = $2
+	`
+
+	var d document.Drain
+
+	err := s.db.QueryRowContext(ctx, query, backendID, auth.UserID(ctx)).Scan(
+		&d.BackendID, &d.TargetID, &d.Total, &d.Moved, &d.Failed, &d.LastError, &d.StartedAt, &d.FinishedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, document.ErrDrainNotFound
+		}
+
+		return nil, fmt.Errorf("getting drain: %w", err)
+	}
+
+	return &d, nil
+}
+
+func (s *Store) SaveDrain(ctx context.Context, d *document.Drain) error {
+	query := `
+		INSERT INTO backend_drains (backend_id, target_id, total, moved, failed, last_error, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (backend_id) DO UPDATE SET
+			target_id   = EXCLUDED.target_id,
+			total       = EXCLUDED.total,
+			moved       = EXCLUDED.moved,
+			failed      = EXCLUDED.failed,
+			last_error  = EXCLUDED.last_error,
+			started_at  = EXCLUDED.started_at,
+			finished_at = EXCLUDED.finished_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		d.BackendID, d.TargetID, d.Total, d.Moved, d.Failed, d.LastError, d.StartedAt, d.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("saving drain: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) ClaimDrain(ctx context.Context, backendID uuid.UUID, at, staleBefore time.Time) (bool, error) {
+	query := `
+		UPDATE document_backends
+		SET draining_at = $1
+		WHERE id = $2 AND user_id = $3 AND (draining_at IS NULL OR draining_at < $4)
+	`
+
+	result, err := s.db.ExecContext(ctx, query, at, backendID, auth.UserID(ctx), staleBefore)
+	if err != nil {
+		return false, fmt.Errorf("claiming drain: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claiming drain: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+func (s *Store) ReleaseDrain(ctx context.Context, backendID uuid.UUID) error {
+	query := `UPDATE document_backends SET draining_at = NULL WHERE id = $1 AND user_id = $2`
+
+	if _, err := s.db.ExecContext(ctx, query, backendID, auth.UserID(ctx)); err != nil {
+		return fmt.Errorf("releasing drain: %w", err)
+	}
+
+	return nil
+}
+
 // Document operations
 
 func (s *Store) CreateDocument(ctx context.Context, doc *document.Document) error {
 	query := `
-		INSERT INTO documents (user_id, filename, mime_type)
-		VALUES ($1, $2, $3)
+		INSERT INTO documents (user_id, filename, mime_type, size_bytes, sha256)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id, created_at
 	`
 
-	err := s.db.QueryRowContext(ctx, query, doc.UserID, doc.Filename, doc.MIMEType).
+	err := s.db.QueryRowContext(ctx, query, doc.UserID, doc.Filename, doc.MIMEType, doc.Size, doc.SHA256).
 		Scan(&doc.ID, &doc.CreatedAt)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return document.ErrDuplicateContent
+		}
+
 		return fmt.Errorf("creating document: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Store) GetDocument(ctx context.Context, id uuid.UUID) (*document.Document, error) {
-	query := `
-		SELECT id, user_id, filename, mime_type, created_at
-		FROM documents
-		WHERE id = $1 AND user_id = $2
-	`
+const selectDocumentColumns = `id, user_id, filename, mime_type, size_bytes, sha256,
+	invoice_total, vat_total, invoice_date, invoice_number, issuer_name, issuer_nif,
+	buyer_nif, atcud, no_preview, created_at`
 
+// rowScanner is the Scan method shared by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDocument(row rowScanner) (*document.Document, error) {
 	var doc document.Document
 
-	err := s.db.QueryRowContext(ctx, query, id, auth.UserID(ctx)).
-		Scan(&doc.ID, &doc.UserID, &doc.Filename, &doc.MIMEType, &doc.CreatedAt)
+	err := row.Scan(
+		&doc.ID, &doc.UserID, &doc.Filename, &doc.MIMEType, &doc.Size, &doc.SHA256,
+		&doc.Invoice.Total, &doc.Invoice.VAT, &doc.Invoice.Date, &doc.Invoice.Number,
+		&doc.Invoice.IssuerName, &doc.Invoice.IssuerNIF, &doc.Invoice.BuyerNIF, &doc.Invoice.ATCUD,
+		&doc.NoPreview, &doc.CreatedAt,
+	)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, document.ErrDocumentNotFound
 		}
 
-		return nil, fmt.Errorf("getting document: %w", err)
+		return nil, err
 	}
 
 	return &doc, nil
 }
 
+func (s *Store) GetDocument(ctx context.Context, id uuid.UUID) (*document.Document, error) {
+	query := `SELECT ` + selectDocumentColumns + ` FROM documents WHERE id = $1 AND user_id = $2`
+
+	doc, err := scanDocument(s.db.QueryRowContext(ctx, query, id, auth.UserID(ctx)))
+	if err != nil && !errors.Is(err, document.ErrDocumentNotFound) {
+		return nil, fmt.Errorf("getting document: %w", err)
+	}
+
+	return doc, err
+}
+
+func (s *Store) ListDocuments(ctx context.Context) ([]document.Document, error) {
+	query := `SELECT ` + selectDocumentColumns + ` FROM documents WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query, auth.UserID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("listing documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []document.Document
+
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning document: %w", err)
+		}
+
+		docs = append(docs, *doc)
+	}
+
+	return docs, rows.Err()
+}
+
+func (s *Store) FindDocumentBySHA256(ctx context.Context, sha256 string) (*document.Document, error) {
+	query := `SELECT ` + selectDocumentColumns + ` FROM documents WHERE user_id = $1 AND sha256 = $2 AND sha256 <> ''`
+
+	doc, err := scanDocument(s.db.QueryRowContext(ctx, query, auth.UserID(ctx), sha256))
+	if err != nil && !errors.Is(err, document.ErrDocumentNotFound) {
+		return nil, fmt.Errorf("finding document by hash: %w", err)
+	}
+
+	return doc, err
+}
+
+func (s *Store) ListUnattachedDocuments(ctx context.Context, search string) ([]document.Document, error) {
+	query := `
+		SELECT ` + selectDocumentColumns + `
+		FROM documents d
+		WHERE user_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM transaction_documents td WHERE td.document_id = d.id)`
+	args := []any{auth.UserID(ctx)}
+
+	if search != "" {
+		query += ` AND (filename ILIKE '%' || $2 || '%' ESCAPE '\' OR issuer_name ILIKE '%' || $2 || '%' ESCAPE '\'
+			OR issuer_nif ILIKE '%' || $2 || '%' ESCAPE '\' OR invoice_number ILIKE '%' || $2 || '%' ESCAPE '\'
+			OR atcud ILIKE '%' || $2 || '%' ESCAPE '\'
+			OR text_content ILIKE '%' || $2 || '%' ESCAPE '\')`
+		args = append(args, database.EscapeLike(search))
+	}
+
+	query += ` ORDER BY created_at DESC, id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing unattached documents: %w", err)
+	}
+	defer rows.Close()
+
+	docs := []document.Document{}
+
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning document: %w", err)
+		}
+
+		docs = append(docs, *doc)
+	}
+
+	return docs, rows.Err()
+}
+
+func (s *Store) DocumentInUse(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM transaction_documents td
+			JOIN transactions t ON t.id = td.transaction_id
+			WHERE td.document_id = $1 AND t.user_id = $2
+		)`
+
+	var inUse bool
+	if err := s.db.QueryRowContext(ctx, query, id, auth.UserID(ctx)).Scan(&inUse); err != nil {
+		return false, fmt.Errorf("checking document references: %w", err)
+	}
+
+	return inUse, nil
+}
+
+func (s *Store) ListDocumentTransactions(ctx context.Context, documentID uuid.UUID) ([]document.TransactionFields, error) {
+	query := `
+		SELECT t.date, t.amount, t.description, COALESCE(mr.name, '')
+		FROM transaction_documents td
+		JOIN transactions t ON t.id = td.transaction_id
+		LEFT JOIN merchants mr ON t.merchant_id = mr.id AND mr.user_id = t.user_id
+		WHERE td.document_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+		ORDER BY td.role = 'invoice' DESC, td.created_at, t.id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, documentID, auth.UserID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("listing document transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var txs []document.TransactionFields
+
+	for rows.Next() {
+		var tx document.TransactionFields
+
+		if err := rows.Scan(&tx.Date, &tx.Amount, &tx.Description, &tx.Merchant); err != nil {
+			return nil, fmt.Errorf("scanning document transaction: %w", err)
+		}
+
+		txs = append(txs, tx)
+	}
+
+	return txs, rows.Err()
+}
+
 func (s *Store) DeleteDocument(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM documents WHERE id = $1 AND user_id = $2`
 
@@ -214,6 +477,101 @@ func (s *Store) DeleteDocument(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (s *Store) UpdateInvoice(ctx context.Context, id uuid.UUID, inv document.Invoice) error {
+	query := `
+		UPDATE documents
+		SET invoice_total = $1, vat_total = $2, invoice_date = $3, invoice_number = $4,
+			issuer_name = $5, issuer_nif = $6, buyer_nif = $7, atcud = $8
+		WHERE id = $9 AND user_id = $10
+	`
+
+	result, err := s.db.ExecContext(ctx, query,
+		inv.Total, inv.VAT, inv.Date, inv.Number, inv.IssuerName, inv.IssuerNIF, inv.BuyerNIF, inv.ATCUD,
+		id, auth.UserID(ctx))
+	if err != nil {
+		return fmt.Errorf("updating invoice fields: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return document.ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) SetText(ctx context.Context, id uuid.UUID, text string) error {
+	query := `UPDATE documents SET text_content = $1 WHERE id = $2 AND user_id = $3`
+
+	result, err := s.db.ExecContext(ctx, query, text, id, auth.UserID(ctx))
+	if err != nil {
+		return fmt.Errorf("setting document text: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return document.ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) GetPreview(ctx context.Context, documentID uuid.UUID) ([]byte, error) {
+	query := `
+		SELECT p.data
+		FROM document_previews p
+		JOIN documents d ON d.id = p.document_id
+		WHERE p.document_id = $1 AND d.user_id = $2
+	`
+
+	var data []byte
+
+	err := s.db.QueryRowContext(ctx, query, documentID, auth.UserID(ctx)).Scan(&data)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, document.ErrPreviewNotFound
+		}
+
+		return nil, fmt.Errorf("getting preview: %w", err)
+	}
+
+	return data, nil
+}
+
+func (s *Store) SavePreview(ctx context.Context, documentID uuid.UUID, data []byte) error {
+	query := `
+		INSERT INTO document_previews (document_id, data)
+		SELECT id, $2 FROM documents WHERE id = $1 AND user_id = $3
+		ON CONFLICT (document_id) DO UPDATE SET
+			data       = EXCLUDED.data,
+			created_at = NOW()
+	`
+
+	result, err := s.db.ExecContext(ctx, query, documentID, data, auth.UserID(ctx))
+	if err != nil {
+		return fmt.Errorf("saving preview: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return document.ErrDocumentNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) MarkNoPreview(ctx context.Context, documentID uuid.UUID) error {
+	query := `UPDATE documents SET no_preview = TRUE WHERE id = $1 AND user_id = $2`
+
+	result, err := s.db.ExecContext(ctx, query, documentID, auth.UserID(ctx))
+	if err != nil {
+		return fmt.Errorf("marking document without preview: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return document.ErrDocumentNotFound
+	}
+
+	return nil
+}
+
 // Location operations
 
 func (s *Store) AddLocation(ctx context.Context, loc *document.Location) error {
@@ -260,3 +618,48 @@ func (s *Store) ListLocations(ctx context.Context, documentID uuid.UUID) ([]docu
 
 	return locs, rows.Err()
 }
+
+func (s *Store) ListBackendLocations(ctx context.Context, backendID uuid.UUID) ([]document.Location, error) {
+	query := `
+		SELECT l.id, l.document_id, l.backend_id, l.key
+		FROM document_locations l
+		JOIN documents d ON d.id = l.document_id
+		WHERE l.backend_id = $1 AND d.user_id = $2
+		ORDER BY l.created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, backendID, auth.UserID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("listing backend locations: %w", err)
+	}
+	defer rows.Close()
+
+	var locs []document.Location
+
+	for rows.Next() {
+		var loc document.Location
+
+		if err := rows.Scan(&loc.ID, &loc.DocumentID, &loc.BackendID, &loc.Key); err != nil {
+			return nil, fmt.Errorf("scanning location: %w", err)
+		}
+
+		locs = append(locs, loc)
+	}
+
+	return locs, rows.Err()
+}
+
+func (s *Store) DeleteLocation(ctx context.Context, id uuid.UUID) error {
+	query := `
+		DELETE FROM document_locations l
+		USING documents d
+		WHERE l.id = $1 AND d.id = l.document_id AND d.user_id = $2
+	`
+
+	_, err := s.db.ExecContext(ctx, query, id, auth.UserID(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting location: %w", err)
+	}
+
+	return nil
+}
@@ -9,24 +9,84 @@ import (
 
 // BackendConfig is a user-configured storage backend record from the DB.
 type BackendConfig struct {
-	ID        uuid.UUID
-	UserID    uuid.UUID
-	Type      string          // "paperless", "local", …
-	Name      string          // user-friendly label
-	Config    json.RawMessage // backend-specific JSON (base_url, token, …)
-	Enabled   bool
-	CreatedAt time.Time
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Type       string          // "paperless", "local", …
+	Name       string          // user-friendly label
+	Config     json.RawMessage // backend-specific JSON (base_url, token, …)
+	Enabled    bool
+	Health     *BackendHealth // latest check; nil if never checked
+	DrainingAt *time.Time     // last marked by a running drain; nil if unmarked
+	CreatedAt  time.Time
 }
 
+// BackendHealth is the outcome of a backend health check.
+type BackendHealth struct {
+	CheckedAt time.Time
+	Error     string // why the check failed; "" if it passed
+}
+
+// Healthy reports whether the check passed.
+func (h BackendHealth) Healthy() bool { return h.Error == "" }
+
 // Document is a logical document (metadata only; content lives on backends).
 type Document struct {
-	ID        uuid.UUID
-	UserID    uuid.UUID
-	Filename  string
-	MIMEType  string
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	Filename string
+	MIMEType string
+	// Size and SHA256 (hex) describe the content; they are 0 and "" for
+	// documents linked by URL, whose content Finny never saw.
+	Size      int64
+	SHA256    string
+	Invoice   Invoice
+	NoPreview bool // the content could not be rendered as a preview
 	CreatedAt time.Time
 }
 
+// Invoice holds what a document bills, as entered by the user or parsed from
+// the document's text. Zero values mean unknown.
+type Invoice struct {
+	Total      *int64     // in cents, VAT included
+	VAT        *int64     // in cents
+	Date       *time.Time // issue date
+	Number     string     // e.g. "FT 2024A/123"
+	IssuerName string
+	IssuerNIF  string
+	BuyerNIF   string
+	ATCUD      string // unique document code, e.g. "CSDF7T5H-123"
+}
+
+// fill returns inv with its unknown fields taken from parsed.
+func (inv Invoice) fill(parsed Invoice) Invoice {
+	if inv.Total == nil {
+		inv.Total = parsed.Total
+	}
+	if inv.VAT == nil {
+		inv.VAT = parsed.VAT
+	}
+	if inv.Date == nil {
+		inv.Date = parsed.Date
+	}
+	if inv.Number == "" {
+		inv.Number = parsed.Number
+	}
+	if inv.IssuerName == "" {
+		inv.IssuerName = parsed.IssuerName
+	}
+	if inv.IssuerNIF == "" {
+		inv.IssuerNIF = parsed.IssuerNIF
+	}
+	if inv.BuyerNIF == "" {
+		inv.BuyerNIF = parsed.BuyerNIF
+	}
+	if inv.ATCUD == "" {
+		inv.ATCUD = parsed.ATCUD
+	}
+
+	return inv
+}
+
 // Location records where one copy of a document is stored on a specific backend.
 type Location struct {
 	ID         uuid.UUID
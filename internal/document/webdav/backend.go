@@ -0,0 +1,273 @@
+package webdav
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+)
+
+// Config holds the WebDAV server details stored in document_backends.config.
+type Config struct {
+	// URL is the WebDAV root, e.g. "https://cloud.example.com/remote.php/dav/files/alice"
+	// for Nextcloud.
+	URL string `json:"url"`
+	// Username and Password are sent with Basic auth. For Nextcloud, use an app
+	// password rather than the account password.
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Folder is the collection under URL that documents are stored in, e.g.
+	// "Documents/Invoices". Missing collections are created.
+	Folder string `json:"folder"`
+	// Subfolders groups uploads by upload date: "year" (2026/), "month"
+	// (2026/10/) or "day" (2026/10/18/). Empty stores everything in Folder.
+	Subfolders string `json:"subfolders"`
+}
+
+// subfolderLayouts maps Config.Subfolders to the time layout of the date path.
+var subfolderLayouts = map[string]string{
+	"":      "",
+	"year":  "2006",
+	"month": "2006/01",
+	"day":   "2006/01/02",
+}
+
+type Backend struct {
+	client   *http.Client
+	root     *url.URL
+	username string
+	password string
+	folder   string
+	layout   string
+
+	// collections caches the collections known to exist so that MKCOL is only
+	// sent for the first upload into a folder.
+	collections sync.Map
+}
+
+// NewFromConfig creates a WebDAV Backend from the JSONB config stored in the DB.
+func NewFromConfig(raw json.RawMessage) (document.Backend, error) {
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("webdav: invalid config: %w", err)
+	}
+
+	root, err := url.Parse(strings.TrimRight(cfg.URL, "/"))
+	if err != nil || root.Scheme == "" || root.Host == "" {
+		return nil, fmt.Errorf("webdav: url must be an absolute URL: %q", cfg.URL)
+	}
+
+	layout, ok := subfolderLayouts[cfg.Subfolders]
+	if !ok {
+		return nil, fmt.Errorf("webdav: subfolders must be one of year, month or day, got %q", cfg.Subfolders)
+	}
+
+	folder := strings.Trim(cfg.Folder, "/")
+	if folder != "" && !validKey(folder) {
+		return nil, fmt.Errorf("webdav: invalid folder: %q", cfg.Folder)
+	}
+
+	return &Backend{
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		root:     root,
+		username: cfg.Username,
+		password: cfg.Password,
+		folder:   folder,
+		layout:   layout,
+	}, nil
+}
+
+func (b *Backend) Type() string { return "webdav" }
+
+// validKey reports whether key is a clean relative path that stays inside the
+// folder it is resolved against.
+func validKey(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "/") && path.Clean(key) == key &&
+		key != ".." && !strings.HasPrefix(key, "../")
+}
+
+// resolveKey returns the URL of key inside the configured folder and rejects
+// keys that would escape it, like the local backend does for its base path.
+func (b *Backend) resolveKey(key string) (*url.URL, error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("webdav: invalid key: %q", key)
+	}
+
+	return b.collectionURL(path.Join(b.folder, key)), nil
+}
+
+// collectionURL returns the URL of p, a slash-separated path relative to the root.
+func (b *Backend) collectionURL(p string) *url.URL {
+	return b.root.JoinPath(strings.Split(p, "/")...)
+}
+
+// Upload writes the content to <folder>/[<date>/]<uuid>_<filename>, creating
+// missing collections, and returns the path relative to folder as the key.
+func (b *Backend) Upload(ctx context.Context, filename string, content io.Reader) (string, error) {
+	key := uuid.New().String() + "_" + filepath.Base(filename)
+	if b.layout != "" {
+		key = time.Now().Format(b.layout) + "/" + key
+	}
+
+	return b.UploadAt(ctx, key, content)
+}
+
+// UploadAt writes the content to <folder>/<key>, creating missing
+// collections. The PUT only succeeds if nothing is stored under key yet.
+func (b *Backend) UploadAt(ctx context.Context, key string, content io.Reader) (string, error) {
+	u, err := b.resolveKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := b.mkcolAll(ctx, path.Dir(path.Join(b.folder, key))); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), content)
+	if err != nil {
+		return "", fmt.Errorf("webdav: creating request: %w", err)
+	}
+
+	req.Header.Set("If-None-Match", "*")
+
+	resp, err := b.send(req)
+	if err != nil {
+		return "", fmt.Errorf("webdav: uploading %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return "", fmt.Errorf("webdav: %w: %s", document.ErrKeyExists, key)
+	}
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent &&
+		resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("webdav: uploading %s: %w", key, responseError(resp))
+	}
+
+	return key, nil
+}
+
+// mkcolAll creates dir and each of its missing parents, outermost first.
+func (b *Backend) mkcolAll(ctx context.Context, dir string) error {
+	if dir == "." || dir == "" {
+		return nil
+	}
+
+	if _, ok := b.collections.Load(dir); ok {
+		return nil
+	}
+
+	var current string
+
+	for _, segment := range strings.Split(dir, "/") {
+		current = path.Join(current, segment)
+
+		if _, ok := b.collections.Load(current); ok {
+			continue
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "MKCOL", b.collectionURL(current).String(), nil)
+		if err != nil {
+			return fmt.Errorf("webdav: creating request: %w", err)
+		}
+
+		resp, err := b.send(req)
+		if err != nil {
+			return fmt.Errorf("webdav: creating collection %s: %w", current, err)
+		}
+		resp.Body.Close()
+
+		// 405 Method Not Allowed is the response for a collection that already exists.
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+			return fmt.Errorf("webdav: creating collection %s: %w", current, responseError(resp))
+		}
+
+		b.collections.Store(current, struct{}{})
+	}
+
+	return nil
+}
+
+// Download retrieves the file stored under key.
+func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	u, err := b.resolveKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("webdav: creating request: %w", err)
+	}
+
+	resp, err := b.send(req)
+	if err != nil {
+		return nil, fmt.Errorf("webdav: downloading %s: %w", key, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("webdav: downloading %s: %w", key, responseError(resp))
+	}
+
+	return resp.Body, nil
+}
+
+// Delete removes the file stored under key. A file that is already gone is not an error.
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	u, err := b.resolveKey(key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("webdav: creating request: %w", err)
+	}
+
+	resp, err := b.send(req)
+	if err != nil {
+		return fmt.Errorf("webdav: deleting %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("webdav: deleting %s: %w", key, responseError(resp))
+	}
+}
+
+// send authenticates and sends req.
+func (b *Backend) send(req *http.Request) (*http.Response, error) {
+	if b.username != "" || b.password != "" {
+		req.SetBasicAuth(b.username, b.password)
+	}
+
+	return b.client.Do(req)
+}
+
+// responseError describes an unexpected response, including the start of its body.
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if msg := strings.TrimSpace(string(data)); msg != "" {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, msg)
+	}
+
+	return fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
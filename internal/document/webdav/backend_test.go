@@ -0,0 +1,198 @@
+package webdav_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/document/webdav"
+)
+
+const davRoot = "/remote.php/dav/files/alice"
+
+// fakeDAV is an in-memory WebDAV server with Nextcloud's status codes: PUT and
+// MKCOL answer 409 when the parent collection is missing, MKCOL answers 405 for
+// an existing collection.
+type fakeDAV struct {
+	mu          sync.Mutex
+	files       map[string][]byte
+	collections map[string]bool
+	mkcols      []string
+}
+
+func newFakeDAV(t *testing.T) (*fakeDAV, *httptest.Server) {
+	f := &fakeDAV{
+		files:       make(map[string][]byte),
+		collections: map[string]bool{davRoot: true},
+	}
+
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	return f, srv
+}
+
+func (f *fakeDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "app-password" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := path.Clean(r.URL.Path)
+
+	switch r.Method {
+	case "MKCOL":
+		f.mkcols = append(f.mkcols, strings.TrimPrefix(p, davRoot+"/"))
+
+		switch {
+		case f.collections[p]:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case !f.collections[path.Dir(p)]:
+			w.WriteHeader(http.StatusConflict)
+		default:
+			f.collections[p] = true
+			w.WriteHeader(http.StatusCreated)
+		}
+	case http.MethodPut:
+		if !f.collections[path.Dir(p)] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		f.files[p], _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		data, ok := f.files[p]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		if _, ok := f.files[p]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		delete(f.files, p)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newBackend(t *testing.T, cfg webdav.Config) document.Backend {
+	raw, err := json.Marshal(cfg)
+	require.NoError(t, err)
+
+	b, err := webdav.NewFromConfig(raw)
+	require.NoError(t, err)
+
+	return b
+}
+
+func TestBackend_RoundTripWithSubfolders(t *testing.T) {
+	fake, srv := newFakeDAV(t)
+
+	b := newBackend(t, webdav.Config{
+		URL:        srv.URL + davRoot + "/",
+		Username:   "alice",
+		Password:   "app-password",
+		Folder:     "/Finny/Invoices/",
+		Subfolders: "month",
+	})
+	ctx := context.Background()
+
+	key, err := b.Upload(ctx, "Fatura #12 ção.pdf", strings.NewReader("%PDF-1.7"))
+	require.NoError(t, err)
+
+	month := time.Now().Format("2006/01")
+	assert.True(t, strings.HasPrefix(key, month+"/"), key)
+	assert.True(t, strings.HasSuffix(key, "_Fatura #12 ção.pdf"), key)
+	assert.Contains(t, fake.files, davRoot+"/Finny/Invoices/"+key)
+	assert.Equal(t, []string{"Finny", "Finny/Invoices", "Finny/Invoices/" + month[:4], "Finny/Invoices/" + month}, fake.mkcols)
+
+	// Collections are only created once per backend.
+	_, err = b.Upload(ctx, "second.pdf", strings.NewReader("x"))
+	require.NoError(t, err)
+	assert.Len(t, fake.mkcols, 4)
+
+	rc, err := b.Download(ctx, key)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "%PDF-1.7", string(data))
+
+	require.NoError(t, b.Delete(ctx, key))
+	require.NoError(t, b.Delete(ctx, key), "deleting a missing file is not an error")
+
+	_, err = b.Download(ctx, key)
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestBackend_ExistingCollections(t *testing.T) {
+	fake, srv := newFakeDAV(t)
+	fake.collections[davRoot+"/Invoices"] = true
+
+	b := newBackend(t, webdav.Config{URL: srv.URL + davRoot, Username: "alice", Password: "app-password", Folder: "Invoices"})
+
+	key, err := b.Upload(context.Background(), "a.pdf", strings.NewReader("x"))
+	require.NoError(t, err)
+	assert.NotContains(t, key, "/")
+	assert.Equal(t, []string{"Invoices"}, fake.mkcols)
+}
+
+func TestBackend_HealthProbeLeavesNoFolders(t *testing.T) {
+	fake, srv := newFakeDAV(t)
+
+	b := newBackend(t, webdav.Config{URL: srv.URL + davRoot, Username: "alice", Password: "app-password", Folder: "Finny", Subfolders: "month"})
+
+	require.NoError(t, document.CheckHealth(context.Background(), b))
+	assert.Equal(t, []string{"Finny"}, fake.mkcols, "the probe is written to the base folder")
+	assert.Empty(t, fake.files, "the probe is deleted")
+}
+
+func TestBackend_Unauthorized(t *testing.T) {
+	_, srv := newFakeDAV(t)
+
+	b := newBackend(t, webdav.Config{URL: srv.URL + davRoot, Username: "alice", Password: "wrong", Folder: "Invoices"})
+
+	_, err := b.Upload(context.Background(), "a.pdf", strings.NewReader("x"))
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestBackend_RejectsEscapingKeys(t *testing.T) {
+	_, srv := newFakeDAV(t)
+
+	b := newBackend(t, webdav.Config{URL: srv.URL + davRoot, Username: "alice", Password: "app-password", Folder: "Invoices"})
+
+	for _, key := range []string{"", "/etc/passwd", "../other.pdf", "2026/../../other.pdf"} {
+		_, err := b.Download(context.Background(), key)
+		assert.ErrorContains(t, err, "invalid key", key)
+		assert.ErrorContains(t, b.Delete(context.Background(), key), "invalid key", key)
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	_, err := webdav.NewFromConfig(json.RawMessage(`{"url":"cloud.example.com"}`))
+	assert.ErrorContains(t, err, "absolute URL")
+
+	_, err = webdav.NewFromConfig(json.RawMessage(`{"url":"https://cloud.example.com","subfolders":"week"}`))
+	assert.ErrorContains(t, err, "subfolders")
+
+	_, err = webdav.NewFromConfig(json.RawMessage(`{"url":"https://cloud.example.com","folder":"a/../../b"}`))
+	assert.ErrorContains(t, err, "invalid folder")
+}
@@ -12,10 +12,16 @@ import (
 	"github.com/MrJamesThe3rd/finny/internal/transaction"
 )
 
-// Item represents a single exported transaction with its local file path.
+// Item represents a single exported transaction with its downloaded documents.
 type Item struct {
 	Transaction *transaction.Transaction
-	FilePath    string
+	Files       []File // oldest attachment first; empty without documents
+}
+
+// File is a downloaded document of a transaction.
+type File struct {
+	Role transaction.DocumentRole
+	Path string
 }
 
 // Service handles the export of transactions and their associated documents.
@@ -49,13 +55,18 @@ func (s *Service) Export(ctx context.Context, filter transaction.ListFilter, out
 	for _, t := range transactions {
 		item := Item{Transaction: t}
 
-		if t.DocumentID != nil {
-			path, err := s.downloadDocument(ctx, t, outputDir)
+		docs, err := s.transactions.ListDocuments(ctx, t.ID)
+		if err != nil {
+			return nil, fmt.Errorf("listing documents of transaction %s: %w", t.ID, err)
+		}
+
+		for _, d := range docs {
+			path, err := s.downloadDocument(ctx, t, d, outputDir)
 			if err != nil {
 				return nil, fmt.Errorf("downloading document for transaction %s: %w", t.ID, err)
 			}
 
-			item.FilePath = path
+			item.Files = append(item.Files, File{Role: d.Role, Path: path})
 		}
 
 		items = append(items, item)
@@ -64,21 +75,24 @@ func (s *Service) Export(ctx context.Context, filter transaction.ListFilter, out
 	return items, nil
 }
 
-func (s *Service) downloadDocument(ctx context.Context, tx *transaction.Transaction, dir string) (string, error) {
-	rc, doc, err := s.docs.Download(ctx, *tx.DocumentID)
+func (s *Service) downloadDocument(ctx context.Context, tx *transaction.Transaction, d transaction.Document, dir string) (string, error) {
+	rc, doc, err := s.docs.Download(ctx, d.ID)
 	if err != nil {
 		return "", fmt.Errorf("downloading document: %w", err)
 	}
 	defer rc.Close()
 
-	filename := doc.Filename
-	if filename == "" || filename == "invoice" || filename == "unknown" {
+	filename := filepath.Base(doc.Filename)
+	if filename == "" || filename == "." || filename == "invoice" || filename == "unknown" {
 		filename = generateFilename(tx)
 	}
 
-	path := filepath.Join(dir, filename)
+	// Invoices keep their name; the role tells the other documents apart.
+	if d.Role != transaction.RoleInvoice {
+		filename = string(d.Role) + "_" + filename
+	}
 
-	f, err := os.Create(path)
+	f, path, err := createUnique(dir, filename)
 	if err != nil {
 		return "", fmt.Errorf("creating file: %w", err)
 	}
@@ -91,6 +105,25 @@ func (s *Service) downloadDocument(ctx context.Context, tx *transaction.Transact
 	return path, nil
 }
 
+// createUnique creates filename in dir, numbering it when a document of the
+// export already has that name.
+func createUnique(dir, filename string) (*os.File, string, error) {
+	ext := filepath.Ext(filename)
+	stem := strings.TrimSuffix(filename, ext)
+
+	for n := 1; ; n++ {
+		path := filepath.Join(dir, filename)
+		if n > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s_%d%s", stem, n, ext))
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !os.IsExist(err) {
+			return f, path, err
+		}
+	}
+}
+
 // generateFilename produces a deterministic filename from transaction metadata.
 func generateFilename(tx *transaction.Transaction) string {
 	safeDesc := strings.Map(func(r rune) rune {
@@ -119,8 +152,12 @@ func (s *Service) GenerateSummary(items []Item) string {
 		}
 
 		fileStatus := "Sem Fatura"
-		if item.FilePath != "" {
-			fileStatus = filepath.Base(item.FilePath)
+		if len(item.Files) > 0 {
+			names := make([]string, len(item.Files))
+			for i, f := range item.Files {
+				names[i] = filepath.Base(f.Path)
+			}
+			fileStatus = strings.Join(names, ", ")
 		}
 
 		sb.WriteString(fmt.Sprintf("* %s | %s | %s%.2f € | %s\n", date, desc, sign, amount, fileStatus))
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -19,7 +21,8 @@ import (
 // ── transaction repository stub ───────────────────────────────────────────────
 
 type mockTxRepo struct {
-	txs []*transaction.Transaction
+	txs  []*transaction.Transaction
+	docs map[uuid.UUID][]transaction.Document // by transaction ID
 }
 
 func (m *mockTxRepo) CreateTransaction(_ context.Context, _ *transaction.Transaction) error {
@@ -35,13 +38,19 @@ func (m *mockTxRepo) ListTransactions(_ context.Context, _ transaction.ListFilte
 	return m.txs, nil
 }
 func (m *mockTxRepo) DeleteTransaction(_ context.Context, _ uuid.UUID) error { return nil }
-func (m *mockTxRepo) AttachDocument(_ context.Context, _ uuid.UUID, _ uuid.UUID) error {
+func (m *mockTxRepo) ListDocuments(_ context.Context, txID uuid.UUID) ([]transaction.Document, error) {
+	return m.docs[txID], nil
+}
+func (m *mockTxRepo) AddDocument(_ context.Context, _, _ uuid.UUID, _ transaction.DocumentRole) error {
 	return nil
 }
-func (m *mockTxRepo) DetachDocument(_ context.Context, _ uuid.UUID) error { return nil }
+func (m *mockTxRepo) RemoveDocument(_ context.Context, _, _ uuid.UUID) error { return nil }
 func (m *mockTxRepo) UpdateStatus(_ context.Context, _ uuid.UUID, _ transaction.Status) error {
 	return nil
 }
+func (m *mockTxRepo) UpdateDescriptions(_ context.Context, _ map[uuid.UUID]string) error {
+	return nil
+}
 func (m *mockTxRepo) BeginImport(_ context.Context, _, _ time.Time) (transaction.ImportTx, error) {
 	return nil, nil
 }
@@ -99,6 +108,36 @@ func (m *mockDocRepo) GetDocument(_ context.Context, id uuid.UUID) (*document.Do
 	}
 	return d, nil
 }
+func (m *mockDocRepo) ListDocuments(_ context.Context) ([]document.Document, error) {
+	return nil, nil
+}
+func (m *mockDocRepo) FindDocumentBySHA256(_ context.Context, _ string) (*document.Document, error) {
+	return nil, document.ErrDocumentNotFound
+}
+func (m *mockDocRepo) ListUnattachedDocuments(_ context.Context, _ string) ([]document.Document, error) {
+	return nil, nil
+}
+func (m *mockDocRepo) DocumentInUse(_ context.Context, _ uuid.UUID) (bool, error) {
+	return false, nil
+}
+func (m *mockDocRepo) UpdateInvoice(_ context.Context, _ uuid.UUID, _ document.Invoice) error {
+	return nil
+}
+func (m *mockDocRepo) SetText(_ context.Context, _ uuid.UUID, _ string) error {
+	return nil
+}
+func (m *mockDocRepo) ListDocumentTransactions(_ context.Context, _ uuid.UUID) ([]document.TransactionFields, error) {
+	return nil, nil
+}
+func (m *mockDocRepo) GetPreview(_ context.Context, _ uuid.UUID) ([]byte, error) {
+	return nil, document.ErrPreviewNotFound
+}
+func (m *mockDocRepo) MarkNoPreview(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockDocRepo) SavePreview(_ context.Context, _ uuid.UUID, _ []byte) error {
+	return nil
+}
 func (m *mockDocRepo) AddLocation(_ context.Context, loc *document.Location) error {
 	m.locations[loc.DocumentID] = append(m.locations[loc.DocumentID], *loc)
 	return nil
@@ -106,6 +145,27 @@ func (m *mockDocRepo) AddLocation(_ context.Context, loc *document.Location) err
 func (m *mockDocRepo) ListLocations(_ context.Context, documentID uuid.UUID) ([]document.Location, error) {
 	return m.locations[documentID], nil
 }
+func (m *mockDocRepo) DeleteLocation(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockDocRepo) ListBackendLocations(_ context.Context, _ uuid.UUID) ([]document.Location, error) {
+	return nil, nil
+}
+func (m *mockDocRepo) SetBackendHealth(_ context.Context, _ uuid.UUID, _ document.BackendHealth) error {
+	return nil
+}
+func (m *mockDocRepo) GetDrain(_ context.Context, _ uuid.UUID) (*document.Drain, error) {
+	return nil, document.ErrDrainNotFound
+}
+func (m *mockDocRepo) ClaimDrain(_ context.Context, _ uuid.UUID, _, _ time.Time) (bool, error) {
+	return false, nil
+}
+func (m *mockDocRepo) ReleaseDrain(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+func (m *mockDocRepo) SaveDrain(_ context.Context, _ *document.Drain) error {
+	return nil
+}
 
 // ── fake backend ──────────────────────────────────────────────────────────────
 
@@ -174,13 +234,25 @@ func TestExportService_Export(t *testing.T) {
 		return &fakeBackend{content: "fake pdf content"}, nil
 	})
 
+	docID3 := uuid.New()
+	docRepo.docs[docID3] = &document.Document{ID: docID3, Filename: "invoice.pdf", MIMEType: "application/pdf"}
+	docRepo.locations[docID3] = []document.Location{{DocumentID: docID3, BackendID: backendID2, Key: "7"}}
+
 	txs := []*transaction.Transaction{
 		{ID: uuid.New(), Amount: 1000, Description: "Test Transaction 1", Date: date, DocumentID: &docID},
 		{ID: uuid.New(), Amount: 2000, Description: "Test Transaction 2", Date: date, DocumentID: &docID2},
 		{ID: uuid.New(), Amount: 3000, Description: "No Document", Date: date},
 	}
 
-	txSvc := transaction.NewService(&mockTxRepo{txs: txs})
+	txDocs := map[uuid.UUID][]transaction.Document{
+		txs[0].ID: {
+			{ID: docID, Filename: "invoice.pdf", Role: transaction.RoleInvoice},
+			{ID: docID3, Filename: "invoice.pdf", Role: transaction.RoleReceipt},
+		},
+		txs[1].ID: {{ID: docID2, Role: transaction.RoleInvoice}},
+	}
+
+	txSvc := transaction.NewService(&mockTxRepo{txs: txs, docs: txDocs})
 	docSvc := document.NewService(docRepo, registry)
 
 	// Use the docstore package to satisfy the unused-import check in test builds.
@@ -197,22 +269,68 @@ func TestExportService_Export(t *testing.T) {
 		t.Fatalf("expected 3 items, got %d", len(items))
 	}
 
-	// Item 1 — named document
-	if items[0].FilePath == "" {
-		t.Error("item 1: expected non-empty FilePath")
+	// Item 1 — named invoice plus a receipt, named by role
+	if got := fileNames(items[0]); strings.Join(got, ",") != "invoice.pdf,receipt_invoice.pdf" {
+		t.Errorf("item 1: expected invoice and receipt, got %v", got)
+	}
+	if items[0].Files[1].Role != transaction.RoleReceipt {
+		t.Errorf("item 1: expected receipt role, got %s", items[0].Files[1].Role)
 	}
 
 	// Item 2 — unnamed document; filename generated from transaction
-	if items[1].FilePath == "" {
-		t.Error("item 2: expected non-empty FilePath")
+	if got := fileNames(items[1]); strings.Join(got, ",") != "20231027_Test_Transaction_2.pdf" {
+		t.Errorf("item 2: expected generated filename, got %v", got)
 	}
 
 	// Item 3 — no document
-	if items[2].FilePath != "" {
-		t.Errorf("item 3: expected empty FilePath, got %s", items[2].FilePath)
+	if len(items[2].Files) != 0 {
+		t.Errorf("item 3: expected no files, got %v", items[2].Files)
+	}
+
+	content, err := os.ReadFile(items[0].Files[1].Path)
+	if err != nil || string(content) != "fake pdf content" {
+		t.Errorf("item 1: receipt content %q, %v", content, err)
 	}
 }
 
+func TestExportService_Export_NumbersClashingNames(t *testing.T) {
+	docRepo, docID, backendID := buildDocRepo()
+
+	docID2 := uuid.New()
+	docRepo.docs[docID2] = &document.Document{ID: docID2, Filename: "invoice.pdf", MIMEType: "application/pdf"}
+	docRepo.locations[docID2] = []document.Location{{DocumentID: docID2, BackendID: backendID, Key: "43"}}
+
+	registry := document.NewRegistry()
+	registry.Register("fake", func(_ json.RawMessage) (document.Backend, error) {
+		return &fakeBackend{content: "fake pdf content"}, nil
+	})
+
+	txs := []*transaction.Transaction{{ID: uuid.New()}, {ID: uuid.New()}}
+	txDocs := map[uuid.UUID][]transaction.Document{
+		txs[0].ID: {{ID: docID, Role: transaction.RoleInvoice}},
+		txs[1].ID: {{ID: docID2, Role: transaction.RoleInvoice}},
+	}
+
+	svc := NewService(transaction.NewService(&mockTxRepo{txs: txs, docs: txDocs}), document.NewService(docRepo, registry))
+
+	items, err := svc.Export(context.Background(), transaction.ListFilter{}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if a, b := fileNames(items[0]), fileNames(items[1]); a[0] != "invoice.pdf" || b[0] != "invoice_2.pdf" {
+		t.Errorf("expected invoice.pdf and invoice_2.pdf, got %v and %v", a, b)
+	}
+}
+
+func fileNames(item Item) []string {
+	names := make([]string, len(item.Files))
+	for i, f := range item.Files {
+		names[i] = filepath.Base(f.Path)
+	}
+	return names
+}
+
 func TestService_GenerateSummary(t *testing.T) {
 	s := &Service{}
 
@@ -225,7 +343,10 @@ func TestService_GenerateSummary(t *testing.T) {
 				Description: "Hosting",
 				Type:        transaction.TypeExpense,
 			},
-			FilePath: "/tmp/invoice.pdf",
+			Files: []File{
+				{Role: transaction.RoleInvoice, Path: "/tmp/invoice.pdf"},
+				{Role: transaction.RoleReceipt, Path: "/tmp/receipt_talao.pdf"},
+			},
 		},
 		{
 			Transaction: &transaction.Transaction{
@@ -234,14 +355,13 @@ func TestService_GenerateSummary(t *testing.T) {
 				Description: "Coffee",
 				Type:        transaction.TypeExpense,
 			},
-			FilePath: "",
 		},
 	}
 
 	body := s.GenerateSummary(items)
 
 	for _, want := range []string{
-		"2023-10-27 | Hosting | -12.50 € | invoice.pdf",
+		"2023-10-27 | Hosting | -12.50 € | invoice.pdf, receipt_talao.pdf",
 		"2023-10-27 | Coffee | -5.00 € | Sem Fatura",
 	} {
 		if !strings.Contains(body, want) {
@@ -1,6 +1,7 @@
 package document
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,6 +9,9 @@ import (
 	"log/slog"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -28,17 +32,45 @@ func NewHandler(docSvc *document.Service, txSvc *transaction.Service, registry *
 	return &Handler{docSvc: docSvc, txSvc: txSvc, registry: registry}
 }
 
+// TransactionDocumentRoutes serve the transaction's primary document: the
+// single-document API that predates multiple documents per transaction.
 func (h *Handler) TransactionDocumentRoutes(r chi.Router) {
 	r.Post("/", h.uploadDocument)
 	r.Get("/", h.downloadDocument)
 	r.Delete("/", h.deleteDocument)
+	r.Get("/preview", h.previewDocument)
+}
+
+// TransactionDocumentsRoutes manage every document attached to a transaction.
+func (h *Handler) TransactionDocumentsRoutes(r chi.Router) {
+	r.Get("/", h.listTransactionDocuments)
+	r.Post("/", h.addTransactionDocument)
+	r.Put("/{documentID}", h.attachInboxDocument)
+	r.Get("/{documentID}", h.downloadTransactionDocument)
+	r.Get("/{documentID}/preview", h.previewTransactionDocument)
+	r.Delete("/{documentID}", h.removeTransactionDocument)
+}
+
+// InboxRoutes manage documents uploaded before their transaction exists.
+func (h *Handler) InboxRoutes(r chi.Router) {
+	r.Post("/", h.uploadInboxDocument)
+	r.Get("/inbox", h.listInbox)
+	r.Get("/{id}", h.downloadInboxDocument)
+	r.Get("/{id}/preview", h.previewInboxDocument)
+	r.Delete("/{id}", h.discardInboxDocument)
+	r.Put("/{id}/invoice", h.updateInvoice)
+	r.Post("/{id}/extract", h.extractInvoice)
 }
 
 func (h *Handler) BackendRoutes(r chi.Router) {
 	r.Get("/", h.listBackends)
 	r.Post("/", h.createBackend)
+	r.Post("/replicate", h.replicate)
 	r.Patch("/{id}", h.updateBackend)
 	r.Delete("/{id}", h.deleteBackend)
+	r.Post("/{id}/test", h.testBackend)
+	r.Post("/{id}/drain", h.startDrain)
+	r.Get("/{id}/drain", h.getDrain)
 }
 
 // ── Document upload ────────────────────────────────────────────────────────────
@@ -50,7 +82,8 @@ func (h *Handler) uploadDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := h.txSvc.Get(r.Context(), id); err != nil {
+	tx, err := h.txSvc.Get(r.Context(), id)
+	if err != nil {
 		if errors.Is(err, transaction.ErrNotFound) {
 			httputil.NotFound(w)
 			return
@@ -60,55 +93,264 @@ func (h *Handler) uploadDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := r.ParseMultipartForm(50 << 20); err != nil {
-		httputil.BadRequest(w, "Failed to parse upload.")
+	// This endpoint predates document roles: it stores the one invoice and
+	// still refuses a second. Other documents go through /documents.
+	docs, err := h.txSvc.ListDocuments(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to list transaction documents", "id", id, "error", err)
+		httputil.InternalError(w)
 		return
 	}
 
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		httputil.BadRequest(w, "The file field is required.")
+	for _, d := range docs {
+		if d.Role == transaction.RoleInvoice {
+			httputil.WriteError(w, http.StatusConflict, "DOCUMENT_EXISTS",
+				"Transaction already has an invoice. Delete it first.")
+			return
+		}
+	}
+
+	doc, ok := h.attach(w, r, tx, transaction.RoleInvoice)
+	if !ok {
 		return
 	}
-	defer file.Close()
 
-	mimeType := detectMIMEType(file, header)
+	httputil.WriteJSON(w, http.StatusCreated, toDocumentResponse(doc))
+}
+
+// attach uploads the request's file and attaches it to the transaction in the
+// given role, writing the error response itself on failure.
+func (h *Handler) attach(w http.ResponseWriter, r *http.Request, tx *transaction.Transaction, role transaction.DocumentRole) (*document.Document, bool) {
+	// Key templates store the upload under its transaction straight away.
+	doc, ok := h.upload(w, r, keyFields(tx))
+	if !ok {
+		return nil, false
+	}
+
+	if err := h.txSvc.AddDocument(r.Context(), tx.ID, doc.ID, role); err != nil {
+		// Drop the upload unless it is a deduplicated document in use elsewhere.
+		_ = h.docSvc.Release(r.Context(), doc.ID)
+
+		switch {
+		case errors.Is(err, transaction.ErrNotFound):
+			httputil.NotFound(w)
+		case errors.Is(err, transaction.ErrDocumentAlreadyAttached):
+			httputil.WriteError(w, http.StatusConflict, "DOCUMENT_EXISTS",
+				"This document is already attached to the transaction.")
+		default:
+			slog.Error("failed to attach document to transaction", "id", tx.ID, "error", err)
+			httputil.InternalError(w)
+		}
+		return nil, false
+	}
+
+	// A deduplicated upload returns the existing document, stored where its
+	// first transaction put it.
+	h.relocate(r, doc.ID)
 
-	doc, err := h.docSvc.Upload(r.Context(), header.Filename, mimeType, file)
+	return doc, true
+}
+
+// maxUploadSize bounds the size of an upload request body.
+const maxUploadSize = 50 << 20
+
+// upload streams the "file" part of a multipart request into the document
+// service without buffering it, writing the error response itself on failure.
+// tx is the transaction the file is uploaded for, or nil.
+func (h *Handler) upload(w http.ResponseWriter, r *http.Request, tx *document.TransactionFields) (*document.Document, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	mr, err := r.MultipartReader()
 	if err != nil {
-		if errors.Is(err, document.ErrNoBackends) {
+		httputil.BadRequest(w, "Failed to parse upload.")
+		return nil, false
+	}
+
+	var part *multipart.Part
+
+	for {
+		part, err = mr.NextPart()
+		if err != nil {
+			if isTooLarge(err) {
+				writeTooLarge(w)
+			} else {
+				httputil.BadRequest(w, "The file field is required.")
+			}
+			return nil, false
+		}
+
+		if part.FormName() == "file" && part.FileName() != "" {
+			break
+		}
+
+		part.Close()
+	}
+	defer part.Close()
+
+	content := bufio.NewReader(part)
+	mimeType := detectMIMEType(part.Header, content)
+
+	doc, err := h.docSvc.Upload(r.Context(), part.FileName(), mimeType, content, tx)
+	if err != nil {
+		switch {
+		case errors.Is(err, document.ErrNoBackends):
 			httputil.WriteError(w, http.StatusUnprocessableEntity, "NO_BACKEND",
 				"No document backend is configured.")
+		case isTooLarge(err):
+			writeTooLarge(w)
+		default:
+			slog.Error("failed to upload document", "error", err)
+			httputil.InternalError(w)
+		}
+		return nil, false
+	}
+
+	return doc, true
+}
+
+func isTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
+
+func writeTooLarge(w http.ResponseWriter) {
+	httputil.WriteError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE",
+		fmt.Sprintf("Uploads are limited to %d MB.", maxUploadSize>>20))
+}
+
+// ── Document download ──────────────────────────────────────────────────────────
+
+func (h *Handler) downloadDocument(w http.ResponseWriter, r *http.Request) {
+	if documentID, ok := h.primaryDocumentID(w, r); ok {
+		h.stream(w, r, documentID)
+	}
+}
+
+// primaryDocumentID returns the ID of the transaction's primary document, or
+// writes the error response.
+func (h *Handler) primaryDocumentID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid transaction ID.")
+		return uuid.Nil, false
+	}
+
+	tx, err := h.txSvc.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, transaction.ErrNotFound) {
+			httputil.NotFound(w)
+			return uuid.Nil, false
+		}
+		slog.Error("failed to get transaction", "id", id, "error", err)
+		httputil.InternalError(w)
+		return uuid.Nil, false
+	}
+
+	if tx.DocumentID == nil {
+		httputil.NotFound(w)
+		return uuid.Nil, false
+	}
+
+	return *tx.DocumentID, true
+}
+
+// stream writes the document's content as an attachment.
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, documentID uuid.UUID) {
+	rc, doc, err := h.docSvc.Download(r.Context(), documentID)
+	if err != nil {
+		if errors.Is(err, document.ErrDocumentNotFound) {
+			httputil.NotFound(w)
 			return
 		}
-		slog.Error("failed to upload document", "error", err)
+		slog.Error("failed to download document", "document_id", documentID, "error", err)
 		httputil.InternalError(w)
 		return
 	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", doc.MIMEType)
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="%s"`, doc.Filename))
+
+	if _, err := io.Copy(w, rc); err != nil {
+		slog.Error("failed to stream document", "error", err)
+	}
+}
+
+// ── Document preview ───────────────────────────────────────────────────────────
+
+func (h *Handler) previewDocument(w http.ResponseWriter, r *http.Request) {
+	if documentID, ok := h.primaryDocumentID(w, r); ok {
+		h.preview(w, r, documentID)
+	}
+}
+
+func (h *Handler) previewTransactionDocument(w http.ResponseWriter, r *http.Request) {
+	id, documentID, ok := parseTransactionDocumentIDs(w, r)
+	if !ok {
+		return
+	}
 
-	if err := h.txSvc.AttachDocument(r.Context(), id, doc.ID); err != nil {
+	docs, err := h.txSvc.ListDocuments(r.Context(), id)
+	if err != nil {
 		if errors.Is(err, transaction.ErrNotFound) {
 			httputil.NotFound(w)
 			return
 		}
-		if errors.Is(err, transaction.ErrDocumentAlreadyAttached) {
-			// Concurrent upload won the race — clean up our upload.
-			_ = h.docSvc.Delete(r.Context(), doc.ID)
-			httputil.WriteError(w, http.StatusConflict, "DOCUMENT_EXISTS",
-				"Transaction already has a document. Delete it first.")
+		slog.Error("failed to list transaction documents", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	for _, d := range docs {
+		if d.ID == documentID {
+			h.preview(w, r, documentID)
 			return
 		}
-		slog.Error("failed to attach document to transaction", "id", id, "error", err)
-		httputil.InternalError(w)
+	}
+
+	httputil.NotFound(w)
+}
+
+func (h *Handler) previewInboxDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid document ID.")
 		return
 	}
 
-	httputil.WriteJSON(w, http.StatusCreated, toDocumentResponse(doc))
+	h.preview(w, r, id)
 }
 
-// ── Document download ──────────────────────────────────────────────────────────
+// preview writes the document's preview image. Previews never change, so
+// clients may cache them.
+func (h *Handler) preview(w http.ResponseWriter, r *http.Request, documentID uuid.UUID) {
+	data, err := h.docSvc.Preview(r.Context(), documentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, document.ErrDocumentNotFound):
+			httputil.NotFound(w)
+		case errors.Is(err, document.ErrNoPreview):
+			httputil.WriteError(w, http.StatusUnprocessableEntity, "NO_PREVIEW",
+				"Previews are only available for PDFs and images.")
+		default:
+			slog.Error("failed to get document preview", "document_id", documentID, "error", err)
+			httputil.InternalError(w)
+		}
+		return
+	}
 
-func (h *Handler) downloadDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", document.PreviewMIMEType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+
+	if _, err := w.Write(data); err != nil {
+		slog.Error("failed to write document preview", "error", err)
+	}
+}
+
+// ── Document delete ────────────────────────────────────────────────────────────
+
+func (h *Handler) deleteDocument(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		httputil.BadRequest(w, "Invalid transaction ID.")
@@ -131,32 +373,71 @@ func (h *Handler) downloadDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rc, doc, err := h.docSvc.Download(r.Context(), *tx.DocumentID)
-	if err != nil {
-		slog.Error("failed to download document", "document_id", tx.DocumentID, "error", err)
+	h.remove(w, r, id, *tx.DocumentID)
+}
+
+// remove detaches the document from the transaction and deletes it once no
+// other transaction shares it through deduplication.
+func (h *Handler) remove(w http.ResponseWriter, r *http.Request, txID, documentID uuid.UUID) {
+	if err := h.txSvc.RemoveDocument(r.Context(), txID, documentID); err != nil {
+		if errors.Is(err, transaction.ErrNotFound) || errors.Is(err, transaction.ErrDocumentNotAttached) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to detach document from transaction", "id", txID, "error", err)
 		httputil.InternalError(w)
 		return
 	}
-	defer rc.Close()
 
-	w.Header().Set("Content-Type", doc.MIMEType)
-	w.Header().Set("Content-Disposition",
-		fmt.Sprintf(`attachment; filename="%s"`, doc.Filename))
-
-	if _, err := io.Copy(w, rc); err != nil {
-		slog.Error("failed to stream document", "error", err)
+	if err := h.docSvc.Release(r.Context(), documentID); err != nil {
+		slog.Error("failed to delete document", "document_id", documentID, "error", err)
+		httputil.InternalError(w)
+		return
 	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// ── Document delete ────────────────────────────────────────────────────────────
+// ── Transaction documents ─────────────────────────────────────────────────────
 
-func (h *Handler) deleteDocument(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) listTransactionDocuments(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid transaction ID.")
+		return
+	}
+
+	docs, err := h.txSvc.ListDocuments(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, transaction.ErrNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to list transaction documents", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := make([]transactionDocumentResponse, len(docs))
+	for i, d := range docs {
+		resp[i] = toTransactionDocumentResponse(d)
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) addTransactionDocument(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		httputil.BadRequest(w, "Invalid transaction ID.")
 		return
 	}
 
+	role, ok := parseRole(w, r)
+	if !ok {
+		return
+	}
+
 	tx, err := h.txSvc.Get(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, transaction.ErrNotFound) {
@@ -168,26 +449,323 @@ func (h *Handler) deleteDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if tx.DocumentID == nil {
-		httputil.NotFound(w)
+	doc, ok := h.attach(w, r, tx, role)
+	if !ok {
+		return
+	}
+
+	docs, err := h.txSvc.ListDocuments(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to list transaction documents", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	for _, d := range docs {
+		if d.ID == doc.ID {
+			httputil.WriteJSON(w, http.StatusCreated, toTransactionDocumentResponse(d))
+			return
+		}
+	}
+
+	// Removed again by a concurrent request.
+	httputil.NotFound(w)
+}
+
+func (h *Handler) downloadTransactionDocument(w http.ResponseWriter, r *http.Request) {
+	id, documentID, ok := parseTransactionDocumentIDs(w, r)
+	if !ok {
+		return
+	}
+
+	docs, err := h.txSvc.ListDocuments(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, transaction.ErrNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to list transaction documents", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	for _, d := range docs {
+		if d.ID == documentID {
+			h.stream(w, r, documentID)
+			return
+		}
+	}
+
+	httputil.NotFound(w)
+}
+
+func (h *Handler) removeTransactionDocument(w http.ResponseWriter, r *http.Request) {
+	id, documentID, ok := parseTransactionDocumentIDs(w, r)
+	if !ok {
+		return
+	}
+
+	h.remove(w, r, id, documentID)
+}
+
+// relocate moves a newly attached document to the keys backend key templates
+// render for its transaction. Failures only delay that until replication.
+func (h *Handler) relocate(r *http.Request, documentID uuid.UUID) {
+	if err := h.docSvc.Relocate(r.Context(), documentID); err != nil {
+		slog.Warn("failed to relocate document", "document_id", documentID, "error", err)
+	}
+}
+
+// keyFields are the fields of t backend key templates name documents after.
+func keyFields(t *transaction.Transaction) *document.TransactionFields {
+	return &document.TransactionFields{Date: t.Date, Amount: t.Amount, Description: t.Description, Merchant: t.MerchantName}
+}
+
+// attachInboxDocument attaches an already stored document, typically one from
+// the inbox, to a transaction.
+func (h *Handler) attachInboxDocument(w http.ResponseWriter, r *http.Request) {
+	id, documentID, ok := parseTransactionDocumentIDs(w, r)
+	if !ok {
+		return
+	}
+
+	role, ok := parseRole(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.txSvc.AddDocument(r.Context(), id, documentID, role); err != nil {
+		switch {
+		case errors.Is(err, transaction.ErrNotFound):
+			httputil.NotFound(w)
+		case errors.Is(err, transaction.ErrDocumentAlreadyAttached):
+			httputil.WriteError(w, http.StatusConflict, "DOCUMENT_EXISTS",
+				"This document is already attached to the transaction.")
+		default:
+			slog.Error("failed to attach document to transaction", "id", id, "document_id", documentID, "error", err)
+			httputil.InternalError(w)
+		}
 		return
 	}
 
-	if err := h.docSvc.Delete(r.Context(), *tx.DocumentID); err != nil {
-		slog.Error("failed to delete document", "document_id", tx.DocumentID, "error", err)
+	h.relocate(r, documentID)
+
+	docs, err := h.txSvc.ListDocuments(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to list transaction documents", "id", id, "error", err)
 		httputil.InternalError(w)
 		return
 	}
 
-	if err := h.txSvc.DetachDocument(r.Context(), id); err != nil {
-		slog.Error("failed to detach document from transaction", "id", id, "error", err)
+	for _, d := range docs {
+		if d.ID == documentID {
+			httputil.WriteJSON(w, http.StatusCreated, toTransactionDocumentResponse(d))
+			return
+		}
+	}
+
+	// Removed again by a concurrent request.
+	httputil.NotFound(w)
+}
+
+// parseRole reads the optional role query parameter, defaulting to invoice.
+func parseRole(w http.ResponseWriter, r *http.Request) (transaction.DocumentRole, bool) {
+	role := transaction.RoleInvoice
+	if v := r.URL.Query().Get("role"); v != "" {
+		role = transaction.DocumentRole(v)
+	}
+
+	if !role.Valid() {
+		httputil.BadRequest(w, "Invalid role. Use invoice, receipt, credit_note or other.")
+		return "", false
+	}
+
+	return role, true
+}
+
+func parseTransactionDocumentIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid transaction ID.")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	documentID, err := uuid.Parse(chi.URLParam(r, "documentID"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid document ID.")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return id, documentID, true
+}
+
+// ── Inbox ─────────────────────────────────────────────────────────────────────
+
+func (h *Handler) uploadInboxDocument(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.upload(w, r, nil)
+	if !ok {
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusCreated, toDocumentResponse(doc))
+}
+
+func (h *Handler) listInbox(w http.ResponseWriter, r *http.Request) {
+	docs, err := h.docSvc.Inbox(r.Context(), strings.TrimSpace(r.URL.Query().Get("q")))
+	if err != nil {
+		slog.Error("failed to list inbox", "error", err)
 		httputil.InternalError(w)
 		return
 	}
 
+	resp := make([]documentResponse, len(docs))
+	for i := range docs {
+		resp[i] = toDocumentResponse(&docs[i])
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) downloadInboxDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid document ID.")
+		return
+	}
+
+	h.stream(w, r, id)
+}
+
+func (h *Handler) discardInboxDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid document ID.")
+		return
+	}
+
+	if err := h.docSvc.Discard(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, document.ErrDocumentNotFound):
+			httputil.NotFound(w)
+		case errors.Is(err, document.ErrDocumentInUse):
+			httputil.WriteError(w, http.StatusConflict, "DOCUMENT_IN_USE",
+				"The document is attached to a transaction. Detach it there instead.")
+		default:
+			slog.Error("failed to delete document", "document_id", id, "error", err)
+			httputil.InternalError(w)
+		}
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+type invoiceRequest struct {
+	Total      *int64     `json:"total,omitempty"`
+	VAT        *int64     `json:"vat,omitempty"`
+	Date       *time.Time `json:"date,omitempty"`
+	Number     string     `json:"number"`
+	IssuerName string     `json:"issuer_name"`
+	IssuerNIF  string     `json:"issuer_nif"`
+	BuyerNIF   string     `json:"buyer_nif"`
+	ATCUD      string     `json:"atcud"`
+}
+
+// updateInvoice replaces the invoice fields used to match the document to a
+// transaction. Omitted fields become unknown.
+func (h *Handler) updateInvoice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid document ID.")
+		return
+	}
+
+	var req invoiceRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+
+	if req.Total != nil && *req.Total <= 0 {
+		httputil.BadRequest(w, "The total must be a positive amount in cents.")
+		return
+	}
+
+	if req.VAT != nil && *req.VAT < 0 {
+		httputil.BadRequest(w, "The VAT must not be negative.")
+		return
+	}
+
+	nif := strings.TrimSpace(req.IssuerNIF)
+	if nif != "" && !document.ValidNIF(nif) {
+		httputil.BadRequest(w, "Invalid issuer NIF.")
+		return
+	}
+
+	buyerNIF := strings.TrimSpace(req.BuyerNIF)
+	if buyerNIF != "" && !document.ValidNIF(buyerNIF) {
+		httputil.BadRequest(w, "Invalid buyer NIF.")
+		return
+	}
+
+	inv := document.Invoice{
+		Total:      req.Total,
+		VAT:        req.VAT,
+		Date:       req.Date,
+		Number:     strings.TrimSpace(req.Number),
+		IssuerName: strings.TrimSpace(req.IssuerName),
+		IssuerNIF:  nif,
+		BuyerNIF:   buyerNIF,
+		ATCUD:      strings.TrimSpace(req.ATCUD),
+	}
+
+	if err := h.docSvc.UpdateInvoice(r.Context(), id, inv); err != nil {
+		if errors.Is(err, document.ErrDocumentNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to update invoice fields", "document_id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	doc, err := h.docSvc.Get(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to get document", "document_id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toDocumentResponse(doc))
+}
+
+// extractInvoice re-reads the document's text and fills the invoice fields
+// still unknown, e.g. for documents uploaded before text extraction existed.
+func (h *Handler) extractInvoice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid document ID.")
+		return
+	}
+
+	doc, err := h.docSvc.Extract(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, document.ErrDocumentNotFound):
+			httputil.NotFound(w)
+		case errors.Is(err, document.ErrNoText):
+			httputil.WriteError(w, http.StatusUnprocessableEntity, "NO_TEXT",
+				"The document has no readable text. Enter its invoice fields instead.")
+		default:
+			slog.Error("failed to extract document text", "document_id", id, "error", err)
+			httputil.InternalError(w)
+		}
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toDocumentResponse(doc))
+}
+
 // ── Backend list ───────────────────────────────────────────────────────────────
 
 func (h *Handler) listBackends(w http.ResponseWriter, r *http.Request) {
@@ -209,7 +787,7 @@ func (h *Handler) listBackends(w http.ResponseWriter, r *http.Request) {
 // ── Backend create ─────────────────────────────────────────────────────────────
 
 type createBackendRequest struct {
-	Type   string          `json:"type"   validate:"required,oneof=paperless local"`
+	Type   string          `json:"type"   validate:"required"`
 	Name   string          `json:"name"   validate:"required"`
 	Config json.RawMessage `json:"config" validate:"required"`
 }
@@ -244,6 +822,13 @@ func (h *Handler) createBackend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A config that parses may still not work; report it right away.
+	health, err := h.docSvc.CheckBackend(r.Context(), cfg.ID)
+	if err != nil {
+		slog.Warn("failed to check new backend", "id", cfg.ID, "error", err)
+	}
+	cfg.Health = health
+
 	httputil.WriteJSON(w, http.StatusCreated, toBackendResponse(*cfg))
 }
 
@@ -271,6 +856,23 @@ func (h *Handler) updateBackend(w http.ResponseWriter, r *http.Request) {
 	var config json.RawMessage
 	if req.Config != nil {
 		config = *req.Config
+
+		cfg, err := h.docSvc.GetBackend(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, document.ErrBackendNotFound) {
+				httputil.NotFound(w)
+				return
+			}
+			slog.Error("failed to get backend", "id", id, "error", err)
+			httputil.InternalError(w)
+			return
+		}
+
+		// Validate the new config as createBackend does.
+		if _, err := h.registry.Create(cfg.Type, config); err != nil {
+			httputil.BadRequest(w, fmt.Sprintf("Invalid config for %s backend: %s", cfg.Type, err.Error()))
+			return
+		}
 	}
 
 	if err := h.docSvc.UpdateBackend(r.Context(), id, req.Name, config, req.Enabled); err != nil {
@@ -283,9 +885,39 @@ func (h *Handler) updateBackend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Record whether a new config works; GET /backends shows the outcome.
+	if req.Config != nil {
+		if _, err := h.docSvc.CheckBackend(r.Context(), id); err != nil {
+			slog.Warn("failed to check updated backend", "id", id, "error", err)
+		}
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ── Backend test ───────────────────────────────────────────────────────────────
+
+func (h *Handler) testBackend(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid backend ID.")
+		return
+	}
+
+	health, err := h.docSvc.CheckBackend(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, document.ErrBackendNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to check backend", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toBackendHealthResponse(*health))
+}
+
 // ── Backend delete ─────────────────────────────────────────────────────────────
 
 func (h *Handler) deleteBackend(w http.ResponseWriter, r *http.Request) {
@@ -307,7 +939,7 @@ func (h *Handler) deleteBackend(w http.ResponseWriter, r *http.Request) {
 
 		if hasDocuments {
 			httputil.WriteError(w, http.StatusConflict, "BACKEND_HAS_DOCUMENTS",
-				"Backend still has documents. Use ?force=true to delete anyway.")
+				"Backend still has documents. Drain it first, or use ?force=true to delete anyway.")
 			return
 		}
 	}
@@ -325,15 +957,119 @@ func (h *Handler) deleteBackend(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ── Backend drain ──────────────────────────────────────────────────────────────
+
+type startDrainRequest struct {
+	TargetID uuid.UUID `json:"target_id" validate:"required"`
+}
+
+func (h *Handler) startDrain(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid backend ID.")
+		return
+	}
+
+	var req startDrainRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+	if !httputil.Validate(w, req) {
+		return
+	}
+
+	drain, err := h.docSvc.StartDrain(r.Context(), id, req.TargetID)
+	if err != nil {
+		switch {
+		case errors.Is(err, document.ErrBackendNotFound):
+			httputil.NotFound(w)
+		case errors.Is(err, document.ErrDrainToSelf):
+			httputil.BadRequest(w, "Cannot drain a backend onto itself.")
+		case errors.Is(err, document.ErrDrainRunning):
+			httputil.WriteError(w, http.StatusConflict, "DRAIN_RUNNING", "Backend is already being drained.")
+		default:
+			slog.Error("failed to start drain", "id", id, "error", err)
+			httputil.InternalError(w)
+		}
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusAccepted, toDrainResponse(drain))
+}
+
+func (h *Handler) getDrain(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid backend ID.")
+		return
+	}
+
+	drain, err := h.docSvc.GetDrain(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, document.ErrDrainNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to get drain", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toDrainResponse(drain))
+}
+
+// ── Replication ────────────────────────────────────────────────────────────────
+
+type replicationFailureResponse struct {
+	DocumentID uuid.UUID  `json:"document_id"`
+	BackendID  *uuid.UUID `json:"backend_id,omitempty"`
+	Error      string     `json:"error"`
+}
+
+type replicationResponse struct {
+	Documents int                          `json:"documents"`
+	Copied    int                          `json:"copied"`
+	Repaired  int                          `json:"repaired"`
+	Relocated int                          `json:"relocated"`
+	Failures  []replicationFailureResponse `json:"failures"`
+}
+
+// replicate runs the replication job for the user now, instead of waiting for
+// its next scheduled run.
+func (h *Handler) replicate(w http.ResponseWriter, r *http.Request) {
+	report, err := h.docSvc.Replicate(r.Context())
+	if err != nil {
+		slog.Error("failed to replicate documents", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := replicationResponse{
+		Documents: report.Documents,
+		Copied:    report.Copied,
+		Repaired:  report.Repaired,
+		Relocated: report.Relocated,
+		Failures:  make([]replicationFailureResponse, len(report.Failures)),
+	}
+
+	for i, f := range report.Failures {
+		resp.Failures[i] = replicationFailureResponse{DocumentID: f.DocumentID, Error: f.Err.Error()}
+		if f.BackendID != uuid.Nil {
+			resp.Failures[i].BackendID = &f.BackendID
+		}
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
 // ── Helpers ────────────────────────────────────────────────────────────────────
 
-func detectMIMEType(file multipart.File, header *multipart.FileHeader) string {
-	if ct := header.Header.Get("Content-Type"); ct != "" && ct != "application/octet-stream" {
+func detectMIMEType(header textproto.MIMEHeader, content *bufio.Reader) string {
+	if ct := header.Get("Content-Type"); ct != "" && ct != "application/octet-stream" {
 		return ct
 	}
 
-	buf := make([]byte, 512)
-	n, _ := file.Read(buf)
-	file.Seek(0, io.SeekStart) //nolint:errcheck
-	return http.DetectContentType(buf[:n])
+	head, _ := content.Peek(512)
+	return http.DetectContentType(head)
 }
@@ -0,0 +1,255 @@
+package document_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/auth"
+	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/document/local"
+	"github.com/MrJamesThe3rd/finny/internal/document/paperless"
+	"github.com/MrJamesThe3rd/finny/internal/document/s3"
+	"github.com/MrJamesThe3rd/finny/internal/document/sftp"
+	"github.com/MrJamesThe3rd/finny/internal/document/webdav"
+	documentHandler "github.com/MrJamesThe3rd/finny/internal/http/document"
+)
+
+// backendRepo keeps backend configs in memory; the handlers under test touch
+// nothing else of document.Repository.
+type backendRepo struct {
+	document.Repository
+
+	mu       sync.Mutex
+	backends map[uuid.UUID]*document.BackendConfig
+}
+
+func newBackendRepo() *backendRepo {
+	return &backendRepo{backends: make(map[uuid.UUID]*document.BackendConfig)}
+}
+
+func (r *backendRepo) CreateBackend(_ context.Context, cfg *document.BackendConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg.ID = uuid.New()
+	stored := *cfg
+	r.backends[cfg.ID] = &stored
+
+	return nil
+}
+
+func (r *backendRepo) GetBackend(_ context.Context, id uuid.UUID) (*document.BackendConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, ok := r.backends[id]
+	if !ok {
+		return nil, document.ErrBackendNotFound
+	}
+
+	stored := *cfg
+
+	return &stored, nil
+}
+
+func (r *backendRepo) UpdateBackend(_ context.Context, id uuid.UUID, name *string, config json.RawMessage, enabled *bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, ok := r.backends[id]
+	if !ok {
+		return document.ErrBackendNotFound
+	}
+
+	if name != nil {
+		cfg.Name = *name
+	}
+	if config != nil {
+		cfg.Config = config
+	}
+	if enabled != nil {
+		cfg.Enabled = *enabled
+	}
+
+	return nil
+}
+
+func (r *backendRepo) SetBackendHealth(_ context.Context, id uuid.UUID, health document.BackendHealth) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cfg, ok := r.backends[id]; ok {
+		cfg.Health = &health
+	}
+
+	return nil
+}
+
+// newBackendRouter serves the backend routes for userID with every backend
+// type the API registers.
+func newBackendRouter(repo document.Repository, userID uuid.UUID) http.Handler {
+	registry := document.NewRegistry()
+	registry.Register("paperless", paperless.NewFromConfig)
+	registry.Register("local", local.NewFromConfig)
+	registry.Register("s3", s3.NewFromConfig)
+	registry.Register("sftp", sftp.NewFromConfig)
+	registry.Register("webdav", webdav.NewFromConfig)
+
+	h := documentHandler.NewHandler(document.NewService(repo, registry), nil, registry)
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(auth.WithUserID(req.Context(), userID)))
+		})
+	})
+	r.Route("/backends", h.BackendRoutes)
+
+	return r
+}
+
+func serve(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestCreateBackend_S3(t *testing.T) {
+	// Stands in for the bucket so the health check stays local.
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(bucket.Close)
+
+	repo := newBackendRepo()
+	router := newBackendRouter(repo, uuid.New())
+
+	rec := serve(t, router, http.MethodPost, "/backends", `{
+		"type": "s3",
+		"name": "MinIO",
+		"config": {
+			"endpoint": "`+bucket.URL+`",
+			"bucket": "finny",
+			"access_key_id": "key",
+			"secret_access_key": "secret",
+			"path_style": true
+		}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Len(t, repo.backends, 1)
+
+	for _, cfg := range repo.backends {
+		assert.Equal(t, "s3", cfg.Type)
+		assert.Equal(t, "MinIO", cfg.Name)
+	}
+}
+
+func TestCreateBackend_WebDAV(t *testing.T) {
+	dav := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(dav.Close)
+
+	repo := newBackendRepo()
+	router := newBackendRouter(repo, uuid.New())
+
+	rec := serve(t, router, http.MethodPost, "/backends", `{
+		"type": "webdav",
+		"name": "Nextcloud",
+		"config": {
+			"url": "`+dav.URL+`",
+			"username": "alice",
+			"password": "app-password",
+			"folder": "Documents/Invoices"
+		}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Len(t, repo.backends, 1)
+
+	for _, cfg := range repo.backends {
+		assert.Equal(t, "webdav", cfg.Type)
+	}
+}
+
+func TestCreateBackend_SFTP(t *testing.T) {
+	// Nothing listens on the port once it is closed, so the health check
+	// fails fast; the backend is saved anyway.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	repo := newBackendRepo()
+	router := newBackendRouter(repo, uuid.New())
+
+	rec := serve(t, router, http.MethodPost, "/backends", `{
+		"type": "sftp",
+		"name": "NAS",
+		"config": {
+			"host": "127.0.0.1",
+			"port": `+strconv.Itoa(port)+`,
+			"user": "finny",
+			"password": "secret",
+			"base_dir": "documents",
+			"host_key": "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+		}
+	}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Len(t, repo.backends, 1)
+
+	for _, cfg := range repo.backends {
+		assert.Equal(t, "sftp", cfg.Type)
+		require.NotNil(t, cfg.Health)
+		assert.False(t, cfg.Health.Healthy())
+	}
+}
+
+func TestCreateBackend_RejectsUnknownType(t *testing.T) {
+	repo := newBackendRepo()
+	router := newBackendRouter(repo, uuid.New())
+
+	rec := serve(t, router, http.MethodPost, "/backends", `{"type": "ftp", "name": "Old", "config": {}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, repo.backends)
+}
+
+func TestUpdateBackend_ValidatesConfig(t *testing.T) {
+	userID := uuid.New()
+	repo := newBackendRepo()
+	router := newBackendRouter(repo, userID)
+
+	cfg := &document.BackendConfig{
+		UserID:  userID,
+		Type:    "local",
+		Name:    "Documents",
+		Config:  json.RawMessage(`{"base_path": "` + t.TempDir() + `"}`),
+		Enabled: true,
+	}
+	require.NoError(t, repo.CreateBackend(context.Background(), cfg))
+
+	rec := serve(t, router, http.MethodPatch, "/backends/"+cfg.ID.String(), `{"config": {"base_path": "/srv", "key_template": "{{year}}/{{vendor}}"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, string(cfg.Config), string(repo.backends[cfg.ID].Config), "the rejected config is not saved")
+
+	dir := t.TempDir()
+	rec = serve(t, router, http.MethodPatch, "/backends/"+cfg.ID.String(), `{"config": {"base_path": "`+dir+`", "key_template": "{{year}}/{{filename}}"}}`)
+	assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	assert.Contains(t, string(repo.backends[cfg.ID].Config), dir)
+
+	rec = serve(t, router, http.MethodPatch, "/backends/"+uuid.NewString(), `{"config": {"base_path": "/srv"}}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
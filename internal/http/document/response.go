@@ -6,36 +6,136 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
 )
 
 type documentResponse struct {
-	ID       uuid.UUID `json:"id"`
-	Filename string    `json:"filename"`
-	MIMEType string    `json:"mime_type"`
+	ID        uuid.UUID        `json:"id"`
+	Filename  string           `json:"filename"`
+	MIMEType  string           `json:"mime_type"`
+	Size      int64            `json:"size,omitempty"`
+	SHA256    string           `json:"sha256,omitempty"`
+	Invoice   *invoiceResponse `json:"invoice,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+type invoiceResponse struct {
+	Total      *int64     `json:"total,omitempty"`
+	VAT        *int64     `json:"vat,omitempty"`
+	Date       *time.Time `json:"date,omitempty"`
+	Number     string     `json:"number,omitempty"`
+	IssuerName string     `json:"issuer_name,omitempty"`
+	IssuerNIF  string     `json:"issuer_nif,omitempty"`
+	BuyerNIF   string     `json:"buyer_nif,omitempty"`
+	ATCUD      string     `json:"atcud,omitempty"`
+}
+
+type transactionDocumentResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Filename   string    `json:"filename"`
+	MIMEType   string    `json:"mime_type"`
+	Role       string    `json:"role"`
+	AttachedAt time.Time `json:"attached_at"`
 }
 
 type backendResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Type      string    `json:"type"`
-	Name      string    `json:"name"`
-	Enabled   bool      `json:"enabled"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID              `json:"id"`
+	Type      string                 `json:"type"`
+	Name      string                 `json:"name"`
+	Enabled   bool                   `json:"enabled"`
+	Health    *backendHealthResponse `json:"health,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type backendHealthResponse struct {
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type drainResponse struct {
+	BackendID  uuid.UUID  `json:"backend_id"`
+	TargetID   uuid.UUID  `json:"target_id"`
+	Total      int        `json:"total"`
+	Moved      int        `json:"moved"`
+	Failed     int        `json:"failed"`
+	LastError  string     `json:"last_error,omitempty"`
+	Running    bool       `json:"running"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
 }
 
 func toDocumentResponse(doc *document.Document) documentResponse {
-	return documentResponse{
-		ID:       doc.ID,
-		Filename: doc.Filename,
-		MIMEType: doc.MIMEType,
+	resp := documentResponse{
+		ID:        doc.ID,
+		Filename:  doc.Filename,
+		MIMEType:  doc.MIMEType,
+		Size:      doc.Size,
+		SHA256:    doc.SHA256,
+		CreatedAt: doc.CreatedAt,
+	}
+
+	if inv := doc.Invoice; inv != (document.Invoice{}) {
+		resp.Invoice = &invoiceResponse{
+			Total:      inv.Total,
+			VAT:        inv.VAT,
+			Date:       inv.Date,
+			Number:     inv.Number,
+			IssuerName: inv.IssuerName,
+			IssuerNIF:  inv.IssuerNIF,
+			BuyerNIF:   inv.BuyerNIF,
+			ATCUD:      inv.ATCUD,
+		}
+	}
+
+	return resp
+}
+
+func toTransactionDocumentResponse(d transaction.Document) transactionDocumentResponse {
+	return transactionDocumentResponse{
+		ID:         d.ID,
+		Filename:   d.Filename,
+		MIMEType:   d.MIMEType,
+		Role:       string(d.Role),
+		AttachedAt: d.AttachedAt,
 	}
 }
 
 func toBackendResponse(cfg document.BackendConfig) backendResponse {
-	return backendResponse{
+	resp := backendResponse{
 		ID:        cfg.ID,
 		Type:      cfg.Type,
 		Name:      cfg.Name,
 		Enabled:   cfg.Enabled,
 		CreatedAt: cfg.CreatedAt,
 	}
+
+	if cfg.Health != nil {
+		health := toBackendHealthResponse(*cfg.Health)
+		resp.Health = &health
+	}
+
+	return resp
+}
+
+func toBackendHealthResponse(h document.BackendHealth) backendHealthResponse {
+	return backendHealthResponse{
+		Healthy:   h.Healthy(),
+		Error:     h.Error,
+		CheckedAt: h.CheckedAt,
+	}
+}
+
+func toDrainResponse(d *document.Drain) drainResponse {
+	return drainResponse{
+		BackendID:  d.BackendID,
+		TargetID:   d.TargetID,
+		Total:      d.Total,
+		Moved:      d.Moved,
+		Failed:     d.Failed,
+		LastError:  d.LastError,
+		Running:    d.Running,
+		StartedAt:  d.StartedAt,
+		FinishedAt: d.FinishedAt,
+	}
 }
@@ -1,6 +1,9 @@
 package importcsv
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
@@ -11,20 +14,23 @@ import (
 	"github.com/MrJamesThe3rd/finny/internal/httputil"
 	"github.com/MrJamesThe3rd/finny/internal/importer"
 	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/merchant"
 	"github.com/MrJamesThe3rd/finny/internal/transaction"
 )
 
 type Handler struct {
-	importSvc *importer.Service
-	txSvc     *transaction.Service
-	matchSvc  *matching.Service
+	importSvc   *importer.Service
+	txSvc       *transaction.Service
+	matchSvc    *matching.Service
+	merchantSvc *merchant.Service
 }
 
-func NewHandler(importSvc *importer.Service, txSvc *transaction.Service, matchSvc *matching.Service) *Handler {
+func NewHandler(importSvc *importer.Service, txSvc *transaction.Service, matchSvc *matching.Service, merchantSvc *merchant.Service) *Handler {
 	return &Handler{
-		importSvc: importSvc,
-		txSvc:     txSvc,
-		matchSvc:  matchSvc,
+		importSvc:   importSvc,
+		txSvc:       txSvc,
+		matchSvc:    matchSvc,
+		merchantSvc: merchantSvc,
 	}
 }
 
@@ -34,14 +40,19 @@ func (h *Handler) Routes(r chi.Router) {
 }
 
 type transactionResponse struct {
-	ID             uuid.UUID          `json:"id"`
-	Amount         int64              `json:"amount"`
-	Type           transaction.Type   `json:"type"`
-	Status         transaction.Status `json:"status"`
-	Description    string             `json:"description"`
-	RawDescription string             `json:"raw_description,omitempty"`
-	Date           time.Time          `json:"date"`
-	CreatedAt      time.Time          `json:"created_at"`
+	ID                    uuid.UUID          `json:"id"`
+	Amount                int64              `json:"amount"`
+	Type                  transaction.Type   `json:"type"`
+	Status                transaction.Status `json:"status"`
+	Description           string             `json:"description"`
+	RawDescription        string             `json:"raw_description,omitempty"`
+	NormalizedDescription string             `json:"normalized_description,omitempty"`
+	Category              string             `json:"category,omitempty"`
+	Tags                  []string           `json:"tags"`
+	Source                string             `json:"source,omitempty"`
+	MerchantID            *uuid.UUID         `json:"merchant_id,omitempty"`
+	Date                  time.Time          `json:"date"`
+	CreatedAt             time.Time          `json:"created_at"`
 }
 
 type importSuccessResponse struct {
@@ -50,11 +61,32 @@ type importSuccessResponse struct {
 }
 
 type createParamsDTO struct {
-	Amount         int64            `json:"amount"  validate:"required,ne=0"`
-	Type           transaction.Type `json:"type"    validate:"required,oneof=income expense"`
-	Description    string           `json:"description"`
-	RawDescription string           `json:"raw_description"`
-	Date           time.Time        `json:"date"    validate:"required"`
+	Amount                int64              `json:"amount"  validate:"required,ne=0"`
+	Type                  transaction.Type   `json:"type"    validate:"required,oneof=income expense"`
+	Description           string             `json:"description"`
+	RawDescription        string             `json:"raw_description"`
+	NormalizedDescription string             `json:"normalized_description"`
+	Category              string             `json:"category"`
+	Tags                  []string           `json:"tags"`
+	Source                string             `json:"source"`
+	MerchantID            *uuid.UUID         `json:"merchant_id,omitempty"`
+	Status                transaction.Status `json:"status"  validate:"omitempty,oneof=draft pending_invoice complete no_invoice"`
+	Date                  time.Time          `json:"date"    validate:"required"`
+	// Suggestions is output only: classifier suggestions shown in the import preview.
+	Suggestions *suggestionsDTO `json:"suggestions,omitempty"`
+}
+
+// previewSuggestions is how many classifier suggestions each previewed row gets.
+const previewSuggestions = 3
+
+type suggestionDTO struct {
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+}
+
+type suggestionsDTO struct {
+	Descriptions []suggestionDTO `json:"descriptions"`
+	Categories   []suggestionDTO `json:"categories"`
 }
 
 type conflictDTO struct {
@@ -96,15 +128,9 @@ func (h *Handler) importCSV(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for i, p := range params {
-		suggested, err := h.matchSvc.Suggest(r.Context(), p.RawDescription)
-		if err != nil {
-			continue
-		}
-		if suggested == "" {
-			continue
-		}
-		params[i].Description = suggested
+	if err := h.matchSvc.Apply(r.Context(), params); err != nil {
+		// Matching only enriches rows; import them as parsed rather than failing.
+		slog.Warn("failed to apply matching rules", "error", err)
 	}
 
 	result, err := h.txSvc.ImportBatch(r.Context(), params)
@@ -120,11 +146,11 @@ func (h *Handler) importCSV(w http.ResponseWriter, r *http.Request) {
 			Conflicts: make([]conflictDTO, 0, len(result.Conflicts)),
 		}
 		for _, p := range result.New {
-			resp.New = append(resp.New, toParamsDTO(p))
+			resp.New = append(resp.New, h.toPreviewDTO(r.Context(), p))
 		}
 		for _, c := range result.Conflicts {
 			resp.Conflicts = append(resp.Conflicts, conflictDTO{
-				Incoming: toParamsDTO(c.Incoming),
+				Incoming: h.toPreviewDTO(r.Context(), c.Incoming),
 				Existing: toTxResponse(c.Existing),
 			})
 		}
@@ -132,6 +158,8 @@ func (h *Handler) importCSV(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordMatches(r.Context(), result.Imported)
+
 	httputil.WriteJSON(w, http.StatusCreated, toSuccessResponse(result.Imported))
 }
 
@@ -153,15 +181,37 @@ func (h *Handler) confirmImport(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if !h.checkMerchants(w, r, req.Params) {
+		return
+	}
+
 	params := make([]transaction.CreateParams, 0, len(req.Params))
 	for _, p := range req.Params {
+		// Rows echoed back from the conflict response carry the status set by
+		// matching rules; anything else starts as a draft.
+		status := p.Status
+		if status == "" {
+			status = transaction.StatusDraft
+		}
+
+		// Older clients do not echo the normalized description back.
+		normalized := p.NormalizedDescription
+		if normalized == "" && p.RawDescription != "" {
+			normalized = matching.Normalize(p.Source, p.RawDescription)
+		}
+
 		params = append(params, transaction.CreateParams{
-			Amount:         p.Amount,
-			Type:           p.Type,
-			Status:         transaction.StatusDraft,
-			Description:    p.Description,
-			RawDescription: p.RawDescription,
-			Date:           p.Date,
+			Amount:                p.Amount,
+			Type:                  p.Type,
+			Status:                status,
+			Description:           p.Description,
+			RawDescription:        p.RawDescription,
+			NormalizedDescription: normalized,
+			Category:              p.Category,
+			Tags:                  p.Tags,
+			Source:                p.Source,
+			MerchantID:            p.MerchantID,
+			Date:                  p.Date,
 		})
 	}
 
@@ -172,9 +222,46 @@ func (h *Handler) confirmImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.recordMatches(r.Context(), txs)
+
 	httputil.WriteJSON(w, http.StatusCreated, toSuccessResponse(txs))
 }
 
+// checkMerchants writes a 422 response unless every merchant the rows link to
+// belongs to the user. The foreign key alone would accept another user's
+// merchant.
+func (h *Handler) checkMerchants(w http.ResponseWriter, r *http.Request, rows []createParamsDTO) bool {
+	checked := make(map[uuid.UUID]bool)
+
+	for _, p := range rows {
+		if p.MerchantID == nil || checked[*p.MerchantID] {
+			continue
+		}
+
+		if _, err := h.merchantSvc.Get(r.Context(), *p.MerchantID); err != nil {
+			if errors.Is(err, merchant.ErrNotFound) {
+				httputil.WriteError(w, http.StatusUnprocessableEntity, "UNKNOWN_MERCHANT", fmt.Sprintf("Unknown merchant %s.", *p.MerchantID))
+				return false
+			}
+			slog.Error("failed to get merchant", "id", *p.MerchantID, "error", err)
+			httputil.InternalError(w)
+			return false
+		}
+
+		checked[*p.MerchantID] = true
+	}
+
+	return true
+}
+
+// recordMatches updates mapping usage statistics for stored transactions.
+// The statistics are informational, so a failure is only logged.
+func (h *Handler) recordMatches(ctx context.Context, txs []*transaction.Transaction) {
+	if err := h.matchSvc.RecordImported(ctx, txs); err != nil {
+		slog.Warn("failed to record mapping matches", "error", err)
+	}
+}
+
 func toSuccessResponse(txs []*transaction.Transaction) importSuccessResponse {
 	responses := make([]transactionResponse, 0, len(txs))
 	for _, tx := range txs {
@@ -188,23 +275,78 @@ func toSuccessResponse(txs []*transaction.Transaction) importSuccessResponse {
 
 func toTxResponse(tx *transaction.Transaction) transactionResponse {
 	return transactionResponse{
-		ID:             tx.ID,
-		Amount:         tx.Amount,
-		Type:           tx.Type,
-		Status:         tx.Status,
-		Description:    tx.Description,
-		RawDescription: tx.RawDescription,
-		Date:           tx.Date,
-		CreatedAt:      tx.CreatedAt,
+		ID:                    tx.ID,
+		Amount:                tx.Amount,
+		Type:                  tx.Type,
+		Status:                tx.Status,
+		Description:           tx.Description,
+		RawDescription:        tx.RawDescription,
+		NormalizedDescription: tx.NormalizedDescription,
+		Category:              tx.Category,
+		Tags:                  nonNilTags(tx.Tags),
+		Source:                tx.Source,
+		MerchantID:            tx.MerchantID,
+		Date:                  tx.Date,
+		CreatedAt:             tx.CreatedAt,
 	}
 }
 
 func toParamsDTO(p transaction.CreateParams) createParamsDTO {
 	return createParamsDTO{
-		Amount:         p.Amount,
-		Type:           p.Type,
-		Description:    p.Description,
-		RawDescription: p.RawDescription,
-		Date:           p.Date,
+		Amount:                p.Amount,
+		Type:                  p.Type,
+		Description:           p.Description,
+		RawDescription:        p.RawDescription,
+		NormalizedDescription: p.NormalizedDescription,
+		Category:              p.Category,
+		Tags:                  nonNilTags(p.Tags),
+		Source:                p.Source,
+		MerchantID:            p.MerchantID,
+		Status:                p.Status,
+		Date:                  p.Date,
+	}
+}
+
+// toPreviewDTO is toParamsDTO plus classifier suggestions for the row.
+// Suggestions are optional, so a classifier failure is only logged.
+func (h *Handler) toPreviewDTO(ctx context.Context, p transaction.CreateParams) createParamsDTO {
+	dto := toParamsDTO(p)
+
+	prediction, err := h.matchSvc.Classify(ctx, matching.Candidate{
+		RawDescription:        p.RawDescription,
+		NormalizedDescription: p.NormalizedDescription,
+		Amount:                p.Amount,
+		Type:                  p.Type,
+		Date:                  p.Date,
+		Source:                p.Source,
+	}, previewSuggestions)
+	if err != nil {
+		slog.Warn("failed to classify import row", "error", err)
+		return dto
 	}
+
+	dto.Suggestions = &suggestionsDTO{
+		Descriptions: toSuggestionDTOs(prediction.Descriptions),
+		Categories:   toSuggestionDTOs(prediction.Categories),
+	}
+
+	return dto
+}
+
+func toSuggestionDTOs(s []matching.Suggestion) []suggestionDTO {
+	dtos := make([]suggestionDTO, len(s))
+	for i, v := range s {
+		dtos[i] = suggestionDTO{Value: v.Value, Confidence: v.Confidence}
+	}
+
+	return dtos
+}
+
+// nonNilTags makes empty tag lists serialise as [] rather than null.
+func nonNilTags(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
 }
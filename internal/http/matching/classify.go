@@ -0,0 +1,87 @@
+package matching
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/MrJamesThe3rd/finny/internal/httputil"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+const (
+	defaultSuggestions = 3
+	maxSuggestions     = 10
+)
+
+type suggestionResponse struct {
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+}
+
+type classifyResponse struct {
+	Descriptions []suggestionResponse `json:"descriptions"`
+	Categories   []suggestionResponse `json:"categories"`
+}
+
+func toClassifyResponse(p matching.Prediction) classifyResponse {
+	return classifyResponse{
+		Descriptions: toSuggestionResponses(p.Descriptions),
+		Categories:   toSuggestionResponses(p.Categories),
+	}
+}
+
+func toSuggestionResponses(s []matching.Suggestion) []suggestionResponse {
+	resp := make([]suggestionResponse, len(s))
+	for i, v := range s {
+		resp[i] = suggestionResponse{Value: v.Value, Confidence: v.Confidence}
+	}
+
+	return resp
+}
+
+func (h *Handler) classify(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	rawDesc := q.Get("raw_description")
+	if rawDesc == "" {
+		httputil.BadRequest(w, "The raw_description query parameter is required.")
+		return
+	}
+
+	cand := matching.Candidate{
+		RawDescription:        rawDesc,
+		NormalizedDescription: matching.Normalize(q.Get("source"), rawDesc),
+		Type:                  transaction.Type(q.Get("type")),
+		Source:                q.Get("source"),
+	}
+
+	if v := q.Get("amount"); v != "" {
+		amount, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || amount < 0 {
+			httputil.BadRequest(w, "amount must be a non-negative number of cents.")
+			return
+		}
+		cand.Amount = amount
+	}
+
+	n := defaultSuggestions
+	if v := q.Get("n"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > maxSuggestions {
+			httputil.BadRequest(w, "n must be between 1 and 10.")
+			return
+		}
+		n = parsed
+	}
+
+	prediction, err := h.svc.Classify(r.Context(), cand, n)
+	if err != nil {
+		slog.Error("failed to classify transaction", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toClassifyResponse(prediction))
+}
@@ -1,26 +1,52 @@
 package matching
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 
 	"github.com/MrJamesThe3rd/finny/internal/httputil"
 	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
 )
 
 type Handler struct {
-	svc *matching.Service
+	svc   *matching.Service
+	txSvc *transaction.Service
 }
 
-func NewHandler(svc *matching.Service) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(svc *matching.Service, txSvc *transaction.Service) *Handler {
+	return &Handler{svc: svc, txSvc: txSvc}
 }
 
 func (h *Handler) Routes(r chi.Router) {
 	r.Get("/suggest", h.suggest)
+	r.Get("/classify", h.classify)
 	r.Post("/", h.learn)
+
+	r.Get("/mappings", h.list)
+	r.Get("/mappings/export", h.export)
+	r.Post("/mappings/import", h.importMappings)
+	r.Post("/mappings/reapply/preview", h.previewReapply)
+	r.Post("/mappings/reapply", h.reapply)
+	r.Get("/mappings/report", h.report)
+	r.Get("/mappings/{id}", h.get)
+	r.Get("/mappings/{id}/transactions", h.listMatched)
+	r.Patch("/mappings/{id}", h.update)
+	r.Delete("/mappings/{id}", h.delete)
+
+	r.Get("/settings", h.getSettings)
+	r.Put("/settings", h.updateSettings)
+
+	r.Route("/rules", h.ruleRoutes)
 }
 
 type suggestResponse struct {
@@ -35,7 +61,8 @@ func (h *Handler) suggest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	preferred, err := h.svc.Suggest(r.Context(), rawDesc)
+	// source selects the bank's normalizer chain; it is optional.
+	preferred, err := h.svc.Suggest(r.Context(), r.URL.Query().Get("source"), rawDesc)
 	if err != nil {
 		slog.Error("failed to suggest description", "error", err)
 		httputil.InternalError(w)
@@ -71,3 +98,206 @@ func (h *Handler) learn(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusCreated)
 }
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	mappings, err := h.svc.List(r.Context(), matching.ListFilter{Search: r.URL.Query().Get("q")})
+	if err != nil {
+		slog.Error("failed to list mappings", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toMappingResponseList(mappings))
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid mapping ID.")
+		return
+	}
+
+	m, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, matching.ErrMappingNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to get mapping", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toMappingResponse(m))
+}
+
+type updateMappingRequest struct {
+	RawPattern           *string `json:"raw_pattern,omitempty"`
+	PreferredDescription *string `json:"preferred_description,omitempty"`
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid mapping ID.")
+		return
+	}
+
+	var req updateMappingRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+
+	m, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, matching.ErrMappingNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to get mapping", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	if req.RawPattern != nil {
+		m.RawPattern = strings.TrimSpace(*req.RawPattern)
+	}
+	if req.PreferredDescription != nil {
+		m.PreferredDescription = strings.TrimSpace(*req.PreferredDescription)
+	}
+
+	if m.RawPattern == "" || m.PreferredDescription == "" {
+		httputil.BadRequest(w, "raw_pattern and preferred_description must not be empty.")
+		return
+	}
+
+	if err := h.svc.Update(r.Context(), m); err != nil {
+		if errors.Is(err, matching.ErrMappingNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		if errors.Is(err, matching.ErrMappingExists) {
+			httputil.WriteError(w, http.StatusConflict, "MAPPING_EXISTS",
+				"Another mapping already uses this raw pattern.")
+			return
+		}
+		slog.Error("failed to update mapping", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toMappingResponse(m))
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid mapping ID.")
+		return
+	}
+
+	if err := h.svc.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, matching.ErrMappingNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to delete mapping", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) export(w http.ResponseWriter, r *http.Request) {
+	format := matching.FormatJSON
+	if f := r.URL.Query().Get("format"); f != "" {
+		format = matching.Format(f)
+	}
+
+	if format != matching.FormatJSON && format != matching.FormatCSV {
+		httputil.BadRequest(w, "The format query parameter must be json or csv.")
+		return
+	}
+
+	mappings, err := h.svc.List(r.Context(), matching.ListFilter{})
+	if err != nil {
+		slog.Error("failed to list mappings for export", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	// Encode into a buffer first so an encoding failure can still produce a JSON error.
+	var buf bytes.Buffer
+	if err := matching.Encode(&buf, format, mappings); err != nil {
+		slog.Error("failed to encode mappings", "format", format, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	contentType := "application/json"
+	if format == matching.FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="mappings_%s.%s"`, time.Now().Format("20060102"), format))
+
+	if _, err := buf.WriteTo(w); err != nil {
+		slog.Error("failed to write mappings export", "error", err)
+	}
+}
+
+func (h *Handler) importMappings(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		httputil.BadRequest(w, "Failed to parse multipart form.")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		httputil.BadRequest(w, "The file field is required.")
+		return
+	}
+	defer file.Close()
+
+	format := matching.Format(r.FormValue("format"))
+	if format == "" {
+		format = formatFromFilename(header.Filename)
+	}
+
+	if format != matching.FormatJSON && format != matching.FormatCSV {
+		httputil.BadRequest(w, "The format field must be json or csv.")
+		return
+	}
+
+	mappings, err := matching.Decode(file, format)
+	if err != nil {
+		httputil.BadRequest(w, fmt.Sprintf("Failed to parse %s file.", format))
+		return
+	}
+
+	n, err := h.svc.Import(r.Context(), mappings)
+	if err != nil {
+		if errors.Is(err, matching.ErrInvalidMapping) {
+			httputil.BadRequest(w, err.Error()+".")
+			return
+		}
+		slog.Error("failed to import mappings", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, importResponse{Imported: n})
+}
+
+// formatFromFilename infers the import format from the file extension, defaulting to JSON.
+func formatFromFilename(name string) matching.Format {
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		return matching.FormatCSV
+	}
+
+	return matching.FormatJSON
+}
@@ -0,0 +1,140 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/httputil"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+type reapplyPreviewRequest struct {
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+}
+
+type descriptionChangeResponse struct {
+	TransactionID      uuid.UUID `json:"transaction_id"`
+	Date               time.Time `json:"date"`
+	RawDescription     string    `json:"raw_description"`
+	CurrentDescription string    `json:"current_description"`
+	NewDescription     string    `json:"new_description"`
+	MappingID          uuid.UUID `json:"mapping_id"`
+	RawPattern         string    `json:"raw_pattern"`
+}
+
+type reapplyPreviewResponse struct {
+	Changes []descriptionChangeResponse `json:"changes"`
+}
+
+type reapplyRequest struct {
+	StartDate      *time.Time  `json:"start_date,omitempty"`
+	EndDate        *time.Time  `json:"end_date,omitempty"`
+	TransactionIDs []uuid.UUID `json:"transaction_ids" validate:"required,min=1"`
+}
+
+type reapplyResponse struct {
+	Updated int `json:"updated"`
+}
+
+// previewReapply is a dry run: it lists the description changes the current
+// mappings would make to transactions in the date range.
+func (h *Handler) previewReapply(w http.ResponseWriter, r *http.Request) {
+	// An empty body previews every transaction.
+	var req reapplyPreviewRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+
+	changes, err := h.reapplyChanges(r.Context(), req.StartDate, req.EndDate)
+	if err != nil {
+		slog.Error("failed to preview mapping re-apply", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := reapplyPreviewResponse{Changes: make([]descriptionChangeResponse, len(changes))}
+	for i, c := range changes {
+		resp.Changes[i] = descriptionChangeResponse{
+			TransactionID:      c.Transaction.ID,
+			Date:               c.Transaction.Date,
+			RawDescription:     c.Transaction.RawDescription,
+			CurrentDescription: c.Transaction.Description,
+			NewDescription:     c.Description,
+			MappingID:          c.Mapping.ID,
+			RawPattern:         c.Mapping.RawPattern,
+		}
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
+// reapply recomputes the preview for the date range and applies the changes for
+// the selected transactions in one DB transaction. Selected IDs that no longer
+// have a pending change are ignored, so a stale preview cannot write old values.
+func (h *Handler) reapply(w http.ResponseWriter, r *http.Request) {
+	var req reapplyRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+	if !httputil.Validate(w, req) {
+		return
+	}
+
+	changes, err := h.reapplyChanges(r.Context(), req.StartDate, req.EndDate)
+	if err != nil {
+		slog.Error("failed to compute mapping re-apply", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	selected := make(map[uuid.UUID]bool, len(req.TransactionIDs))
+	for _, id := range req.TransactionIDs {
+		selected[id] = true
+	}
+
+	descriptions := make(map[uuid.UUID]string)
+	applied := make([]matching.DescriptionChange, 0, len(req.TransactionIDs))
+
+	for _, c := range changes {
+		if selected[c.Transaction.ID] {
+			descriptions[c.Transaction.ID] = c.Description
+			applied = append(applied, c)
+		}
+	}
+
+	if err := h.txSvc.UpdateDescriptions(r.Context(), descriptions); err != nil {
+		if errors.Is(err, transaction.ErrNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to apply mapping re-apply", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	// Usage statistics are informational; the descriptions are already saved.
+	if err := h.svc.RecordReapplied(r.Context(), applied); err != nil {
+		slog.Warn("failed to record mapping matches", "error", err)
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, reapplyResponse{Updated: len(descriptions)})
+}
+
+func (h *Handler) reapplyChanges(ctx context.Context, start, end *time.Time) ([]matching.DescriptionChange, error) {
+	txs, err := h.txSvc.List(ctx, transaction.ListFilter{StartDate: start, EndDate: end})
+	if err != nil {
+		return nil, err
+	}
+
+	return h.svc.PreviewReapply(ctx, txs)
+}
@@ -0,0 +1,46 @@
+package matching
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+)
+
+type mappingResponse struct {
+	ID                   uuid.UUID  `json:"id"`
+	RawPattern           string     `json:"raw_pattern"`
+	PreferredDescription string     `json:"preferred_description"`
+	CreatedAt            time.Time  `json:"created_at"`
+	MatchCount           int        `json:"match_count"`
+	LastMatchedAt        *time.Time `json:"last_matched_at,omitempty"`
+	TransactionCount     int        `json:"transaction_count"`
+	DistinctDescriptions int        `json:"distinct_descriptions"`
+}
+
+type importResponse struct {
+	Imported int `json:"imported"`
+}
+
+func toMappingResponse(m *matching.Mapping) mappingResponse {
+	return mappingResponse{
+		ID:                   m.ID,
+		RawPattern:           m.RawPattern,
+		PreferredDescription: m.PreferredDescription,
+		CreatedAt:            m.CreatedAt,
+		MatchCount:           m.MatchCount,
+		LastMatchedAt:        m.LastMatchedAt,
+		TransactionCount:     m.TransactionCount,
+		DistinctDescriptions: m.DistinctDescriptions,
+	}
+}
+
+func toMappingResponseList(mappings []*matching.Mapping) []mappingResponse {
+	resp := make([]mappingResponse, len(mappings))
+	for i, m := range mappings {
+		resp[i] = toMappingResponse(m)
+	}
+
+	return resp
+}
@@ -0,0 +1,275 @@
+package matching
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/httputil"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// defaultRulePriority leaves room to slot rules before and after ones created without a priority.
+const defaultRulePriority = 100
+
+func (h *Handler) ruleRoutes(r chi.Router) {
+	r.Get("/", h.listRules)
+	r.Post("/", h.createRule)
+	r.Post("/run", h.runRules)
+	r.Get("/{id}", h.getRule)
+	r.Patch("/{id}", h.updateRule)
+	r.Delete("/{id}", h.deleteRule)
+}
+
+type ruleResponse struct {
+	ID         uuid.UUID           `json:"id"`
+	Name       string              `json:"name"`
+	Priority   int                 `json:"priority"`
+	Enabled    bool                `json:"enabled"`
+	Conditions matching.Conditions `json:"conditions"`
+	Actions    matching.Actions    `json:"actions"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+func toRuleResponse(r *matching.Rule) ruleResponse {
+	return ruleResponse{
+		ID:         r.ID,
+		Name:       r.Name,
+		Priority:   r.Priority,
+		Enabled:    r.Enabled,
+		Conditions: r.Conditions,
+		Actions:    r.Actions,
+		CreatedAt:  r.CreatedAt,
+		UpdatedAt:  r.UpdatedAt,
+	}
+}
+
+func (h *Handler) listRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.svc.ListRules(r.Context())
+	if err != nil {
+		slog.Error("failed to list rules", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := make([]ruleResponse, 0, len(rules))
+	for _, rule := range rules {
+		resp = append(resp, toRuleResponse(rule))
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) getRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid rule ID.")
+		return
+	}
+
+	rule, err := h.svc.GetRule(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, matching.ErrRuleNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to get rule", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toRuleResponse(rule))
+}
+
+type createRuleRequest struct {
+	Name       string              `json:"name"       validate:"required"`
+	Priority   *int                `json:"priority,omitempty"`
+	Enabled    *bool               `json:"enabled,omitempty"`
+	Conditions matching.Conditions `json:"conditions"`
+	Actions    matching.Actions    `json:"actions"`
+}
+
+func (h *Handler) createRule(w http.ResponseWriter, r *http.Request) {
+	var req createRuleRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+	if !httputil.Validate(w, req) {
+		return
+	}
+
+	rule := &matching.Rule{
+		Name:       req.Name,
+		Priority:   defaultRulePriority,
+		Enabled:    true,
+		Conditions: req.Conditions,
+		Actions:    req.Actions,
+	}
+
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+
+	if err := h.svc.CreateRule(r.Context(), rule); err != nil {
+		if errors.Is(err, matching.ErrInvalidRule) {
+			httputil.BadRequest(w, err.Error()+".")
+			return
+		}
+		slog.Error("failed to create rule", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusCreated, toRuleResponse(rule))
+}
+
+type updateRuleRequest struct {
+	Name       *string              `json:"name,omitempty"`
+	Priority   *int                 `json:"priority,omitempty"`
+	Enabled    *bool                `json:"enabled,omitempty"`
+	Conditions *matching.Conditions `json:"conditions,omitempty"`
+	Actions    *matching.Actions    `json:"actions,omitempty"`
+}
+
+func (h *Handler) updateRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid rule ID.")
+		return
+	}
+
+	var req updateRuleRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+
+	rule, err := h.svc.GetRule(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, matching.ErrRuleNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to get rule", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	// Conditions and actions are replaced as a whole, not merged field by field.
+	if req.Name != nil {
+		rule.Name = *req.Name
+	}
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.Conditions != nil {
+		rule.Conditions = *req.Conditions
+	}
+	if req.Actions != nil {
+		rule.Actions = *req.Actions
+	}
+
+	if err := h.svc.UpdateRule(r.Context(), rule); err != nil {
+		if errors.Is(err, matching.ErrRuleNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		if errors.Is(err, matching.ErrInvalidRule) {
+			httputil.BadRequest(w, err.Error()+".")
+			return
+		}
+		slog.Error("failed to update rule", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toRuleResponse(rule))
+}
+
+func (h *Handler) deleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid rule ID.")
+		return
+	}
+
+	if err := h.svc.DeleteRule(r.Context(), id); err != nil {
+		if errors.Is(err, matching.ErrRuleNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to delete rule", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type runRulesRequest struct {
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+}
+
+type runRulesResponse struct {
+	Updated int         `json:"updated"`
+	IDs     []uuid.UUID `json:"ids"`
+}
+
+// runRules re-evaluates the current rules against existing transactions in the
+// date range and saves those that change.
+func (h *Handler) runRules(w http.ResponseWriter, r *http.Request) {
+	// An empty body runs the rules over every transaction.
+	var req runRulesRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+
+	txs, err := h.txSvc.List(r.Context(), transaction.ListFilter{
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	})
+	if err != nil {
+		slog.Error("failed to list transactions for rule run", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	changes, err := h.svc.RunRules(r.Context(), txs)
+	if err != nil {
+		slog.Error("failed to run rules", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := runRulesResponse{IDs: make([]uuid.UUID, 0, len(changes))}
+
+	for _, c := range changes {
+		if err := h.txSvc.Update(r.Context(), c.After); err != nil {
+			slog.Error("failed to save rule changes", "id", c.After.ID, "error", err)
+			httputil.InternalError(w)
+			return
+		}
+
+		resp.IDs = append(resp.IDs, c.After.ID)
+	}
+
+	resp.Updated = len(resp.IDs)
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
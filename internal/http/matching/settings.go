@@ -0,0 +1,49 @@
+package matching
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/MrJamesThe3rd/finny/internal/httputil"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+)
+
+type settingsResponse struct {
+	AutoLearn bool `json:"auto_learn"`
+}
+
+type updateSettingsRequest struct {
+	AutoLearn *bool `json:"auto_learn" validate:"required"`
+}
+
+func (h *Handler) getSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.svc.Settings(r.Context())
+	if err != nil {
+		slog.Error("failed to get matching settings", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, settingsResponse{AutoLearn: settings.AutoLearn})
+}
+
+func (h *Handler) updateSettings(w http.ResponseWriter, r *http.Request) {
+	var req updateSettingsRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+	if !httputil.Validate(w, req) {
+		return
+	}
+
+	settings := &matching.Settings{AutoLearn: *req.AutoLearn}
+
+	if err := h.svc.UpdateSettings(r.Context(), settings); err != nil {
+		slog.Error("failed to update matching settings", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, settingsResponse{AutoLearn: settings.AutoLearn})
+}
@@ -0,0 +1,117 @@
+package matching
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/httputil"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// defaultStaleDays is how long a mapping may go without matching before the
+// report lists it as dead.
+const defaultStaleDays = 180
+
+type broadMappingResponse struct {
+	Mapping mappingResponse        `json:"mapping"`
+	Reasons []matching.BroadReason `json:"reasons"`
+}
+
+type reportResponse struct {
+	StaleDays         int                    `json:"stale_days"`
+	TotalTransactions int                    `json:"total_transactions"`
+	Dead              []mappingResponse      `json:"dead"`
+	OverBroad         []broadMappingResponse `json:"over_broad"`
+}
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request) {
+	staleDays := defaultStaleDays
+
+	if v := r.URL.Query().Get("stale_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			httputil.BadRequest(w, "The stale_days query parameter must be a positive integer.")
+			return
+		}
+
+		staleDays = n
+	}
+
+	report, err := h.svc.Report(r.Context(), time.Now().AddDate(0, 0, -staleDays))
+	if err != nil {
+		slog.Error("failed to build mapping report", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := reportResponse{
+		StaleDays:         staleDays,
+		TotalTransactions: report.TotalTransactions,
+		Dead:              toMappingResponseList(report.Dead),
+		OverBroad:         make([]broadMappingResponse, len(report.OverBroad)),
+	}
+
+	for i, b := range report.OverBroad {
+		resp.OverBroad[i] = broadMappingResponse{Mapping: toMappingResponse(b.Mapping), Reasons: b.Reasons}
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
+type matchedTransactionResponse struct {
+	TransactionID  uuid.UUID        `json:"transaction_id"`
+	Date           time.Time        `json:"date"`
+	Amount         int64            `json:"amount"`
+	Type           transaction.Type `json:"type"`
+	RawDescription string           `json:"raw_description"`
+	Description    string           `json:"description"`
+	MatchedAt      time.Time        `json:"matched_at"`
+}
+
+func (h *Handler) listMatched(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid mapping ID.")
+		return
+	}
+
+	// Look the mapping up first so an unknown ID is a 404 rather than an empty list.
+	if _, err := h.svc.Get(r.Context(), id); err != nil {
+		if errors.Is(err, matching.ErrMappingNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to get mapping", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	matched, err := h.svc.ListMatched(r.Context(), id)
+	if err != nil {
+		slog.Error("failed to list matched transactions", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := make([]matchedTransactionResponse, len(matched))
+	for i, m := range matched {
+		resp[i] = matchedTransactionResponse{
+			TransactionID:  m.TransactionID,
+			Date:           m.Date,
+			Amount:         m.Amount,
+			Type:           m.Type,
+			RawDescription: m.RawDescription,
+			Description:    m.Description,
+			MatchedAt:      m.MatchedAt,
+		}
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,296 @@
+package merchant
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/httputil"
+	"github.com/MrJamesThe3rd/finny/internal/merchant"
+)
+
+type Handler struct {
+	svc *merchant.Service
+}
+
+func NewHandler(svc *merchant.Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Post("/", h.create)
+	r.Get("/report", h.report)
+	r.Get("/{id}", h.get)
+	r.Patch("/{id}", h.update)
+	r.Delete("/{id}", h.delete)
+}
+
+type merchantResponse struct {
+	ID              uuid.UUID `json:"id"`
+	Name            string    `json:"name"`
+	NIF             string    `json:"nif,omitempty"`
+	DefaultCategory string    `json:"default_category,omitempty"`
+	Website         string    `json:"website,omitempty"`
+	LogoURL         string    `json:"logo_url,omitempty"`
+	RequiresInvoice bool      `json:"requires_invoice"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func toResponse(m *merchant.Merchant) merchantResponse {
+	return merchantResponse{
+		ID:              m.ID,
+		Name:            m.Name,
+		NIF:             m.NIF,
+		DefaultCategory: m.DefaultCategory,
+		Website:         m.Website,
+		LogoURL:         m.LogoURL,
+		RequiresInvoice: m.RequiresInvoice,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
+	}
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	merchants, err := h.svc.List(r.Context(), merchant.ListFilter{Search: r.URL.Query().Get("q")})
+	if err != nil {
+		slog.Error("failed to list merchants", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := make([]merchantResponse, len(merchants))
+	for i, m := range merchants {
+		resp[i] = toResponse(m)
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid merchant ID.")
+		return
+	}
+
+	m, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, merchant.ErrNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to get merchant", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toResponse(m))
+}
+
+type createMerchantRequest struct {
+	Name            string `json:"name"     validate:"required"`
+	NIF             string `json:"nif"`
+	DefaultCategory string `json:"default_category"`
+	Website         string `json:"website"  validate:"omitempty,url"`
+	LogoURL         string `json:"logo_url" validate:"omitempty,url"`
+	RequiresInvoice *bool  `json:"requires_invoice,omitempty"`
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	var req createMerchantRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+	if !httputil.Validate(w, req) {
+		return
+	}
+
+	m := &merchant.Merchant{
+		Name:            req.Name,
+		NIF:             req.NIF,
+		DefaultCategory: req.DefaultCategory,
+		Website:         req.Website,
+		LogoURL:         req.LogoURL,
+		RequiresInvoice: true,
+	}
+
+	if req.RequiresInvoice != nil {
+		m.RequiresInvoice = *req.RequiresInvoice
+	}
+
+	if err := h.svc.Create(r.Context(), m); err != nil {
+		h.writeSaveError(w, err, "failed to create merchant", uuid.Nil)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusCreated, toResponse(m))
+}
+
+type updateMerchantRequest struct {
+	Name            *string `json:"name,omitempty"`
+	NIF             *string `json:"nif,omitempty"`
+	DefaultCategory *string `json:"default_category,omitempty"`
+	Website         *string `json:"website,omitempty"  validate:"omitempty,url"`
+	LogoURL         *string `json:"logo_url,omitempty" validate:"omitempty,url"`
+	RequiresInvoice *bool   `json:"requires_invoice,omitempty"`
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid merchant ID.")
+		return
+	}
+
+	var req updateMerchantRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+	if !httputil.Validate(w, req) {
+		return
+	}
+
+	m, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, merchant.ErrNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to get merchant", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	if req.Name != nil {
+		m.Name = *req.Name
+	}
+	if req.NIF != nil {
+		m.NIF = *req.NIF
+	}
+	if req.DefaultCategory != nil {
+		m.DefaultCategory = *req.DefaultCategory
+	}
+	if req.Website != nil {
+		m.Website = *req.Website
+	}
+	if req.LogoURL != nil {
+		m.LogoURL = *req.LogoURL
+	}
+	if req.RequiresInvoice != nil {
+		m.RequiresInvoice = *req.RequiresInvoice
+	}
+
+	if err := h.svc.Update(r.Context(), m); err != nil {
+		h.writeSaveError(w, err, "failed to update merchant", id)
+		return
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, toResponse(m))
+}
+
+// writeSaveError maps the errors of Create and Update to responses.
+func (h *Handler) writeSaveError(w http.ResponseWriter, err error, msg string, id uuid.UUID) {
+	switch {
+	case errors.Is(err, merchant.ErrInvalidMerchant):
+		httputil.BadRequest(w, err.Error()+".")
+	case errors.Is(err, merchant.ErrNotFound):
+		httputil.NotFound(w)
+	case errors.Is(err, merchant.ErrNameTaken):
+		httputil.WriteError(w, http.StatusConflict, "MERCHANT_EXISTS", "Another merchant already has this name.")
+	default:
+		slog.Error(msg, "id", id, "error", err)
+		httputil.InternalError(w)
+	}
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid merchant ID.")
+		return
+	}
+
+	if err := h.svc.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, merchant.ErrNotFound) {
+			httputil.NotFound(w)
+			return
+		}
+		slog.Error("failed to delete merchant", "id", id, "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type summaryResponse struct {
+	MerchantID   uuid.UUID `json:"merchant_id"`
+	Name         string    `json:"name"`
+	Transactions int       `json:"transactions"`
+	Expenses     int64     `json:"expenses"`
+	Income       int64     `json:"income"`
+}
+
+// report sums transactions per merchant. start_date and end_date (YYYY-MM-DD)
+// are optional and inclusive.
+func (h *Handler) report(w http.ResponseWriter, r *http.Request) {
+	start, err := parseDate(r, "start_date")
+	if err != nil {
+		httputil.BadRequest(w, "The start_date query parameter must be a date (YYYY-MM-DD).")
+		return
+	}
+
+	end, err := parseDate(r, "end_date")
+	if err != nil {
+		httputil.BadRequest(w, "The end_date query parameter must be a date (YYYY-MM-DD).")
+		return
+	}
+
+	if end != nil {
+		// Include the whole end day.
+		*end = end.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	summaries, err := h.svc.Report(r.Context(), merchant.ReportFilter{StartDate: start, EndDate: end})
+	if err != nil {
+		slog.Error("failed to build merchant report", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := make([]summaryResponse, len(summaries))
+	for i, s := range summaries {
+		resp[i] = summaryResponse{
+			MerchantID:   s.MerchantID,
+			Name:         s.Name,
+			Transactions: s.Transactions,
+			Expenses:     s.Expenses,
+			Income:       s.Income,
+		}
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
+// parseDate reads an optional YYYY-MM-DD query parameter. Returns nil if it is absent.
+func parseDate(r *http.Request, name string) (*time.Time, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
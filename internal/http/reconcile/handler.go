@@ -0,0 +1,283 @@
+package reconcile
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/httputil"
+	"github.com/MrJamesThe3rd/finny/internal/reconcile"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// defaultCandidates is how many transactions are proposed per document when
+// the request does not say.
+const defaultCandidates = 3
+
+type Handler struct {
+	svc *reconcile.Service
+}
+
+func NewHandler(svc *reconcile.Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/suggestions", h.suggestions)
+	r.Post("/accept", h.accept)
+	r.Post("/documents/{id}/qr", h.applyQRCode)
+}
+
+type documentResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Filename   string     `json:"filename"`
+	Total      *int64     `json:"total,omitempty"`
+	VAT        *int64     `json:"vat,omitempty"`
+	Date       *time.Time `json:"date,omitempty"`
+	Number     string     `json:"number,omitempty"`
+	IssuerName string     `json:"issuer_name,omitempty"`
+	IssuerNIF  string     `json:"issuer_nif,omitempty"`
+	ATCUD      string     `json:"atcud,omitempty"`
+}
+
+func toDocumentResponse(doc *document.Document) documentResponse {
+	return documentResponse{
+		ID:         doc.ID,
+		Filename:   doc.Filename,
+		Total:      doc.Invoice.Total,
+		VAT:        doc.Invoice.VAT,
+		Date:       doc.Invoice.Date,
+		Number:     doc.Invoice.Number,
+		IssuerName: doc.Invoice.IssuerName,
+		IssuerNIF:  doc.Invoice.IssuerNIF,
+		ATCUD:      doc.Invoice.ATCUD,
+	}
+}
+
+type candidateResponse struct {
+	TransactionID uuid.UUID        `json:"transaction_id"`
+	Amount        int64            `json:"amount"`
+	Type          transaction.Type `json:"type"`
+	Description   string           `json:"description"`
+	MerchantName  string           `json:"merchant_name,omitempty"`
+	Date          time.Time        `json:"date"`
+	Score         float64          `json:"score"`
+}
+
+type suggestionResponse struct {
+	Document   documentResponse    `json:"document"`
+	Candidates []candidateResponse `json:"candidates"`
+}
+
+func toCandidateResponses(candidates []reconcile.Candidate) []candidateResponse {
+	resp := make([]candidateResponse, len(candidates))
+
+	for i, c := range candidates {
+		resp[i] = candidateResponse{
+			TransactionID: c.Transaction.ID,
+			Amount:        c.Transaction.Amount,
+			Type:          c.Transaction.Type,
+			Description:   c.Transaction.Description,
+			MerchantName:  c.Transaction.MerchantName,
+			Date:          c.Transaction.Date,
+			Score:         c.Score,
+		}
+	}
+
+	return resp
+}
+
+func toSuggestionResponse(s reconcile.Suggestion) suggestionResponse {
+	return suggestionResponse{
+		Document:   toDocumentResponse(&s.Document),
+		Candidates: toCandidateResponses(s.Candidates),
+	}
+}
+
+// parseLimit reads the limit query parameter, which caps the candidates per
+// document. It writes a 400 and returns false when the value is invalid.
+func parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return defaultCandidates, true
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		httputil.BadRequest(w, "The limit query parameter must be a positive integer.")
+		return 0, false
+	}
+
+	return n, true
+}
+
+// suggestions proposes pending_invoice transactions for the inbox documents
+// whose invoice total is known.
+func (h *Handler) suggestions(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+
+	suggestions, err := h.svc.Suggest(r.Context(), limit)
+	if err != nil {
+		slog.Error("failed to suggest invoice matches", "error", err)
+		httputil.InternalError(w)
+		return
+	}
+
+	resp := make([]suggestionResponse, len(suggestions))
+	for i, s := range suggestions {
+		resp[i] = toSuggestionResponse(s)
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
+type matchRequest struct {
+	DocumentID    uuid.UUID `json:"document_id"    validate:"required"`
+	TransactionID uuid.UUID `json:"transaction_id" validate:"required"`
+}
+
+type acceptRequest struct {
+	Matches []matchRequest `json:"matches" validate:"required,min=1,dive"`
+}
+
+type resultResponse struct {
+	DocumentID    uuid.UUID `json:"document_id"`
+	TransactionID uuid.UUID `json:"transaction_id"`
+	Accepted      bool      `json:"accepted"`
+	Error         string    `json:"error,omitempty"`
+}
+
+type acceptResponse struct {
+	Accepted int              `json:"accepted"`
+	Results  []resultResponse `json:"results"`
+}
+
+// accept attaches each document to its transaction as the invoice. Matches
+// are applied independently; the response reports each outcome.
+func (h *Handler) accept(w http.ResponseWriter, r *http.Request) {
+	var req acceptRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+	if !httputil.Validate(w, req) {
+		return
+	}
+
+	matches := make([]reconcile.Match, len(req.Matches))
+	for i, m := range req.Matches {
+		matches[i] = reconcile.Match{DocumentID: m.DocumentID, TransactionID: m.TransactionID}
+	}
+
+	resp := acceptResponse{Results: make([]resultResponse, 0, len(matches))}
+
+	for _, res := range h.svc.Accept(r.Context(), matches) {
+		out := resultResponse{
+			DocumentID:    res.DocumentID,
+			TransactionID: res.TransactionID,
+			Accepted:      res.Err == nil,
+		}
+
+		switch {
+		case res.Err == nil:
+			resp.Accepted++
+		case errors.Is(res.Err, transaction.ErrNotFound):
+			out.Error = "Transaction or document not found."
+		case errors.Is(res.Err, transaction.ErrDocumentAlreadyAttached):
+			out.Error = "The document is already attached to the transaction."
+		default:
+			slog.Error("failed to accept invoice match", "document_id", res.DocumentID,
+				"transaction_id", res.TransactionID, "error", res.Err)
+			out.Error = "Internal error."
+		}
+
+		resp.Results = append(resp.Results, out)
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
+
+type qrCodeRequest struct {
+	Payload string `json:"payload" validate:"required"`
+}
+
+type checkResponse struct {
+	TransactionID uuid.UUID `json:"transaction_id"`
+	Amount        int64     `json:"amount"`
+	Date          time.Time `json:"date"`
+	AmountMatches bool      `json:"amount_matches"`
+	DateMatches   bool      `json:"date_matches"`
+}
+
+type reviewResponse struct {
+	Document   documentResponse    `json:"document"`
+	Checks     []checkResponse     `json:"checks"`
+	Candidates []candidateResponse `json:"candidates"`
+}
+
+// applyQRCode stores the fields of the document's invoice QR code, then checks
+// the transactions the document is attached to against them or, if there are
+// none, proposes transactions for it. The payload is the text the QR code
+// decodes to.
+func (h *Handler) applyQRCode(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, "Invalid document ID.")
+		return
+	}
+
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+
+	var req qrCodeRequest
+	if err := httputil.DecodeJSON(r, &req); err != nil {
+		httputil.BadRequest(w, "Invalid request body.")
+		return
+	}
+	if !httputil.Validate(w, req) {
+		return
+	}
+
+	review, err := h.svc.ApplyQRCode(r.Context(), id, req.Payload, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, document.ErrInvalidQRCode):
+			httputil.WriteError(w, http.StatusBadRequest, "INVALID_QR_CODE", err.Error())
+		case errors.Is(err, document.ErrDocumentNotFound):
+			httputil.NotFound(w)
+		default:
+			slog.Error("failed to apply invoice QR code", "document_id", id, "error", err)
+			httputil.InternalError(w)
+		}
+		return
+	}
+
+	resp := reviewResponse{
+		Document:   toDocumentResponse(review.Document),
+		Checks:     make([]checkResponse, len(review.Checks)),
+		Candidates: toCandidateResponses(review.Candidates),
+	}
+
+	for i, c := range review.Checks {
+		resp.Checks[i] = checkResponse{
+			TransactionID: c.Transaction.ID,
+			Amount:        c.Transaction.Amount,
+			Date:          c.Transaction.Date,
+			AmountMatches: c.AmountMatches,
+			DateMatches:   c.DateMatches,
+		}
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
+}
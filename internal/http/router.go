@@ -12,7 +12,9 @@ import (
 	"github.com/MrJamesThe3rd/finny/internal/http/export"
 	"github.com/MrJamesThe3rd/finny/internal/http/importcsv"
 	"github.com/MrJamesThe3rd/finny/internal/http/matching"
+	merchantHandler "github.com/MrJamesThe3rd/finny/internal/http/merchant"
 	finnyMiddleware "github.com/MrJamesThe3rd/finny/internal/http/middleware"
+	reconcileHandler "github.com/MrJamesThe3rd/finny/internal/http/reconcile"
 	"github.com/MrJamesThe3rd/finny/internal/http/transaction"
 )
 
@@ -29,6 +31,8 @@ func New(
 	matchingV1 *matching.Handler,
 	exportV1 *export.Handler,
 	documentV1 *documentHandler.Handler,
+	merchantV1 *merchantHandler.Handler,
+	reconcileV1 *reconcileHandler.Handler,
 	authV1 *authHandler.Handler,
 	cfg Config,
 ) http.Handler {
@@ -61,9 +65,21 @@ func New(
 			})
 
 			r.Route("/transactions", func(r chi.Router) {
-				r.Use(middleware.AllowContentType("application/json"))
-				transactionsV1.Routes(r)
+				// Document routes take multipart uploads, so only the
+				// transaction routes themselves are JSON-only.
+				r.Group(func(r chi.Router) {
+					r.Use(middleware.AllowContentType("application/json"))
+					transactionsV1.Routes(r)
+				})
 				r.Route("/{id}/document", documentV1.TransactionDocumentRoutes)
+				r.Route("/{id}/documents", documentV1.TransactionDocumentsRoutes)
+			})
+
+			r.Route("/documents", documentV1.InboxRoutes)
+
+			r.Route("/reconcile", func(r chi.Router) {
+				r.Use(middleware.AllowContentType("application/json"))
+				reconcileV1.Routes(r)
 			})
 
 			r.Route("/import", importV1.Routes)
@@ -74,6 +90,11 @@ func New(
 
 			r.Route("/export", exportV1.Routes)
 
+			r.Route("/merchants", func(r chi.Router) {
+				r.Use(middleware.AllowContentType("application/json"))
+				merchantV1.Routes(r)
+			})
+
 			r.Route("/backends", func(r chi.Router) {
 				r.Use(middleware.AllowContentType("application/json"))
 				documentV1.BackendRoutes(r)
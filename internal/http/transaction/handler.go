@@ -10,15 +10,19 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/MrJamesThe3rd/finny/internal/httputil"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/merchant"
 	"github.com/MrJamesThe3rd/finny/internal/transaction"
 )
 
 type Handler struct {
-	svc *transaction.Service
+	svc         *transaction.Service
+	matchSvc    *matching.Service
+	merchantSvc *merchant.Service
 }
 
-func NewHandler(svc *transaction.Service) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(svc *transaction.Service, matchSvc *matching.Service, merchantSvc *merchant.Service) *Handler {
+	return &Handler{svc: svc, matchSvc: matchSvc, merchantSvc: merchantSvc}
 }
 
 func (h *Handler) Routes(r chi.Router) {
@@ -34,6 +38,9 @@ type createTransactionRequest struct {
 	Amount      int64            `json:"amount"      validate:"required,ne=0"`
 	Type        transaction.Type `json:"type"        validate:"required,oneof=income expense"`
 	Description string           `json:"description" validate:"required"`
+	Category    string           `json:"category"`
+	Tags        []string         `json:"tags"`
+	MerchantID  *uuid.UUID       `json:"merchant_id,omitempty"`
 	Date        time.Time        `json:"date"        validate:"required"`
 }
 
@@ -47,11 +54,23 @@ func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var merchantName string
+	if req.MerchantID != nil {
+		m, ok := h.getMerchant(w, r, *req.MerchantID)
+		if !ok {
+			return
+		}
+		merchantName = m.Name
+	}
+
 	tx, err := h.svc.Create(r.Context(), transaction.CreateParams{
 		Amount:      req.Amount,
 		Type:        req.Type,
 		Status:      transaction.StatusComplete,
 		Description: req.Description,
+		Category:    req.Category,
+		Tags:        req.Tags,
+		MerchantID:  req.MerchantID,
 		Date:        req.Date,
 	})
 	if err != nil {
@@ -60,6 +79,8 @@ func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tx.MerchantName = merchantName
+
 	httputil.WriteJSON(w, http.StatusCreated, toResponse(tx))
 }
 
@@ -139,7 +160,11 @@ type updateTransactionRequest struct {
 	Amount      *int64            `json:"amount,omitempty"`
 	Type        *transaction.Type `json:"type,omitempty"`
 	Date        *time.Time        `json:"date,omitempty"`
-	NoInvoice   *bool             `json:"no_invoice,omitempty"`
+	Category    *string           `json:"category,omitempty"`
+	Tags        *[]string         `json:"tags,omitempty"`
+	// MerchantID links a merchant; an empty string unlinks it.
+	MerchantID *string `json:"merchant_id,omitempty"`
+	NoInvoice  *bool   `json:"no_invoice,omitempty"`
 }
 
 func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
@@ -166,6 +191,8 @@ func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	oldDescription := tx.Description
+
 	if req.Description != nil {
 		tx.Description = *req.Description
 	}
@@ -178,13 +205,37 @@ func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
 	if req.Date != nil {
 		tx.Date = *req.Date
 	}
+	if req.Category != nil {
+		tx.Category = *req.Category
+	}
+	if req.Tags != nil {
+		tx.Tags = *req.Tags
+	}
+	if req.MerchantID != nil {
+		if *req.MerchantID == "" {
+			tx.MerchantID = nil
+			tx.MerchantName = ""
+		} else {
+			merchantID, err := uuid.Parse(*req.MerchantID)
+			if err != nil {
+				httputil.BadRequest(w, "Invalid merchant ID.")
+				return
+			}
+			m, ok := h.getMerchant(w, r, merchantID)
+			if !ok {
+				return
+			}
+			tx.MerchantID = &m.ID
+			tx.MerchantName = m.Name
+		}
+	}
 
 	// Auto-infer status from current state.
 	noInvoice := req.NoInvoice != nil && *req.NoInvoice
 	switch {
 	case noInvoice:
 		tx.Status = transaction.StatusNoInvoice
-	case tx.Description != "" && tx.DocumentID != nil:
+	case tx.Description != "" && tx.HasInvoice():
 		tx.Status = transaction.StatusComplete
 	case tx.Description != "":
 		tx.Status = transaction.StatusPendingInvoice
@@ -198,7 +249,21 @@ func (h *Handler) update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	httputil.WriteJSON(w, http.StatusOK, toResponse(tx))
+	resp := updateTransactionResponse{transactionResponse: toResponse(tx)}
+
+	// Learning is best effort: the update has already been saved.
+	proposal, err := h.matchSvc.LearnFromEdit(r.Context(), tx.Source, tx.RawDescription, oldDescription, tx.Description)
+	if err != nil {
+		slog.Warn("failed to learn mapping from edit", "id", id, "error", err)
+	} else if proposal != nil {
+		resp.MappingProposal = &mappingProposalResponse{
+			RawPattern:           proposal.RawPattern,
+			PreferredDescription: proposal.PreferredDescription,
+			Created:              proposal.Created,
+		}
+	}
+
+	httputil.WriteJSON(w, http.StatusOK, resp)
 }
 
 type updateStatusRequest struct {
@@ -234,3 +299,20 @@ func (h *Handler) updateStatus(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// getMerchant loads the merchant a request links to, writing a 400 response if
+// it does not belong to the user. The foreign key alone would accept another
+// user's merchant.
+func (h *Handler) getMerchant(w http.ResponseWriter, r *http.Request, id uuid.UUID) (*merchant.Merchant, bool) {
+	m, err := h.merchantSvc.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, merchant.ErrNotFound) {
+			httputil.BadRequest(w, "Unknown merchant.")
+			return nil, false
+		}
+		slog.Error("failed to get merchant", "id", id, "error", err)
+		httputil.InternalError(w)
+		return nil, false
+	}
+
+	return m, true
+}
@@ -0,0 +1,92 @@
+package transaction_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	transactionHandler "github.com/MrJamesThe3rd/finny/internal/http/transaction"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+func TestUpdate_InfersStatus(t *testing.T) {
+	docID := uuid.New()
+
+	tests := []struct {
+		name       string
+		document   *transaction.Document
+		wantStatus transaction.Status
+	}{
+		{
+			name:       "invoice attached",
+			document:   &transaction.Document{ID: docID, Role: transaction.RoleInvoice},
+			wantStatus: transaction.StatusComplete,
+		},
+		{
+			name:       "only a receipt attached",
+			document:   &transaction.Document{ID: docID, Role: transaction.RoleReceipt},
+			wantStatus: transaction.StatusPendingInvoice,
+		},
+		{
+			name:       "nothing attached",
+			wantStatus: transaction.StatusPendingInvoice,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := transaction.NewMockRepository(ctrl)
+
+			tx := &transaction.Transaction{
+				ID:       uuid.New(),
+				Amount:   -1250,
+				Type:     transaction.TypeExpense,
+				Status:   transaction.StatusDraft,
+				Document: tt.document,
+			}
+			if tt.document != nil {
+				tx.DocumentID = &tt.document.ID
+				tx.DocumentCount = 1
+			}
+
+			var saved transaction.Status
+			repo.EXPECT().GetTransaction(gomock.Any(), tx.ID).Return(tx, nil)
+			repo.EXPECT().UpdateTransaction(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, tx *transaction.Transaction) error {
+					saved = tx.Status
+					return nil
+				})
+
+			// A manual transaction has no raw description, so nothing is learned
+			// and the matching service is never consulted.
+			h := transactionHandler.NewHandler(transaction.NewService(repo), matching.NewService(nil, nil), nil)
+			r := chi.NewRouter()
+			r.Route("/transactions", h.Routes)
+
+			req := httptest.NewRequest(http.MethodPatch, "/transactions/"+tx.ID.String(), strings.NewReader(`{"description": "Groceries"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.wantStatus, saved)
+
+			var resp struct {
+				Status transaction.Status `json:"status"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantStatus, resp.Status)
+		})
+	}
+}
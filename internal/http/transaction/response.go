@@ -9,17 +9,38 @@ import (
 )
 
 type transactionResponse struct {
-	ID             uuid.UUID          `json:"id"`
-	Amount         int64              `json:"amount"`
-	Type           transaction.Type   `json:"type"`
-	Status         transaction.Status `json:"status"`
-	Description    string             `json:"description"`
-	RawDescription string             `json:"raw_description,omitempty"`
-	Date           time.Time          `json:"date"`
-	DocumentID     *uuid.UUID         `json:"document_id,omitempty"`
-	Document       *documentResponse  `json:"document,omitempty"`
-	CreatedAt      time.Time          `json:"created_at"`
-	UpdatedAt      *time.Time         `json:"updated_at,omitempty"`
+	ID                    uuid.UUID          `json:"id"`
+	Amount                int64              `json:"amount"`
+	Type                  transaction.Type   `json:"type"`
+	Status                transaction.Status `json:"status"`
+	Description           string             `json:"description"`
+	RawDescription        string             `json:"raw_description,omitempty"`
+	NormalizedDescription string             `json:"normalized_description,omitempty"`
+	Category              string             `json:"category,omitempty"`
+	Tags                  []string           `json:"tags"`
+	Source                string             `json:"source,omitempty"`
+	Date                  time.Time          `json:"date"`
+	DocumentID            *uuid.UUID         `json:"document_id,omitempty"`
+	Document              *documentResponse  `json:"document,omitempty"`
+	DocumentCount         int                `json:"document_count"`
+	MerchantID            *uuid.UUID         `json:"merchant_id,omitempty"`
+	MerchantName          string             `json:"merchant_name,omitempty"`
+	CreatedAt             time.Time          `json:"created_at"`
+	UpdatedAt             *time.Time         `json:"updated_at,omitempty"`
+}
+
+// updateTransactionResponse is the updated transaction plus the mapping learned
+// from a description change, if any. When Created is false the client can
+// accept the proposal with POST /matching.
+type updateTransactionResponse struct {
+	transactionResponse
+	MappingProposal *mappingProposalResponse `json:"mapping_proposal,omitempty"`
+}
+
+type mappingProposalResponse struct {
+	RawPattern           string `json:"raw_pattern"`
+	PreferredDescription string `json:"preferred_description"`
+	Created              bool   `json:"created"`
 }
 
 type documentResponse struct {
@@ -30,16 +51,27 @@ type documentResponse struct {
 
 func toResponse(tx *transaction.Transaction) transactionResponse {
 	resp := transactionResponse{
-		ID:             tx.ID,
-		Amount:         tx.Amount,
-		Type:           tx.Type,
-		Status:         tx.Status,
-		Description:    tx.Description,
-		RawDescription: tx.RawDescription,
-		Date:           tx.Date,
-		DocumentID:     tx.DocumentID,
-		CreatedAt:      tx.CreatedAt,
-		UpdatedAt:      tx.UpdatedAt,
+		ID:                    tx.ID,
+		Amount:                tx.Amount,
+		Type:                  tx.Type,
+		Status:                tx.Status,
+		Description:           tx.Description,
+		RawDescription:        tx.RawDescription,
+		NormalizedDescription: tx.NormalizedDescription,
+		Category:              tx.Category,
+		Tags:                  tx.Tags,
+		Source:                tx.Source,
+		Date:                  tx.Date,
+		DocumentID:            tx.DocumentID,
+		DocumentCount:         tx.DocumentCount,
+		MerchantID:            tx.MerchantID,
+		MerchantName:          tx.MerchantName,
+		CreatedAt:             tx.CreatedAt,
+		UpdatedAt:             tx.UpdatedAt,
+	}
+
+	if resp.Tags == nil {
+		resp.Tags = []string{}
 	}
 
 	if tx.Document != nil {
@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/MrJamesThe3rd/finny/internal/importer/cgd"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
 	"github.com/MrJamesThe3rd/finny/internal/transaction"
 )
 
@@ -28,5 +29,15 @@ func (s *Service) Import(bank Bank, r io.Reader) ([]transaction.CreateParams, er
 		return nil, fmt.Errorf("unknown bank: %s", bank)
 	}
 
-	return importer.Parse(r)
+	params, err := importer.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range params {
+		params[i].Source = string(bank)
+		params[i].NormalizedDescription = matching.Normalize(string(bank), params[i].RawDescription)
+	}
+
+	return params, nil
 }
@@ -0,0 +1,189 @@
+package matching
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// Example is a reviewed transaction the classifier learns from.
+type Example struct {
+	Text        string // normalized description, or raw if not normalized
+	Amount      int64
+	Type        transaction.Type
+	Description string
+	Category    string
+}
+
+// Suggestion is a predicted value with the classifier's confidence in [0, 1].
+type Suggestion struct {
+	Value      string
+	Confidence float64
+}
+
+// Prediction holds the most likely descriptions and categories, best first.
+type Prediction struct {
+	Descriptions []Suggestion
+	Categories   []Suggestion
+}
+
+// Classifier is a naive Bayes model over description tokens, amount bucket and
+// type, trained on the user's own history. It needs no network access.
+type Classifier struct {
+	descriptions *naiveBayes
+	categories   *naiveBayes
+}
+
+// Train builds a classifier from examples. Examples without a description or
+// category only train the other model.
+func Train(examples []Example) *Classifier {
+	c := &Classifier{descriptions: newNaiveBayes(), categories: newNaiveBayes()}
+
+	for _, e := range examples {
+		f := features(e.Text, e.Amount, e.Type)
+
+		if e.Description != "" {
+			c.descriptions.add(e.Description, f)
+		}
+
+		if e.Category != "" {
+			c.categories.add(e.Category, f)
+		}
+	}
+
+	return c
+}
+
+// Predict returns up to n suggestions per field for the candidate. Fields are
+// empty when none of the candidate's words were seen during training.
+func (c *Classifier) Predict(cand Candidate, n int) Prediction {
+	text := cand.NormalizedDescription
+	if text == "" {
+		text = cand.RawDescription
+	}
+
+	f := features(text, cand.Amount, cand.Type)
+
+	return Prediction{
+		Descriptions: c.descriptions.predict(f, n),
+		Categories:   c.categories.predict(f, n),
+	}
+}
+
+// amountBuckets are the upper bounds, in cents, of the amount ranges used as a feature.
+var amountBuckets = []int64{500, 1000, 2000, 5000, 10000, 20000, 50000, 100000}
+
+// features turns a transaction into the tokens the model counts: the words of
+// its description plus its type and amount range.
+func features(text string, amount int64, typ transaction.Type) []string {
+	words := strings.FieldsFunc(NormalizePattern(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	f := make([]string, 0, len(words)+2)
+
+	for _, w := range words {
+		if len(w) < 2 || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+
+		f = append(f, "w:"+w)
+	}
+
+	bucket, _ := slices.BinarySearch(amountBuckets, amount)
+
+	return append(f, "type:"+string(typ), "amount:"+strconv.Itoa(bucket))
+}
+
+type nbClass struct {
+	docs   int
+	tokens map[string]int
+	total  int
+}
+
+type naiveBayes struct {
+	classes map[string]*nbClass
+	vocab   map[string]struct{}
+	docs    int
+}
+
+func newNaiveBayes() *naiveBayes {
+	return &naiveBayes{classes: make(map[string]*nbClass), vocab: make(map[string]struct{})}
+}
+
+func (nb *naiveBayes) add(label string, features []string) {
+	c, ok := nb.classes[label]
+	if !ok {
+		c = &nbClass{tokens: make(map[string]int)}
+		nb.classes[label] = c
+	}
+
+	c.docs++
+	nb.docs++
+
+	for _, f := range features {
+		c.tokens[f]++
+		c.total++
+		nb.vocab[f] = struct{}{}
+	}
+}
+
+// predict scores every class with Laplace-smoothed log probabilities and turns
+// the scores into confidences with a softmax. Only word features decide whether
+// there is anything to predict: type and amount alone are too weak a signal.
+func (nb *naiveBayes) predict(features []string, n int) []Suggestion {
+	known := make([]string, 0, len(features))
+	hasWord := false
+
+	for _, f := range features {
+		if _, ok := nb.vocab[f]; !ok {
+			continue
+		}
+
+		known = append(known, f)
+		hasWord = hasWord || strings.HasPrefix(f, "w:")
+	}
+
+	if !hasWord || n <= 0 {
+		return nil
+	}
+
+	vocab := float64(len(nb.vocab))
+	scores := make([]Suggestion, 0, len(nb.classes))
+	maxScore := math.Inf(-1)
+
+	for label, c := range nb.classes {
+		score := math.Log(float64(c.docs) / float64(nb.docs))
+		for _, f := range known {
+			score += math.Log((float64(c.tokens[f]) + 1) / (float64(c.total) + vocab))
+		}
+
+		scores = append(scores, Suggestion{Value: label, Confidence: score})
+		maxScore = max(maxScore, score)
+	}
+
+	var sum float64
+	for i := range scores {
+		scores[i].Confidence = math.Exp(scores[i].Confidence - maxScore)
+		sum += scores[i].Confidence
+	}
+
+	for i := range scores {
+		scores[i].Confidence /= sum
+	}
+
+	slices.SortFunc(scores, func(a, b Suggestion) int {
+		if c := cmp.Compare(b.Confidence, a.Confidence); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.Value, b.Value)
+	})
+
+	return scores[:min(n, len(scores))]
+}
@@ -0,0 +1,53 @@
+package matching_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+func TestClassifier_Predict(t *testing.T) {
+	examples := []matching.Example{
+		{Text: "COMPRA CONTINENTE LISBOA", Amount: 4523, Type: transaction.TypeExpense, Description: "Continente", Category: "Groceries"},
+		{Text: "COMPRA CONTINENTE PORTO", Amount: 3210, Type: transaction.TypeExpense, Description: "Continente", Category: "Groceries"},
+		{Text: "COMPRA PINGO DOCE", Amount: 1850, Type: transaction.TypeExpense, Description: "Pingo Doce", Category: "Groceries"},
+		{Text: "PAG SERV EDP", Amount: 6400, Type: transaction.TypeExpense, Description: "EDP", Category: "Utilities"},
+		{Text: "TRF SALARIO ACME", Amount: 250000, Type: transaction.TypeIncome, Description: "Salary"},
+	}
+
+	c := matching.Train(examples)
+
+	got := c.Predict(matching.Candidate{
+		NormalizedDescription: "COMPRA CONTINENTE FARO",
+		Amount:                2999,
+		Type:                  transaction.TypeExpense,
+	}, 2)
+
+	require.Len(t, got.Descriptions, 2)
+	assert.Equal(t, "Continente", got.Descriptions[0].Value)
+	assert.Greater(t, got.Descriptions[0].Confidence, got.Descriptions[1].Confidence)
+
+	require.NotEmpty(t, got.Categories)
+	assert.Equal(t, "Groceries", got.Categories[0].Value)
+
+	var total float64
+	for _, s := range c.Predict(matching.Candidate{RawDescription: "pag serv edp"}, 10).Descriptions {
+		total += s.Confidence
+	}
+	assert.InDelta(t, 1.0, total, 1e-9, "confidences over all classes sum to one")
+}
+
+func TestClassifier_UnknownWords(t *testing.T) {
+	c := matching.Train([]matching.Example{
+		{Text: "COMPRA CONTINENTE", Amount: 1000, Type: transaction.TypeExpense, Description: "Continente"},
+	})
+
+	got := c.Predict(matching.Candidate{RawDescription: "LEV ATM", Amount: 1000, Type: transaction.TypeExpense}, 3)
+
+	assert.Empty(t, got.Descriptions, "type and amount alone are not enough to suggest")
+	assert.Empty(t, got.Categories)
+}
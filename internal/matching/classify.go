@@ -0,0 +1,61 @@
+package matching
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MrJamesThe3rd/finny/internal/auth"
+)
+
+const (
+	// classifierTTL bounds how stale a user's model may get before it is
+	// retrained from their latest transactions.
+	classifierTTL = 15 * time.Minute
+
+	// maxTrainingExamples caps training cost for users with long histories;
+	// the most recent transactions are the most relevant anyway.
+	maxTrainingExamples = 5000
+)
+
+type trainedClassifier struct {
+	classifier *Classifier
+	trainedAt  time.Time
+}
+
+// Classify suggests up to n descriptions and categories for the candidate from
+// a model trained on the user's own reviewed transactions. The model is kept in
+// memory per user and retrained after classifierTTL.
+func (s *Service) Classify(ctx context.Context, cand Candidate, n int) (Prediction, error) {
+	c, err := s.classifier(ctx)
+	if err != nil {
+		return Prediction{}, err
+	}
+
+	return c.Predict(cand, n), nil
+}
+
+func (s *Service) classifier(ctx context.Context) (*Classifier, error) {
+	userID := auth.UserID(ctx)
+
+	s.mu.Lock()
+	cached, ok := s.classifiers[userID]
+	s.mu.Unlock()
+
+	if ok && time.Since(cached.trainedAt) < classifierTTL {
+		return cached.classifier, nil
+	}
+
+	examples, err := s.repo.ListExamples(ctx, maxTrainingExamples)
+	if err != nil {
+		return nil, fmt.Errorf("listing training examples: %w", err)
+	}
+
+	c := Train(examples)
+
+	s.mu.Lock()
+	s.classifiers[userID] = trainedClassifier{classifier: c, trainedAt: time.Now()}
+	s.mu.Unlock()
+
+	return c, nil
+}
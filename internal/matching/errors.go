@@ -0,0 +1,25 @@
+package matching
+
+import "errors"
+
+var (
+	// ErrMappingNotFound is returned when a mapping ID does not exist or does not
+	// belong to the requesting user.
+	ErrMappingNotFound = errors.New("mapping not found")
+
+	// ErrMappingExists is returned when an update would give a mapping the same
+	// raw pattern as another mapping of the same user.
+	ErrMappingExists = errors.New("a mapping with this raw pattern already exists")
+
+	// ErrInvalidMapping is returned when an imported mapping is missing its pattern
+	// or preferred description.
+	ErrInvalidMapping = errors.New("invalid mapping")
+
+	// ErrRuleNotFound is returned when a rule ID does not exist or does not
+	// belong to the requesting user.
+	ErrRuleNotFound = errors.New("rule not found")
+
+	// ErrInvalidRule is returned when a rule has no conditions, no actions, or
+	// out-of-range values.
+	ErrInvalidRule = errors.New("invalid rule")
+)
@@ -0,0 +1,73 @@
+package matching
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Settings are the user's matching preferences.
+type Settings struct {
+	// AutoLearn creates a mapping as soon as the user renames an imported
+	// transaction. When false the mapping is only proposed.
+	AutoLearn bool
+}
+
+// Proposal is a mapping derived from a manual description edit.
+type Proposal struct {
+	RawPattern           string
+	PreferredDescription string
+	// Created reports whether the mapping was stored (auto-learn) or only proposed.
+	Created bool
+}
+
+func (s *Service) Settings(ctx context.Context) (*Settings, error) {
+	return s.repo.GetSettings(ctx)
+}
+
+func (s *Service) UpdateSettings(ctx context.Context, settings *Settings) error {
+	return s.repo.UpdateSettings(ctx, settings)
+}
+
+// LearnFromEdit is called after the user changes a transaction's description.
+// It returns a mapping from the normalized raw description to the new
+// description, creating it when the user has auto-learn enabled. Returns nil if
+// the edit teaches nothing new: the transaction was not imported, the
+// description did not change, or the current mappings already produce it.
+func (s *Service) LearnFromEdit(ctx context.Context, source, rawDescription, oldDescription, newDescription string) (*Proposal, error) {
+	preferred := strings.TrimSpace(newDescription)
+	if rawDescription == "" || preferred == "" || preferred == strings.TrimSpace(oldDescription) {
+		return nil, nil
+	}
+
+	pattern := Normalize(source, rawDescription)
+	if pattern == "" {
+		return nil, nil
+	}
+
+	current, err := s.findMatch(ctx, pattern, rawDescription)
+	if err != nil {
+		return nil, fmt.Errorf("finding current mapping: %w", err)
+	}
+
+	if current == preferred {
+		return nil, nil
+	}
+
+	settings, err := s.repo.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading matching settings: %w", err)
+	}
+
+	p := &Proposal{RawPattern: pattern, PreferredDescription: preferred}
+
+	if settings.AutoLearn {
+		if err := s.repo.CreateMapping(ctx, pattern, preferred); err != nil {
+			return nil, fmt.Errorf("creating mapping: %w", err)
+		}
+
+		p.Created = true
+	}
+
+	return p, nil
+}
@@ -0,0 +1,121 @@
+package matching_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+)
+
+// learnRepo keeps mappings by pattern; FindMatch returns the longest contained
+// one, as the store does.
+type learnRepo struct {
+	matching.Repository
+	mappings  map[string]string
+	autoLearn bool
+}
+
+func (r *learnRepo) FindMatch(_ context.Context, description string) (string, error) {
+	var best, preferred string
+	for pattern, p := range r.mappings {
+		if strings.Contains(strings.ToLower(description), strings.ToLower(pattern)) && len(pattern) > len(best) {
+			best, preferred = pattern, p
+		}
+	}
+	return preferred, nil
+}
+
+func (r *learnRepo) CreateMapping(_ context.Context, rawPattern, preferredDescription string) error {
+	r.mappings[rawPattern] = preferredDescription
+	return nil
+}
+
+func (r *learnRepo) GetSettings(context.Context) (*matching.Settings, error) {
+	return &matching.Settings{AutoLearn: r.autoLearn}, nil
+}
+
+func TestService_LearnFromEdit(t *testing.T) {
+	tests := []struct {
+		name      string
+		mappings  map[string]string
+		autoLearn bool
+		source    string
+		raw       string
+		old, new  string
+		want      *matching.Proposal
+	}{
+		{
+			name:      "learns the normalized description",
+			autoLearn: true,
+			source:    "cgd",
+			raw:       "COMPRA 4521XXXXXX1234 CONTINENTE 12/10",
+			old:       "COMPRA 4521XXXXXX1234 CONTINENTE 12/10",
+			new:       " Groceries ",
+			want:      &matching.Proposal{RawPattern: "COMPRA CONTINENTE", PreferredDescription: "Groceries", Created: true},
+		},
+		{
+			name:   "only proposes without auto-learn",
+			source: "cgd",
+			raw:    "PAG SERV EDP 2026-10-01",
+			new:    "Electricity",
+			want:   &matching.Proposal{RawPattern: "PAG SERV EDP", PreferredDescription: "Electricity"},
+		},
+		{
+			name:      "unknown source only folds spelling",
+			autoLearn: true,
+			raw:       "Café Central 12/10",
+			new:       "Coffee",
+			want:      &matching.Proposal{RawPattern: "CAFE CENTRAL 12/10", PreferredDescription: "Coffee", Created: true},
+		},
+		{
+			name:     "mappings already produce it",
+			mappings: map[string]string{"COMPRA CONTINENTE": "Groceries"},
+			source:   "cgd",
+			raw:      "COMPRA ****9876 CONTINENTE",
+			new:      "Groceries",
+		},
+		{
+			name:     "legacy raw mapping already produces it",
+			mappings: map[string]string{"****9876 CONTINENTE": "Groceries"},
+			source:   "cgd",
+			raw:      "COMPRA ****9876 CONTINENTE",
+			new:      "Groceries",
+		},
+		{
+			name:   "description unchanged",
+			source: "cgd",
+			raw:    "COMPRA CONTINENTE",
+			old:    "Groceries",
+			new:    "Groceries ",
+		},
+		{
+			name: "manual transaction",
+			new:  "Groceries",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &learnRepo{mappings: make(map[string]string), autoLearn: tt.autoLearn}
+			for pattern, preferred := range tt.mappings {
+				repo.mappings[pattern] = preferred
+			}
+
+			svc := matching.NewService(repo, nil)
+
+			got, err := svc.LearnFromEdit(context.Background(), tt.source, tt.raw, tt.old, tt.new)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			if tt.want != nil && tt.want.Created {
+				assert.Equal(t, tt.want.PreferredDescription, repo.mappings[tt.want.RawPattern])
+			} else {
+				assert.Len(t, repo.mappings, len(tt.mappings), "nothing is stored")
+			}
+		})
+	}
+}
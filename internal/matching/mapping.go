@@ -0,0 +1,54 @@
+package matching
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mapping links a raw bank description pattern to the description the user prefers.
+// A transaction matches when its raw description contains RawPattern (case-insensitive).
+type Mapping struct {
+	ID                   uuid.UUID
+	RawPattern           string
+	PreferredDescription string
+	CreatedAt            time.Time
+
+	// MatchCount is how many times the mapping has set a stored transaction's
+	// description, on import or re-apply.
+	MatchCount    int
+	LastMatchedAt *time.Time
+	// TransactionCount is how many live transactions currently owe their
+	// description to this mapping; DistinctDescriptions is how many different
+	// (normalized) raw descriptions those transactions have.
+	TransactionCount     int
+	DistinctDescriptions int
+}
+
+// ListFilter narrows the mappings returned by List.
+type ListFilter struct {
+	// Search matches case-insensitively against both the pattern and the preferred description.
+	Search string
+}
+
+// bestMapping returns the mapping FindMatch would pick for description: the
+// longest contained pattern, newest first on ties. Returns nil if none match.
+func bestMapping(mappings []*Mapping, description string) *Mapping {
+	text := strings.ToLower(description)
+
+	var best *Mapping
+
+	for _, m := range mappings {
+		if !strings.Contains(text, strings.ToLower(m.RawPattern)) {
+			continue
+		}
+
+		if best == nil || len(m.RawPattern) > len(best.RawPattern) ||
+			(len(m.RawPattern) == len(best.RawPattern) && m.CreatedAt.After(best.CreatedAt)) {
+			best = m
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,79 @@
+package matching
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/merchant"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// validateRule checks the rule itself and that the merchant it assigns exists.
+func (s *Service) validateRule(ctx context.Context, rule *Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	id := rule.Actions.SetMerchantID
+	if id == nil {
+		return nil
+	}
+
+	if _, err := s.merchants.Get(ctx, *id); err != nil {
+		if errors.Is(err, merchant.ErrNotFound) {
+			return fmt.Errorf("%w: unknown merchant %s", ErrInvalidRule, id)
+		}
+
+		return fmt.Errorf("getting merchant: %w", err)
+	}
+
+	return nil
+}
+
+// applyMerchant fills the category and status the rules left empty from the
+// defaults of the merchant they assigned: the default category, and no_invoice
+// for merchants that do not issue invoices. A merchant that cannot be loaded
+// (e.g. deleted since the rule was written) is dropped from the outcome.
+// Lookups are cached in cache for the duration of one run.
+func (s *Service) applyMerchant(ctx context.Context, out *Outcome, cache map[uuid.UUID]*merchant.Merchant) {
+	if out.MerchantID == nil {
+		return
+	}
+
+	m, ok := cache[*out.MerchantID]
+	if !ok {
+		var err error
+
+		m, err = s.merchants.Get(ctx, *out.MerchantID)
+		if err != nil {
+			slog.Warn("failed to load merchant assigned by rule", "merchant_id", *out.MerchantID, "error", err)
+		}
+
+		cache[*out.MerchantID] = m
+	}
+
+	if m == nil {
+		out.MerchantID = nil
+		return
+	}
+
+	if out.Category == "" {
+		out.Category = m.DefaultCategory
+	}
+
+	if out.Status == "" && !m.RequiresInvoice {
+		out.Status = transaction.StatusNoInvoice
+	}
+}
+
+func sameMerchant(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
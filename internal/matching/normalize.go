@@ -0,0 +1,143 @@
+package matching
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Normalizer rewrites one aspect of a raw bank description.
+type Normalizer func(string) string
+
+// Chain is an ordered list of normalizers applied one after the other.
+type Chain []Normalizer
+
+// Normalize runs every step of the chain over s.
+func (c Chain) Normalize(s string) string {
+	for _, n := range c {
+		s = n(s)
+	}
+
+	return s
+}
+
+// DefaultChain only evens out spelling differences. It is used for sources
+// without a dedicated chain and for user-typed mapping patterns.
+var DefaultChain = Chain{FoldAccents, strings.ToUpper, CollapseWhitespace}
+
+// chains holds the normalizer chain of each transaction source (importer bank ID).
+var chains = map[string]Chain{
+	"cgd": {
+		FoldAccents,
+		strings.ToUpper,
+		StripCardMasks,
+		StripMBWayPhones,
+		StripTerminalRefs,
+		StripTrailingDateTime,
+		CollapseWhitespace,
+	},
+}
+
+// RegisterChain sets the normalizer chain for a source. It is meant to be
+// called during initialisation, before any descriptions are normalized.
+func RegisterChain(source string, c Chain) {
+	chains[source] = c
+}
+
+// ChainFor returns the chain registered for source, or DefaultChain.
+func ChainFor(source string) Chain {
+	if c, ok := chains[source]; ok {
+		return c
+	}
+
+	return DefaultChain
+}
+
+// Normalize reduces a raw description from source to the stable form used to
+// match mappings: the parts that change between statements for the same
+// merchant (card numbers, phone numbers, dates, terminal IDs) are removed.
+func Normalize(source, rawDescription string) string {
+	return ChainFor(source).Normalize(rawDescription)
+}
+
+// NormalizePattern brings a user-supplied mapping pattern into the same
+// spelling as normalized descriptions so the two can be compared.
+func NormalizePattern(pattern string) string {
+	return DefaultChain.Normalize(pattern)
+}
+
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+	"º", "O", "ª", "A",
+)
+
+// FoldAccents replaces accented Latin letters with their plain counterparts.
+func FoldAccents(s string) string {
+	return accentFolder.Replace(s)
+}
+
+// CollapseWhitespace trims s and squeezes runs of whitespace into one space.
+func CollapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// cardMask matches masked card numbers such as "4521XXXXXX1234" or "****1234".
+var cardMask = regexp.MustCompile(`(?i)\b\d{0,6}[X*]{4,}\d{0,4}\b|\*{4,}\d{0,4}`)
+
+// StripCardMasks removes masked card numbers.
+func StripCardMasks(s string) string {
+	return cardMask.ReplaceAllString(s, " ")
+}
+
+var (
+	mbWay = regexp.MustCompile(`(?i)MB\s?WAY`)
+	// mobilePhone matches Portuguese mobile numbers, optionally prefixed by +351/00351.
+	mobilePhone = regexp.MustCompile(`\+?\b(?:(?:00)?351\s?)?9[1236]\d{7}\b`)
+)
+
+// StripMBWayPhones removes the counterpart's phone number from MB WAY transfers.
+// Other descriptions are left alone, as a 9-digit number there may be a reference
+// that identifies the merchant.
+func StripMBWayPhones(s string) string {
+	if !mbWay.MatchString(s) {
+		return s
+	}
+
+	return mobilePhone.ReplaceAllString(s, " ")
+}
+
+// terminalRef matches terminal and transaction references such as "TPA 123456",
+// "REF. 000123" or "N.º 42".
+var terminalRef = regexp.MustCompile(`(?i)\b(?:TERM|TPA|REF|AUT|N\.?O)\b\.?:?\s*\d+\b`)
+
+// StripTerminalRefs removes terminal, authorisation and reference numbers.
+func StripTerminalRefs(s string) string {
+	return terminalRef.ReplaceAllString(s, " ")
+}
+
+// trailingDateTime matches a date, time or long numeric reference at the end of
+// a description, e.g. "12/10", "2026-10-12", "14:32" or "0012345".
+var trailingDateTime = regexp.MustCompile(`\s+(?:\d{1,4}[./-]\d{1,2}(?:[./-]\d{2,4})?|\d{1,2}[:hH]\d{2}(?::\d{2})?|\d{5,})\s*$`)
+
+// StripTrailingDateTime repeatedly removes trailing dates, times and numeric
+// references, but never the whole description.
+func StripTrailingDateTime(s string) string {
+	for {
+		trimmed := trailingDateTime.ReplaceAllString(s, "")
+		if trimmed == s || strings.TrimSpace(trimmed) == "" {
+			return s
+		}
+
+		s = trimmed
+	}
+}
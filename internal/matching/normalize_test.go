@@ -0,0 +1,43 @@
+package matching_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+)
+
+func TestNormalize_CGD(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "COMPRA 4521XXXXXX1234 CONTINENTE", want: "COMPRA CONTINENTE"},
+		{raw: "COMPRA ****1234 PINGO DOCE", want: "COMPRA PINGO DOCE"},
+		{raw: "MBWAY P/ +351 912345678", want: "MBWAY P/"},
+		{raw: "TRF MB WAY 936543210 JOAO", want: "TRF MB WAY JOAO"},
+		{raw: "LEV ATM 12/10 14:32", want: "LEV ATM"},
+		{raw: "PAG SERV EDP 2026-10-01", want: "PAG SERV EDP"},
+		{raw: "COMPRA TPA 123456 CAFÉ  CENTRAL", want: "COMPRA CAFE CENTRAL"},
+		{raw: "TRF SEPA REF. 000123 ALUGUER", want: "TRF SEPA ALUGUER"},
+		{raw: "UBER   *TRIP             HELP.UBER.COMNL", want: "UBER *TRIP HELP.UBER.COMNL"},
+		{raw: "12/10", want: "12/10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			assert.Equal(t, tt.want, matching.Normalize("cgd", tt.raw))
+		})
+	}
+}
+
+func TestNormalize_UnknownSourceOnlyFoldsSpelling(t *testing.T) {
+	got := matching.Normalize("", " Café   912345678 12/10 ")
+
+	assert.Equal(t, "CAFE 912345678 12/10", got)
+}
+
+func TestNormalizePattern(t *testing.T) {
+	assert.Equal(t, "PINGO DOCE", matching.NormalizePattern("  pingo   doce "))
+}
@@ -0,0 +1,149 @@
+package matching_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// reapplyRepo serves mappings and records matches. Any other repository call
+// panics, so a preview that writes fails the test.
+type reapplyRepo struct {
+	matching.Repository
+	mappings []*matching.Mapping
+	recorded []matching.Match
+}
+
+func (r *reapplyRepo) ListMappings(context.Context, matching.ListFilter) ([]*matching.Mapping, error) {
+	return r.mappings, nil
+}
+
+func (r *reapplyRepo) RecordMatches(_ context.Context, matches []matching.Match) error {
+	r.recorded = append(r.recorded, matches...)
+	return nil
+}
+
+func TestService_PreviewReapply(t *testing.T) {
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.AddDate(0, 1, 0)
+
+	continente := &matching.Mapping{ID: uuid.New(), RawPattern: "continente", PreferredDescription: "Groceries", CreatedAt: older}
+	continenteLisboa := &matching.Mapping{ID: uuid.New(), RawPattern: "CONTINENTE LISBOA", PreferredDescription: "Groceries Lisbon", CreatedAt: older}
+	spotifyOld := &matching.Mapping{ID: uuid.New(), RawPattern: "SPOTIFY", PreferredDescription: "Music", CreatedAt: older}
+	netflixNew := &matching.Mapping{ID: uuid.New(), RawPattern: "NETFLIX", PreferredDescription: "Streaming", CreatedAt: newer}
+	spotifyNew := &matching.Mapping{ID: uuid.New(), RawPattern: "SPOTIFY", PreferredDescription: "Subscriptions", CreatedAt: newer}
+
+	tests := []struct {
+		name     string
+		mappings []*matching.Mapping
+		tx       transaction.Transaction
+		want     *matching.Mapping // nil when the description stays
+	}{
+		{
+			name:     "contained pattern, ignoring case",
+			mappings: []*matching.Mapping{continente},
+			tx:       transaction.Transaction{RawDescription: "COMPRA CONTINENTE PORTO", Description: "COMPRA CONTINENTE PORTO"},
+			want:     continente,
+		},
+		{
+			name:     "longest pattern wins",
+			mappings: []*matching.Mapping{continente, continenteLisboa},
+			tx:       transaction.Transaction{RawDescription: "COMPRA CONTINENTE LISBOA 123"},
+			want:     continenteLisboa,
+		},
+		{
+			name:     "newest mapping wins a tie",
+			mappings: []*matching.Mapping{spotifyOld, netflixNew, spotifyNew},
+			tx:       transaction.Transaction{RawDescription: "SPOTIFY P1234"},
+			want:     spotifyNew,
+		},
+		{
+			name:     "newest mapping wins a tie in any order",
+			mappings: []*matching.Mapping{spotifyNew, spotifyOld},
+			tx:       transaction.Transaction{RawDescription: "SPOTIFY P1234"},
+			want:     spotifyNew,
+		},
+		{
+			name:     "no mapping matches",
+			mappings: []*matching.Mapping{continente, spotifyOld},
+			tx:       transaction.Transaction{RawDescription: "TRF MB WAY"},
+		},
+		{
+			name:     "description already preferred",
+			mappings: []*matching.Mapping{continente},
+			tx:       transaction.Transaction{RawDescription: "COMPRA CONTINENTE", Description: "Groceries"},
+		},
+		{
+			name:     "manual transaction without a raw description",
+			mappings: []*matching.Mapping{continente},
+			tx:       transaction.Transaction{Description: "continente"},
+		},
+		{
+			name:     "normalized description first",
+			mappings: []*matching.Mapping{continente, spotifyOld},
+			tx:       transaction.Transaction{RawDescription: "COMPRA 1234 SPOTIFY", NormalizedDescription: "CONTINENTE"},
+			want:     continente,
+		},
+		{
+			name:     "raw description when the normalized one matches nothing",
+			mappings: []*matching.Mapping{spotifyOld},
+			tx:       transaction.Transaction{RawDescription: "COMPRA 1234 SPOTIFY", NormalizedDescription: "COMPRA"},
+			want:     spotifyOld,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &reapplyRepo{mappings: tt.mappings}
+			svc := matching.NewService(repo, nil)
+
+			tx := tt.tx
+			tx.ID = uuid.New()
+
+			changes, err := svc.PreviewReapply(context.Background(), []*transaction.Transaction{&tx})
+			require.NoError(t, err)
+
+			if tt.want == nil {
+				assert.Empty(t, changes)
+				return
+			}
+
+			require.Len(t, changes, 1)
+			assert.Same(t, &tx, changes[0].Transaction)
+			assert.Same(t, tt.want, changes[0].Mapping)
+			assert.Equal(t, tt.want.PreferredDescription, changes[0].Description)
+			assert.Empty(t, repo.recorded, "previewing records nothing")
+		})
+	}
+}
+
+func TestService_RecordReapplied_OnlyAppliedChanges(t *testing.T) {
+	m := &matching.Mapping{ID: uuid.New(), RawPattern: "CONTINENTE", PreferredDescription: "Groceries"}
+	repo := &reapplyRepo{mappings: []*matching.Mapping{m}}
+	svc := matching.NewService(repo, nil)
+
+	txs := []*transaction.Transaction{
+		{ID: uuid.New(), RawDescription: "COMPRA CONTINENTE 1"},
+		{ID: uuid.New(), RawDescription: "COMPRA CONTINENTE 2"},
+	}
+
+	changes, err := svc.PreviewReapply(context.Background(), txs)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Empty(t, repo.recorded)
+	assert.Empty(t, txs[0].Description, "previewing leaves transactions as they are")
+
+	require.NoError(t, svc.RecordReapplied(context.Background(), changes[1:]))
+	assert.Equal(t, []matching.Match{{MappingID: m.ID, TransactionID: txs[1].ID}}, repo.recorded)
+
+	// Applying nothing touches nothing.
+	require.NoError(t, svc.RecordReapplied(context.Background(), nil))
+	assert.Len(t, repo.recorded, 1)
+}
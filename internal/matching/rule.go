@@ -0,0 +1,205 @@
+package matching
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// Rule sets fields on transactions whose attributes satisfy all of its conditions.
+// Rules are evaluated in ascending Priority order.
+type Rule struct {
+	ID         uuid.UUID
+	Name       string
+	Priority   int
+	Enabled    bool
+	Conditions Conditions
+	Actions    Actions
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Conditions are ANDed together; zero-valued fields are ignored.
+// Stored as JSONB in matching_rules.conditions.
+type Conditions struct {
+	// RawPattern matches when the raw or normalized description contains it
+	// (case- and accent-insensitive).
+	RawPattern string `json:"raw_pattern,omitempty"`
+	// MinAmount and MaxAmount bound the amount in cents, inclusive. Amounts are
+	// always positive; use Type to tell income from expenses.
+	MinAmount *int64           `json:"min_amount,omitempty"`
+	MaxAmount *int64           `json:"max_amount,omitempty"`
+	Type      transaction.Type `json:"type,omitempty"`
+	// Weekdays matches the transaction date's day of week (0 = Sunday).
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`
+	// Source matches the transaction source exactly (e.g. "cgd").
+	Source string `json:"source,omitempty"`
+}
+
+// Actions are the changes a matching rule makes. Zero-valued fields are no-ops.
+// Stored as JSONB in matching_rules.actions.
+type Actions struct {
+	SetDescription string             `json:"set_description,omitempty"`
+	SetCategory    string             `json:"set_category,omitempty"`
+	AddTags        []string           `json:"add_tags,omitempty"`
+	SetStatus      transaction.Status `json:"set_status,omitempty"`
+	// SetMerchantID links the transaction to a merchant. The merchant's default
+	// category and invoice requirement fill in what other actions leave unset.
+	SetMerchantID *uuid.UUID `json:"set_merchant_id,omitempty"`
+}
+
+func (c Conditions) empty() bool {
+	return c.RawPattern == "" && c.MinAmount == nil && c.MaxAmount == nil &&
+		c.Type == "" && len(c.Weekdays) == 0 && c.Source == ""
+}
+
+func (a Actions) empty() bool {
+	return a.SetDescription == "" && a.SetCategory == "" && len(a.AddTags) == 0 && a.SetStatus == "" &&
+		a.SetMerchantID == nil
+}
+
+// Validate reports whether the rule can be stored. A rule needs at least one
+// condition (so it cannot silently match everything) and at least one action.
+func (r *Rule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRule)
+	}
+
+	c, a := r.Conditions, r.Actions
+
+	if c.empty() {
+		return fmt.Errorf("%w: at least one condition is required", ErrInvalidRule)
+	}
+
+	if a.empty() {
+		return fmt.Errorf("%w: at least one action is required", ErrInvalidRule)
+	}
+
+	if c.MinAmount != nil && c.MaxAmount != nil && *c.MinAmount > *c.MaxAmount {
+		return fmt.Errorf("%w: min_amount must not exceed max_amount", ErrInvalidRule)
+	}
+
+	if c.Type != "" && c.Type != transaction.TypeIncome && c.Type != transaction.TypeExpense {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidRule, c.Type)
+	}
+
+	for _, d := range c.Weekdays {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("%w: weekday %d out of range 0-6", ErrInvalidRule, d)
+		}
+	}
+
+	switch a.SetStatus {
+	case "", transaction.StatusDraft, transaction.StatusPendingInvoice,
+		transaction.StatusComplete, transaction.StatusNoInvoice:
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidRule, a.SetStatus)
+	}
+
+	return nil
+}
+
+// Candidate is the subset of transaction fields that rule conditions inspect.
+type Candidate struct {
+	RawDescription        string
+	NormalizedDescription string
+	Amount                int64
+	Type                  transaction.Type
+	Date                  time.Time
+	Source                string
+}
+
+// Matches reports whether the candidate satisfies every condition of the rule.
+func (c Conditions) Matches(cand Candidate) bool {
+	if c.RawPattern != "" {
+		pattern := NormalizePattern(c.RawPattern)
+		if !strings.Contains(NormalizePattern(cand.RawDescription), pattern) &&
+			!strings.Contains(cand.NormalizedDescription, pattern) {
+			return false
+		}
+	}
+
+	if c.MinAmount != nil && cand.Amount < *c.MinAmount {
+		return false
+	}
+
+	if c.MaxAmount != nil && cand.Amount > *c.MaxAmount {
+		return false
+	}
+
+	if c.Type != "" && cand.Type != c.Type {
+		return false
+	}
+
+	if len(c.Weekdays) > 0 && !slices.Contains(c.Weekdays, cand.Date.Weekday()) {
+		return false
+	}
+
+	if c.Source != "" && cand.Source != c.Source {
+		return false
+	}
+
+	return true
+}
+
+// Outcome is the combined effect of every rule that matched a candidate.
+// Empty fields were not set by any rule.
+type Outcome struct {
+	Description string
+	Category    string
+	Tags        []string
+	Status      transaction.Status
+	MerchantID  *uuid.UUID
+	RuleIDs     []uuid.UUID
+}
+
+// Evaluate runs the enabled rules against the candidate in the given order.
+// For single-valued actions the first matching rule wins; tags accumulate.
+// rules must already be sorted by priority.
+func Evaluate(rules []*Rule, cand Candidate) Outcome {
+	var out Outcome
+
+	for _, r := range rules {
+		if !r.Enabled || !r.Conditions.Matches(cand) {
+			continue
+		}
+
+		out.RuleIDs = append(out.RuleIDs, r.ID)
+
+		if out.Description == "" {
+			out.Description = r.Actions.SetDescription
+		}
+
+		if out.Category == "" {
+			out.Category = r.Actions.SetCategory
+		}
+
+		if out.Status == "" {
+			out.Status = r.Actions.SetStatus
+		}
+
+		if out.MerchantID == nil {
+			out.MerchantID = r.Actions.SetMerchantID
+		}
+
+		out.Tags = mergeTags(out.Tags, r.Actions.AddTags)
+	}
+
+	return out
+}
+
+// mergeTags appends the tags in add that are not already present in tags.
+func mergeTags(tags, add []string) []string {
+	for _, t := range add {
+		if t != "" && !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags
+}
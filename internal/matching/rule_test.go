@@ -0,0 +1,121 @@
+package matching_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestRuleValidate(t *testing.T) {
+	valid := matching.Rule{
+		Name:       "Bank fees",
+		Conditions: matching.Conditions{RawPattern: "COMISSAO"},
+		Actions:    matching.Actions{SetStatus: transaction.StatusNoInvoice},
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(r *matching.Rule)
+		ok     bool
+	}{
+		{name: "valid", mutate: func(*matching.Rule) {}, ok: true},
+		{name: "missing name", mutate: func(r *matching.Rule) { r.Name = " " }},
+		{name: "no conditions", mutate: func(r *matching.Rule) { r.Conditions = matching.Conditions{} }},
+		{name: "no actions", mutate: func(r *matching.Rule) { r.Actions = matching.Actions{} }},
+		{name: "inverted amount range", mutate: func(r *matching.Rule) {
+			r.Conditions.MinAmount = ptr(int64(500))
+			r.Conditions.MaxAmount = ptr(int64(100))
+		}},
+		{name: "bad weekday", mutate: func(r *matching.Rule) { r.Conditions.Weekdays = []time.Weekday{7} }},
+		{name: "bad status", mutate: func(r *matching.Rule) { r.Actions.SetStatus = "archived" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid
+			tt.mutate(&r)
+
+			err := r.Validate()
+			if tt.ok {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, matching.ErrInvalidRule), "got %v", err)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	fee := &matching.Rule{
+		ID:         uuid.New(),
+		Name:       "Bank fees",
+		Enabled:    true,
+		Conditions: matching.Conditions{RawPattern: "comissao", Type: transaction.TypeExpense},
+		Actions: matching.Actions{
+			SetCategory: "Fees",
+			SetStatus:   transaction.StatusNoInvoice,
+			AddTags:     []string{"bank"},
+		},
+	}
+	weekend := &matching.Rule{
+		ID:         uuid.New(),
+		Name:       "Weekend",
+		Enabled:    true,
+		Conditions: matching.Conditions{Weekdays: []time.Weekday{time.Saturday, time.Sunday}},
+		Actions:    matching.Actions{SetCategory: "Leisure", AddTags: []string{"weekend", "bank"}},
+	}
+	disabled := &matching.Rule{
+		ID:         uuid.New(),
+		Name:       "Disabled",
+		Conditions: matching.Conditions{Source: "cgd"},
+		Actions:    matching.Actions{SetDescription: "Never"},
+	}
+
+	rules := []*matching.Rule{disabled, fee, weekend}
+
+	saturday := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	out := matching.Evaluate(rules, matching.Candidate{
+		RawDescription: "COMISSAO MANUTENCAO",
+		Amount:         500,
+		Type:           transaction.TypeExpense,
+		Date:           saturday,
+		Source:         "cgd",
+	})
+
+	assert.Equal(t, "Fees", out.Category, "first matching rule wins")
+	assert.Equal(t, transaction.StatusNoInvoice, out.Status)
+	assert.Empty(t, out.Description, "disabled rules are skipped")
+	assert.Equal(t, []string{"bank", "weekend"}, out.Tags)
+	assert.Equal(t, []uuid.UUID{fee.ID, weekend.ID}, out.RuleIDs)
+
+	out = matching.Evaluate(rules, matching.Candidate{
+		RawDescription: "COMISSAO MANUTENCAO",
+		Type:           transaction.TypeIncome,
+		Date:           saturday.AddDate(0, 0, 2),
+	})
+
+	assert.Empty(t, out.RuleIDs)
+}
+
+func TestEvaluate_FirstMerchantWins(t *testing.T) {
+	continente, pingoDoce := uuid.New(), uuid.New()
+
+	rules := []*matching.Rule{
+		{Enabled: true, Conditions: matching.Conditions{RawPattern: "CONTINENTE"}, Actions: matching.Actions{SetMerchantID: &continente}},
+		{Enabled: true, Conditions: matching.Conditions{RawPattern: "COMPRA"}, Actions: matching.Actions{SetMerchantID: &pingoDoce}},
+	}
+
+	out := matching.Evaluate(rules, matching.Candidate{RawDescription: "COMPRA CONTINENTE"})
+
+	assert.Equal(t, &continente, out.MerchantID)
+}
@@ -2,28 +2,345 @@ package matching
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/merchant"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
 )
 
 type Repository interface {
-	FindMatch(ctx context.Context, rawDescription string) (string, error)
+	// FindMatch returns the preferred description of the longest pattern contained
+	// in description, or "" if none matches.
+	FindMatch(ctx context.Context, description string) (string, error)
 	CreateMapping(ctx context.Context, rawPattern, preferredDescription string) error
+
+	ListMappings(ctx context.Context, filter ListFilter) ([]*Mapping, error)
+	GetMapping(ctx context.Context, id uuid.UUID) (*Mapping, error)
+	UpdateMapping(ctx context.Context, m *Mapping) error
+	DeleteMapping(ctx context.Context, id uuid.UUID) error
+	// UpsertMappings creates or overwrites mappings by raw pattern in a single DB transaction.
+	UpsertMappings(ctx context.Context, mappings []*Mapping) error
+
+	// ListRules returns the user's rules ordered by priority, then creation time.
+	ListRules(ctx context.Context) ([]*Rule, error)
+	GetRule(ctx context.Context, id uuid.UUID) (*Rule, error)
+	CreateRule(ctx context.Context, rule *Rule) error
+	UpdateRule(ctx context.Context, rule *Rule) error
+	DeleteRule(ctx context.Context, id uuid.UUID) error
+
+	// GetSettings returns the defaults when the user has not saved any settings.
+	GetSettings(ctx context.Context) (*Settings, error)
+	UpdateSettings(ctx context.Context, settings *Settings) error
+
+	// RecordMatches attributes each transaction to its mapping, replacing any
+	// earlier attribution, and bumps the mappings' match counters.
+	RecordMatches(ctx context.Context, matches []Match) error
+	// ListMatched returns the live transactions currently attributed to a mapping.
+	ListMatched(ctx context.Context, mappingID uuid.UUID) ([]*MatchedTransaction, error)
+	// CountImported returns the number of live transactions with a raw description.
+	CountImported(ctx context.Context) (int, error)
+
+	// ListExamples returns up to limit of the user's most recent reviewed
+	// (non-draft) imported transactions, for training the classifier.
+	ListExamples(ctx context.Context, limit int) ([]Example, error)
+}
+
+// Merchants looks up the merchants that rules assign.
+type Merchants interface {
+	Get(ctx context.Context, id uuid.UUID) (*merchant.Merchant, error)
 }
 
 type Service struct {
-	repo Repository
+	repo      Repository
+	merchants Merchants
+
+	mu          sync.Mutex
+	classifiers map[uuid.UUID]trainedClassifier
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, merchants Merchants) *Service {
+	return &Service{repo: repo, merchants: merchants, classifiers: make(map[uuid.UUID]trainedClassifier)}
 }
 
-// Suggest tries to find a preferred description for the given raw description.
-// Returns empty string if no match found.
-func (s *Service) Suggest(ctx context.Context, rawDescription string) (string, error) {
-	return s.repo.FindMatch(ctx, rawDescription)
+// Suggest tries to find a preferred description for a raw description from the
+// given source (importer bank ID, or "" if unknown). Returns empty string if no
+// match found.
+func (s *Service) Suggest(ctx context.Context, source, rawDescription string) (string, error) {
+	return s.findMatch(ctx, Normalize(source, rawDescription), rawDescription)
+}
+
+// findMatch looks up the normalized description first and falls back to the raw
+// one, so mappings learned before normalization keep working.
+func (s *Service) findMatch(ctx context.Context, normalized, raw string) (string, error) {
+	if normalized != "" {
+		preferred, err := s.repo.FindMatch(ctx, normalized)
+		if err != nil || preferred != "" || normalized == raw {
+			return preferred, err
+		}
+	}
+
+	return s.repo.FindMatch(ctx, raw)
 }
 
 // Learn remembers a new mapping between a raw pattern and a preferred description.
+// The pattern is normalized so it compares equal to normalized descriptions.
 func (s *Service) Learn(ctx context.Context, rawPattern, preferredDescription string) error {
-	return s.repo.CreateMapping(ctx, rawPattern, preferredDescription)
+	return s.repo.CreateMapping(ctx, NormalizePattern(rawPattern), strings.TrimSpace(preferredDescription))
+}
+
+// List returns the user's mappings ordered by raw pattern.
+func (s *Service) List(ctx context.Context, filter ListFilter) ([]*Mapping, error) {
+	return s.repo.ListMappings(ctx, filter)
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Mapping, error) {
+	return s.repo.GetMapping(ctx, id)
+}
+
+// Update overwrites the pattern and preferred description of an existing mapping.
+// The pattern is normalized in place. Returns ErrMappingExists if the new pattern
+// collides with another mapping.
+func (s *Service) Update(ctx context.Context, m *Mapping) error {
+	m.RawPattern = NormalizePattern(m.RawPattern)
+
+	return s.repo.UpdateMapping(ctx, m)
+}
+
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteMapping(ctx, id)
+}
+
+// Import creates or overwrites the given mappings atomically and returns how many
+// were written. Patterns are normalized, entries with an empty pattern or
+// description are rejected, and duplicate patterns within the batch keep the
+// last occurrence.
+func (s *Service) Import(ctx context.Context, mappings []*Mapping) (int, error) {
+	byPattern := make(map[string]int, len(mappings))
+	deduped := make([]*Mapping, 0, len(mappings))
+
+	for i, m := range mappings {
+		pattern := NormalizePattern(m.RawPattern)
+		preferred := strings.TrimSpace(m.PreferredDescription)
+
+		if pattern == "" || preferred == "" {
+			return 0, fmt.Errorf("%w: entry %d: raw_pattern and preferred_description are required", ErrInvalidMapping, i+1)
+		}
+
+		entry := &Mapping{RawPattern: pattern, PreferredDescription: preferred}
+
+		if idx, ok := byPattern[pattern]; ok {
+			deduped[idx] = entry
+			continue
+		}
+
+		byPattern[pattern] = len(deduped)
+		deduped = append(deduped, entry)
+	}
+
+	if len(deduped) == 0 {
+		return 0, nil
+	}
+
+	if err := s.repo.UpsertMappings(ctx, deduped); err != nil {
+		return 0, err
+	}
+
+	return len(deduped), nil
+}
+
+func (s *Service) ListRules(ctx context.Context) ([]*Rule, error) {
+	return s.repo.ListRules(ctx)
+}
+
+func (s *Service) GetRule(ctx context.Context, id uuid.UUID) (*Rule, error) {
+	return s.repo.GetRule(ctx, id)
+}
+
+// CreateRule validates and stores a new rule.
+func (s *Service) CreateRule(ctx context.Context, rule *Rule) error {
+	if err := s.validateRule(ctx, rule); err != nil {
+		return err
+	}
+
+	return s.repo.CreateRule(ctx, rule)
+}
+
+// UpdateRule validates and overwrites an existing rule.
+func (s *Service) UpdateRule(ctx context.Context, rule *Rule) error {
+	if err := s.validateRule(ctx, rule); err != nil {
+		return err
+	}
+
+	return s.repo.UpdateRule(ctx, rule)
+}
+
+func (s *Service) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteRule(ctx, id)
+}
+
+// Apply enriches freshly parsed transactions in place: the best description
+// mapping sets the description, then rules may override the description and set
+// category, tags, status and merchant. Mapping and merchant lookups that fail
+// are logged and skipped so a matching problem never blocks an import.
+func (s *Service) Apply(ctx context.Context, params []transaction.CreateParams) error {
+	rules, err := s.repo.ListRules(ctx)
+	if err != nil {
+		return fmt.Errorf("listing rules: %w", err)
+	}
+
+	merchants := make(map[uuid.UUID]*merchant.Merchant)
+
+	for i := range params {
+		p := &params[i]
+
+		preferred, err := s.findMatch(ctx, p.NormalizedDescription, p.RawDescription)
+		if err != nil {
+			slog.Warn("failed to look up description mapping", "raw_description", p.RawDescription, "error", err)
+		} else if preferred != "" {
+			p.Description = preferred
+		}
+
+		out := Evaluate(rules, Candidate{
+			RawDescription:        p.RawDescription,
+			NormalizedDescription: p.NormalizedDescription,
+			Amount:                p.Amount,
+			Type:                  p.Type,
+			Date:                  p.Date,
+			Source:                p.Source,
+		})
+		s.applyMerchant(ctx, &out, merchants)
+
+		if out.Description != "" {
+			p.Description = out.Description
+		}
+
+		if out.MerchantID != nil {
+			p.MerchantID = out.MerchantID
+		}
+
+		if out.Category != "" {
+			p.Category = out.Category
+		}
+
+		if out.Status != "" {
+			p.Status = out.Status
+		}
+
+		p.Tags = mergeTags(p.Tags, out.Tags)
+	}
+
+	return nil
+}
+
+// Change pairs an existing transaction with the version produced by re-running rules.
+type Change struct {
+	Before *transaction.Transaction
+	After  *transaction.Transaction
+}
+
+// RunRules evaluates the current rules against existing transactions and returns
+// only those that would change. The inputs are not modified. Status actions are
+// not applied to transactions that already have an invoice attached, so a rule
+// cannot undo a completed invoice.
+func (s *Service) RunRules(ctx context.Context, txs []*transaction.Transaction) ([]Change, error) {
+	rules, err := s.repo.ListRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing rules: %w", err)
+	}
+
+	merchants := make(map[uuid.UUID]*merchant.Merchant)
+
+	var changes []Change
+
+	for _, tx := range txs {
+		out := Evaluate(rules, Candidate{
+			RawDescription:        tx.RawDescription,
+			NormalizedDescription: tx.NormalizedDescription,
+			Amount:                tx.Amount,
+			Type:                  tx.Type,
+			Date:                  tx.Date,
+			Source:                tx.Source,
+		})
+
+		if len(out.RuleIDs) == 0 {
+			continue
+		}
+
+		s.applyMerchant(ctx, &out, merchants)
+
+		after := *tx
+		after.Tags = mergeTags(slices.Clone(tx.Tags), out.Tags)
+
+		if out.Description != "" {
+			after.Description = out.Description
+		}
+
+		if out.Category != "" {
+			after.Category = out.Category
+		}
+
+		if out.Status != "" && !tx.HasInvoice() {
+			after.Status = out.Status
+		}
+
+		if out.MerchantID != nil {
+			after.MerchantID = out.MerchantID
+		}
+
+		if after.Description == tx.Description && after.Category == tx.Category &&
+			after.Status == tx.Status && len(after.Tags) == len(tx.Tags) &&
+			sameMerchant(after.MerchantID, tx.MerchantID) {
+			continue
+		}
+
+		changes = append(changes, Change{Before: tx, After: &after})
+	}
+
+	return changes, nil
+}
+
+// DescriptionChange is a description that re-applying the current mappings would
+// give an existing transaction.
+type DescriptionChange struct {
+	Transaction *transaction.Transaction
+	Mapping     *Mapping
+	// Description is the mapping's preferred description, replacing Transaction.Description.
+	Description string
+}
+
+// PreviewReapply matches existing transactions against the current mappings and
+// returns those whose description would change. Nothing is written; callers apply
+// the changes they accept with transaction.Service.UpdateDescriptions.
+func (s *Service) PreviewReapply(ctx context.Context, txs []*transaction.Transaction) ([]DescriptionChange, error) {
+	mappings, err := s.repo.ListMappings(ctx, ListFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+
+	var changes []DescriptionChange
+
+	for _, tx := range txs {
+		if tx.RawDescription == "" {
+			continue
+		}
+
+		m := bestMapping(mappings, tx.MatchDescription())
+		if m == nil && tx.NormalizedDescription != "" {
+			m = bestMapping(mappings, tx.RawDescription)
+		}
+
+		if m == nil || m.PreferredDescription == tx.Description {
+			continue
+		}
+
+		changes = append(changes, DescriptionChange{Transaction: tx, Mapping: m, Description: m.PreferredDescription})
+	}
+
+	return changes, nil
 }
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/auth"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+)
+
+const selectRuleColumns = `id, name, priority, enabled, conditions, actions, created_at, updated_at`
+
+func scanRule(row interface{ Scan(dest ...any) error }) (*matching.Rule, error) {
+	var r matching.Rule
+	var rawConditions, rawActions []byte
+
+	if err := row.Scan(
+		&r.ID, &r.Name, &r.Priority, &r.Enabled, &rawConditions, &rawActions, &r.CreatedAt, &r.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(rawConditions, &r.Conditions); err != nil {
+		return nil, fmt.Errorf("decoding rule conditions: %w", err)
+	}
+
+	if err := json.Unmarshal(rawActions, &r.Actions); err != nil {
+		return nil, fmt.Errorf("decoding rule actions: %w", err)
+	}
+
+	return &r, nil
+}
+
+func marshalRule(r *matching.Rule) (conditions, actions []byte, err error) {
+	conditions, err = json.Marshal(r.Conditions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding rule conditions: %w", err)
+	}
+
+	actions, err = json.Marshal(r.Actions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encoding rule actions: %w", err)
+	}
+
+	return conditions, actions, nil
+}
+
+func (s *Store) ListRules(ctx context.Context) ([]*matching.Rule, error) {
+	query := `SELECT ` + selectRuleColumns + `
+		FROM matching_rules
+		WHERE user_id = $1
+		ORDER BY priority ASC, created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, auth.UserID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("listing rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*matching.Rule
+
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rule: %w", err)
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
+
+func (s *Store) GetRule(ctx context.Context, id uuid.UUID) (*matching.Rule, error) {
+	query := `SELECT ` + selectRuleColumns + ` FROM matching_rules WHERE id = $1 AND user_id = $2`
+
+	r, err := scanRule(s.db.QueryRowContext(ctx, query, id, auth.UserID(ctx)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, matching.ErrRuleNotFound
+		}
+
+		return nil, fmt.Errorf("getting rule: %w", err)
+	}
+
+	return r, nil
+}
+
+func (s *Store) CreateRule(ctx context.Context, r *matching.Rule) error {
+	conditions, actions, err := marshalRule(r)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO matching_rules (user_id, name, priority, enabled, conditions, actions)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	err = s.db.QueryRowContext(ctx, query, auth.UserID(ctx), r.Name, r.Priority, r.Enabled, conditions, actions).
+		Scan(&r.ID, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("creating rule: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateRule(ctx context.Context, r *matching.Rule) error {
+	conditions, actions, err := marshalRule(r)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE matching_rules
+		SET name = $1, priority = $2, enabled = $3, conditions = $4, actions = $5, updated_at = NOW()
+		WHERE id = $6 AND user_id = $7
+		RETURNING updated_at
+	`
+
+	err = s.db.QueryRowContext(ctx, query, r.Name, r.Priority, r.Enabled, conditions, actions, r.ID, auth.UserID(ctx)).
+		Scan(&r.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return matching.ErrRuleNotFound
+		}
+
+		return fmt.Errorf("updating rule: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM matching_rules WHERE id = $1 AND user_id = $2`
+
+	result, err := s.db.ExecContext(ctx, query, id, auth.UserID(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting rule: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return matching.ErrRuleNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/MrJamesThe3rd/finny/internal/auth"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+)
+
+// GetSettings returns the user's matching settings, or the defaults if none were saved.
+func (s *Store) GetSettings(ctx context.Context) (*matching.Settings, error) {
+	query := `SELECT auto_learn FROM matching_settings WHERE user_id = $1`
+
+	var settings matching.Settings
+
+	err := s.db.QueryRowContext(ctx, query, auth.UserID(ctx)).Scan(&settings.AutoLearn)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("getting matching settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func (s *Store) UpdateSettings(ctx context.Context, settings *matching.Settings) error {
+	query := `
+		INSERT INTO matching_settings (user_id, auto_learn, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET auto_learn = EXCLUDED.auto_learn, updated_at = NOW()
+	`
+
+	if _, err := s.db.ExecContext(ctx, query, auth.UserID(ctx), settings.AutoLearn); err != nil {
+		return fmt.Errorf("updating matching settings: %w", err)
+	}
+
+	return nil
+}
@@ -3,11 +3,20 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
 	"github.com/MrJamesThe3rd/finny/internal/auth"
+	"github.com/MrJamesThe3rd/finny/internal/database"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
 )
 
+// uniqueViolation is the Postgres SQLSTATE for a unique constraint violation.
+const uniqueViolation = "23505"
+
 type Store struct {
 	db *sql.DB
 }
@@ -53,3 +62,268 @@ func (s *Store) CreateMapping(ctx context.Context, rawPattern, preferredDescript
 
 	return nil
 }
+
+// selectMappings reads mappings together with their usage statistics. Callers
+// append their WHERE clause, qualifying columns with m.
+const selectMappings = `
+	SELECT m.id, m.raw_pattern, m.preferred_description, m.created_at,
+	       m.match_count, m.last_matched_at, u.transactions, u.descriptions
+	FROM description_mappings m
+	CROSS JOIN LATERAL (
+		SELECT COUNT(*) AS transactions,
+		       COUNT(DISTINCT COALESCE(NULLIF(t.normalized_description, ''), t.raw_description)) AS descriptions
+		FROM mapping_matches mm
+		JOIN transactions t ON t.id = mm.transaction_id AND t.deleted_at IS NULL
+		WHERE mm.mapping_id = m.id
+	) u
+`
+
+func scanMapping(row interface{ Scan(dest ...any) error }) (*matching.Mapping, error) {
+	var m matching.Mapping
+	if err := row.Scan(&m.ID, &m.RawPattern, &m.PreferredDescription, &m.CreatedAt,
+		&m.MatchCount, &m.LastMatchedAt, &m.TransactionCount, &m.DistinctDescriptions); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (s *Store) ListMappings(ctx context.Context, filter matching.ListFilter) ([]*matching.Mapping, error) {
+	query := selectMappings + ` WHERE m.user_id = $1`
+	args := []any{auth.UserID(ctx)}
+
+	if filter.Search != "" {
+		query += ` AND (m.raw_pattern ILIKE '%' || $2 || '%' ESCAPE '\' OR m.preferred_description ILIKE '%' || $2 || '%' ESCAPE '\')`
+		args = append(args, database.EscapeLike(filter.Search))
+	}
+
+	query += ` ORDER BY m.raw_pattern ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+	defer rows.Close()
+
+	var mappings []*matching.Mapping
+
+	for rows.Next() {
+		m, err := scanMapping(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning mapping: %w", err)
+		}
+
+		mappings = append(mappings, m)
+	}
+
+	return mappings, rows.Err()
+}
+
+func (s *Store) GetMapping(ctx context.Context, id uuid.UUID) (*matching.Mapping, error) {
+	query := selectMappings + ` WHERE m.id = $1 AND m.user_id = $2`
+
+	m, err := scanMapping(s.db.QueryRowContext(ctx, query, id, auth.UserID(ctx)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, matching.ErrMappingNotFound
+		}
+
+		return nil, fmt.Errorf("getting mapping: %w", err)
+	}
+
+	return m, nil
+}
+
+func (s *Store) UpdateMapping(ctx context.Context, m *matching.Mapping) error {
+	query := `
+		UPDATE description_mappings
+		SET raw_pattern = $1, preferred_description = $2
+		WHERE id = $3 AND user_id = $4
+	`
+
+	result, err := s.db.ExecContext(ctx, query, m.RawPattern, m.PreferredDescription, m.ID, auth.UserID(ctx))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return matching.ErrMappingExists
+		}
+
+		return fmt.Errorf("updating mapping: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return matching.ErrMappingNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteMapping(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM description_mappings WHERE id = $1 AND user_id = $2`
+
+	result, err := s.db.ExecContext(ctx, query, id, auth.UserID(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting mapping: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return matching.ErrMappingNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) UpsertMappings(ctx context.Context, mappings []*matching.Mapping) error {
+	query := `
+		INSERT INTO description_mappings (raw_pattern, preferred_description, user_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, raw_pattern) DO UPDATE SET preferred_description = EXCLUDED.preferred_description
+		RETURNING id, created_at
+	`
+
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning mapping import: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	userID := auth.UserID(ctx)
+
+	for _, m := range mappings {
+		err := dbTx.QueryRowContext(ctx, query, m.RawPattern, m.PreferredDescription, userID).
+			Scan(&m.ID, &m.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("upserting mapping %q: %w", m.RawPattern, err)
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("committing mapping import: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) RecordMatches(ctx context.Context, matches []matching.Match) error {
+	// The mapping_id check keeps one user from attributing transactions to
+	// another user's mapping.
+	attribute := `
+		INSERT INTO mapping_matches (transaction_id, mapping_id, matched_at)
+		SELECT t.id, m.id, NOW()
+		FROM transactions t
+		JOIN description_mappings m ON m.id = $2 AND m.user_id = t.user_id
+		WHERE t.id = $1 AND t.user_id = $3
+		ON CONFLICT (transaction_id) DO UPDATE
+		SET mapping_id = EXCLUDED.mapping_id, matched_at = EXCLUDED.matched_at
+	`
+
+	count := `
+		UPDATE description_mappings
+		SET match_count = match_count + $1, last_matched_at = NOW()
+		WHERE id = $2 AND user_id = $3
+	`
+
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning match recording: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	userID := auth.UserID(ctx)
+	counts := make(map[uuid.UUID]int)
+
+	for _, m := range matches {
+		if _, err := dbTx.ExecContext(ctx, attribute, m.TransactionID, m.MappingID, userID); err != nil {
+			return fmt.Errorf("recording match of transaction %s: %w", m.TransactionID, err)
+		}
+
+		counts[m.MappingID]++
+	}
+
+	for id, n := range counts {
+		if _, err := dbTx.ExecContext(ctx, count, n, id, userID); err != nil {
+			return fmt.Errorf("counting matches of mapping %s: %w", id, err)
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("committing match recording: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) ListMatched(ctx context.Context, mappingID uuid.UUID) ([]*matching.MatchedTransaction, error) {
+	query := `
+		SELECT t.id, t.date, t.amount, t.type, COALESCE(t.raw_description, ''), t.description, mm.matched_at
+		FROM mapping_matches mm
+		JOIN transactions t ON t.id = mm.transaction_id
+		WHERE mm.mapping_id = $1 AND t.user_id = $2 AND t.deleted_at IS NULL
+		ORDER BY t.date DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, mappingID, auth.UserID(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("listing matched transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var matched []*matching.MatchedTransaction
+
+	for rows.Next() {
+		var m matching.MatchedTransaction
+		if err := rows.Scan(&m.TransactionID, &m.Date, &m.Amount, &m.Type,
+			&m.RawDescription, &m.Description, &m.MatchedAt); err != nil {
+			return nil, fmt.Errorf("scanning matched transaction: %w", err)
+		}
+
+		matched = append(matched, &m)
+	}
+
+	return matched, rows.Err()
+}
+
+func (s *Store) CountImported(ctx context.Context) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM transactions
+		WHERE user_id = $1 AND deleted_at IS NULL AND COALESCE(raw_description, '') <> ''
+	`
+
+	var n int
+	if err := s.db.QueryRowContext(ctx, query, auth.UserID(ctx)).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting imported transactions: %w", err)
+	}
+
+	return n, nil
+}
+
+func (s *Store) ListExamples(ctx context.Context, limit int) ([]matching.Example, error) {
+	query := `
+		SELECT COALESCE(NULLIF(normalized_description, ''), raw_description), amount, type, description, category
+		FROM transactions
+		WHERE user_id = $1 AND deleted_at IS NULL AND status <> 'draft'
+		  AND COALESCE(raw_description, '') <> ''
+		ORDER BY date DESC
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, auth.UserID(ctx), limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing training examples: %w", err)
+	}
+	defer rows.Close()
+
+	var examples []matching.Example
+
+	for rows.Next() {
+		var e matching.Example
+		if err := rows.Scan(&e.Text, &e.Amount, &e.Type, &e.Description, &e.Category); err != nil {
+			return nil, fmt.Errorf("scanning training example: %w", err)
+		}
+
+		examples = append(examples, e)
+	}
+
+	return examples, rows.Err()
+}
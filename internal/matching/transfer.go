@@ -0,0 +1,139 @@
+package matching
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format identifies a serialisation format for bulk mapping import/export.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// ErrUnsupportedFormat is returned when encoding or decoding is asked for an unknown Format.
+var ErrUnsupportedFormat = errors.New("unsupported mapping format")
+
+// csvHeader is the header row written on export and expected on import.
+var csvHeader = []string{"raw_pattern", "preferred_description"}
+
+// transferEntry is the on-the-wire shape of a mapping. IDs and timestamps are
+// deliberately omitted so exports can be imported into another account.
+type transferEntry struct {
+	RawPattern           string `json:"raw_pattern"`
+	PreferredDescription string `json:"preferred_description"`
+}
+
+// Encode writes mappings to w in the given format.
+func Encode(w io.Writer, format Format, mappings []*Mapping) error {
+	switch format {
+	case FormatJSON:
+		entries := make([]transferEntry, 0, len(mappings))
+		for _, m := range mappings {
+			entries = append(entries, transferEntry{
+				RawPattern:           m.RawPattern,
+				PreferredDescription: m.PreferredDescription,
+			})
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(entries)
+
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return fmt.Errorf("writing csv header: %w", err)
+		}
+
+		for _, m := range mappings {
+			if err := cw.Write([]string{m.RawPattern, m.PreferredDescription}); err != nil {
+				return fmt.Errorf("writing csv row: %w", err)
+			}
+		}
+
+		cw.Flush()
+
+		return cw.Error()
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// Decode reads mappings from r in the given format. The returned mappings only
+// carry RawPattern and PreferredDescription; validation is left to Service.Import.
+func Decode(r io.Reader, format Format) ([]*Mapping, error) {
+	switch format {
+	case FormatJSON:
+		var entries []transferEntry
+		if err := json.NewDecoder(r).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("decoding json: %w", err)
+		}
+
+		mappings := make([]*Mapping, 0, len(entries))
+		for _, e := range entries {
+			mappings = append(mappings, &Mapping{
+				RawPattern:           e.RawPattern,
+				PreferredDescription: e.PreferredDescription,
+			})
+		}
+
+		return mappings, nil
+
+	case FormatCSV:
+		return decodeCSV(r)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+func decodeCSV(r io.Reader) ([]*Mapping, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+
+	// Tolerate a UTF-8 BOM written by spreadsheet tools.
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	for i, col := range csvHeader {
+		if !strings.EqualFold(strings.TrimSpace(header[i]), col) {
+			return nil, fmt.Errorf("unexpected csv header %q, want %q", strings.Join(header, ","), strings.Join(csvHeader, ","))
+		}
+	}
+
+	var mappings []*Mapping
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("reading csv row: %w", err)
+		}
+
+		mappings = append(mappings, &Mapping{
+			RawPattern:           record[0],
+			PreferredDescription: record[1],
+		})
+	}
+
+	return mappings, nil
+}
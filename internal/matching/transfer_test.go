@@ -0,0 +1,57 @@
+package matching_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+)
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	mappings := []*matching.Mapping{
+		{RawPattern: "COMPRA CONTINENTE", PreferredDescription: "Continente"},
+		{RawPattern: "MB WAY", PreferredDescription: "Transfer, \"MB\" Way"},
+	}
+
+	for _, format := range []matching.Format{matching.FormatJSON, matching.FormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, matching.Encode(&buf, format, mappings))
+
+			got, err := matching.Decode(&buf, format)
+			require.NoError(t, err)
+			require.Len(t, got, 2)
+
+			for i := range mappings {
+				assert.Equal(t, mappings[i].RawPattern, got[i].RawPattern)
+				assert.Equal(t, mappings[i].PreferredDescription, got[i].PreferredDescription)
+			}
+		})
+	}
+}
+
+func TestDecode_CSVWithBOM(t *testing.T) {
+	input := "\ufeffraw_pattern,preferred_description\nPINGO DOCE,Pingo Doce\n"
+
+	got, err := matching.Decode(strings.NewReader(input), matching.FormatCSV)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "PINGO DOCE", got[0].RawPattern)
+	assert.Equal(t, "Pingo Doce", got[0].PreferredDescription)
+}
+
+func TestDecode_CSVWrongHeader(t *testing.T) {
+	input := "pattern,description\nPINGO DOCE,Pingo Doce\n"
+
+	_, err := matching.Decode(strings.NewReader(input), matching.FormatCSV)
+	assert.Error(t, err)
+}
+
+func TestDecode_UnsupportedFormat(t *testing.T) {
+	_, err := matching.Decode(strings.NewReader(""), matching.Format("xml"))
+	assert.ErrorIs(t, err, matching.ErrUnsupportedFormat)
+}
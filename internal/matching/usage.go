@@ -0,0 +1,174 @@
+package matching
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// Match records that a mapping set a transaction's description.
+type Match struct {
+	MappingID     uuid.UUID
+	TransactionID uuid.UUID
+}
+
+// MatchedTransaction is a transaction whose description was set by a mapping.
+type MatchedTransaction struct {
+	TransactionID  uuid.UUID
+	Date           time.Time
+	Amount         int64
+	Type           transaction.Type
+	RawDescription string
+	Description    string
+	MatchedAt      time.Time
+}
+
+// RecordImported attributes freshly stored transactions to the mapping that set
+// their description. A transaction whose description a rule overrode, or that
+// no mapping matches, is not attributed.
+func (s *Service) RecordImported(ctx context.Context, txs []*transaction.Transaction) error {
+	mappings, err := s.repo.ListMappings(ctx, ListFilter{})
+	if err != nil {
+		return fmt.Errorf("listing mappings: %w", err)
+	}
+
+	var matches []Match
+
+	for _, tx := range txs {
+		if tx.RawDescription == "" {
+			continue
+		}
+
+		m := bestMapping(mappings, tx.MatchDescription())
+		if m == nil && tx.NormalizedDescription != "" {
+			m = bestMapping(mappings, tx.RawDescription)
+		}
+
+		if m != nil && m.PreferredDescription == tx.Description {
+			matches = append(matches, Match{MappingID: m.ID, TransactionID: tx.ID})
+		}
+	}
+
+	return s.recordMatches(ctx, matches)
+}
+
+// RecordReapplied attributes re-applied description changes to their mappings.
+// Call it after the changes have been written.
+func (s *Service) RecordReapplied(ctx context.Context, changes []DescriptionChange) error {
+	matches := make([]Match, 0, len(changes))
+	for _, c := range changes {
+		matches = append(matches, Match{MappingID: c.Mapping.ID, TransactionID: c.Transaction.ID})
+	}
+
+	return s.recordMatches(ctx, matches)
+}
+
+func (s *Service) recordMatches(ctx context.Context, matches []Match) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	return s.repo.RecordMatches(ctx, matches)
+}
+
+// ListMatched returns the live transactions whose description the mapping set,
+// newest first.
+func (s *Service) ListMatched(ctx context.Context, mappingID uuid.UUID) ([]*MatchedTransaction, error) {
+	return s.repo.ListMatched(ctx, mappingID)
+}
+
+// BroadReason explains why a mapping is considered over-broad.
+type BroadReason string
+
+const (
+	// ReasonShortPattern: the pattern is so short it is likely contained in
+	// unrelated descriptions.
+	ReasonShortPattern BroadReason = "short_pattern"
+	// ReasonManyDescriptions: the mapping renames many different raw
+	// descriptions to the same thing.
+	ReasonManyDescriptions BroadReason = "many_descriptions"
+	// ReasonLargeShare: the mapping owns a large share of all imported transactions.
+	ReasonLargeShare BroadReason = "large_share"
+)
+
+const (
+	// shortPatternLength is the pattern length, in characters, below which a
+	// mapping is reported as short.
+	shortPatternLength = 4
+	// manyDescriptions is the number of distinct raw descriptions from which a
+	// mapping is reported as renaming too many.
+	manyDescriptions = 5
+	// largeSharePercent is the share of imported transactions from which a
+	// mapping is reported, once there are at least largeShareMinTransactions.
+	largeSharePercent         = 20
+	largeShareMinTransactions = 20
+)
+
+// BroadMapping is a mapping flagged as over-broad, with every reason that applies.
+type BroadMapping struct {
+	Mapping *Mapping
+	Reasons []BroadReason
+}
+
+// UsageReport lists mappings worth reviewing.
+type UsageReport struct {
+	// Dead mappings have not matched since the stale cutoff. Mappings created
+	// after the cutoff are given time and never reported as dead.
+	Dead      []*Mapping
+	OverBroad []BroadMapping
+	// TotalTransactions is the number of imported transactions the shares are relative to.
+	TotalTransactions int
+}
+
+// Report builds a usage report of the user's mappings. Mappings that have not
+// matched since staleBefore are dead.
+func (s *Service) Report(ctx context.Context, staleBefore time.Time) (*UsageReport, error) {
+	mappings, err := s.repo.ListMappings(ctx, ListFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("listing mappings: %w", err)
+	}
+
+	total, err := s.repo.CountImported(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("counting imported transactions: %w", err)
+	}
+
+	return BuildReport(mappings, total, staleBefore), nil
+}
+
+// BuildReport classifies mappings with their usage statistics filled in.
+// total is the number of imported transactions.
+func BuildReport(mappings []*Mapping, total int, staleBefore time.Time) *UsageReport {
+	report := &UsageReport{TotalTransactions: total}
+
+	for _, m := range mappings {
+		if m.CreatedAt.Before(staleBefore) && (m.LastMatchedAt == nil || m.LastMatchedAt.Before(staleBefore)) {
+			report.Dead = append(report.Dead, m)
+		}
+
+		var reasons []BroadReason
+
+		if utf8.RuneCountInString(m.RawPattern) < shortPatternLength {
+			reasons = append(reasons, ReasonShortPattern)
+		}
+
+		if m.DistinctDescriptions >= manyDescriptions {
+			reasons = append(reasons, ReasonManyDescriptions)
+		}
+
+		if total >= largeShareMinTransactions && m.TransactionCount*100 >= total*largeSharePercent {
+			reasons = append(reasons, ReasonLargeShare)
+		}
+
+		if len(reasons) > 0 {
+			report.OverBroad = append(report.OverBroad, BroadMapping{Mapping: m, Reasons: reasons})
+		}
+	}
+
+	return report
+}
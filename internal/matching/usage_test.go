@@ -0,0 +1,40 @@
+package matching_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+)
+
+func TestBuildReport(t *testing.T) {
+	cutoff := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	old := cutoff.AddDate(0, -6, 0)
+	recent := cutoff.AddDate(0, 1, 0)
+
+	never := &matching.Mapping{RawPattern: "NETFLIX", CreatedAt: old}
+	stale := &matching.Mapping{RawPattern: "SPOTIFY", CreatedAt: old, MatchCount: 3, LastMatchedAt: &old}
+	active := &matching.Mapping{RawPattern: "CONTINENTE", CreatedAt: old, MatchCount: 9, LastMatchedAt: &recent, TransactionCount: 9, DistinctDescriptions: 1}
+	fresh := &matching.Mapping{RawPattern: "GALP", CreatedAt: recent}
+	short := &matching.Mapping{RawPattern: "PAG", CreatedAt: old, MatchCount: 4, LastMatchedAt: &recent, TransactionCount: 4, DistinctDescriptions: 2}
+	greedy := &matching.Mapping{RawPattern: "COMPRA", CreatedAt: old, MatchCount: 30, LastMatchedAt: &recent, TransactionCount: 30, DistinctDescriptions: 12}
+
+	report := matching.BuildReport([]*matching.Mapping{never, stale, active, fresh, short, greedy}, 100, cutoff)
+
+	assert.Equal(t, 100, report.TotalTransactions)
+	assert.Equal(t, []*matching.Mapping{never, stale}, report.Dead)
+	assert.Equal(t, []matching.BroadMapping{
+		{Mapping: short, Reasons: []matching.BroadReason{matching.ReasonShortPattern}},
+		{Mapping: greedy, Reasons: []matching.BroadReason{matching.ReasonManyDescriptions, matching.ReasonLargeShare}},
+	}, report.OverBroad)
+}
+
+func TestBuildReport_SmallHistoryHasNoLargeShare(t *testing.T) {
+	m := &matching.Mapping{RawPattern: "CONTINENTE", TransactionCount: 5}
+
+	report := matching.BuildReport([]*matching.Mapping{m}, 10, time.Time{})
+
+	assert.Empty(t, report.OverBroad)
+}
@@ -0,0 +1,17 @@
+package merchant
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when a merchant ID does not exist or does not
+	// belong to the requesting user.
+	ErrNotFound = errors.New("merchant not found")
+
+	// ErrNameTaken is returned when another merchant of the same user already
+	// has the name.
+	ErrNameTaken = errors.New("a merchant with this name already exists")
+
+	// ErrInvalidMerchant is returned when a merchant is missing its name or has
+	// a malformed NIF.
+	ErrInvalidMerchant = errors.New("invalid merchant")
+)
@@ -0,0 +1,73 @@
+package merchant
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Merchant is a counterparty transactions can be linked to, replacing the
+// free-text description as the source of truth for who was paid.
+type Merchant struct {
+	ID              uuid.UUID
+	Name            string
+	NIF             string // tax ID, optionally with a two-letter country prefix (e.g. "PT500100144")
+	DefaultCategory string
+	Website         string
+	LogoURL         string
+	// RequiresInvoice is false for merchants that never issue invoices (bank
+	// fees, tolls); rules that assign them mark transactions as no_invoice.
+	RequiresInvoice bool
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// ListFilter narrows the merchants returned by List.
+type ListFilter struct {
+	// Search matches case-insensitively against the name and NIF.
+	Search string
+}
+
+// ReportFilter bounds the transactions a merchant report covers. Nil bounds are open.
+type ReportFilter struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+// Summary is a merchant's share of the transactions in a report.
+type Summary struct {
+	MerchantID   uuid.UUID
+	Name         string
+	Transactions int
+	Expenses     int64 // in cents
+	Income       int64 // in cents
+}
+
+// nifPattern accepts a tax ID of 2 to 13 letters and digits after an optional
+// two-letter country prefix, once spaces and dots are removed.
+var nifPattern = regexp.MustCompile(`^(?:[A-Z]{2})?[0-9A-Z]{2,13}$`)
+
+// Normalize trims the merchant's fields and brings the NIF into canonical form.
+func (m *Merchant) Normalize() {
+	m.Name = strings.TrimSpace(m.Name)
+	m.NIF = strings.ToUpper(strings.NewReplacer(" ", "", ".", "", "-", "").Replace(m.NIF))
+	m.DefaultCategory = strings.TrimSpace(m.DefaultCategory)
+	m.Website = strings.TrimSpace(m.Website)
+	m.LogoURL = strings.TrimSpace(m.LogoURL)
+}
+
+// Validate reports whether a normalized merchant can be stored.
+func (m *Merchant) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidMerchant)
+	}
+
+	if m.NIF != "" && !nifPattern.MatchString(m.NIF) {
+		return fmt.Errorf("%w: malformed NIF %q", ErrInvalidMerchant, m.NIF)
+	}
+
+	return nil
+}
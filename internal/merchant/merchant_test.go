@@ -0,0 +1,42 @@
+package merchant_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MrJamesThe3rd/finny/internal/merchant"
+)
+
+func TestMerchantNormalizeAndValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      merchant.Merchant
+		wantNIF string
+		ok      bool
+	}{
+		{name: "plain NIF", in: merchant.Merchant{Name: " Continente ", NIF: "500 100 144"}, wantNIF: "500100144", ok: true},
+		{name: "country prefix", in: merchant.Merchant{Name: "IKEA", NIF: "pt-500.100.144"}, wantNIF: "PT500100144", ok: true},
+		{name: "no NIF", in: merchant.Merchant{Name: "Bank fees"}, ok: true},
+		{name: "missing name", in: merchant.Merchant{Name: "  "}},
+		{name: "malformed NIF", in: merchant.Merchant{Name: "Shop", NIF: "50#100"}, wantNIF: "50#100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.in
+			m.Normalize()
+
+			assert.Equal(t, tt.wantNIF, m.NIF)
+
+			err := m.Validate()
+			if tt.ok {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, errors.Is(err, merchant.ErrInvalidMerchant), "got %v", err)
+		})
+	}
+}
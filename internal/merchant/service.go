@@ -0,0 +1,67 @@
+package merchant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type Repository interface {
+	ListMerchants(ctx context.Context, filter ListFilter) ([]*Merchant, error)
+	GetMerchant(ctx context.Context, id uuid.UUID) (*Merchant, error)
+	CreateMerchant(ctx context.Context, m *Merchant) error
+	UpdateMerchant(ctx context.Context, m *Merchant) error
+	// DeleteMerchant removes the merchant; linked transactions are unlinked.
+	DeleteMerchant(ctx context.Context, id uuid.UUID) error
+
+	// Report sums the user's live transactions per linked merchant, largest
+	// expenses first. Transactions without a merchant are not included.
+	Report(ctx context.Context, filter ReportFilter) ([]Summary, error)
+}
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// List returns the user's merchants ordered by name.
+func (s *Service) List(ctx context.Context, filter ListFilter) ([]*Merchant, error) {
+	return s.repo.ListMerchants(ctx, filter)
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Merchant, error) {
+	return s.repo.GetMerchant(ctx, id)
+}
+
+// Create normalizes, validates and stores a new merchant.
+func (s *Service) Create(ctx context.Context, m *Merchant) error {
+	m.Normalize()
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	return s.repo.CreateMerchant(ctx, m)
+}
+
+// Update normalizes, validates and overwrites an existing merchant.
+func (s *Service) Update(ctx context.Context, m *Merchant) error {
+	m.Normalize()
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	return s.repo.UpdateMerchant(ctx, m)
+}
+
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteMerchant(ctx, id)
+}
+
+func (s *Service) Report(ctx context.Context, filter ReportFilter) ([]Summary, error) {
+	return s.repo.Report(ctx, filter)
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/MrJamesThe3rd/finny/internal/auth"
+	"github.com/MrJamesThe3rd/finny/internal/database"
+	"github.com/MrJamesThe3rd/finny/internal/merchant"
+)
+
+// uniqueViolation is the Postgres SQLSTATE for a unique constraint violation.
+const uniqueViolation = "23505"
+
+type Store struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const selectMerchantColumns = `id, name, nif, default_category, website, logo_url, requires_invoice, created_at, updated_at`
+
+func scanMerchant(row interface{ Scan(dest ...any) error }) (*merchant.Merchant, error) {
+	var m merchant.Merchant
+	if err := row.Scan(&m.ID, &m.Name, &m.NIF, &m.DefaultCategory, &m.Website, &m.LogoURL,
+		&m.RequiresInvoice, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+func (s *Store) ListMerchants(ctx context.Context, filter merchant.ListFilter) ([]*merchant.Merchant, error) {
+	query := `SELECT ` + selectMerchantColumns + ` FROM merchants WHERE user_id = $1`
+	args := []any{auth.UserID(ctx)}
+
+	if filter.Search != "" {
+		query += ` AND (name ILIKE '%' || $2 || '%' ESCAPE '\' OR nif ILIKE '%' || $2 || '%' ESCAPE '\')`
+		args = append(args, database.EscapeLike(filter.Search))
+	}
+
+	query += ` ORDER BY name ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing merchants: %w", err)
+	}
+	defer rows.Close()
+
+	var merchants []*merchant.Merchant
+
+	for rows.Next() {
+		m, err := scanMerchant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning merchant: %w", err)
+		}
+
+		merchants = append(merchants, m)
+	}
+
+	return merchants, rows.Err()
+}
+
+func (s *Store) GetMerchant(ctx context.Context, id uuid.UUID) (*merchant.Merchant, error) {
+	query := `SELECT ` + selectMerchantColumns + ` FROM merchants WHERE id = $1 AND user_id = $2`
+
+	m, err := scanMerchant(s.db.QueryRowContext(ctx, query, id, auth.UserID(ctx)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, merchant.ErrNotFound
+		}
+
+		return nil, fmt.Errorf("getting merchant: %w", err)
+	}
+
+	return m, nil
+}
+
+func (s *Store) CreateMerchant(ctx context.Context, m *merchant.Merchant) error {
+	query := `
+		INSERT INTO merchants (name, nif, default_category, website, logo_url, requires_invoice, user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`
+
+	err := s.db.QueryRowContext(ctx, query,
+		m.Name, m.NIF, m.DefaultCategory, m.Website, m.LogoURL, m.RequiresInvoice, auth.UserID(ctx),
+	).Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return merchant.ErrNameTaken
+		}
+
+		return fmt.Errorf("creating merchant: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) UpdateMerchant(ctx context.Context, m *merchant.Merchant) error {
+	query := `
+		UPDATE merchants
+		SET name = $1, nif = $2, default_category = $3, website = $4, logo_url = $5,
+		    requires_invoice = $6, updated_at = NOW()
+		WHERE id = $7 AND user_id = $8
+		RETURNING updated_at
+	`
+
+	err := s.db.QueryRowContext(ctx, query,
+		m.Name, m.NIF, m.DefaultCategory, m.Website, m.LogoURL, m.RequiresInvoice, m.ID, auth.UserID(ctx),
+	).Scan(&m.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return merchant.ErrNotFound
+		}
+
+		if isUniqueViolation(err) {
+			return merchant.ErrNameTaken
+		}
+
+		return fmt.Errorf("updating merchant: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) DeleteMerchant(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM merchants WHERE id = $1 AND user_id = $2`
+
+	result, err := s.db.ExecContext(ctx, query, id, auth.UserID(ctx))
+	if err != nil {
+		return fmt.Errorf("deleting merchant: %w", err)
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return merchant.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Store) Report(ctx context.Context, filter merchant.ReportFilter) ([]merchant.Summary, error) {
+	query := `
+		SELECT m.id, m.name, COUNT(*),
+		       COALESCE(SUM(t.amount) FILTER (WHERE t.type = 'expense'), 0),
+		       COALESCE(SUM(t.amount) FILTER (WHERE t.type = 'income'), 0)
+		FROM transactions t
+		JOIN merchants m ON m.id = t.merchant_id AND m.user_id = t.user_id
+		WHERE t.user_id = $1 AND t.deleted_at IS NULL
+		  AND ($2::timestamptz IS NULL OR t.date >= $2)
+		  AND ($3::timestamptz IS NULL OR t.date <= $3)
+		GROUP BY m.id, m.name
+		ORDER BY 4 DESC, m.name ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, auth.UserID(ctx), filter.StartDate, filter.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("reporting by merchant: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []merchant.Summary
+
+	for rows.Next() {
+		var sum merchant.Summary
+		if err := rows.Scan(&sum.MerchantID, &sum.Name, &sum.Transactions, &sum.Expenses, &sum.Income); err != nil {
+			return nil, fmt.Errorf("scanning merchant summary: %w", err)
+		}
+
+		summaries = append(summaries, sum)
+	}
+
+	return summaries, rows.Err()
+}
@@ -0,0 +1,124 @@
+package reconcile
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/matching"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+const (
+	// Card payments post a day or two after the invoice date, prepayments can
+	// come before it, and bills paid by transfer can follow it by weeks.
+	windowBefore = 7 * 24 * time.Hour
+	windowAfter  = 30 * 24 * time.Hour
+
+	// Weights of the score components. An equal amount is required, so it
+	// contributes a fixed base.
+	amountWeight = 0.5
+	dateWeight   = 0.25
+	nameWeight   = 0.25
+)
+
+// Score rates how likely the transaction is the payment of the invoice, in
+// [0, 1]. It is 0 unless the amounts are equal and the transaction date falls
+// within the window around the invoice date. Unknown dates and issuers score
+// half of their component.
+func Score(inv document.Invoice, tx *transaction.Transaction) float64 {
+	if inv.Total == nil || *inv.Total != tx.Amount {
+		return 0
+	}
+
+	score := amountWeight
+
+	if inv.Date == nil {
+		score += dateWeight / 2
+	} else {
+		p, ok := proximity(*inv.Date, tx.Date)
+		if !ok {
+			return 0
+		}
+
+		score += dateWeight * p
+	}
+
+	if inv.IssuerName == "" {
+		score += nameWeight / 2
+	} else {
+		score += nameWeight * nameSimilarity(inv.IssuerName, tx)
+	}
+
+	return math.Round(score*100) / 100
+}
+
+// proximity rates how close a transaction date is to the invoice date, from 1
+// on the same day down to 0 at the edges of the window. ok is false outside
+// the window.
+func proximity(invDate, txDate time.Time) (p float64, ok bool) {
+	offset := txDate.Sub(invDate)
+
+	window := windowAfter
+	if offset < 0 {
+		offset, window = -offset, windowBefore
+	}
+
+	if offset > window {
+		return 0, false
+	}
+
+	return 1 - float64(offset)/float64(window), true
+}
+
+// nameSimilarity is the share of the issuer's words found in the transaction's
+// merchant name or descriptions.
+func nameSimilarity(issuer string, tx *transaction.Transaction) float64 {
+	want := words(issuer)
+	if len(want) == 0 {
+		return 0
+	}
+
+	have := make(map[string]bool)
+	for _, text := range []string{tx.MerchantName, tx.Description, tx.RawDescription} {
+		for _, w := range words(text) {
+			have[w] = true
+		}
+	}
+
+	found := 0
+
+	for _, w := range want {
+		if have[w] {
+			found++
+		}
+	}
+
+	return float64(found) / float64(len(want))
+}
+
+// legalForms are company suffixes that bank descriptions usually drop.
+var legalForms = map[string]bool{
+	"LDA": true, "SA": true, "UNIPESSOAL": true, "SGPS": true, "CRL": true,
+	"SL": true, "SRL": true, "GMBH": true, "LTD": true, "INC": true,
+}
+
+// words splits text into upper-case, accent-free words of at least two
+// letters, dropping legal forms.
+func words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToUpper(matching.FoldAccents(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	out := fields[:0]
+
+	for _, w := range fields {
+		if len(w) >= 2 && !legalForms[w] {
+			out = append(out, w)
+		}
+	}
+
+	return out
+}
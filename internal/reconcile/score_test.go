@@ -0,0 +1,82 @@
+package reconcile_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/reconcile"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+func TestScore(t *testing.T) {
+	total := int64(12345)
+	issued := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	days := func(n int) time.Time { return issued.AddDate(0, 0, n) }
+
+	invoice := document.Invoice{Total: &total, Date: &issued, IssuerName: "Continente Hipermercados, S.A."}
+
+	tests := []struct {
+		name string
+		inv  document.Invoice
+		tx   transaction.Transaction
+		want float64
+	}{
+		{
+			name: "SameDayAndMerchant",
+			inv:  invoice,
+			tx:   transaction.Transaction{Amount: 12345, Date: issued, MerchantName: "Continente Hipermercados"},
+			want: 1,
+		},
+		{
+			name: "IssuerInRawDescription",
+			inv:  invoice,
+			tx:   transaction.Transaction{Amount: 12345, Date: days(3), RawDescription: "COMPRA 1234 CONTINENTE HIPERMERCADOS LISBOA"},
+			want: 0.98,
+		},
+		{
+			name: "PartialName",
+			inv:  invoice,
+			tx:   transaction.Transaction{Amount: 12345, Date: issued, Description: "Continente"},
+			want: 0.88,
+		},
+		{
+			name: "DifferentAmount",
+			inv:  invoice,
+			tx:   transaction.Transaction{Amount: 12346, Date: issued, MerchantName: "Continente Hipermercados"},
+			want: 0,
+		},
+		{
+			name: "TooLongAfter",
+			inv:  invoice,
+			tx:   transaction.Transaction{Amount: 12345, Date: days(31), MerchantName: "Continente Hipermercados"},
+			want: 0,
+		},
+		{
+			name: "TooLongBefore",
+			inv:  invoice,
+			tx:   transaction.Transaction{Amount: 12345, Date: days(-8), MerchantName: "Continente Hipermercados"},
+			want: 0,
+		},
+		{
+			name: "UnknownDateAndIssuer",
+			inv:  document.Invoice{Total: &total},
+			tx:   transaction.Transaction{Amount: 12345, Date: days(200)},
+			want: 0.75,
+		},
+		{
+			name: "UnknownTotal",
+			inv:  document.Invoice{Date: &issued},
+			tx:   transaction.Transaction{Amount: 12345, Date: issued},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, reconcile.Score(tt.inv, &tt.tx), 0.001)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package reconcile proposes which pending transaction an inbox document pays
+// for, by comparing the invoice fields with the transactions.
+package reconcile
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/MrJamesThe3rd/finny/internal/document"
+	"github.com/MrJamesThe3rd/finny/internal/transaction"
+)
+
+// Suggestion lists the transactions an unattached document may belong to.
+type Suggestion struct {
+	Document   document.Document
+	Candidates []Candidate // best first
+}
+
+// Candidate is a transaction with its Score against the document's invoice.
+type Candidate struct {
+	Transaction *transaction.Transaction
+	Score       float64
+}
+
+// Match pairs a document with the transaction it is accepted for.
+type Match struct {
+	DocumentID    uuid.UUID
+	TransactionID uuid.UUID
+}
+
+// Result is the outcome of accepting one Match; Err is nil on success.
+type Result struct {
+	Match
+	Err error
+}
+
+// Check compares a document's invoice with a transaction it is attached to.
+type Check struct {
+	Transaction   *transaction.Transaction
+	AmountMatches bool // the amounts are equal
+	DateMatches   bool // the transaction falls within the window around the invoice date
+}
+
+// Review is what a document's invoice says about its transactions: a Check of
+// each one it is attached to or, when it is attached to none, the Candidates
+// it may belong to.
+type Review struct {
+	Document   *document.Document
+	Checks     []Check
+	Candidates []Candidate
+}
+
+// Service matches inbox documents to transactions awaiting an invoice.
+type Service struct {
+	transactions *transaction.Service
+	docs         *document.Service
+}
+
+// NewService creates a new reconcile Service.
+func NewService(txService *transaction.Service, docService *document.Service) *Service {
+	return &Service{
+		transactions: txService,
+		docs:         docService,
+	}
+}
+
+// Suggest returns up to n candidate transactions for every inbox document with
+// a known invoice total. Documents without candidates are left out.
+func (s *Service) Suggest(ctx context.Context, n int) ([]Suggestion, error) {
+	docs, err := s.docs.Inbox(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing inbox: %w", err)
+	}
+
+	pending := transaction.StatusPendingInvoice
+
+	txs, err := s.transactions.List(ctx, transaction.ListFilter{Status: &pending})
+	if err != nil {
+		return nil, fmt.Errorf("listing pending transactions: %w", err)
+	}
+
+	suggestions := []Suggestion{}
+
+	for _, doc := range docs {
+		if doc.Invoice.Total == nil {
+			continue
+		}
+
+		candidates := rank(doc.Invoice, txs, n)
+		if len(candidates) == 0 {
+			continue
+		}
+
+		suggestions = append(suggestions, Suggestion{Document: doc, Candidates: candidates})
+	}
+
+	return suggestions, nil
+}
+
+// rank returns up to n of the transactions that may pay the invoice, best
+// first.
+func rank(inv document.Invoice, txs []*transaction.Transaction, n int) []Candidate {
+	var candidates []Candidate
+
+	for _, tx := range txs {
+		if score := Score(inv, tx); score > 0 {
+			candidates = append(candidates, Candidate{Transaction: tx, Score: score})
+		}
+	}
+
+	slices.SortStableFunc(candidates, func(a, b Candidate) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return candidates[:min(n, len(candidates))]
+}
+
+// Accept attaches each document to its transaction as the invoice, which
+// completes the transaction. A failed match does not stop the others.
+func (s *Service) Accept(ctx context.Context, matches []Match) []Result {
+	results := make([]Result, len(matches))
+
+	for i, m := range matches {
+		results[i] = Result{
+			Match: m,
+			Err:   s.transactions.AddDocument(ctx, m.TransactionID, m.DocumentID, transaction.RoleInvoice),
+		}
+
+		if results[i].Err != nil {
+			continue
+		}
+
+		// Key templates may name the document after its transaction.
+		if err := s.docs.Relocate(ctx, m.DocumentID); err != nil {
+			slog.Warn("failed to relocate document", "document_id", m.DocumentID, "error", err)
+		}
+	}
+
+	return results
+}
+
+// ApplyQRCode stores the fields of the document's invoice QR code, then
+// validates the transactions the document is attached to against the QR total
+// and date, or proposes up to n pending transactions if it is attached to
+// none.
+func (s *Service) ApplyQRCode(ctx context.Context, documentID uuid.UUID, payload string, n int) (*Review, error) {
+	doc, err := s.docs.ApplyQRCode(ctx, documentID, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	linked, err := s.transactions.List(ctx, transaction.ListFilter{DocumentID: &documentID})
+	if err != nil {
+		return nil, fmt.Errorf("listing attached transactions: %w", err)
+	}
+
+	review := &Review{Document: doc}
+
+	for _, tx := range linked {
+		_, inWindow := proximity(*doc.Invoice.Date, tx.Date)
+
+		review.Checks = append(review.Checks, Check{
+			Transaction:   tx,
+			AmountMatches: tx.Amount == *doc.Invoice.Total,
+			DateMatches:   inWindow,
+		})
+	}
+
+	if len(linked) > 0 {
+		return review, nil
+	}
+
+	pending := transaction.StatusPendingInvoice
+
+	txs, err := s.transactions.List(ctx, transaction.ListFilter{Status: &pending})
+	if err != nil {
+		return nil, fmt.Errorf("listing pending transactions: %w", err)
+	}
+
+	review.Candidates = rank(doc.Invoice, txs, n)
+
+	return review, nil
+}
@@ -4,5 +4,7 @@ import "errors"
 
 var (
 	ErrNotFound                = errors.New("transaction not found")
-	ErrDocumentAlreadyAttached = errors.New("transaction already has a document")
+	ErrDocumentAlreadyAttached = errors.New("document is already attached to the transaction")
+	ErrDocumentNotAttached     = errors.New("document is not attached to the transaction")
+	ErrInvalidDocumentRole     = errors.New("invalid document role")
 )
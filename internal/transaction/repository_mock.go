@@ -42,6 +42,20 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// AddDocument mocks base method.
+func (m *MockRepository) AddDocument(ctx context.Context, txID, documentID uuid.UUID, role DocumentRole) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDocument", ctx, txID, documentID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDocument indicates an expected call of AddDocument.
+func (mr *MockRepositoryMockRecorder) AddDocument(ctx, txID, documentID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDocument", reflect.TypeOf((*MockRepository)(nil).AddDocument), ctx, txID, documentID, role)
+}
+
 // BeginImport mocks base method.
 func (m *MockRepository) BeginImport(ctx context.Context, minDate, maxDate time.Time) (ImportTx, error) {
 	m.ctrl.T.Helper()
@@ -100,6 +114,21 @@ func (mr *MockRepositoryMockRecorder) GetTransaction(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransaction", reflect.TypeOf((*MockRepository)(nil).GetTransaction), ctx, id)
 }
 
+// ListDocuments mocks base method.
+func (m *MockRepository) ListDocuments(ctx context.Context, txID uuid.UUID) ([]Document, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDocuments", ctx, txID)
+	ret0, _ := ret[0].([]Document)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDocuments indicates an expected call of ListDocuments.
+func (mr *MockRepositoryMockRecorder) ListDocuments(ctx, txID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDocuments", reflect.TypeOf((*MockRepository)(nil).ListDocuments), ctx, txID)
+}
+
 // ListTransactions mocks base method.
 func (m *MockRepository) ListTransactions(ctx context.Context, filter ListFilter) ([]*Transaction, error) {
 	m.ctrl.T.Helper()
@@ -115,32 +144,32 @@ func (mr *MockRepositoryMockRecorder) ListTransactions(ctx, filter any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransactions", reflect.TypeOf((*MockRepository)(nil).ListTransactions), ctx, filter)
 }
 
-// AttachDocument mocks base method.
-func (m *MockRepository) AttachDocument(ctx context.Context, txID uuid.UUID, documentID uuid.UUID) error {
+// RemoveDocument mocks base method.
+func (m *MockRepository) RemoveDocument(ctx context.Context, txID, documentID uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AttachDocument", ctx, txID, documentID)
+	ret := m.ctrl.Call(m, "RemoveDocument", ctx, txID, documentID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// AttachDocument indicates an expected call of AttachDocument.
-func (mr *MockRepositoryMockRecorder) AttachDocument(ctx, txID, documentID any) *gomock.Call {
+// RemoveDocument indicates an expected call of RemoveDocument.
+func (mr *MockRepositoryMockRecorder) RemoveDocument(ctx, txID, documentID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachDocument", reflect.TypeOf((*MockRepository)(nil).AttachDocument), ctx, txID, documentID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveDocument", reflect.TypeOf((*MockRepository)(nil).RemoveDocument), ctx, txID, documentID)
 }
 
-// DetachDocument mocks base method.
-func (m *MockRepository) DetachDocument(ctx context.Context, txID uuid.UUID) error {
+// UpdateDescriptions mocks base method.
+func (m *MockRepository) UpdateDescriptions(ctx context.Context, descriptions map[uuid.UUID]string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DetachDocument", ctx, txID)
+	ret := m.ctrl.Call(m, "UpdateDescriptions", ctx, descriptions)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DetachDocument indicates an expected call of DetachDocument.
-func (mr *MockRepositoryMockRecorder) DetachDocument(ctx, txID any) *gomock.Call {
+// UpdateDescriptions indicates an expected call of UpdateDescriptions.
+func (mr *MockRepositoryMockRecorder) UpdateDescriptions(ctx, descriptions any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachDocument", reflect.TypeOf((*MockRepository)(nil).DetachDocument), ctx, txID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDescriptions", reflect.TypeOf((*MockRepository)(nil).UpdateDescriptions), ctx, descriptions)
 }
 
 // UpdateStatus mocks base method.
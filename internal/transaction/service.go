@@ -14,11 +14,20 @@ type Repository interface {
 	GetTransaction(ctx context.Context, id uuid.UUID) (*Transaction, error)
 	UpdateTransaction(ctx context.Context, tx *Transaction) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status Status) error
+	// UpdateDescriptions sets the description of each transaction keyed by ID in a
+	// single DB transaction. Returns ErrNotFound, and changes nothing, if any ID is missing.
+	UpdateDescriptions(ctx context.Context, descriptions map[uuid.UUID]string) error
 
 	ListTransactions(ctx context.Context, filter ListFilter) ([]*Transaction, error)
 	DeleteTransaction(ctx context.Context, id uuid.UUID) error
-	AttachDocument(ctx context.Context, txID uuid.UUID, documentID uuid.UUID) error
-	DetachDocument(ctx context.Context, txID uuid.UUID) error
+	// ListDocuments returns the documents attached to a transaction, oldest first.
+	ListDocuments(ctx context.Context, txID uuid.UUID) ([]Document, error)
+	// AddDocument attaches a document in the given role. Attaching an invoice
+	// completes the transaction.
+	AddDocument(ctx context.Context, txID, documentID uuid.UUID, role DocumentRole) error
+	// RemoveDocument detaches a document. Removing the last invoice of a
+	// complete transaction sets it back to pending_invoice.
+	RemoveDocument(ctx context.Context, txID, documentID uuid.UUID) error
 
 	BeginImport(ctx context.Context, minDate, maxDate time.Time) (ImportTx, error)
 }
@@ -44,23 +53,36 @@ type CreateParams struct {
 	Status         Status
 	Description    string
 	RawDescription string
-	Date           time.Time
+	// NormalizedDescription is set by the importer; see Transaction.NormalizedDescription.
+	NormalizedDescription string
+	Category              string
+	Tags                  []string
+	Source                string
+	Date                  time.Time
+	MerchantID            *uuid.UUID
 }
 
 type ListFilter struct {
 	Status    *Status
 	StartDate *time.Time
 	EndDate   *time.Time
+	// DocumentID keeps the transactions the document is attached to.
+	DocumentID *uuid.UUID
 }
 
 func (s *Service) Create(ctx context.Context, params CreateParams) (*Transaction, error) {
 	tx := &Transaction{
-		Amount:         params.Amount,
-		Type:           params.Type,
-		Status:         params.Status,
-		Description:    params.Description,
-		RawDescription: params.RawDescription,
-		Date:           params.Date,
+		Amount:                params.Amount,
+		Type:                  params.Type,
+		Status:                params.Status,
+		Description:           params.Description,
+		RawDescription:        params.RawDescription,
+		NormalizedDescription: params.NormalizedDescription,
+		Category:              params.Category,
+		Tags:                  params.Tags,
+		Source:                params.Source,
+		Date:                  params.Date,
+		MerchantID:            params.MerchantID,
 	}
 	if err := s.repo.CreateTransaction(ctx, tx); err != nil {
 		return nil, err
@@ -69,12 +91,22 @@ func (s *Service) Create(ctx context.Context, params CreateParams) (*Transaction
 	return tx, nil
 }
 
-func (s *Service) AttachDocument(ctx context.Context, txID uuid.UUID, documentID uuid.UUID) error {
-	return s.repo.AttachDocument(ctx, txID, documentID)
+func (s *Service) ListDocuments(ctx context.Context, txID uuid.UUID) ([]Document, error) {
+	return s.repo.ListDocuments(ctx, txID)
 }
 
-func (s *Service) DetachDocument(ctx context.Context, txID uuid.UUID) error {
-	return s.repo.DetachDocument(ctx, txID)
+// AddDocument attaches a document to a transaction. The transaction counts as
+// complete once it has a document in the invoice role.
+func (s *Service) AddDocument(ctx context.Context, txID, documentID uuid.UUID, role DocumentRole) error {
+	if !role.Valid() {
+		return fmt.Errorf("%w: %q", ErrInvalidDocumentRole, role)
+	}
+
+	return s.repo.AddDocument(ctx, txID, documentID, role)
+}
+
+func (s *Service) RemoveDocument(ctx context.Context, txID, documentID uuid.UUID) error {
+	return s.repo.RemoveDocument(ctx, txID, documentID)
 }
 
 func (s *Service) List(ctx context.Context, filter ListFilter) ([]*Transaction, error) {
@@ -85,6 +117,15 @@ func (s *Service) Update(ctx context.Context, tx *Transaction) error {
 	return s.repo.UpdateTransaction(ctx, tx)
 }
 
+// UpdateDescriptions rewrites the descriptions of several transactions atomically.
+func (s *Service) UpdateDescriptions(ctx context.Context, descriptions map[uuid.UUID]string) error {
+	if len(descriptions) == 0 {
+		return nil
+	}
+
+	return s.repo.UpdateDescriptions(ctx, descriptions)
+}
+
 func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, status Status) error {
 	return s.repo.UpdateStatus(ctx, id, status)
 }
@@ -97,7 +138,6 @@ func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
 	return s.repo.DeleteTransaction(ctx, id)
 }
 
-
 type ImportResult struct {
 	Imported  []*Transaction
 	New       []CreateParams
@@ -127,23 +167,12 @@ func (s *Service) ImportBatch(ctx context.Context, params []CreateParams) (*Impo
 		return nil, fmt.Errorf("find duplicates: %w", err)
 	}
 
-	type dupKey struct {
-		Date           string
-		Amount         int64
-		Type           Type
-		RawDescription string
-	}
-
-	lookup := make(map[dupKey]*Transaction, len(duplicates))
+	lookup := make(map[DuplicateKey]*Transaction, len(duplicates))
 
 	for _, d := range duplicates {
-		k := dupKey{
-			Date:           d.Date.Format(time.DateOnly),
-			Amount:         d.Amount,
-			Type:           d.Type,
-			RawDescription: d.RawDescription,
+		for _, k := range d.DuplicateKeys() {
+			lookup[k] = d
 		}
-		lookup[k] = d
 	}
 
 	var newParams []CreateParams
@@ -151,14 +180,15 @@ func (s *Service) ImportBatch(ctx context.Context, params []CreateParams) (*Impo
 	var conflicts []Conflict
 
 	for _, p := range params {
-		k := dupKey{
-			Date:           p.Date.Format(time.DateOnly),
-			Amount:         p.Amount,
-			Type:           p.Type,
-			RawDescription: p.RawDescription,
+		var existing *Transaction
+
+		found := false
+		for _, k := range p.DuplicateKeys() {
+			if existing, found = lookup[k]; found {
+				break
+			}
 		}
 
-		existing, found := lookup[k]
 		if found {
 			conflicts = append(conflicts, Conflict{Incoming: p, Existing: existing})
 			continue
@@ -231,14 +261,59 @@ func paramsToTransactions(params []CreateParams) []*Transaction {
 	txs := make([]*Transaction, len(params))
 	for i, p := range params {
 		txs[i] = &Transaction{
-			Amount:         p.Amount,
-			Type:           p.Type,
-			Status:         p.Status,
-			Description:    p.Description,
-			RawDescription: p.RawDescription,
-			Date:           p.Date,
+			Amount:                p.Amount,
+			Type:                  p.Type,
+			Status:                p.Status,
+			Description:           p.Description,
+			RawDescription:        p.RawDescription,
+			NormalizedDescription: p.NormalizedDescription,
+			Category:              p.Category,
+			Tags:                  p.Tags,
+			Source:                p.Source,
+			Date:                  p.Date,
+			MerchantID:            p.MerchantID,
 		}
 	}
 
 	return txs
 }
+
+// DuplicateKey identifies transactions that are probably the same bank movement.
+// Description is either a raw or a normalized description, never a mix: a
+// normalized key only matches another normalized key.
+type DuplicateKey struct {
+	Date        string
+	Amount      int64
+	Type        Type
+	Normalized  bool
+	Description string
+}
+
+// DuplicateKeys returns the keys an incoming transaction is matched on: its raw
+// description, and its normalized description when the importer set one.
+// Existing transactions imported before normalization only have the raw key.
+func (p CreateParams) DuplicateKeys() []DuplicateKey {
+	return duplicateKeys(p.Date, p.Amount, p.Type, p.RawDescription, p.NormalizedDescription)
+}
+
+// DuplicateKeys is the Transaction counterpart of CreateParams.DuplicateKeys.
+func (t *Transaction) DuplicateKeys() []DuplicateKey {
+	return duplicateKeys(t.Date, t.Amount, t.Type, t.RawDescription, t.NormalizedDescription)
+}
+
+func duplicateKeys(date time.Time, amount int64, typ Type, raw, normalized string) []DuplicateKey {
+	base := DuplicateKey{Date: date.Format(time.DateOnly), Amount: amount, Type: typ}
+
+	rawKey := base
+	rawKey.Description = raw
+
+	if normalized == "" {
+		return []DuplicateKey{rawKey}
+	}
+
+	normKey := base
+	normKey.Normalized = true
+	normKey.Description = normalized
+
+	return []DuplicateKey{rawKey, normKey}
+}
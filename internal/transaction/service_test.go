@@ -284,3 +284,56 @@ func TestService_CreateBatch(t *testing.T) {
 	assert.Equal(t, int64(1000), txs[0].Amount)
 	assert.Equal(t, transaction.TypeExpense, txs[0].Type)
 }
+
+func TestService_AddDocument(t *testing.T) {
+	txID, docID := uuid.New(), uuid.New()
+
+	t.Run("ValidRole", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := transaction.NewMockRepository(ctrl)
+		repo.EXPECT().AddDocument(gomock.Any(), txID, docID, transaction.RoleReceipt).Return(nil)
+
+		svc := transaction.NewService(repo)
+		require.NoError(t, svc.AddDocument(context.Background(), txID, docID, transaction.RoleReceipt))
+	})
+
+	t.Run("InvalidRole", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := transaction.NewMockRepository(ctrl)
+
+		svc := transaction.NewService(repo)
+		err := svc.AddDocument(context.Background(), txID, docID, "warranty")
+		assert.ErrorIs(t, err, transaction.ErrInvalidDocumentRole)
+	})
+}
+
+func TestService_UpdateDescriptions(t *testing.T) {
+	t.Run("Applied", func(t *testing.T) {
+		descriptions := map[uuid.UUID]string{uuid.New(): "Groceries"}
+
+		ctrl := gomock.NewController(t)
+		repo := transaction.NewMockRepository(ctrl)
+		repo.EXPECT().UpdateDescriptions(gomock.Any(), descriptions).Return(nil)
+
+		svc := transaction.NewService(repo)
+		require.NoError(t, svc.UpdateDescriptions(context.Background(), descriptions))
+	})
+
+	t.Run("NothingSelected", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := transaction.NewMockRepository(ctrl)
+
+		svc := transaction.NewService(repo)
+		require.NoError(t, svc.UpdateDescriptions(context.Background(), map[uuid.UUID]string{}))
+	})
+
+	t.Run("MissingTransaction", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := transaction.NewMockRepository(ctrl)
+		repo.EXPECT().UpdateDescriptions(gomock.Any(), gomock.Any()).Return(transaction.ErrNotFound)
+
+		svc := transaction.NewService(repo)
+		err := svc.UpdateDescriptions(context.Background(), map[uuid.UUID]string{uuid.New(): "Groceries"})
+		assert.ErrorIs(t, err, transaction.ErrNotFound)
+	})
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"hash/fnv"
 	"time"
@@ -27,19 +28,25 @@ type scanner interface {
 }
 
 // scanTransaction reads a transaction row and returns a populated Transaction.
-// Expected column order: id, amount, type, status, description, raw_description, date,
-// document_id, doc_filename, doc_mime_type, created_at, updated_at, deleted_at
+// Expected column order: id, amount, type, status, description, raw_description,
+// normalized_description, category, tags, source, date, document_id, doc_filename, doc_mime_type,
+// doc_role, doc_attached_at, doc_count, merchant_id, merchant_name, created_at, updated_at, deleted_at
 func scanTransaction(s scanner) (*transaction.Transaction, error) {
 	var tx transaction.Transaction
 
 	var typeStr, statusStr string
 	var rawDesc sql.NullString
+	var tagsJSON []byte
 	var docID *uuid.UUID
-	var docFilename, docMIMEType sql.NullString
+	var docFilename, docMIMEType, docRole, merchantName sql.NullString
+	var docAttachedAt sql.NullTime
+	var docCount sql.NullInt64
 
 	if err := s.Scan(
-		&tx.ID, &tx.Amount, &typeStr, &statusStr, &tx.Description, &rawDesc, &tx.Date,
-		&docID, &docFilename, &docMIMEType,
+		&tx.ID, &tx.Amount, &typeStr, &statusStr, &tx.Description, &rawDesc,
+		&tx.NormalizedDescription, &tx.Category, &tagsJSON, &tx.Source, &tx.Date,
+		&docID, &docFilename, &docMIMEType, &docRole, &docAttachedAt, &docCount,
+		&tx.MerchantID, &merchantName,
 		&tx.CreatedAt, &tx.UpdatedAt, &tx.DeletedAt,
 	); err != nil {
 		return nil, err
@@ -48,13 +55,22 @@ func scanTransaction(s scanner) (*transaction.Transaction, error) {
 	tx.Type = transaction.Type(typeStr)
 	tx.Status = transaction.Status(statusStr)
 	tx.RawDescription = rawDesc.String
+	tx.MerchantName = merchantName.String
+
+	if err := json.Unmarshal(tagsJSON, &tx.Tags); err != nil {
+		return nil, fmt.Errorf("decoding tags: %w", err)
+	}
+
 	tx.DocumentID = docID
+	tx.DocumentCount = int(docCount.Int64)
 
 	if docID != nil && docFilename.Valid {
 		tx.Document = &transaction.Document{
-			ID:       *docID,
-			Filename: docFilename.String,
-			MIMEType: docMIMEType.String,
+			ID:         *docID,
+			Filename:   docFilename.String,
+			MIMEType:   docMIMEType.String,
+			Role:       transaction.DocumentRole(docRole.String),
+			AttachedAt: docAttachedAt.Time,
 		}
 	}
 
@@ -62,26 +78,46 @@ func scanTransaction(s scanner) (*transaction.Transaction, error) {
 }
 
 const selectTransactionColumns = `
-	t.id, t.amount, t.type, t.status, t.description, t.raw_description, t.date,
-	t.document_id, d.filename AS doc_filename, d.mime_type AS doc_mime_type,
+	t.id, t.amount, t.type, t.status, t.description, t.raw_description,
+	t.normalized_description, t.category, array_to_json(t.tags) AS tags, t.source, t.date,
+	pd.document_id, d.filename AS doc_filename, d.mime_type AS doc_mime_type,
+	pd.role AS doc_role, pd.created_at AS doc_attached_at, pd.doc_count,
+	t.merchant_id, mr.name AS merchant_name,
 	t.created_at, t.updated_at, t.deleted_at
 `
 
 const transactionJoin = `
 	FROM transactions t
-	LEFT JOIN documents d ON t.document_id = d.id
+	LEFT JOIN LATERAL (
+		SELECT td.document_id, td.role, td.created_at, COUNT(*) OVER () AS doc_count
+		FROM transaction_documents td
+		WHERE td.transaction_id = t.id
+		ORDER BY td.role = 'invoice' DESC, td.created_at, td.document_id
+		LIMIT 1
+	) pd ON TRUE
+	LEFT JOIN documents d ON pd.document_id = d.id
+	LEFT JOIN merchants mr ON t.merchant_id = mr.id AND mr.user_id = t.user_id
 `
 
+// tagsArg converts nil tags to an empty slice so the NOT NULL tags column is satisfied.
+func tagsArg(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
 func (s *Store) CreateTransaction(ctx context.Context, tx *transaction.Transaction) error {
 	query := `
-		INSERT INTO transactions (amount, type, status, description, raw_description, date, document_id, user_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		INSERT INTO transactions (amount, type, status, description, raw_description, normalized_description, category, tags, source, date, merchant_id, user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
 		RETURNING id, created_at, updated_at
 	`
 
 	err := s.db.QueryRowContext(ctx, query,
 		tx.Amount, tx.Type, tx.Status, tx.Description, tx.RawDescription,
-		tx.Date, tx.DocumentID, auth.UserID(ctx),
+		tx.NormalizedDescription, tx.Category, tagsArg(tx.Tags), tx.Source, tx.Date, tx.MerchantID, auth.UserID(ctx),
 	).Scan(&tx.ID, &tx.CreatedAt, &tx.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("creating transaction: %w", err)
@@ -131,6 +167,13 @@ func (s *Store) ListTransactions(ctx context.Context, filter transaction.ListFil
 		argIdx++
 	}
 
+	if filter.DocumentID != nil {
+		query += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM transaction_documents td WHERE td.transaction_id = t.id AND td.document_id = $%d)`, argIdx)
+		args = append(args, *filter.DocumentID)
+		argIdx++
+	}
+
 	query += " ORDER BY t.date ASC"
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -156,12 +199,13 @@ func (s *Store) ListTransactions(ctx context.Context, filter transaction.ListFil
 func (s *Store) UpdateTransaction(ctx context.Context, tx *transaction.Transaction) error {
 	query := `
 		UPDATE transactions
-		SET amount = $1, type = $2, status = $3, description = $4, updated_at = NOW()
-		WHERE id = $5 AND user_id = $6 AND deleted_at IS NULL
+		SET amount = $1, type = $2, status = $3, description = $4, category = $5, tags = $6,
+		    merchant_id = $7, updated_at = NOW()
+		WHERE id = $8 AND user_id = $9 AND deleted_at IS NULL
 	`
 
 	result, err := s.db.ExecContext(ctx, query,
-		tx.Amount, tx.Type, tx.Status, tx.Description,
+		tx.Amount, tx.Type, tx.Status, tx.Description, tx.Category, tagsArg(tx.Tags), tx.MerchantID,
 		tx.ID, auth.UserID(ctx),
 	)
 	if err != nil {
@@ -194,6 +238,43 @@ func (s *Store) UpdateStatus(ctx context.Context, id uuid.UUID, status transacti
 	return nil
 }
 
+func (s *Store) UpdateDescriptions(ctx context.Context, descriptions map[uuid.UUID]string) error {
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer dbTx.Rollback()
+
+	stmt, err := dbTx.PrepareContext(ctx, `
+		UPDATE transactions
+		SET description = $1, updated_at = NOW()
+		WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("preparing description update: %w", err)
+	}
+	defer stmt.Close()
+
+	userID := auth.UserID(ctx)
+
+	for id, description := range descriptions {
+		result, err := stmt.ExecContext(ctx, description, id, userID)
+		if err != nil {
+			return fmt.Errorf("updating description of %s: %w", id, err)
+		}
+
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("%w: %s", transaction.ErrNotFound, id)
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("committing description updates: %w", err)
+	}
+
+	return nil
+}
+
 func (s *Store) DeleteTransaction(ctx context.Context, id uuid.UUID) error {
 	query := `
 		UPDATE transactions
@@ -213,51 +294,150 @@ func (s *Store) DeleteTransaction(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// AttachDocument links a document to a transaction and sets its status to complete.
-// Returns ErrDocumentAlreadyAttached if the transaction already has a document,
-// ensuring the check-then-attach is atomic at the DB level.
-func (s *Store) AttachDocument(ctx context.Context, txID uuid.UUID, documentID uuid.UUID) error {
+// ListDocuments returns the documents attached to a transaction, oldest first.
+func (s *Store) ListDocuments(ctx context.Context, txID uuid.UUID) ([]transaction.Document, error) {
+	if err := s.checkTransaction(ctx, txID); err != nil {
+		return nil, err
+	}
+
 	query := `
-		UPDATE transactions
-		SET document_id = $1, status = 'complete', updated_at = NOW()
-		WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL AND document_id IS NULL
+		SELECT d.id, d.filename, d.mime_type, td.role, td.created_at
+		FROM transaction_documents td
+		JOIN documents d ON d.id = td.document_id
+		WHERE td.transaction_id = $1
+		ORDER BY td.created_at, d.id
 	`
 
-	result, err := s.db.ExecContext(ctx, query, documentID, txID, auth.UserID(ctx))
+	rows, err := s.db.QueryContext(ctx, query, txID)
 	if err != nil {
-		return fmt.Errorf("attaching document: %w", err)
+		return nil, fmt.Errorf("listing transaction documents: %w", err)
 	}
+	defer rows.Close()
 
-	if n, _ := result.RowsAffected(); n == 0 {
-		// Distinguish "already has document" from "not found".
-		var exists bool
-		checkQuery := `SELECT EXISTS(SELECT 1 FROM transactions WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)`
-		if err := s.db.QueryRowContext(ctx, checkQuery, txID, auth.UserID(ctx)).Scan(&exists); err != nil {
-			return fmt.Errorf("checking transaction: %w", err)
+	docs := []transaction.Document{}
+
+	for rows.Next() {
+		var d transaction.Document
+		if err := rows.Scan(&d.ID, &d.Filename, &d.MIMEType, &d.Role, &d.AttachedAt); err != nil {
+			return nil, fmt.Errorf("scanning transaction document: %w", err)
 		}
-		if !exists {
+
+		docs = append(docs, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating transaction documents: %w", err)
+	}
+
+	return docs, nil
+}
+
+// AddDocument links a document to a transaction in the given role and updates
+// the status in the same DB transaction. Returns ErrDocumentAlreadyAttached if
+// the document is already linked to it.
+func (s *Store) AddDocument(ctx context.Context, txID, documentID uuid.UUID, role transaction.DocumentRole) error {
+	return s.changeDocuments(ctx, txID, func(dbTx *sql.Tx) error {
+		query := `
+			INSERT INTO transaction_documents (transaction_id, document_id, role)
+			SELECT $1, d.id, $3 FROM documents d WHERE d.id = $2 AND d.user_id = $4
+			ON CONFLICT (transaction_id, document_id) DO NOTHING
+		`
+
+		result, err := dbTx.ExecContext(ctx, query, txID, documentID, role, auth.UserID(ctx))
+		if err != nil {
+			return fmt.Errorf("attaching document: %w", err)
+		}
+
+		if n, _ := result.RowsAffected(); n == 0 {
+			// Either the link exists or the document is not the user's.
+			var linked bool
+			checkQuery := `SELECT EXISTS(SELECT 1 FROM transaction_documents WHERE transaction_id = $1 AND document_id = $2)`
+			if err := dbTx.QueryRowContext(ctx, checkQuery, txID, documentID).Scan(&linked); err != nil {
+				return fmt.Errorf("checking document link: %w", err)
+			}
+
+			if linked {
+				return transaction.ErrDocumentAlreadyAttached
+			}
+
 			return transaction.ErrNotFound
 		}
-		return transaction.ErrDocumentAlreadyAttached
-	}
 
-	return nil
+		return nil
+	})
 }
 
-// DetachDocument clears the document link from a transaction and resets its status to pending_invoice.
-func (s *Store) DetachDocument(ctx context.Context, txID uuid.UUID) error {
-	query := `
-		UPDATE transactions
-		SET document_id = NULL, status = 'pending_invoice', updated_at = NOW()
-		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
-	`
+// RemoveDocument unlinks a document from a transaction and updates the status
+// in the same DB transaction. Returns ErrDocumentNotAttached if it was not linked.
+func (s *Store) RemoveDocument(ctx context.Context, txID, documentID uuid.UUID) error {
+	return s.changeDocuments(ctx, txID, func(dbTx *sql.Tx) error {
+		query := `DELETE FROM transaction_documents WHERE transaction_id = $1 AND document_id = $2`
+
+		result, err := dbTx.ExecContext(ctx, query, txID, documentID)
+		if err != nil {
+			return fmt.Errorf("detaching document: %w", err)
+		}
 
-	result, err := s.db.ExecContext(ctx, query, txID, auth.UserID(ctx))
+		if n, _ := result.RowsAffected(); n == 0 {
+			return transaction.ErrDocumentNotAttached
+		}
+
+		return nil
+	})
+}
+
+// changeDocuments runs change on the transaction's document links and then
+// derives the status from them: a transaction with an invoice is complete, and
+// a complete one without an invoice goes back to pending_invoice.
+func (s *Store) changeDocuments(ctx context.Context, txID uuid.UUID, change func(*sql.Tx) error) error {
+	dbTx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("detaching document: %w", err)
+		return fmt.Errorf("beginning transaction: %w", err)
 	}
+	defer dbTx.Rollback() //nolint:errcheck
 
-	if n, _ := result.RowsAffected(); n == 0 {
+	// Lock the row so concurrent changes derive the status from the same links.
+	lockQuery := `SELECT id FROM transactions WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL FOR UPDATE`
+	if err := dbTx.QueryRowContext(ctx, lockQuery, txID, auth.UserID(ctx)).Scan(new(uuid.UUID)); err != nil {
+		if err == sql.ErrNoRows {
+			return transaction.ErrNotFound
+		}
+
+		return fmt.Errorf("locking transaction: %w", err)
+	}
+
+	if err := change(dbTx); err != nil {
+		return err
+	}
+
+	statusQuery := `
+		UPDATE transactions t
+		SET status = CASE
+				WHEN EXISTS (SELECT 1 FROM transaction_documents td
+				             WHERE td.transaction_id = t.id AND td.role = 'invoice') THEN 'complete'
+				WHEN t.status = 'complete' THEN 'pending_invoice'
+				ELSE t.status
+			END,
+			updated_at = NOW()
+		WHERE t.id = $1
+	`
+	if _, err := dbTx.ExecContext(ctx, statusQuery, txID); err != nil {
+		return fmt.Errorf("updating status: %w", err)
+	}
+
+	return dbTx.Commit()
+}
+
+// checkTransaction returns ErrNotFound unless the user has a live transaction with the ID.
+func (s *Store) checkTransaction(ctx context.Context, txID uuid.UUID) error {
+	var exists bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM transactions WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL)`
+	if err := s.db.QueryRowContext(ctx, query, txID, auth.UserID(ctx)).Scan(&exists); err != nil {
+		return fmt.Errorf("checking transaction: %w", err)
+	}
+
+	if !exists {
 		return transaction.ErrNotFound
 	}
 
@@ -300,16 +480,9 @@ func (itx *importTx) FindDuplicates(ctx context.Context, params []transaction.Cr
 		return nil, nil
 	}
 
-	type lookupKey struct {
-		Date           string
-		Amount         int64
-		Type           transaction.Type
-		RawDescription string
-	}
-
 	minDate := params[0].Date
 	maxDate := params[0].Date
-	keySet := make(map[lookupKey]struct{}, len(params))
+	keySet := make(map[transaction.DuplicateKey]struct{}, len(params))
 
 	for _, p := range params {
 		if p.Date.Before(minDate) {
@@ -320,12 +493,9 @@ func (itx *importTx) FindDuplicates(ctx context.Context, params []transaction.Cr
 			maxDate = p.Date
 		}
 
-		keySet[lookupKey{
-			Date:           p.Date.Format("2006-01-02"),
-			Amount:         p.Amount,
-			Type:           p.Type,
-			RawDescription: p.RawDescription,
-		}] = struct{}{}
+		for _, k := range p.DuplicateKeys() {
+			keySet[k] = struct{}{}
+		}
 	}
 
 	query := `SELECT ` + selectTransactionColumns + transactionJoin +
@@ -346,15 +516,11 @@ func (itx *importTx) FindDuplicates(ctx context.Context, params []transaction.Cr
 			return nil, fmt.Errorf("scanning transaction: %w", err)
 		}
 
-		k := lookupKey{
-			Date:           tx.Date.Format("2006-01-02"),
-			Amount:         tx.Amount,
-			Type:           tx.Type,
-			RawDescription: tx.RawDescription,
-		}
-
-		if _, found := keySet[k]; found {
-			duplicates = append(duplicates, tx)
+		for _, k := range tx.DuplicateKeys() {
+			if _, found := keySet[k]; found {
+				duplicates = append(duplicates, tx)
+				break
+			}
 		}
 	}
 
@@ -367,8 +533,8 @@ func (itx *importTx) FindDuplicates(ctx context.Context, params []transaction.Cr
 
 func (itx *importTx) CreateTransactions(ctx context.Context, txs []*transaction.Transaction) error {
 	query := `
-		INSERT INTO transactions (amount, type, status, description, raw_description, date, document_id, user_id, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		INSERT INTO transactions (amount, type, status, description, raw_description, normalized_description, category, tags, source, date, merchant_id, user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
 		RETURNING id, created_at, updated_at
 	`
 
@@ -377,7 +543,7 @@ func (itx *importTx) CreateTransactions(ctx context.Context, txs []*transaction.
 	for _, tx := range txs {
 		err := itx.tx.QueryRowContext(ctx, query,
 			tx.Amount, tx.Type, tx.Status, tx.Description, tx.RawDescription,
-			tx.Date, tx.DocumentID, userID,
+			tx.NormalizedDescription, tx.Category, tagsArg(tx.Tags), tx.Source, tx.Date, tx.MerchantID, userID,
 		).Scan(&tx.ID, &tx.CreatedAt, &tx.UpdatedAt)
 		if err != nil {
 			return fmt.Errorf("creating transaction: %w", err)
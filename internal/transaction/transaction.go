@@ -24,6 +24,26 @@ const (
 	StatusNoInvoice      Status = "no_invoice"
 )
 
+// DocumentRole is what a document attached to a transaction is for.
+type DocumentRole string
+
+const (
+	RoleInvoice    DocumentRole = "invoice"
+	RoleReceipt    DocumentRole = "receipt"
+	RoleCreditNote DocumentRole = "credit_note"
+	RoleOther      DocumentRole = "other"
+)
+
+// Valid reports whether r is one of the known roles.
+func (r DocumentRole) Valid() bool {
+	switch r {
+	case RoleInvoice, RoleReceipt, RoleCreditNote, RoleOther:
+		return true
+	default:
+		return false
+	}
+}
+
 // Transaction represents a financial transaction.
 type Transaction struct {
 	ID             uuid.UUID
@@ -32,18 +52,48 @@ type Transaction struct {
 	Status         Status
 	Description    string
 	RawDescription string
-	Date           time.Time
-	DocumentID     *uuid.UUID
-	Document       *Document // Loaded via JOIN; contains metadata only (no download URL)
-	CreatedAt      time.Time
-	UpdatedAt      *time.Time
-	DeletedAt      *time.Time
+	// NormalizedDescription is RawDescription with statement noise (card masks,
+	// dates, references) removed; empty for manual and legacy transactions.
+	NormalizedDescription string
+	Category              string
+	Tags                  []string
+	Source                string // origin of the transaction, e.g. the importer bank id; empty if manual
+	Date                  time.Time
+	// DocumentID and Document are the primary attached document: the oldest
+	// invoice, or the oldest document of any role if there is no invoice.
+	DocumentID *uuid.UUID
+	Document   *Document // Loaded via JOIN; contains metadata only (no download URL)
+	// DocumentCount is the number of documents attached, in any role.
+	DocumentCount int
+	MerchantID    *uuid.UUID
+	MerchantName  string // Loaded via JOIN; empty when no merchant is linked
+	CreatedAt     time.Time
+	UpdatedAt     *time.Time
+	DeletedAt     *time.Time
+}
+
+// MatchDescription returns the text description mappings are matched against:
+// the normalized description when present, the raw description otherwise.
+func (t *Transaction) MatchDescription() string {
+	if t.NormalizedDescription != "" {
+		return t.NormalizedDescription
+	}
+
+	return t.RawDescription
+}
+
+// HasInvoice reports whether an invoice is attached. The primary document is
+// an invoice whenever there is one, so only it needs checking.
+func (t *Transaction) HasInvoice() bool {
+	return t.Document != nil && t.Document.Role == RoleInvoice
 }
 
 // Document is the document metadata attached to a transaction.
 // Content is retrieved via the document service.
 type Document struct {
-	ID       uuid.UUID
-	Filename string
-	MIMEType string
+	ID         uuid.UUID
+	Filename   string
+	MIMEType   string
+	Role       DocumentRole
+	AttachedAt time.Time
 }
@@ -0,0 +1,37 @@
+-- +goose Up
+
+-- Fields that matching rules can set on transactions.
+-- source records where a transaction came from (e.g. the importer bank id);
+-- it is empty for manually created transactions.
+ALTER TABLE transactions
+    ADD COLUMN category TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN tags     TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN source   TEXT   NOT NULL DEFAULT '';
+
+CREATE INDEX idx_transactions_category ON transactions(category);
+
+-- User-defined rules evaluated on import (and on demand) in priority order.
+-- conditions and actions are JSON objects; see matching.Conditions / matching.Actions.
+CREATE TABLE matching_rules (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    priority   INTEGER NOT NULL DEFAULT 100,
+    enabled    BOOLEAN NOT NULL DEFAULT true,
+    conditions JSONB NOT NULL DEFAULT '{}',
+    actions    JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_matching_rules_user_id ON matching_rules(user_id);
+
+-- +goose Down
+DROP TABLE matching_rules;
+
+DROP INDEX idx_transactions_category;
+
+ALTER TABLE transactions
+    DROP COLUMN source,
+    DROP COLUMN tags,
+    DROP COLUMN category;
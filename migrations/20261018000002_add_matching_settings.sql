@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- Per-user matching preferences. A missing row means the defaults apply.
+-- auto_learn creates a mapping straight away when a user renames an imported
+-- transaction; otherwise the mapping is only proposed.
+CREATE TABLE matching_settings (
+    user_id    UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    auto_learn BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE matching_settings;
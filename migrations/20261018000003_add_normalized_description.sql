@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- normalized_description is raw_description after the source's normalizer chain
+-- (see matching.Normalize). It is what description mappings are matched against.
+-- Rows imported before this migration keep '' and fall back to raw_description.
+ALTER TABLE transactions
+    ADD COLUMN normalized_description TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE transactions
+    DROP COLUMN normalized_description;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE description_mappings
+    ADD COLUMN match_count INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN last_matched_at TIMESTAMPTZ;
+
+-- The mapping that last set each transaction's description.
+CREATE TABLE mapping_matches (
+    transaction_id UUID PRIMARY KEY REFERENCES transactions(id) ON DELETE CASCADE,
+    mapping_id     UUID NOT NULL REFERENCES description_mappings(id) ON DELETE CASCADE,
+    matched_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_mapping_matches_mapping_id ON mapping_matches(mapping_id);
+
+-- +goose Down
+DROP TABLE mapping_matches;
+
+ALTER TABLE description_mappings
+    DROP COLUMN last_matched_at,
+    DROP COLUMN match_count;
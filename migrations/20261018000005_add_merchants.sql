@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE merchants (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id          UUID NOT NULL REFERENCES users(id),
+    name             TEXT NOT NULL,
+    nif              TEXT NOT NULL DEFAULT '',
+    default_category TEXT NOT NULL DEFAULT '',
+    website          TEXT NOT NULL DEFAULT '',
+    logo_url         TEXT NOT NULL DEFAULT '',
+    -- When false, transactions assigned to the merchant by rules default to no_invoice.
+    requires_invoice BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT merchants_user_name_unique UNIQUE (user_id, name)
+);
+
+ALTER TABLE transactions
+    ADD COLUMN merchant_id UUID REFERENCES merchants(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_transactions_merchant_id ON transactions(merchant_id);
+
+-- +goose Down
+DROP INDEX idx_transactions_merchant_id;
+
+ALTER TABLE transactions
+    DROP COLUMN merchant_id;
+
+DROP TABLE merchants;
@@ -0,0 +1,13 @@
+-- +goose Up
+
+-- size_bytes and sha256 describe the uploaded content. They are computed while
+-- the upload is spooled; documents linked by URL or uploaded before this
+-- migration keep 0 and ''.
+ALTER TABLE documents
+    ADD COLUMN size_bytes BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN sha256     TEXT   NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE documents
+    DROP COLUMN sha256,
+    DROP COLUMN size_bytes;
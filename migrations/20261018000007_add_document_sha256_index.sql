@@ -0,0 +1,8 @@
+-- +goose Up
+
+-- A user stores each distinct content once; uploads of the same bytes reuse the
+-- existing document. Documents without a known hash ('') are exempt.
+CREATE UNIQUE INDEX idx_documents_user_sha256 ON documents(user_id, sha256) WHERE sha256 <> '';
+
+-- +goose Down
+DROP INDEX idx_documents_user_sha256;
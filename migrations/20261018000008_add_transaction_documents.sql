@@ -0,0 +1,38 @@
+-- +goose Up
+
+-- A transaction can have several documents (invoice, receipt, credit note, …),
+-- and a deduplicated document can belong to several transactions.
+CREATE TABLE transaction_documents (
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    document_id    UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    role           TEXT NOT NULL DEFAULT 'invoice'
+        CHECK (role IN ('invoice', 'receipt', 'credit_note', 'other')),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (transaction_id, document_id)
+);
+
+CREATE INDEX idx_transaction_documents_document_id ON transaction_documents(document_id);
+
+-- Existing single documents were all attached as the transaction's invoice.
+INSERT INTO transaction_documents (transaction_id, document_id, role, created_at)
+SELECT id, document_id, 'invoice', COALESCE(updated_at, created_at)
+FROM transactions
+WHERE document_id IS NOT NULL;
+
+ALTER TABLE transactions DROP COLUMN document_id;
+
+-- +goose Down
+ALTER TABLE transactions
+    ADD COLUMN document_id UUID REFERENCES documents(id);
+
+-- Keep one document per transaction, preferring the oldest invoice.
+UPDATE transactions t
+SET document_id = (
+    SELECT td.document_id
+    FROM transaction_documents td
+    WHERE td.transaction_id = t.id
+    ORDER BY td.role = 'invoice' DESC, td.created_at, td.document_id
+    LIMIT 1
+);
+
+DROP TABLE transaction_documents;
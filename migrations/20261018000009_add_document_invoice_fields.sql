@@ -0,0 +1,14 @@
+-- +goose Up
+
+-- Invoice fields describe what a document bills: they let Finny propose the
+-- transaction an inbox document belongs to. NULL and '' mean unknown.
+ALTER TABLE documents
+    ADD COLUMN invoice_total BIGINT,
+    ADD COLUMN invoice_date DATE,
+    ADD COLUMN issuer_name TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE documents
+    DROP COLUMN issuer_name,
+    DROP COLUMN invoice_date,
+    DROP COLUMN invoice_total;
@@ -0,0 +1,16 @@
+-- +goose Up
+
+-- Fields parsed from the text of uploaded PDFs, alongside the invoice fields
+-- they help fill. text_content keeps the extracted text for search.
+ALTER TABLE documents
+    ADD COLUMN issuer_nif TEXT NOT NULL DEFAULT '',
+    ADD COLUMN invoice_number TEXT NOT NULL DEFAULT '',
+    ADD COLUMN vat_total BIGINT,
+    ADD COLUMN text_content TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE documents
+    DROP COLUMN text_content,
+    DROP COLUMN vat_total,
+    DROP COLUMN invoice_number,
+    DROP COLUMN issuer_nif;
@@ -0,0 +1,11 @@
+-- +goose Up
+
+-- Fields only the invoice QR code carries. '' means unknown.
+ALTER TABLE documents
+    ADD COLUMN buyer_nif TEXT NOT NULL DEFAULT '',
+    ADD COLUMN atcud TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE documents
+    DROP COLUMN atcud,
+    DROP COLUMN buyer_nif;
@@ -0,0 +1,20 @@
+-- +goose Up
+
+-- Moves of every document off a backend onto another, one per drained backend.
+-- finished_at stays NULL until the backend holds no documents.
+CREATE TABLE backend_drains (
+    backend_id  UUID PRIMARY KEY REFERENCES document_backends(id) ON DELETE CASCADE,
+    target_id   UUID NOT NULL REFERENCES document_backends(id) ON DELETE CASCADE,
+    total       INTEGER NOT NULL,
+    moved       INTEGER NOT NULL DEFAULT 0,
+    failed      INTEGER NOT NULL DEFAULT 0,
+    last_error  TEXT NOT NULL DEFAULT '',
+    started_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_document_locations_backend_id ON document_locations(backend_id);
+
+-- +goose Down
+DROP INDEX idx_document_locations_backend_id;
+DROP TABLE backend_drains;
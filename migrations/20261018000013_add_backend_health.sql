@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- Latest backend health check. health_checked_at is NULL until the first
+-- check; health_error is '' when it passed.
+ALTER TABLE document_backends
+    ADD COLUMN health_checked_at TIMESTAMPTZ,
+    ADD COLUMN health_error TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE document_backends
+    DROP COLUMN health_error,
+    DROP COLUMN health_checked_at;
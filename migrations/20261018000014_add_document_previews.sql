@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- Small JPEG renderings of documents: the first page of a PDF or a scaled-down
+-- image. Generated on upload, or on first request for older documents.
+CREATE TABLE document_previews (
+    document_id UUID PRIMARY KEY REFERENCES documents(id) ON DELETE CASCADE,
+    data        BYTEA NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE document_previews;
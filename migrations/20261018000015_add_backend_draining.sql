@@ -0,0 +1,10 @@
+-- +goose Up
+
+-- When a running drain last marked the backend, so only one process drains
+-- it at a time. NULL when no drain holds it.
+ALTER TABLE document_backends
+    ADD COLUMN draining_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE document_backends
+    DROP COLUMN draining_at;
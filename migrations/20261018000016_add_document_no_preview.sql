@@ -0,0 +1,10 @@
+-- +goose Up
+
+-- Set once a document's content failed to render as a preview, so it is not
+-- downloaded again on every request.
+ALTER TABLE documents
+    ADD COLUMN no_preview BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE documents
+    DROP COLUMN no_preview;